@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/clotilde/carplay-assistant/internal/budget"
+	"github.com/clotilde/carplay-assistant/internal/logging"
+	"github.com/clotilde/carplay-assistant/internal/stt"
+	"github.com/clotilde/carplay-assistant/internal/validator"
+)
+
+// transcriptionTimeout bounds speech-to-text so most of the request budget is left for the answer
+const transcriptionTimeout = 10 * time.Second
+
+// maxMultipartMemory is the in-memory limit when parsing multipart uploads (the body is capped at validator.MaxAudioBodySize)
+const maxMultipartMemory = 4 << 20
+
+// handleAudio answers a spoken question: the audio is transcribed and then goes through the
+// same pipeline as /chat (prompt injection checks, routing, generation, formatting)
+//
+// The audio (m4a, wav or ogg) is sent either as the raw request body, with optional
+// session_id/format/audio_response query parameters, or as multipart/form-data with an "audio"
+// (or "file") field and optional session_id/format/audio_response fields
+func (s *Server) handleAudio(w http.ResponseWriter, r *http.Request) {
+	// Handle CORS preflight
+	if r.Method == http.MethodOptions {
+		setCORSHeaders(w)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Start timing before transcription so the whole request shares the request budget
+	startTime := time.Now()
+	b := newRequestBudget(startTime)
+	r = r.WithContext(budget.NewContext(r.Context(), b))
+
+	requestID := logging.GetRequestID(r.Context())
+	if requestID == "" {
+		requestID = logging.GenerateRequestID()
+	}
+	w.Header().Set("X-Request-ID", requestID)
+
+	if s.transcriber == nil {
+		respondError(w, "Speech-to-text not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	// The audio is only buffered here, once the caller is authenticated
+	r.Body = http.MaxBytesReader(w, r.Body, validator.MaxAudioBodySize)
+	audio, req, err := readAudioRequest(r)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		s.logRequest(requestID, r, "", "", "", "", time.Since(startTime), "error", "Audio too large")
+		respondError(w, "Audio too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		s.logRequest(requestID, r, "", "", "", "", time.Since(startTime), "error", "Invalid audio upload: "+err.Error())
+		respondError(w, "Invalid audio upload", http.StatusBadRequest)
+		return
+	}
+
+	format, ok := stt.DetectFormat(audio)
+	if !ok {
+		s.logRequest(requestID, r, "", "", "", "", time.Since(startTime), "error", "Unsupported audio format")
+		respondError(w, "Unsupported audio format (use m4a, wav or ogg)", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.Allot(budget.Transcription, transcriptionTimeout))
+	stop := b.Track(budget.Transcription)
+	transcript, err := s.transcriber.Transcribe(ctx, audio, format)
+	stop()
+	cancel()
+	if err != nil {
+		log.Printf("[%s] Transcription error (%s): %v", requestID, s.transcriber.Name(), err)
+		s.logRequest(requestID, r, "", "", "", "", time.Since(startTime), "error", "Transcription failed: "+err.Error())
+		respondError(w, "Failed to transcribe audio", http.StatusBadGateway)
+		return
+	}
+
+	log.Printf("[%s] Audio transcribed: format=%s, size=%d, transcript_length=%d, time=%v",
+		requestID, format, len(audio), len(transcript), time.Since(startTime))
+
+	if transcript == "" {
+		// Silence or noise: ask the driver to repeat instead of failing
+		respondSuccess(w, ChatResponse{Response: "Não consegui entender o áudio. Pode repetir?", SessionID: req.SessionID})
+		return
+	}
+
+	if len(transcript) > validator.MaxMessageLength {
+		s.logRequest(requestID, r, "", "", "", "", time.Since(startTime), "error", "Transcript too long")
+		respondError(w, "Message too long", http.StatusBadRequest)
+		return
+	}
+
+	req.Message = transcript
+	s.processChat(w, r, requestID, b, req, transcript)
+}
+
+// readAudioRequest extracts the audio bytes and chat options from a raw or multipart upload
+func readAudioRequest(r *http.Request) ([]byte, ChatRequest, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		audio, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, ChatRequest{}, err
+		}
+		query := r.URL.Query()
+		return audio, ChatRequest{SessionID: query.Get("session_id"), Format: query.Get("format"), AudioResponse: query.Get("audio_response")}, nil
+	}
+
+	if err := r.ParseMultipartForm(maxMultipartMemory); err != nil {
+		return nil, ChatRequest{}, err
+	}
+	req := ChatRequest{SessionID: r.FormValue("session_id"), Format: r.FormValue("format"), AudioResponse: r.FormValue("audio_response")}
+
+	for _, field := range []string{"audio", "file"} {
+		file, _, err := r.FormFile(field)
+		if err != nil {
+			continue
+		}
+		defer file.Close()
+		audio, err := io.ReadAll(file)
+		return audio, req, err
+	}
+	return nil, req, fmt.Errorf("missing audio field")
+}
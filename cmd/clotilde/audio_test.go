@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clotilde/carplay-assistant/internal/logging"
+	"github.com/clotilde/carplay-assistant/internal/stt"
+	"github.com/clotilde/carplay-assistant/internal/validator"
+)
+
+// fakeTranscriber returns a fixed transcript and records the detected format
+type fakeTranscriber struct {
+	text   string
+	format string
+}
+
+func (f *fakeTranscriber) Name() string { return "fake" }
+func (f *fakeTranscriber) Transcribe(ctx context.Context, audio []byte, format string) (string, error) {
+	f.format = format
+	return f.text, nil
+}
+
+var fakeWAV = []byte("RIFF\x24\x00\x00\x00WAVEfmt \x10\x00\x00\x00")
+
+func TestHandleAudio_RawBody(t *testing.T) {
+	useLocalTestModel(t, localAnswer("Vai chover à tarde."))
+	transcriber := &fakeTranscriber{text: "Vai chover hoje?"}
+	server := &Server{logger: logging.GetLogger(), transcriber: transcriber}
+
+	req := httptest.NewRequest("POST", "/chat/audio?format=speech", bytes.NewReader(fakeWAV))
+	req.Header.Set("Content-Type", "audio/wav")
+	rr := httptest.NewRecorder()
+	server.handleAudio(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp ChatResponse
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if resp.Transcript != "Vai chover hoje?" || resp.Response != "Vai chover à tarde." {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+	if transcriber.format != stt.FormatWAV {
+		t.Errorf("Expected wav format, got %q", transcriber.format)
+	}
+}
+
+func TestHandleAudio_Multipart(t *testing.T) {
+	useLocalTestModel(t, localAnswer("Resposta"))
+	server := &Server{logger: logging.GetLogger(), transcriber: &fakeTranscriber{text: "Oi"}}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("session_id", "trip-1")
+	part, _ := writer.CreateFormFile("audio", "gravacao.wav")
+	part.Write(fakeWAV)
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/chat/audio", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rr := httptest.NewRecorder()
+	server.handleAudio(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp ChatResponse
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if resp.Transcript != "Oi" || resp.SessionID != "trip-1" {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+}
+
+func TestHandleAudio_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		transcriber stt.Transcriber
+		body        []byte
+		status      int
+	}{
+		{"not configured", nil, fakeWAV, http.StatusServiceUnavailable},
+		{"unsupported format", &fakeTranscriber{text: "Oi"}, []byte("ID3\x04mp3data"), http.StatusUnsupportedMediaType},
+		{"transcript too long", &fakeTranscriber{text: string(bytes.Repeat([]byte("a"), 1001))}, fakeWAV, http.StatusBadRequest},
+		{"audio too large", &fakeTranscriber{text: "Oi"}, append(append([]byte{}, fakeWAV...), make([]byte, validator.MaxAudioBodySize)...), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &Server{logger: logging.GetLogger(), transcriber: tt.transcriber}
+			rr := httptest.NewRecorder()
+			server.handleAudio(rr, httptest.NewRequest("POST", "/chat/audio", bytes.NewReader(tt.body)))
+			if rr.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleAudio_MultipartTooLarge(t *testing.T) {
+	server := &Server{logger: logging.GetLogger(), transcriber: &fakeTranscriber{text: "Oi"}}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("audio", "gravacao.wav")
+	part.Write(fakeWAV)
+	part.Write(make([]byte, validator.MaxAudioBodySize))
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/chat/audio", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rr := httptest.NewRecorder()
+	server.handleAudio(rr, req)
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/clotilde/carplay-assistant/internal/budget"
+)
+
+// requestBudget is the time a request may take, from arrival (before transcription) to the answer
+// IMPORTANT: Apple Shortcuts has ~30s internal timeout. We use 25s to leave buffer
+// for network latency and response processing on the client side.
+const requestBudget = 25 * time.Second
+
+const (
+	maxSearchTime     = 8 * time.Second         // Perplexity results rarely come later than this
+	minSearchTime     = 1500 * time.Millisecond // Below this the search is skipped for the native web search
+	minGenerationTime = 10 * time.Second        // Kept for generation while searching
+)
+
+// newRequestBudget creates the budget of a request that arrived at start
+func newRequestBudget(start time.Time) *budget.Budget {
+	return budget.New(start, requestBudget)
+}
+
+// generationContext returns the context of the generation stage, with the request deadline minus
+// the time reserved for the stages after it, and the function that ends the stage
+func generationContext(ctx context.Context) (context.Context, func()) {
+	b := budget.FromContext(ctx)
+	stop := b.Track(budget.Generation)
+	var cancel context.CancelFunc
+	if b == nil {
+		ctx, cancel = context.WithCancel(ctx)
+	} else {
+		ctx, cancel = context.WithTimeout(ctx, b.Allot(budget.Generation, 0))
+	}
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clotilde/carplay-assistant/internal/breaker"
+	"github.com/clotilde/carplay-assistant/internal/budget"
+	"github.com/clotilde/carplay-assistant/internal/logging"
+)
+
+func TestHandleAudio_StageTimings(t *testing.T) {
+	useLocalTestModel(t, localAnswer("Vai chover à tarde."))
+	server := &Server{logger: logging.GetLogger(), transcriber: &fakeTranscriber{text: "Vai chover hoje?"}, synthesizer: &fakeSynthesizer{}}
+
+	req := httptest.NewRequest("POST", "/chat/audio?audio_response=base64", bytes.NewReader(fakeWAV))
+	req.Header.Set("Content-Type", "audio/wav")
+	rr := httptest.NewRecorder()
+	server.handleAudio(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	entry := server.logger.GetEntries(1, 0)[0]
+	for _, stage := range []string{budget.Transcription, budget.Generation, budget.Synthesis} {
+		if _, ok := entry.Stages[stage]; !ok {
+			t.Errorf("Expected the %s stage timed, got %v", stage, entry.Stages)
+		}
+	}
+	if _, ok := entry.Stages[budget.Search]; ok {
+		t.Errorf("Expected no search stage for a question without web search, got %v", entry.Stages)
+	}
+}
+
+func TestPerformPerplexitySearch_SkippedWhenBudgetTight(t *testing.T) {
+	breaker.Get(perplexityBreaker).Reset()
+	t.Cleanup(breaker.Get(perplexityBreaker).Reset)
+	server := &Server{perplexityAPIKey: "test-key"}
+
+	// 14s already spent (e.g. a slow transcription): generation needs the rest
+	b := newRequestBudget(time.Now().Add(-14 * time.Second))
+	b.Reserve(budget.Generation, minGenerationTime)
+	_, err := server.performPerplexitySearch(budget.NewContext(context.Background(), b), "previsão do tempo hoje")
+	if err == nil || !strings.Contains(err.Error(), "search skipped") {
+		t.Fatalf("Expected the search skipped, got %v", err)
+	}
+	if status := breaker.Get(perplexityBreaker).Status(); status.Calls != 0 {
+		t.Errorf("Expected a skipped search not to count against Perplexity, got %+v", status)
+	}
+	if _, ok := b.Timings()[budget.Search]; ok {
+		t.Error("Expected a skipped search not to be timed")
+	}
+}
+
+func TestGenerationContext(t *testing.T) {
+	b := newRequestBudget(time.Now())
+	b.Reserve(budget.Generation, minGenerationTime)
+	b.Reserve(budget.Synthesis, synthesisReserve)
+
+	ctx, done := generationContext(budget.NewContext(context.Background(), b))
+	deadline, ok := ctx.Deadline()
+	if !ok || deadline.Sub(b.Deadline().Add(-synthesisReserve)) > 10*time.Millisecond {
+		t.Errorf("Expected generation to stop before the synthesis reserve, got %v (request deadline %v)", deadline, b.Deadline())
+	}
+	done()
+	if ctx.Err() == nil {
+		t.Error("Expected the generation context cancelled once the stage is done")
+	}
+	if _, ok := b.Timings()[budget.Generation]; !ok {
+		t.Error("Expected the generation stage timed")
+	}
+	if b.Allot(budget.Synthesis, 0) < requestBudget-synthesisReserve {
+		t.Error("Expected the generation reservation to end with the stage")
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/clotilde/carplay-assistant/internal/admin"
+	"github.com/clotilde/carplay-assistant/internal/budget"
+	"github.com/clotilde/carplay-assistant/internal/cache"
+	"github.com/clotilde/carplay-assistant/internal/logging"
+	"github.com/clotilde/carplay-assistant/internal/metrics"
+	"github.com/clotilde/carplay-assistant/internal/provider"
+	"github.com/clotilde/carplay-assistant/internal/router"
+)
+
+// mathOperators are kept in cache keys, since router.Normalize drops them ("2+2" and "2*2" differ)
+const mathOperators = "+-*/^%=<>×÷"
+
+// embeddingTimeout bounds the embedding call: past it the question is answered without the semantic cache
+// It is shortened when the request budget has less time to spare
+const embeddingTimeout = 2 * time.Second
+
+// cacheKey returns the response cache key of a question, and false when its answer is not cached
+// Follow-ups depend on the conversation, so only questions without history are cached
+func (s *Server) cacheKey(route router.RouteDecision, input string, history []provider.Message) (cache.Key, bool) {
+	if s.cache == nil || len(history) > 0 || !s.cache.Cacheable(string(route.Category)) {
+		return cache.Key{}, false
+	}
+	question := router.Normalize(input)
+	operators := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(mathOperators, r) {
+			return r
+		}
+		return -1
+	}, input)
+	if operators != "" {
+		question += " " + operators
+	}
+	return cache.Key{Question: question, Category: string(route.Category), Model: route.Model, Prompt: s.promptVersion(route.Category)}, true
+}
+
+// promptVersion identifies the system prompt of a category and the day it is used on (the prompt
+// carries the date), so answers are not served after the prompt changes or on the next day
+func (s *Server) promptVersion(category router.Category) string {
+	sum := sha256.Sum256([]byte(s.buildSystemPrompt(admin.GetConfig(), category, "")))
+	return brazilNow().Format("2006-01-02") + " " + hex.EncodeToString(sum[:8])
+}
+
+// semanticKey returns the scope of semantic matches for a question: its category, model, prompt
+// version, numbers and math operators ("15% de 80" -> "15% 80")
+func semanticKey(route router.RouteDecision, input, prompt string) cache.SemanticKey {
+	numbers := strings.Fields(strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) || strings.ContainsRune(mathOperators, r) {
+			return r
+		}
+		return ' '
+	}, input))
+	return cache.SemanticKey{Category: string(route.Category), Model: route.Model, Numbers: strings.Join(numbers, " "), Prompt: prompt}
+}
+
+// purgeCaches drops the cached answers of this instance
+func (s *Server) purgeCaches() {
+	purged := 0
+	if s.cache != nil {
+		purged += s.cache.Purge()
+	}
+	if s.semantic != nil {
+		purged += s.semantic.Purge()
+	}
+	if purged > 0 {
+		log.Printf("Response cache purged after a config change (%d answers)", purged)
+	}
+}
+
+// semanticThreshold returns the minimum similarity for a paraphrase to get a cached answer
+// (0 when semantic matching is off for the category)
+func (s *Server) semanticThreshold(route router.RouteDecision) float64 {
+	if s.semantic == nil || s.embedder == nil {
+		return 0
+	}
+	return admin.GetConfig().SemanticThreshold(string(route.Category))
+}
+
+// embedQuestion returns the embedding of a question, or nil when the embedding failed
+func (s *Server) embedQuestion(ctx context.Context, input string) []float32 {
+	b := budget.FromContext(ctx)
+	timeout := b.Allot(budget.Embedding, embeddingTimeout)
+	if timeout <= 0 {
+		log.Printf("No time left in the request budget, skipping semantic cache")
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	defer b.Track(budget.Embedding)()
+	vector, err := s.embedder.Embed(ctx, input)
+	if err != nil {
+		log.Printf("Embedding failed (%s), skipping semantic cache: %v", s.embedder.Name(), err)
+		return nil
+	}
+	return vector
+}
+
+// cachedAnswer returns a cached answer, streaming it sentence by sentence when onSentence is set
+func cachedAnswer(text, outcome string, similarity float64, onSentence func(sentence string) error) (answer, error) {
+	result := answer{Text: text, Cache: outcome, CacheSimilarity: similarity}
+	if onSentence != nil {
+		chunker := newSentenceChunker(onSentence)
+		if err := chunker.Write(text); err != nil {
+			return result, err
+		}
+		return result, chunker.Flush()
+	}
+	return result, nil
+}
+
+// generate answers the question, from the response cache when the same question (or a close
+// paraphrase, with the semantic cache) was answered recently
+// onSentence receives the answer one sentence at a time for streaming clients (nil otherwise)
+func (s *Server) generate(ctx context.Context, route router.RouteDecision, instructions, input string, history []provider.Message, onSentence func(sentence string) error) (answer, error) {
+	key, cacheable := s.cacheKey(route, input, history)
+	var vector []float32
+	var similarity float64
+	if cacheable {
+		if text, ok := s.cache.Get(key); ok {
+			log.Printf("Response cache hit: category=%s, model=%s", route.Category, route.Model)
+			metrics.CacheLookups.WithLabelValues(logging.CacheHit).Inc()
+			return cachedAnswer(text, logging.CacheHit, 0, onSentence)
+		}
+		if threshold := s.semanticThreshold(route); threshold > 0 {
+			if vector = s.embedQuestion(ctx, input); vector != nil {
+				text, best, ok := s.semantic.Lookup(semanticKey(route, input, key.Prompt), vector, threshold)
+				if ok {
+					log.Printf("Semantic cache hit: category=%s, model=%s, similarity=%.3f", route.Category, route.Model, best)
+					metrics.CacheLookups.WithLabelValues(logging.CacheSemanticHit).Inc()
+					return cachedAnswer(text, logging.CacheSemanticHit, best, onSentence)
+				}
+				similarity = best
+			}
+		}
+	}
+
+	internalRoute := RouteDecision{
+		Model:           route.Model,
+		WebSearch:       route.WebSearch,
+		ReasoningEffort: route.ReasoningEffort,
+		Fallbacks:       fallbackRoutes(route),
+		Hedge:           hedgeRoute(route),
+	}
+	var result answer
+	var err error
+	if onSentence != nil {
+		result, err = s.streamResponse(ctx, internalRoute, instructions, input, history, onSentence)
+	} else {
+		result, err = s.createResponse(ctx, internalRoute, instructions, input, history)
+	}
+	if cacheable {
+		metrics.CacheLookups.WithLabelValues(logging.CacheMiss).Inc()
+		result.Cache = logging.CacheMiss
+		result.CacheSimilarity = similarity
+		// Answers from a fallback or hedge model are not cached, so the routed model answers again once it recovers
+		if err == nil && result.Fallbacks == 0 && result.HedgeWinner != logging.HedgeSecondary {
+			s.cache.Set(key, result.Text)
+			if vector != nil {
+				s.semantic.Add(semanticKey(route, input, key.Prompt), vector, result.Text)
+			}
+		}
+	}
+	return result, err
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clotilde/carplay-assistant/internal/admin"
+	"github.com/clotilde/carplay-assistant/internal/cache"
+	"github.com/clotilde/carplay-assistant/internal/embedding"
+	"github.com/clotilde/carplay-assistant/internal/logging"
+	"github.com/clotilde/carplay-assistant/internal/provider"
+	"github.com/clotilde/carplay-assistant/internal/router"
+	"github.com/clotilde/carplay-assistant/internal/session"
+)
+
+func TestCacheKey(t *testing.T) {
+	server := &Server{cache: cache.New(cache.DefaultConfig())}
+	route := router.RouteDecision{Category: router.CategoryFactual, Model: "gpt-4o-mini"}
+
+	a, ok := server.cacheKey(route, "Qual a capital da Austrália?", nil)
+	b, _ := server.cacheKey(route, "qual a capital da australia", nil)
+	if !ok || a != b {
+		t.Errorf("Expected case, accents and punctuation to be ignored, got %+v and %+v", a, b)
+	}
+
+	sum, _ := server.cacheKey(route, "Quanto é 2+2?", nil)
+	product, _ := server.cacheKey(route, "Quanto é 2*2?", nil)
+	if sum == product {
+		t.Errorf("Expected math operators in the key, got %+v for both", sum)
+	}
+
+	if _, ok := server.cacheKey(route, "E a do Brasil?", []provider.Message{{Role: provider.RoleUser, Content: "Oi"}}); ok {
+		t.Error("Follow-ups should not be cached")
+	}
+	if _, ok := server.cacheKey(router.RouteDecision{Category: router.CategoryCreative}, "Conte uma piada", nil); ok {
+		t.Error("Creative answers should not be cached")
+	}
+	if _, ok := (&Server{}).cacheKey(route, "Qual a capital da Austrália?", nil); ok {
+		t.Error("Nothing is cached without a cache")
+	}
+
+	if !strings.HasPrefix(a.Prompt, brazilNow().Format("2006-01-02")+" ") {
+		t.Errorf("Expected the day in the prompt version, got %q", a.Prompt)
+	}
+	setQuotaConfig(t, func(config *admin.RuntimeConfig) {
+		config.CategoryPrompts = map[string]string{"factual": "Responda em uma frase. Agora: %s"}
+	})
+	if edited, _ := server.cacheKey(route, "Qual a capital da Austrália?", nil); edited == a {
+		t.Errorf("Expected a new key after the category prompt changed, got %+v", edited)
+	}
+}
+
+// TestPurgeCaches verifies that a config change drops the cached answers
+func TestPurgeCaches(t *testing.T) {
+	server := &Server{cache: cache.New(cache.DefaultConfig()), semantic: cache.NewSemantic(cache.DefaultConfig())}
+	server.cache.Set(cache.Key{Question: "qual capital australia", Category: "factual"}, "Camberra")
+	server.semantic.Add(cache.SemanticKey{Category: "factual"}, []float32{1, 0}, "Camberra")
+	admin.OnConfigApplied(server.purgeCaches)
+	t.Cleanup(func() { admin.OnConfigApplied(nil) })
+
+	setQuotaConfig(t, func(config *admin.RuntimeConfig) {
+		config.CategoryPrompts = map[string]string{"factual": "Responda em uma frase. Agora: %s"}
+	})
+	if server.cache.Len() != 0 || server.semantic.Len() != 0 {
+		t.Errorf("Expected both caches purged, got %d and %d entries", server.cache.Len(), server.semantic.Len())
+	}
+	(&Server{}).purgeCaches()
+}
+
+// TestHandleChat_CachedAnswer verifies that a repeated question is answered without calling the model
+func TestHandleChat_CachedAnswer(t *testing.T) {
+	calls := 0
+	useLocalTestModel(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		localAnswer("Camberra é a capital. Fica no território da capital.")(w, r)
+	})
+
+	server := &Server{
+		logger:   logging.GetLogger(),
+		cache:    cache.New(cache.DefaultConfig()),
+		sessions: session.NewMemoryStore(session.DefaultMemoryConfig()),
+	}
+	send := func(path, message, sessionID string) *httptest.ResponseRecorder {
+		bodyBytes, _ := json.Marshal(ChatRequest{Message: message, SessionID: sessionID})
+		rr := httptest.NewRecorder()
+		server.handleChat(rr, httptest.NewRequest("POST", path, bytes.NewReader(bodyBytes)))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		return rr
+	}
+
+	send("/chat", "Qual é a capital da Austrália?", "")
+	if entry := server.logger.GetEntries(1, 0)[0]; entry.Cache != logging.CacheMiss {
+		t.Errorf("Expected the first answer logged as a miss, got %q", entry.Cache)
+	}
+
+	rr := send("/chat", "qual e a capital da australia", "")
+	var resp ChatResponse
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if calls != 1 || !strings.HasPrefix(resp.Response, "Camberra") {
+		t.Errorf("Expected the cached answer without a model call, got %d calls and %q", calls, resp.Response)
+	}
+	entry := server.logger.GetEntries(1, 0)[0]
+	if entry.Cache != logging.CacheHit || entry.Cost != 0 || entry.InputTokens != 0 {
+		t.Errorf("Expected a free cache hit in the log, got %+v", entry)
+	}
+
+	// Streaming clients get the cached answer sentence by sentence
+	rr = send("/chat/stream", "Qual é a capital da Austrália?", "")
+	if calls != 1 || strings.Count(rr.Body.String(), "event: chunk") != 2 {
+		t.Errorf("Expected 2 cached chunks without a model call, got %d calls and body:\n%s", calls, rr.Body.String())
+	}
+
+	// Follow-ups in a session always reach the model
+	send("/chat", "Oi", "trip-cache")
+	send("/chat", "Qual é a capital da Austrália?", "trip-cache")
+	if calls != 3 {
+		t.Errorf("Expected the follow-up to bypass the cache, got %d calls", calls)
+	}
+}
+
+func TestSemanticKey(t *testing.T) {
+	route := router.RouteDecision{Category: router.CategoryMathematical, Model: "gpt-4o-mini"}
+	if got := semanticKey(route, "Quanto é 15% de 80?", "v1").Numbers; got != "15% 80" {
+		t.Errorf("Expected numbers and operators, got %q", got)
+	}
+	if semanticKey(route, "Quanto é 15% de 80?", "v1") == semanticKey(route, "Quanto é 15% de 90?", "v1") {
+		t.Error("Questions with other numbers should not share answers")
+	}
+	if semanticKey(route, "Quanto é 15% de 80?", "v1") == semanticKey(route, "Quanto é 15% de 80?", "v2") {
+		t.Error("Answers to another prompt should not be shared")
+	}
+}
+
+// TestHandleChat_SemanticCache verifies that a paraphrase above the threshold gets the cached answer
+func TestHandleChat_SemanticCache(t *testing.T) {
+	calls := 0
+	useLocalTestModel(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		localAnswer("Camberra.")(w, r)
+	})
+	setQuotaConfig(t, func(config *admin.RuntimeConfig) {
+		config.SemanticThresholds = map[string]float64{"factual": 0.85}
+	})
+
+	server := &Server{
+		logger:   logging.GetLogger(),
+		cache:    cache.New(cache.DefaultConfig()),
+		semantic: cache.NewSemantic(cache.DefaultConfig()),
+		embedder: embedding.NewHashing(0),
+	}
+	send := func(message string) logging.LogEntry {
+		bodyBytes, _ := json.Marshal(ChatRequest{Message: message})
+		rr := httptest.NewRecorder()
+		server.handleChat(rr, httptest.NewRequest("POST", "/chat", bytes.NewReader(bodyBytes)))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		return server.logger.GetEntries(1, 0)[0]
+	}
+
+	send("Qual é a capital da Austrália?")
+	entry := send("Me diga qual é a capital da Austrália")
+	if calls != 1 || entry.Cache != logging.CacheSemanticHit || entry.CacheSimilarity < 0.85 || entry.Cost != 0 {
+		t.Errorf("Expected a semantic hit without a model call, got %d calls and %+v", calls, entry)
+	}
+
+	entry = send("Qual é a capital do Canadá?")
+	if calls != 2 || entry.Cache != logging.CacheMiss || entry.CacheSimilarity == 0 {
+		t.Errorf("Expected a miss below the threshold with the closest similarity, got %d calls and %+v", calls, entry)
+	}
+
+	// 0 turns semantic matching off for the category
+	setQuotaConfig(t, func(config *admin.RuntimeConfig) {
+		config.SemanticThresholds = map[string]float64{"factual": 0}
+	})
+	if entry = send("Diga qual é a capital da Austrália"); calls != 3 || entry.Cache != logging.CacheMiss {
+		t.Errorf("Expected the model to answer with semantic matching off, got %d calls and %+v", calls, entry)
+	}
+}
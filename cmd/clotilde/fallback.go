@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/clotilde/carplay-assistant/internal/breaker"
+	"github.com/clotilde/carplay-assistant/internal/provider"
+	"github.com/clotilde/carplay-assistant/internal/router"
+)
+
+// fallbackReserve is the time kept for each remaining fallback model when the model before it
+// is called, so a model that hangs until the deadline does not leave the fallbacks without time
+const fallbackReserve = 5 * time.Second
+
+// fallbackRoutes converts the fallback chain of a routing decision to the internal format
+func fallbackRoutes(route router.RouteDecision) []RouteDecision {
+	var routes []RouteDecision
+	for _, fallback := range router.Fallbacks(route) {
+		routes = append(routes, RouteDecision{
+			Model:           fallback.Model,
+			WebSearch:       fallback.WebSearch,
+			ReasoningEffort: fallback.ReasoningEffort,
+		})
+	}
+	return routes
+}
+
+// stepContext returns the context of one model call, with the request deadline minus the time
+// reserved for the fallbacks left after it (but at least an equal share of the time left)
+func stepContext(ctx context.Context, fallbacksLeft int) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok || fallbacksLeft == 0 {
+		return context.WithCancel(ctx)
+	}
+	remaining := time.Until(deadline)
+	timeout := remaining - time.Duration(fallbacksLeft)*fallbackReserve
+	if share := remaining / time.Duration(fallbacksLeft+1); timeout < share {
+		timeout = share
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// retarget returns the provider and request for a fallback model, keeping the Perplexity results
+// of the first attempt; the native web search tool is used only when they are missing
+func retarget(route RouteDecision, req provider.Request, perplexityCalls int) (provider.Provider, provider.Request, error) {
+	p, ok := provider.ForModel(route.Model)
+	if !ok {
+		return nil, req, fmt.Errorf("no provider configured for model %s", route.Model)
+	}
+	req.Model = route.Model
+	req.ReasoningEffort = route.ReasoningEffort
+	req.WebSearch = route.WebSearch && perplexityCalls == 0 && provider.CapabilitiesFor(route.Model).WebSearch
+	return p, req, nil
+}
+
+// upstreamResult tells the breaker of an upstream whether a call shows it unhealthy
+// Errors caused by the request itself mean the upstream answered, and a client that hung up says nothing
+func upstreamResult(err error) breaker.Result {
+	switch {
+	case err == nil:
+		return breaker.Success
+	case errors.Is(err, context.Canceled):
+		return breaker.Ignored
+	case provider.Retryable(err):
+		return breaker.Failure
+	default:
+		return breaker.Success
+	}
+}
+
+// budgetCut reports whether ctx gives a call less time than the provider clients allow: a timeout
+// then comes from the request budget, not from the upstream
+func budgetCut(ctx context.Context) bool {
+	deadline, ok := ctx.Deadline()
+	return ok && time.Until(deadline) < provider.ClientTimeout
+}
+
+// callResult is upstreamResult for a call whose deadline was cut by the request budget (see
+// budgetCut): running out of it says nothing about the provider, whose other models (with their
+// own speed) share the breaker
+func callResult(err error, cut bool) breaker.Result {
+	if cut && errors.Is(err, context.DeadlineExceeded) {
+		return breaker.Ignored
+	}
+	return upstreamResult(err)
+}
+
+// generateWithFallback calls the provider with the request and, when it fails with a retryable
+// error (overload, rate limit, server error, timeout), each model of route.Fallbacks in turn
+// call makes one attempt and reports whether part of the answer already reached the client, in
+// which case the error is returned as is: another model would repeat or contradict it
+// Failed attempts that report usage (e.g. a stream that broke off) are charged with the answer
+func (s *Server) generateWithFallback(ctx context.Context, route RouteDecision, p provider.Provider, req provider.Request, perplexityCalls int,
+	call func(ctx context.Context, p provider.Provider, req provider.Request) (provider.Response, bool, error)) (answer, error) {
+	result := answer{Request: req, PerplexityCalls: perplexityCalls}
+	for i := 0; ; i++ {
+		var resp provider.Response
+		var delivered bool
+		// A provider whose breaker is open is skipped at once, so the fallback gets the whole time left
+		done, err := breaker.Get(p.Name()).Allow()
+		if err == nil {
+			stepCtx, cancel := stepContext(ctx, len(route.Fallbacks)-i)
+			cut := budgetCut(stepCtx)
+			resp, delivered, err = call(stepCtx, p, req)
+			cancel()
+			done(callResult(err, cut))
+		}
+		if err == nil {
+			result.Text = resp.Text
+			result.Usage = resp.Usage
+			return result, nil
+		}
+		if resp.Usage.Reported() {
+			result.Discarded = append(result.Discarded, discardedCall{Model: req.Model, Usage: resp.Usage})
+		}
+		if delivered || i == len(route.Fallbacks) || ctx.Err() != nil || !(provider.Retryable(err) || errors.Is(err, breaker.ErrOpen)) {
+			return result, err
+		}
+
+		next := route.Fallbacks[i]
+		log.Printf("Model %s failed (%v), falling back to %s", req.Model, err, next.Model)
+		nextProvider, nextReq, retargetErr := retarget(next, req, perplexityCalls)
+		if retargetErr != nil {
+			return result, err
+		}
+		p, req = nextProvider, nextReq
+		result.Request = req
+		result.Model = req.Model
+		result.FallbackFrom = route.Model
+		result.Fallbacks = i + 1
+	}
+}
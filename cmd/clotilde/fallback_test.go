@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/clotilde/carplay-assistant/internal/admin"
+	"github.com/clotilde/carplay-assistant/internal/breaker"
+	"github.com/clotilde/carplay-assistant/internal/logging"
+	"github.com/clotilde/carplay-assistant/internal/provider"
+)
+
+// useFallbackModels serves local:test (the routed model) with primary and local:backup with a fixed answer,
+// with local:backup as the fallback of every category
+func useFallbackModels(t *testing.T, primary http.HandlerFunc) *int {
+	t.Helper()
+	backupCalls := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Model == "backup" {
+			backupCalls++
+			localAnswer("Resposta do modelo reserva.")(w, r)
+			return
+		}
+		primary(w, r)
+	}))
+	t.Cleanup(upstream.Close)
+
+	provider.Register(provider.NewLocal(upstream.URL, "", []string{"test", "backup"}))
+	breaker.Get("local").Reset()
+	t.Cleanup(breaker.Get("local").Reset)
+	setQuotaConfig(t, func(config *admin.RuntimeConfig) {
+		config.StandardModel = "local:test"
+		config.PremiumModel = "local:test"
+		config.FallbackModels = map[string][]string{"default": {"local:backup"}}
+	})
+	return &backupCalls
+}
+
+func TestHandleChat_FallbackOnOverload(t *testing.T) {
+	backupCalls := useFallbackModels(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(529)
+		w.Write([]byte(`{"error": {"type": "overloaded_error", "message": "Overloaded"}}`))
+	})
+
+	server := &Server{logger: logging.GetLogger()}
+	before := server.logger.GetStats().Fallbacks
+	bodyBytes, _ := json.Marshal(ChatRequest{Message: "Oi, tudo bem?"})
+	rr := httptest.NewRecorder()
+	server.handleChat(rr, httptest.NewRequest("POST", "/chat", bytes.NewReader(bodyBytes)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected the fallback model to answer, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp ChatResponse
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if resp.Response != "Resposta do modelo reserva." || *backupCalls != 1 {
+		t.Errorf("Expected the backup answer, got %q (%d backup calls)", resp.Response, *backupCalls)
+	}
+
+	entry := server.logger.GetEntries(1, 0)[0]
+	if entry.Model != "local:backup" || entry.FallbackFrom != "local:test" || entry.Fallbacks != 1 {
+		t.Errorf("Expected the answering model in the log, got %+v", entry)
+	}
+	if got := server.logger.GetStats().Fallbacks; got != before+1 {
+		t.Errorf("Expected the fallback counted, got %d (was %d)", got, before)
+	}
+}
+
+func TestHandleChat_NoFallbackOnInvalidRequest(t *testing.T) {
+	backupCalls := useFallbackModels(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+
+	server := &Server{logger: logging.GetLogger()}
+	bodyBytes, _ := json.Marshal(ChatRequest{Message: "Oi, tudo bem?"})
+	rr := httptest.NewRecorder()
+	server.handleChat(rr, httptest.NewRequest("POST", "/chat", bytes.NewReader(bodyBytes)))
+	if rr.Code != http.StatusInternalServerError || *backupCalls != 0 {
+		t.Errorf("Expected the error without a fallback, got %d (%d backup calls)", rr.Code, *backupCalls)
+	}
+	if entry := server.logger.GetEntries(1, 0)[0]; entry.Model != "local:test" || entry.Fallbacks != 0 {
+		t.Errorf("Expected the routed model in the log, got %+v", entry)
+	}
+}
+
+func TestHandleChat_StreamFallback(t *testing.T) {
+	backupCalls := useFallbackModels(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	server := &Server{logger: logging.GetLogger()}
+	bodyBytes, _ := json.Marshal(ChatRequest{Message: "Oi, tudo bem?"})
+	rr := httptest.NewRecorder()
+	server.handleChat(rr, httptest.NewRequest("POST", "/chat/stream", bytes.NewReader(bodyBytes)))
+	if *backupCalls != 1 || !bytes.Contains(rr.Body.Bytes(), []byte("Resposta do modelo reserva.")) {
+		t.Errorf("Expected the backup answer streamed, got %d backup calls and body:\n%s", *backupCalls, rr.Body.String())
+	}
+}
+
+// TestGenerateWithFallback_ChargesFailedAttempts verifies that the usage reported by a failed attempt
+// (e.g. a stream that broke off) is charged along with the fallback's answer
+func TestGenerateWithFallback_ChargesFailedAttempts(t *testing.T) {
+	useFallbackModels(t, localAnswer("unused"))
+	p, _ := provider.ForModel("local:test")
+	route := RouteDecision{Model: "local:test", Fallbacks: []RouteDecision{{Model: "local:backup"}}}
+	server := &Server{logger: logging.GetLogger()}
+
+	result, err := server.generateWithFallback(context.Background(), route, p, provider.Request{Model: "local:test", Input: "Oi"}, 0,
+		func(ctx context.Context, p provider.Provider, req provider.Request) (provider.Response, bool, error) {
+			if req.Model == "local:test" {
+				return provider.Response{Usage: provider.Usage{InputTokens: 40, OutputTokens: 3}}, false, &provider.Error{Message: "overloaded", Type: "overloaded_error"}
+			}
+			return provider.Response{Text: "Resposta", Usage: provider.Usage{InputTokens: 40, OutputTokens: 10}}, false, nil
+		})
+	if err != nil {
+		t.Fatalf("Expected the fallback to answer, got %v", err)
+	}
+	if len(result.Discarded) != 1 || result.Discarded[0].Model != "local:test" || result.Discarded[0].Usage.OutputTokens != 3 {
+		t.Fatalf("Expected the failed attempt recorded, got %+v", result.Discarded)
+	}
+	if usage := result.usage("local:backup"); usage.InputTokens != 80 || usage.OutputTokens != 13 {
+		t.Errorf("Expected both attempts charged, got %+v", usage)
+	}
+}
+
+func TestStepContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 25*time.Second)
+	defer cancel()
+
+	// Two fallbacks left: 5s is kept for each
+	step, stepCancel := stepContext(ctx, 2)
+	deadline, _ := step.Deadline()
+	stepCancel()
+	if remaining := time.Until(deadline); remaining > 15*time.Second || remaining < 14*time.Second {
+		t.Errorf("Expected about 15s for the first model, got %v", remaining)
+	}
+
+	// The last model gets all the time left
+	step, stepCancel = stepContext(ctx, 0)
+	last, _ := step.Deadline()
+	stepCancel()
+	if parent, _ := ctx.Deadline(); !last.Equal(parent) {
+		t.Errorf("Expected the request deadline for the last model, got %v", last)
+	}
+
+	// With little time left, each model gets at least an equal share
+	short, shortCancel := context.WithTimeout(context.Background(), 6*time.Second)
+	defer shortCancel()
+	step, stepCancel = stepContext(short, 1)
+	deadline, _ = step.Deadline()
+	stepCancel()
+	if remaining := time.Until(deadline); remaining < 2900*time.Millisecond {
+		t.Errorf("Expected about 3s for the first model, got %v", remaining)
+	}
+}
+
+func TestUpstreamResult(t *testing.T) {
+	tests := []struct {
+		err  error
+		want breaker.Result
+	}{
+		{nil, breaker.Success},
+		{&provider.Error{Message: "overloaded", StatusCode: 529}, breaker.Failure},
+		{context.DeadlineExceeded, breaker.Failure},
+		{&provider.Error{Message: "bad request", StatusCode: http.StatusBadRequest}, breaker.Success},
+		{fmt.Errorf("stream: %w", context.Canceled), breaker.Ignored},
+	}
+	for _, tt := range tests {
+		if got := upstreamResult(tt.err); got != tt.want {
+			t.Errorf("upstreamResult(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestCallResult(t *testing.T) {
+	timeout := fmt.Errorf("request failed: %w", context.DeadlineExceeded)
+	if got := callResult(timeout, true); got != breaker.Ignored {
+		t.Errorf("Expected a timeout of a budget-cut call ignored, got %v", got)
+	}
+	if got := callResult(timeout, false); got != breaker.Failure {
+		t.Errorf("Expected a timeout within the client timeout counted, got %v", got)
+	}
+	if got := callResult(&provider.Error{Message: "overloaded", StatusCode: 529}, true); got != breaker.Failure {
+		t.Errorf("Expected an overload counted even with a cut deadline, got %v", got)
+	}
+
+	short, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if !budgetCut(short) || budgetCut(context.Background()) {
+		t.Error("Expected only a deadline shorter than the client timeout to be a budget cut")
+	}
+}
+
+// TestGenerateWithFallback_BudgetTimeoutKeepsBreakerClosed verifies that slow answers cut short by
+// the request budget do not open the provider's breaker (which would refuse its faster models too)
+func TestGenerateWithFallback_BudgetTimeoutKeepsBreakerClosed(t *testing.T) {
+	useLocalTestModel(t, localAnswer("unused"))
+	p, _ := provider.ForModel("local:test")
+	server := &Server{logger: logging.GetLogger()}
+
+	for i := 0; i < 4; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		_, err := server.generateWithFallback(ctx, RouteDecision{Model: "local:test"}, p, provider.Request{Model: "local:test"}, 0,
+			func(ctx context.Context, p provider.Provider, req provider.Request) (provider.Response, bool, error) {
+				<-ctx.Done()
+				return provider.Response{}, false, ctx.Err()
+			})
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected the budget timeout, got %v", err)
+		}
+	}
+	if status := breaker.Get("local").Status(); status.State != breaker.Closed || status.Failures != 0 {
+		t.Errorf("Expected the breaker closed with no failures, got %+v", status)
+	}
+}
+
+// TestHandleChat_BreakerSkipsFailingProvider verifies that a provider that keeps failing is no
+// longer called until its breaker lets a probe through, and that /health shows the breaker open
+func TestHandleChat_BreakerSkipsFailingProvider(t *testing.T) {
+	upstreamCalls := 0
+	useLocalTestModel(t, func(w http.ResponseWriter, r *http.Request) {
+		upstreamCalls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	server := &Server{logger: logging.GetLogger()}
+	for i := 0; i < 4; i++ {
+		bodyBytes, _ := json.Marshal(ChatRequest{Message: "Oi, tudo bem?"})
+		rr := httptest.NewRecorder()
+		server.handleChat(rr, httptest.NewRequest("POST", "/chat", bytes.NewReader(bodyBytes)))
+		if rr.Code != http.StatusInternalServerError {
+			t.Fatalf("Expected the error, got %d: %s", rr.Code, rr.Body.String())
+		}
+	}
+	if upstreamCalls != 3 {
+		t.Errorf("Expected the provider skipped once its breaker opened, got %d calls", upstreamCalls)
+	}
+
+	rr := httptest.NewRecorder()
+	server.handleHealth(rr, httptest.NewRequest("GET", "/health", nil))
+	var health struct {
+		CircuitBreakers []breaker.Status `json:"circuit_breakers"`
+	}
+	json.NewDecoder(rr.Body).Decode(&health)
+	open := false
+	for _, status := range health.CircuitBreakers {
+		if status.Name == "local" {
+			open = status.State == breaker.Open
+		}
+	}
+	if !open {
+		t.Errorf("Expected the local breaker open on /health, got %+v", health.CircuitBreakers)
+	}
+}
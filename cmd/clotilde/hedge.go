@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/clotilde/carplay-assistant/internal/admin"
+	"github.com/clotilde/carplay-assistant/internal/breaker"
+	"github.com/clotilde/carplay-assistant/internal/logging"
+	"github.com/clotilde/carplay-assistant/internal/provider"
+	"github.com/clotilde/carplay-assistant/internal/router"
+	"github.com/clotilde/carplay-assistant/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	latencySamples    = 100             // Generation times kept per model
+	minLatencySamples = 10              // Below this the observed p90 is not trusted
+	defaultHedgeDelay = 4 * time.Second // Hedge delay until a model has enough samples
+)
+
+// latencies keeps the recent generation times of each model on this instance
+type latencies struct {
+	mu    sync.Mutex
+	times map[string][]int64 // Model -> milliseconds, oldest first
+}
+
+// generationLatency is used to pick the hedge delay of each model
+var generationLatency = &latencies{times: make(map[string][]int64)}
+
+// record adds a generation time of model
+func (l *latencies) record(model string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	times := append(l.times[model], d.Milliseconds())
+	if len(times) > latencySamples {
+		times = times[len(times)-latencySamples:]
+	}
+	l.times[model] = times
+}
+
+// percentile returns a percentile (0-100) of the recent generation times of model
+// ok is false while there are too few samples to trust it
+func (l *latencies) percentile(model string, percentile float64) (time.Duration, bool) {
+	l.mu.Lock()
+	times := append([]int64(nil), l.times[model]...)
+	l.mu.Unlock()
+	if len(times) < minLatencySamples {
+		return 0, false
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	return time.Duration(logging.Percentile(times, percentile)) * time.Millisecond, true
+}
+
+// hedgeRoute converts the hedge of a routing decision to the internal format (nil when off)
+func hedgeRoute(route router.RouteDecision) *RouteDecision {
+	hedge, ok := router.Hedge(route)
+	if !ok {
+		return nil
+	}
+	return &RouteDecision{Model: hedge.Model, WebSearch: hedge.WebSearch, ReasoningEffort: hedge.ReasoningEffort}
+}
+
+// hedgeDelay returns how long model may take before the hedge model is asked too: the configured
+// delay, or the model's observed p90 so only the slowest tenth of the answers is hedged
+func hedgeDelay(model string) time.Duration {
+	if delayMs := admin.GetConfig().HedgeDelayMs; delayMs > 0 {
+		return time.Duration(delayMs) * time.Millisecond
+	}
+	if p90, ok := generationLatency.percentile(model, 90); ok {
+		return p90
+	}
+	return defaultHedgeDelay
+}
+
+// generateTimed calls the provider and records the generation time of successful answers
+func generateTimed(ctx context.Context, p provider.Provider, req provider.Request) (provider.Response, error) {
+	ctx, span := providerSpan(ctx, "provider.generate", p, req)
+	start := time.Now()
+	resp, err := p.Generate(ctx, req)
+	tracing.End(span, err)
+	observeUpstream(p.Name(), err)
+	if err == nil {
+		generationLatency.record(req.Model, time.Since(start))
+	}
+	return resp, err
+}
+
+// providerSpan starts the span of a model call, named after the provider and model
+func providerSpan(ctx context.Context, name string, p provider.Provider, req provider.Request) (context.Context, trace.Span) {
+	return tracing.Start(ctx, name,
+		attribute.String("gen_ai.system", p.Name()),
+		attribute.String("gen_ai.request.model", req.Model),
+	)
+}
+
+// hedgeOutcome is what hedging did for a request
+type hedgeOutcome struct {
+	Hedged    bool             // The hedge model was asked
+	Winner    string           // logging.HedgePrimary or logging.HedgeSecondary (empty if both failed)
+	Request   provider.Request // As sent to the hedge model, when it won
+	Discarded []discardedCall  // Calls whose answer was not used: the one that lost or failed
+}
+
+// hedgeLeg is the result of one of the two calls of a hedged request
+type hedgeLeg struct {
+	req       provider.Request
+	resp      provider.Response
+	err       error
+	secondary bool
+}
+
+// discard records the usage of a leg whose answer is not used
+// A leg cancelled because the other one won was still sent (and is billed), so when it reports no
+// usage its input tokens are estimated; its output until the cancellation cannot be known
+func (o *hedgeOutcome) discard(leg hedgeLeg, cancelled bool) {
+	call := discardedCall{Model: leg.req.Model, Usage: leg.resp.Usage}
+	if !call.Usage.Reported() {
+		if !cancelled {
+			return
+		}
+		call.Usage = provider.Usage{InputTokens: estimateInputTokens(leg.req)}
+		call.Estimated = true
+	}
+	o.Discarded = append(o.Discarded, call)
+}
+
+// generateHedged calls the provider and, if it has not answered after the hedge delay, the hedge
+// model too; the first answer is returned and the other call is cancelled
+// A failure of the first model before the delay is returned at once (the fallbacks handle it);
+// once both were asked, the error of the first model is returned only if both failed
+// The call that lost is cancelled and waited for, so its usage is charged with the answer's
+func generateHedged(ctx context.Context, p provider.Provider, req provider.Request, hedge RouteDecision, perplexityCalls int) (provider.Response, hedgeOutcome, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	start := time.Now()
+	results := make(chan hedgeLeg, 2)
+	go func() {
+		resp, err := generateTimed(ctx, p, req)
+		results <- hedgeLeg{req: req, resp: resp, err: err}
+	}()
+
+	delay := hedgeDelay(req.Model)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var outcome hedgeOutcome
+	var primaryErr error
+	pending := 1
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			hedgeProvider, hedgeReq, err := retarget(hedge, req, perplexityCalls)
+			if err != nil {
+				continue
+			}
+			done, err := breaker.Get(hedgeProvider.Name()).Allow()
+			if err != nil {
+				log.Printf("Model %s has not answered after %v, not hedging: %v", req.Model, delay, err)
+				continue
+			}
+			log.Printf("Model %s has not answered after %v, hedging with %s", req.Model, delay, hedgeReq.Model)
+			outcome.Hedged = true
+			outcome.Request = hedgeReq
+			pending++
+			cut := budgetCut(ctx)
+			go func() {
+				resp, err := generateTimed(ctx, hedgeProvider, hedgeReq)
+				done(callResult(err, cut))
+				results <- hedgeLeg{req: hedgeReq, resp: resp, err: err, secondary: true}
+			}()
+		case leg := <-results:
+			pending--
+			if leg.err == nil {
+				switch {
+				case leg.secondary:
+					outcome.Winner = logging.HedgeSecondary
+					// The first model took at least this long; recording it keeps its p90 from drifting down
+					generationLatency.record(req.Model, time.Since(start))
+				case outcome.Hedged:
+					outcome.Winner = logging.HedgePrimary
+				}
+				// Stop the call that lost and wait for the usage it reports
+				cancel()
+				for ; pending > 0; pending-- {
+					outcome.discard(<-results, true)
+				}
+				return leg.resp, outcome, nil
+			}
+			outcome.discard(leg, false)
+			if leg.secondary {
+				log.Printf("Hedge model %s failed: %v", outcome.Request.Model, leg.err)
+				continue
+			}
+			if !outcome.Hedged {
+				return provider.Response{}, outcome, leg.err
+			}
+			primaryErr = leg.err
+		}
+	}
+	return provider.Response{}, outcome, primaryErr
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/clotilde/carplay-assistant/internal/admin"
+	"github.com/clotilde/carplay-assistant/internal/breaker"
+	"github.com/clotilde/carplay-assistant/internal/logging"
+	"github.com/clotilde/carplay-assistant/internal/provider"
+)
+
+// useHedgeModels serves local:test (the routed model) and local:hedge (the hedge model, asked after
+// 50ms), each answering with its name after the given time unless the call is cancelled
+func useHedgeModels(t *testing.T, primaryTime, hedgeTime time.Duration) *atomic.Int32 {
+	t.Helper()
+	cancelled := &atomic.Int32{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		wait := primaryTime
+		if body.Model == "hedge" {
+			wait = hedgeTime
+		}
+		select {
+		case <-time.After(wait):
+			localAnswer("Resposta de "+body.Model+".")(w, r)
+		case <-r.Context().Done():
+			cancelled.Add(1)
+		}
+	}))
+	t.Cleanup(upstream.Close)
+
+	provider.Register(provider.NewLocal(upstream.URL, "", []string{"test", "hedge"}))
+	breaker.Get("local").Reset()
+	t.Cleanup(breaker.Get("local").Reset)
+	setQuotaConfig(t, func(config *admin.RuntimeConfig) {
+		config.StandardModel = "local:test"
+		config.PremiumModel = "local:test"
+		config.HedgeModel = "local:hedge"
+		config.HedgeDelayMs = 50
+	})
+	return cancelled
+}
+
+// askHedged sends a question and returns the answer and its log entry
+func askHedged(t *testing.T, server *Server) (string, logging.LogEntry) {
+	t.Helper()
+	bodyBytes, _ := json.Marshal(ChatRequest{Message: "Oi, tudo bem?"})
+	rr := httptest.NewRecorder()
+	server.handleChat(rr, httptest.NewRequest("POST", "/chat", bytes.NewReader(bodyBytes)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp ChatResponse
+	json.NewDecoder(rr.Body).Decode(&resp)
+	return resp.Response, server.logger.GetEntries(1, 0)[0]
+}
+
+func TestHandleChat_HedgeWins(t *testing.T) {
+	cancelled := useHedgeModels(t, 5*time.Second, 0)
+	server := &Server{logger: logging.GetLogger()}
+	before := server.logger.GetStats()
+
+	answer, entry := askHedged(t, server)
+	if answer != "Resposta de hedge." {
+		t.Errorf("Expected the hedge model's answer, got %q", answer)
+	}
+	if !entry.Hedged || entry.HedgeWinner != logging.HedgeSecondary || entry.Model != "local:hedge" {
+		t.Errorf("Expected the hedge recorded as the winner, got %+v", entry)
+	}
+	stats := server.logger.GetStats()
+	if stats.Hedges != before.Hedges+1 || stats.HedgeWins != before.HedgeWins+1 {
+		t.Errorf("Expected the hedge counted, got %d hedges and %d wins (was %d and %d)", stats.Hedges, stats.HedgeWins, before.Hedges, before.HedgeWins)
+	}
+
+	// The slow call is cancelled once the hedge answered
+	deadline := time.Now().Add(time.Second)
+	for cancelled.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if cancelled.Load() != 1 {
+		t.Error("Expected the routed model's call to be cancelled")
+	}
+}
+
+// TestHandleChat_HedgeChargesBothCalls verifies that the cancelled call is charged with the answer
+func TestHandleChat_HedgeChargesBothCalls(t *testing.T) {
+	useHedgeModels(t, 0, 0)
+	server := &Server{logger: logging.GetLogger()}
+	_, single := askHedged(t, server)
+
+	useHedgeModels(t, 5*time.Second, 0)
+	_, hedged := askHedged(t, server)
+	if hedged.HedgeWinner != logging.HedgeSecondary {
+		t.Fatalf("Expected the hedge to win, got %+v", hedged)
+	}
+	// Neither server reports usage: the answer is estimated, and the cancelled call's input too
+	if !hedged.UsageEstimated || hedged.InputTokens != 2*single.InputTokens || hedged.OutputTokens != single.OutputTokens {
+		t.Errorf("Expected the input of both calls charged, got %d input and %d output tokens (one call: %d and %d)",
+			hedged.InputTokens, hedged.OutputTokens, single.InputTokens, single.OutputTokens)
+	}
+}
+
+func TestHandleChat_HedgePrimaryWins(t *testing.T) {
+	useHedgeModels(t, 200*time.Millisecond, 5*time.Second)
+	server := &Server{logger: logging.GetLogger()}
+
+	answer, entry := askHedged(t, server)
+	if answer != "Resposta de test." || !entry.Hedged || entry.HedgeWinner != logging.HedgePrimary || entry.Model != "local:test" {
+		t.Errorf("Expected the routed model to win the hedged request, got %q %+v", answer, entry)
+	}
+}
+
+func TestHandleChat_NoHedgeWhenFast(t *testing.T) {
+	useHedgeModels(t, 0, 0)
+	server := &Server{logger: logging.GetLogger()}
+
+	if answer, entry := askHedged(t, server); answer != "Resposta de test." || entry.Hedged || entry.HedgeWinner != "" {
+		t.Errorf("Expected no hedge for a fast answer, got %q %+v", answer, entry)
+	}
+}
+
+func TestLatencies_Percentile(t *testing.T) {
+	l := &latencies{times: make(map[string][]int64)}
+	for i := 1; i < minLatencySamples; i++ {
+		l.record("gpt-4o-mini", time.Duration(i)*100*time.Millisecond)
+	}
+	if _, ok := l.percentile("gpt-4o-mini", 90); ok {
+		t.Error("Expected no percentile with too few samples")
+	}
+
+	l.record("gpt-4o-mini", time.Second)
+	if p90, ok := l.percentile("gpt-4o-mini", 90); !ok || p90 != 900*time.Millisecond {
+		t.Errorf("Expected a p90 of 900ms, got %v %v", p90, ok)
+	}
+
+	for i := 0; i < latencySamples; i++ {
+		l.record("gpt-4o-mini", 2*time.Second)
+	}
+	if p90, _ := l.percentile("gpt-4o-mini", 90); p90 != 2*time.Second || len(l.times["gpt-4o-mini"]) != latencySamples {
+		t.Errorf("Expected only the last %d samples kept, got p90 %v over %d samples", latencySamples, p90, len(l.times["gpt-4o-mini"]))
+	}
+}
@@ -6,14 +6,18 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path"
 	"regexp"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -23,12 +27,29 @@ import (
 	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
 	"github.com/clotilde/carplay-assistant/internal/admin"
 	"github.com/clotilde/carplay-assistant/internal/auth"
+	"github.com/clotilde/carplay-assistant/internal/breaker"
+	"github.com/clotilde/carplay-assistant/internal/budget"
+	"github.com/clotilde/carplay-assistant/internal/cache"
+	"github.com/clotilde/carplay-assistant/internal/configstore"
+	"github.com/clotilde/carplay-assistant/internal/embedding"
 	"github.com/clotilde/carplay-assistant/internal/logging"
+	"github.com/clotilde/carplay-assistant/internal/metrics"
 	"github.com/clotilde/carplay-assistant/internal/promptinjection"
+	"github.com/clotilde/carplay-assistant/internal/provider"
+	"github.com/clotilde/carplay-assistant/internal/quota"
 	"github.com/clotilde/carplay-assistant/internal/ratelimit"
 	"github.com/clotilde/carplay-assistant/internal/router"
+	"github.com/clotilde/carplay-assistant/internal/session"
+	"github.com/clotilde/carplay-assistant/internal/speech"
+	"github.com/clotilde/carplay-assistant/internal/stt"
+	"github.com/clotilde/carplay-assistant/internal/tracing"
+	"github.com/clotilde/carplay-assistant/internal/tts"
 	"github.com/clotilde/carplay-assistant/internal/validator"
+	"github.com/redis/go-redis/v9"
 	"github.com/sashabaranov/go-openai"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/api/iterator"
 )
 
 var startTime = time.Now()
@@ -166,12 +187,21 @@ SEGURANÇA E COMPORTAMENTO:
 )
 
 type ChatRequest struct {
-	Message string `json:"message"`
+	Message   string `json:"message"`
+	SessionID string `json:"session_id,omitempty"` // Optional: continue a multi-turn conversation
+	Format    string `json:"format,omitempty"`     // Optional: "text" (default), "ssml" or "speech"
+	// Optional: "base64" (audio in the JSON response) or "binary" (raw audio body) to get the answer spoken
+	AudioResponse string `json:"audio_response,omitempty"`
 }
 
 type ChatResponse struct {
-	Response string `json:"response"`
-	Error    string `json:"error,omitempty"`
+	Response   string `json:"response"`
+	Transcript string `json:"transcript,omitempty"` // What was understood from an audio upload (/chat/audio)
+	SessionID  string `json:"session_id,omitempty"`
+	Error      string `json:"error,omitempty"`
+	// Spoken answer (base64) when audio_response is "base64"
+	Audio            string `json:"audio,omitempty"`
+	AudioContentType string `json:"audio_content_type,omitempty"`
 }
 
 // RouteDecision is the internal format for createResponse (compatible with router.RouteDecision)
@@ -179,77 +209,21 @@ type RouteDecision struct {
 	Model           string
 	WebSearch       bool
 	ReasoningEffort string
+	Fallbacks       []RouteDecision // Tried in order when the model fails with a retryable error
+	Hedge           *RouteDecision  // Also asked when the model is slow to answer (nil disables hedging)
 }
 
 type Server struct {
 	openaiClient     *openai.Client
-	openaiAPIKey     string
 	perplexityAPIKey string
-	claudeAPIKey     string // Anthropic Claude API key for fast responses
-	apiKeySecret     string
 	logger           *logging.Logger
-}
-
-// ClaudeRequest represents the request body for Claude Messages API
-type ClaudeRequest struct {
-	Model     string          `json:"model"`
-	MaxTokens int             `json:"max_tokens"`
-	System    string          `json:"system,omitempty"`
-	Messages  []ClaudeMessage `json:"messages"`
-}
-
-// ClaudeMessage represents a message in the Claude conversation
-type ClaudeMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
-
-// ClaudeResponse represents the response from Claude Messages API
-type ClaudeResponse struct {
-	ID      string `json:"id"`
-	Type    string `json:"type"`
-	Role    string `json:"role"`
-	Content []struct {
-		Type string `json:"type"`
-		Text string `json:"text"`
-	} `json:"content"`
-	StopReason string `json:"stop_reason"`
-	Error      *struct {
-		Type    string `json:"type"`
-		Message string `json:"message"`
-	} `json:"error,omitempty"`
-}
-
-// ResponsesAPIRequest represents the request body for Responses API
-type ResponsesAPIRequest struct {
-	Model        string           `json:"model"`
-	Input        interface{}      `json:"input"` // Can be string or []map[string]interface{}
-	Instructions string           `json:"instructions,omitempty"`
-	Store        *bool            `json:"store,omitempty"`
-	Tools        []interface{}    `json:"tools,omitempty"` // Tools like web_search
-	Reasoning    *ReasoningConfig `json:"reasoning,omitempty"`
-}
-
-// ReasoningConfig controls reasoning behavior for models that support it
-type ReasoningConfig struct {
-	Effort string `json:"effort"` // "none", "low", "medium", "high"
-}
-
-// WebSearchTool represents the web_search tool configuration
-type WebSearchTool struct {
-	Type string `json:"type"` // "web_search" or "web_search_preview" depending on API version
-}
-
-// ResponsesAPIResponse represents the response from Responses API
-type ResponsesAPIResponse struct {
-	ID         string                   `json:"id"`
-	OutputText string                   `json:"output_text"`
-	Output     interface{}              `json:"output,omitempty"` // Can be string or array of items
-	Items      []map[string]interface{} `json:"items,omitempty"`
-	Error      *struct {
-		Message string `json:"message"`
-		Type    string `json:"type"`
-	} `json:"error,omitempty"`
+	sessions         session.Store      // Conversation history for requests with a session_id (nil disables sessions)
+	transcriber      stt.Transcriber    // Speech-to-text backend for /chat/audio (nil disables audio uploads)
+	synthesizer      tts.Synthesizer    // Text-to-speech backend for audio responses (nil disables them)
+	quotas           *quota.Tracker     // Cost budgets per API key (nil disables them)
+	cache            *cache.Cache       // Answers to repeated questions (nil disables caching)
+	semantic         *cache.Semantic    // Answers to paraphrased questions (nil disables semantic matching)
+	embedder         embedding.Embedder // Embeddings of questions for the semantic cache
 }
 
 func main() {
@@ -258,8 +232,15 @@ func main() {
 		port = "8080"
 	}
 
-	// Initialize Secret Manager client
+	// Tracing: OTEL_TRACES_EXPORTER=otlp|stdout|none (default none; trace context is propagated either way)
 	ctx := context.Background()
+	shutdownTracing, err := tracing.Setup(ctx)
+	if err != nil {
+		log.Printf("Tracing disabled: %v", err)
+		shutdownTracing = func(context.Context) error { return nil }
+	}
+
+	// Initialize Secret Manager client
 	secretClient, err := secretmanager.NewClient(ctx)
 	if err != nil {
 		log.Fatalf("Failed to create secret manager client: %v", err)
@@ -286,9 +267,18 @@ func main() {
 		}
 	}
 
-	// Get API key secret for authentication - prefer environment variable over Secret Manager
+	// Get the shared API key for authentication - prefer a rotation file, then the environment variable,
+	// then every enabled Secret Manager version (so a new version can be rolled out before the old one expires)
+	var sharedKeys []auth.SharedKey
+	var loadSharedKeys func(context.Context) ([]auth.SharedKey, error)
 	apiKeySecret := os.Getenv("API_KEY_SECRET_NAME")
-	if apiKeySecret == "" {
+	if keysFile := os.Getenv("API_KEYS_FILE"); keysFile != "" {
+		loadSharedKeys = func(context.Context) ([]auth.SharedKey, error) {
+			return auth.LoadKeyFile(keysFile)
+		}
+	} else if apiKeySecret != "" {
+		sharedKeys = []auth.SharedKey{{Generation: auth.DefaultGeneration, Key: apiKeySecret}}
+	} else {
 		// Fallback to Secret Manager for local development
 		projectID := os.Getenv("GOOGLE_CLOUD_PROJECT")
 		if projectID == "" {
@@ -299,11 +289,35 @@ func main() {
 		if apiSecretName == "" {
 			log.Fatal("API_SECRET_NAME environment variable not set (required for Secret Manager lookup)")
 		}
+		overlap := 7 * 24 * time.Hour
+		if value, err := time.ParseDuration(os.Getenv("API_KEY_ROTATION_OVERLAP")); err == nil && value >= 0 {
+			overlap = value
+		}
+		loadSharedKeys = func(ctx context.Context) ([]auth.SharedKey, error) {
+			return getSecretGenerations(ctx, secretClient, projectID, apiSecretName, overlap)
+		}
+	}
+	if loadSharedKeys != nil {
 		var err error
-		apiKeySecret, err = getSecret(ctx, secretClient, projectID, apiSecretName)
+		sharedKeys, err = loadSharedKeys(ctx)
 		if err != nil {
 			log.Fatalf("Failed to get API key secret: %v", err)
 		}
+		if len(sharedKeys) == 0 {
+			log.Fatal("No API key found (the key file or secret has no enabled keys)")
+		}
+	}
+	sharedKeySet := auth.NewKeySet(sharedKeys...)
+	if skew, err := time.ParseDuration(os.Getenv("SIGNATURE_MAX_SKEW")); err == nil && skew > 0 {
+		auth.SetMaxSignatureSkew(skew)
+	}
+	if loadSharedKeys != nil {
+		reloadInterval := 5 * time.Minute
+		if interval, err := time.ParseDuration(os.Getenv("API_KEYS_RELOAD_INTERVAL")); err == nil && interval > 0 {
+			reloadInterval = interval
+		}
+		sharedKeySet.StartReload(context.Background(), reloadInterval, loadSharedKeys)
+		log.Printf("Shared API key: %d generation(s) loaded (reload every %v)", len(sharedKeys), reloadInterval)
 	}
 
 	// Get Perplexity API key - prefer environment variable (Cloud Run secrets) over Secret Manager
@@ -353,24 +367,127 @@ func main() {
 		log.Printf("Claude API enabled - fast responses available")
 	}
 
+	// Get Gemini API key - prefer environment variable (Cloud Run secrets) over Secret Manager
+	geminiKey := os.Getenv("GEMINI_KEY_SECRET_NAME")
+	if geminiKey == "" {
+		// Fallback to Secret Manager for local development
+		projectID := os.Getenv("GOOGLE_CLOUD_PROJECT")
+		geminiSecretName := os.Getenv("GEMINI_SECRET_NAME")
+		if geminiSecretName == "" {
+			// Gemini is optional - just log and continue
+			log.Printf("GEMINI_SECRET_NAME not set - Gemini API will be disabled")
+		} else if projectID != "" {
+			var err error
+			geminiKey, err = getSecret(ctx, secretClient, projectID, geminiSecretName)
+			if err != nil {
+				log.Printf("Failed to get Gemini API key: %v - Gemini API will be disabled", err)
+				geminiKey = ""
+			}
+		}
+	}
+	if geminiKey != "" {
+		log.Printf("Gemini API enabled - native search grounding available")
+	}
+
 	// Initialize OpenAI client (still used for router)
 	openaiClient := openai.NewClient(openaiKey)
 
+	// Register configured LLM providers (replaces the built-in, credential-less defaults)
+	provider.Register(provider.NewOpenAI(openaiKey))
+	provider.Register(provider.NewAnthropic(claudeKey))
+	provider.Register(provider.NewGemini(geminiKey))
+
+	// Local/self-hosted models (Ollama, llama.cpp server, vLLM) via an OpenAI-compatible endpoint
+	// Models are addressed as "local:<name>" (e.g. local:llama3) in the runtime config
+	if localBaseURL := os.Getenv("LOCAL_LLM_BASE_URL"); localBaseURL != "" {
+		var localModels []string
+		for _, name := range strings.Split(os.Getenv("LOCAL_LLM_MODELS"), ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				localModels = append(localModels, name)
+			}
+		}
+		provider.Register(provider.NewLocal(localBaseURL, os.Getenv("LOCAL_LLM_API_KEY"), localModels))
+		log.Printf("Local model provider enabled: %s", localBaseURL)
+		breaker.Get("local")
+	}
+
+	// One circuit breaker per configured upstream, listed on /health from startup
+	for name, key := range map[string]string{"openai": openaiKey, "anthropic": claudeKey, "gemini": geminiKey, perplexityBreaker: perplexityKey} {
+		if key != "" {
+			breaker.Get(name)
+		}
+	}
+
 	// Initialize logger
 	logger := logging.GetLogger()
 
+	// Initialize conversation session store (in-memory, bounded per instance)
+	sessionConfig := session.DefaultMemoryConfig()
+	if ttl, err := time.ParseDuration(os.Getenv("SESSION_TTL")); err == nil && ttl > 0 {
+		sessionConfig.TTL = ttl
+	}
+	if maxTurns, err := strconv.Atoi(os.Getenv("SESSION_MAX_TURNS")); err == nil && maxTurns > 0 {
+		sessionConfig.MaxTurns = maxTurns
+	}
+	if maxSessions, err := strconv.Atoi(os.Getenv("SESSION_MAX_SESSIONS")); err == nil && maxSessions > 0 {
+		sessionConfig.MaxSessions = maxSessions
+	}
+
+	// Speech-to-text backend for /chat/audio: OpenAI Whisper API (default) or a self-hosted whisper.cpp server
+	var transcriber stt.Transcriber
+	sttLanguage := os.Getenv("STT_LANGUAGE")
+	if sttLanguage == "" {
+		sttLanguage = "pt"
+	}
+	switch sttBackend := os.Getenv("STT_BACKEND"); sttBackend {
+	case "", "openai":
+		transcriber = stt.NewOpenAI(openaiKey, os.Getenv("STT_MODEL"), sttLanguage)
+	case "whispercpp":
+		if whisperURL := os.Getenv("WHISPER_CPP_URL"); whisperURL != "" {
+			transcriber = stt.NewWhisperCPP(whisperURL, sttLanguage)
+		} else {
+			log.Printf("STT_BACKEND=whispercpp but WHISPER_CPP_URL not set - audio uploads will be disabled")
+		}
+	default:
+		log.Printf("STT_BACKEND=%s not recognized - audio uploads will be disabled", sttBackend)
+	}
+	if transcriber != nil {
+		log.Printf("Speech-to-text enabled at %s (backend=%s)", validator.AudioPath, transcriber.Name())
+	}
+
+	// Text-to-speech backend for audio responses: OpenAI speech API (default) or a self-hosted Piper server
+	var synthesizer tts.Synthesizer
+	switch ttsBackend := os.Getenv("TTS_BACKEND"); ttsBackend {
+	case "", "openai":
+		synthesizer = tts.NewOpenAI(openaiKey, os.Getenv("TTS_MODEL"))
+	case "piper":
+		if piperURL := os.Getenv("PIPER_URL"); piperURL != "" {
+			synthesizer = tts.NewPiper(piperURL)
+		} else {
+			log.Printf("TTS_BACKEND=piper but PIPER_URL not set - audio responses will be disabled")
+		}
+	case "none":
+	default:
+		log.Printf("TTS_BACKEND=%s not recognized - audio responses will be disabled", ttsBackend)
+	}
+	if synthesizer != nil {
+		log.Printf("Text-to-speech enabled (backend=%s)", synthesizer.Name())
+	}
+
 	server := &Server{
 		openaiClient:     openaiClient,
-		openaiAPIKey:     openaiKey,
 		perplexityAPIKey: perplexityKey,
-		claudeAPIKey:     claudeKey,
-		apiKeySecret:     apiKeySecret,
 		logger:           logger,
+		sessions:         session.NewMemoryStore(sessionConfig),
+		transcriber:      transcriber,
+		synthesizer:      synthesizer,
 	}
 
 	// Setup middleware chain
 	mux := http.NewServeMux()
 	mux.HandleFunc("/chat", server.handleChat)
+	mux.HandleFunc("/chat/stream", server.handleChat) // Server-Sent Events (same as /chat with Accept: text/event-stream)
+	mux.HandleFunc(validator.AudioPath, server.handleAudio)
 	mux.HandleFunc("/health", server.handleHealth)
 	mux.HandleFunc("/", handleOptions) // CORS preflight for root
 
@@ -382,6 +499,8 @@ func main() {
 	// This prevents 404 errors and provides better user feedback
 	adminHandler := admin.NewHandler(logger)
 	adminHandler.RegisterRoutes(mux)
+	// Circuit breaker state changes go to the audit log
+	breaker.SetTransitionHook(adminHandler.LogBreakerTransition)
 	if adminHandler.IsEnabled() {
 		log.Printf("Admin dashboard enabled at /admin/")
 	} else {
@@ -401,21 +520,227 @@ func main() {
 	}
 	admin.SetDefaultCategoryPrompts(defaultCategoryPrompts)
 
+	// Persist runtime config so dashboard/API changes survive restarts and reach every instance
+	// The change history is kept next to the config (runtime-config-history.json), and so are
+	// the API keys (api-keys.json)
+	var configStore, historyStore, keysStore configstore.Store
+	switch backend := os.Getenv("CONFIG_STORE"); backend {
+	case "":
+	case "file":
+		configPath := os.Getenv("CONFIG_FILE")
+		if configPath == "" {
+			configPath = "runtime-config.json"
+		}
+		configStore = configstore.NewFile(configPath)
+		historyStore = configstore.NewFile(historyName(configPath))
+		keysStore = configstore.NewFile(keysName(configPath))
+	case "gcs":
+		bucket := os.Getenv("CONFIG_GCS_BUCKET")
+		object := os.Getenv("CONFIG_GCS_OBJECT")
+		if object == "" {
+			object = "runtime-config.json"
+		}
+		if bucket == "" {
+			log.Printf("CONFIG_STORE=gcs but CONFIG_GCS_BUCKET not set - runtime config will not be persisted")
+		} else if configStore, err = configstore.NewGCS(ctx, bucket, object); err != nil {
+			log.Printf("Failed to create GCS config store: %v - runtime config will not be persisted", err)
+			configStore = nil
+		} else if historyStore, err = configstore.NewGCS(ctx, bucket, historyName(object)); err != nil {
+			log.Printf("Failed to create GCS history store: %v - config history will be kept in memory", err)
+			historyStore = nil
+		} else if keysStore, err = configstore.NewGCS(ctx, bucket, keysName(object)); err != nil {
+			log.Printf("Failed to create GCS key store: %v - API keys will be kept in memory", err)
+			keysStore = nil
+		}
+	default:
+		log.Printf("CONFIG_STORE=%s not recognized - runtime config will not be persisted", backend)
+	}
+	reloadInterval := 30 * time.Second
+	if interval, err := time.ParseDuration(os.Getenv("CONFIG_RELOAD_INTERVAL")); err == nil && interval > 0 {
+		reloadInterval = interval
+	}
+	if historyStore != nil {
+		admin.SetHistoryStore(historyStore)
+	}
+	if configStore != nil {
+		admin.SetConfigStore(configStore)
+		if err := admin.LoadConfig(ctx); err != nil {
+			log.Printf("Failed to load stored runtime config, using defaults: %v", err)
+		}
+		admin.StartConfigReload(context.Background(), reloadInterval)
+		log.Printf("Runtime config persisted in %s store (reload every %v)", configStore.Name(), reloadInterval)
+	}
+	// The first dashboard change after a deploy can then be compared with and rolled back to this
+	admin.RecordInitialConfig()
+
+	// Per-client API keys, managed from the dashboard (the shared API key keeps working alongside them)
+	keyRegistry := auth.NewRegistry(keysStore)
+	if keysStore != nil {
+		if err := keyRegistry.Load(ctx); err != nil {
+			log.Printf("Failed to load API keys: %v", err)
+		}
+		keyRegistry.StartReload(context.Background(), reloadInterval)
+	} else {
+		log.Printf("API keys are kept in memory (set CONFIG_STORE to persist them)")
+	}
+	adminHandler.SetKeyRegistry(keyRegistry)
+	adminHandler.SetSharedKeys(sharedKeySet)
+
+	// Rate limits come from the runtime config so they can be tuned from the dashboard or /api/config
+	ratelimit.SetSettingsSource(func() ratelimit.Settings {
+		config := admin.GetConfig()
+		return ratelimit.Settings{
+			Client: ratelimit.Limits{
+				PerMinute: config.RateLimitPerMinute,
+				PerHour:   config.RateLimitPerHour,
+				Burst:     config.RateLimitBurst,
+			},
+			PreAuth: ratelimit.Limits{
+				PerMinute: config.PreAuthRateLimitPerMinute,
+				PerHour:   config.PreAuthRateLimitPerHour,
+			},
+		}
+	})
+
+	// Request costs use the model prices of the runtime config, falling back to the list prices
+	quota.SetPriceSource(func() quota.Prices {
+		return admin.GetConfig().Prices()
+	})
+
+	// Share rate limit state between instances so the limits (and brute force protection) do not
+	// multiply with the instance count or reset on cold starts
+	var redisClient *redis.Client
+	switch backend := os.Getenv("RATE_LIMIT_STORE"); backend {
+	case "", "memory":
+	case "redis":
+		redisURL := os.Getenv("REDIS_URL")
+		if redisURL == "" {
+			log.Printf("RATE_LIMIT_STORE=redis but REDIS_URL not set - rate limits are per instance")
+			break
+		}
+		opts, err := redis.ParseURL(redisURL)
+		if err != nil {
+			log.Printf("Invalid REDIS_URL: %v - rate limits are per instance", err)
+			break
+		}
+		redisClient = redis.NewClient(opts)
+		pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		err = redisClient.Ping(pingCtx).Err()
+		cancel()
+		if err != nil {
+			// Keep the store: the limiter falls back to per-instance limits until Redis is reachable
+			log.Printf("Redis not reachable yet: %v", err)
+		}
+		ratelimit.SetStores(
+			ratelimit.NewRedisStore(redisClient, "clotilde:ratelimit:key:"),
+			ratelimit.NewRedisStore(redisClient, "clotilde:ratelimit:ip:"),
+		)
+		log.Printf("Rate limits shared through Redis at %s", opts.Addr)
+	default:
+		log.Printf("RATE_LIMIT_STORE=%s not recognized - rate limits are per instance", backend)
+	}
+
+	// Cost budgets per API key, counted in Redis alongside the rate limits when it is configured
+	// Days and months start at midnight in Brazil
+	var quotaStore quota.Store
+	if redisClient != nil {
+		quotaStore = quota.NewRedisStore(redisClient, "clotilde:quota:")
+	} else {
+		log.Printf("Quota usage is counted per instance (set RATE_LIMIT_STORE=redis to share it)")
+	}
+	quotaLocation, err := time.LoadLocation(timezoneBR)
+	if err != nil {
+		quotaLocation = time.UTC
+	}
+	server.quotas = quota.NewTracker(quotaStore, quotaLocation)
+	adminHandler.SetQuotas(server.quotas)
+
+	// Answers to repeated questions are served from memory (RESPONSE_CACHE_SIZE=0 disables the cache)
+	cacheConfig := cache.DefaultConfig()
+	if size := os.Getenv("RESPONSE_CACHE_SIZE"); size != "" {
+		if n, err := strconv.Atoi(size); err == nil && n >= 0 {
+			cacheConfig.MaxEntries = n
+		} else {
+			log.Printf("Invalid RESPONSE_CACHE_SIZE %q - using %d", size, cacheConfig.MaxEntries)
+		}
+	}
+	if cacheConfig.MaxEntries > 0 {
+		server.cache = cache.New(cacheConfig)
+		adminHandler.SetResponseCache(server.cache)
+		log.Printf("Response cache enabled (%d answers)", cacheConfig.MaxEntries)
+
+		// Semantic cache: paraphrases of a cached question get its answer, matched by embedding similarity
+		// EMBEDDING_BACKEND: openai (default), hashing (in process, spelling only) or none
+		switch backend := os.Getenv("EMBEDDING_BACKEND"); backend {
+		case "", "openai":
+			if openaiKey != "" {
+				server.embedder = embedding.NewOpenAI(openaiKey, os.Getenv("EMBEDDING_MODEL"))
+			} else {
+				log.Printf("OpenAI key not set - semantic cache disabled")
+			}
+		case "hashing":
+			server.embedder = embedding.NewHashing(0)
+		case "none":
+		default:
+			log.Printf("EMBEDDING_BACKEND=%s not recognized - semantic cache disabled", backend)
+		}
+		if server.embedder != nil {
+			server.semantic = cache.NewSemantic(cacheConfig)
+			adminHandler.SetSemanticCache(server.semantic)
+			log.Printf("Semantic cache enabled (embeddings=%s)", server.embedder.Name())
+		}
+		// Answers generated with the previous prompts and models are dropped when the config changes
+		admin.OnConfigApplied(server.purgeCaches)
+	} else {
+		log.Printf("Response cache disabled")
+	}
+
 	// Middleware order (execution order when request arrives):
 	// 1. PreAuth: IP-based rate limiting BEFORE authentication (prevents brute force)
 	// 2. RequestID: Adds unique request ID for tracing
 	// 3. Validator: Limits request size early (prevents large payloads)
-	// 4. Auth: Validates API key
-	// 5. RateLimit: Rate-limits using VALIDATED API keys (prevents bypass attacks)
+	// 4. Auth: Validates API key (shared key or registry key) or HMAC signature, and the key's scope
+	// 5. RateLimit: Rate-limits using VALIDATED API key IDs (prevents bypass attacks)
 	//
 	// Note: In Go middleware wrapping, the last wrapped executes first.
 	// So we wrap in reverse order: RateLimit → Auth → PreAuth → Validator → RequestID → Mux
 	// Execution Order: RequestID → Validator → PreAuth → Auth → RateLimit
-	handler := ratelimit.Middleware()(mux)           // Uses validated API key from context (runs LAST)
-	handler = auth.Middleware(apiKeySecret)(handler) // Validates API key, sets context
-	handler = ratelimit.PreAuthMiddleware()(handler) // IP-based, runs BEFORE auth
-	handler = validator.Middleware()(handler)        // Limits request size early
-	handler = logging.RequestIDMiddleware(handler)   // Adds ID first (runs FIRST)
+	// Each middleware has its own span, nested in the order they run
+	handler := tracing.Wrap("ratelimit.Middleware", ratelimit.Middleware())(mux)                           // Uses validated API key from context (runs LAST)
+	handler = tracing.Wrap("auth.Middleware", auth.MiddlewareWithKeys(sharedKeySet, keyRegistry))(handler) // Validates API key, sets context
+	handler = tracing.Wrap("ratelimit.PreAuthMiddleware", ratelimit.PreAuthMiddleware())(handler)          // IP-based, runs BEFORE auth
+	handler = tracing.Wrap("validator.Middleware", validator.Middleware())(handler)                        // Limits request size early
+	handler = tracing.Wrap("logging.RequestIDMiddleware", logging.RequestIDMiddleware)(handler)            // Adds ID first (runs FIRST)
+	handler = metrics.Middleware(handler)                                                                  // Counts every response, including rejections
+	handler = tracing.Middleware(handler)                                                                  // Server span, continuing the caller's trace (traceparent)
+
+	// Prometheus metrics: on METRICS_PORT when set (a port only the scraper can reach), otherwise on
+	// /metrics of the main port, outside the API key chain and protected by METRICS_TOKEN instead
+	metricsToken := os.Getenv("METRICS_TOKEN")
+	var metricsSrv *http.Server
+	if metricsPort := os.Getenv("METRICS_PORT"); metricsPort != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", metrics.Handler(metricsToken))
+		metricsSrv = &http.Server{
+			Addr:              ":" + metricsPort,
+			Handler:           metricsMux,
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+		go func() {
+			log.Printf("Metrics available on :%s/metrics", metricsPort)
+			if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("Metrics server failed: %v", err)
+			}
+		}()
+	} else if metricsToken != "" {
+		root := http.NewServeMux()
+		root.Handle("/metrics", metrics.Handler(metricsToken))
+		root.Handle("/", handler)
+		handler = root
+		log.Printf("Metrics available at /metrics (bearer token)")
+	} else {
+		log.Printf("Metrics disabled (set METRICS_TOKEN or METRICS_PORT)")
+	}
 
 	serverAddr := fmt.Sprintf(":%s", port)
 
@@ -461,9 +786,17 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	if metricsSrv != nil {
+		if err := metricsSrv.Shutdown(ctx); err != nil {
+			log.Printf("Metrics server forced to shutdown: %v", err)
+		}
+	}
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("Error flushing traces: %v", err)
+	}
 
 	// Close Cloud Logging client
 	if cloudLogger.IsEnabled() {
@@ -486,6 +819,50 @@ func getSecret(ctx context.Context, client *secretmanager.Client, projectID, sec
 	return string(result.Payload.Data), nil
 }
 
+// getSecretGenerations returns every enabled version of a secret as a shared key generation
+// Each version is valid from its creation until overlap after the next version was created, so clients
+// have that long to pick up a rotated key; disabling a version in Secret Manager retires it at the next reload
+func getSecretGenerations(ctx context.Context, client *secretmanager.Client, projectID, secretName string, overlap time.Duration) ([]auth.SharedKey, error) {
+	it := client.ListSecretVersions(ctx, &secretmanagerpb.ListSecretVersionsRequest{
+		Parent: fmt.Sprintf("projects/%s/secrets/%s", projectID, secretName),
+		Filter: "state:ENABLED",
+	})
+	var versions []*secretmanagerpb.SecretVersion
+	for {
+		version, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list secret versions: %w", err)
+		}
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].GetCreateTime().AsTime().Before(versions[j].GetCreateTime().AsTime())
+	})
+
+	keys := make([]auth.SharedKey, 0, len(versions))
+	for i, version := range versions {
+		result, err := client.AccessSecretVersion(ctx, &secretmanagerpb.AccessSecretVersionRequest{
+			Name: version.GetName(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to access secret version: %w", err)
+		}
+		key := auth.SharedKey{
+			Generation: "v" + path.Base(version.GetName()),
+			Key:        string(result.Payload.Data),
+			NotBefore:  version.GetCreateTime().AsTime(),
+		}
+		if i+1 < len(versions) {
+			key.NotAfter = versions[i+1].GetCreateTime().AsTime().Add(overlap)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	stats := s.logger.GetStats()
 
@@ -498,6 +875,7 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 		"total_requests":    stats.TotalRequests,
 		"memory_mb":         memStats.Alloc / 1024 / 1024,
 		"last_request_time": stats.LastRequestTime,
+		"circuit_breakers":  breaker.Statuses(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -529,6 +907,8 @@ func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
 
 	// Start timing for logging
 	startTime := time.Now()
+	b := newRequestBudget(startTime)
+	r = r.WithContext(budget.NewContext(r.Context(), b))
 
 	// Get request ID from context (added by middleware)
 	requestID := logging.GetRequestID(r.Context())
@@ -550,15 +930,48 @@ func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.processChat(w, r, requestID, b, req, "")
+}
+
+// processChat answers a chat request: validation, routing, generation, formatting and logging
+// transcript is set when the message came from an audio upload and is echoed back to the client
+// The budget started with the request, so time spent transcribing audio is already used
+func (s *Server) processChat(w http.ResponseWriter, r *http.Request, requestID string, b *budget.Budget, req ChatRequest, transcript string) {
+	startTime := b.Start()
 	if req.Message == "" {
 		s.logRequest(requestID, r, "", "", "", "", time.Since(startTime), "error", "Message is required")
 		respondError(w, "Message is required", http.StatusBadRequest)
 		return
 	}
 
+	format, ok := speech.ParseFormat(req.Format)
+	if !ok {
+		s.logRequest(requestID, r, "", "", "", "", time.Since(startTime), "error", "Invalid format")
+		respondError(w, "Invalid format (use text, ssml or speech)", http.StatusBadRequest)
+		return
+	}
+
+	audioMode, ok := audioResponseMode(r, req)
+	if !ok {
+		s.logRequest(requestID, r, "", "", "", "", time.Since(startTime), "error", "Invalid audio_response")
+		respondError(w, "Invalid audio_response (use base64 or binary)", http.StatusBadRequest)
+		return
+	}
+	if audioMode != "" {
+		if s.synthesizer == nil {
+			respondError(w, "Text-to-speech not configured", http.StatusServiceUnavailable)
+			return
+		}
+		if wantsEventStream(r) {
+			respondError(w, "Audio responses are not available when streaming", http.StatusBadRequest)
+			return
+		}
+	}
+
 	// Sanitize input to prevent prompt injection attacks (OWASP LLM Top 10 A1)
 	sanitizedMessage, err := promptinjection.ValidateInput(req.Message)
 	if err != nil {
+		metrics.PromptInjections.WithLabelValues(metrics.InjectionRejected).Inc()
 		s.logRequest(requestID, r, "", "", "", "", time.Since(startTime), "error", "Invalid input: "+err.Error())
 		respondError(w, "Invalid input", http.StatusBadRequest)
 		return
@@ -566,21 +979,66 @@ func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
 
 	// Log if prompt injection was detected (for monitoring)
 	if sanitizedMessage != req.Message {
+		metrics.PromptInjections.WithLabelValues(metrics.InjectionNeutralized).Inc()
 		log.Printf("[%s] Prompt injection detected and neutralized: IP=%s", requestID, hashIP(r.RemoteAddr))
 	}
 
 	// Log request metadata (no sensitive data)
 	log.Printf("[%s] Request received: IP=%s, MessageLength=%d", requestID, hashIP(r.RemoteAddr), len(sanitizedMessage))
 
+	// Load conversation history when the client continues a session
+	// Session IDs are scoped by API key ID so clients cannot read each other's conversations
+	var conversation session.Session
+	if req.SessionID != "" && s.sessions != nil {
+		if !session.ValidID(req.SessionID) {
+			s.logRequest(requestID, r, "", "", "", "", time.Since(startTime), "error", "Invalid session_id")
+			respondError(w, "Invalid session_id", http.StatusBadRequest)
+			return
+		}
+		scopedID := session.ScopedID(auth.GetKeyID(r.Context()), req.SessionID)
+		loaded, found, err := s.sessions.Load(r.Context(), scopedID)
+		if err != nil {
+			// History is best-effort: answer the question standalone rather than failing
+			log.Printf("[%s] Failed to load session: %v", requestID, err)
+		}
+		if found {
+			conversation = loaded
+		} else {
+			conversation = session.Session{ID: scopedID}
+		}
+	}
+
 	// Route to appropriate model and determine if web search is needed
 	// Use sanitized message for routing to prevent injection via routing logic
-	route := router.Route(sanitizedMessage)
+	// Follow-ups without their own category inherit the previous question's category
+	_, routeSpan := tracing.Start(r.Context(), "router.Route")
+	route := router.RouteFollowUp(sanitizedMessage, router.Category(conversation.LastCategory))
+	routeSpan.SetAttributes(
+		attribute.String("clotilde.category", string(route.Category)),
+		attribute.String("clotilde.model", route.Model),
+		attribute.Bool("clotilde.web_search", route.WebSearch),
+	)
+	routeSpan.End()
 	log.Printf("[%s] Route decision: Category=%s, Model=%s, WebSearch=%v", requestID, route.Category, route.Model, route.WebSearch)
 
-	// Call OpenAI with selected model and tools
-	// IMPORTANT: Apple Shortcuts has ~30s internal timeout. We use 25s to leave buffer
-	// for network latency and response processing on the client side.
-	ctx, cancel := context.WithTimeout(context.Background(), 25*time.Second)
+	// Cost budgets of the API key: reject, or answer with the standard model, once one is used up
+	route, quotaReason, ok := s.checkQuota(r.Context(), requestID, route)
+	if !ok {
+		s.logRequest(requestID, r, sanitizedMessage, "", route.Model, string(route.Category), time.Since(startTime), "error", "Quota exceeded: "+quotaReason)
+		respondError(w, "Quota exceeded ("+quotaReason+")", http.StatusTooManyRequests)
+		return
+	}
+
+	// Call the model within what is left of the request budget
+	// A slow search is cut short to leave generation enough time, and when the answer will be
+	// spoken generation stops early to leave time for synthesis
+	b.Reserve(budget.Generation, minGenerationTime)
+	if audioMode != "" {
+		b.Reserve(budget.Synthesis, synthesisReserve)
+	}
+	// The upstream calls keep the request's trace but not its cancellation
+	ctx := trace.ContextWithSpan(budget.NewContext(context.Background(), b), trace.SpanFromContext(r.Context()))
+	ctx, cancel := context.WithDeadline(ctx, b.Deadline())
 	defer cancel()
 
 	// Get current date/time in Brazil timezone for context
@@ -589,45 +1047,124 @@ func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
 	config := admin.GetConfig()
 	systemPrompt := s.buildSystemPrompt(config, route.Category, currentTime)
 
-	// Use Responses API instead of Chat Completions
-	// Convert router.RouteDecision to internal RouteDecision format
-	internalRoute := RouteDecision{
-		Model:           route.Model,
-		WebSearch:       route.WebSearch,
-		ReasoningEffort: route.ReasoningEffort,
-	}
 	// Use sanitized message to prevent prompt injection
-	response, err := s.createResponse(ctx, internalRoute, systemPrompt, sanitizedMessage)
+	history := make([]provider.Message, 0, len(conversation.Turns))
+	for _, turn := range conversation.Turns {
+		history = append(history, provider.Message{Role: turn.Role, Content: turn.Content})
+	}
+
+	// Streaming mode: send each sentence as soon as it is complete so voice clients can start speaking
+	var stream *sseWriter
+	var onSentence func(sentence string) error
+	if wantsEventStream(r) {
+		stream = newSSEWriter(w)
+		onSentence = func(sentence string) error {
+			return stream.send("chunk", StreamChunk{Text: speech.Render(sentence, format)})
+		}
+	}
+	result, err := s.generate(ctx, route, systemPrompt, sanitizedMessage, history, onSentence)
+	b.Release(budget.Generation) // Cache hits never reach the model
+	model := result.model(route.Model)
+	metrics.SetRequestLabels(r.Context(), string(route.Category), model, result.providerName(model))
+	usage := result.usage(model)
 	if err != nil {
-		log.Printf("[%s] OpenAI Responses API error: %v", requestID, err)
+		log.Printf("[%s] AI provider error: %v", requestID, err)
 		// Log original message for debugging, but use sanitized for API calls
-		s.logRequest(requestID, r, sanitizedMessage, "", route.Model, string(route.Category), time.Since(startTime), "error", err.Error())
+		// Searches already made are still paid, so they are logged and charged
+		s.logUsage(requestID, r, sanitizedMessage, "", model, string(route.Category), time.Since(startTime), "error", err.Error(), usage)
+		s.chargeQuota(r.Context(), requestID, route, usage)
 		
 		// Check if it's a timeout error and provide friendly message
 		if ctx.Err() == context.DeadlineExceeded || strings.Contains(err.Error(), "context deadline exceeded") || strings.Contains(err.Error(), "timeout") {
 			// Provide a helpful response for timeouts - spoken via CarPlay
-			respondSuccess(w, "Desculpe, a pergunta demorou demais para processar. Tente uma pergunta mais simples ou tente novamente.")
+			timeoutMessage := "Desculpe, a pergunta demorou demais para processar. Tente uma pergunta mais simples ou tente novamente."
+			if stream != nil {
+				stream.send("chunk", StreamChunk{Text: speech.Render(timeoutMessage, format)})
+				stream.send("done", ChatResponse{Response: speech.Render(timeoutMessage, format), SessionID: req.SessionID, Transcript: transcript})
+				return
+			}
+			if audioMode != "" {
+				s.respondAudio(w, requestID, b, audioMode, ChatResponse{Response: speech.Render(timeoutMessage, format), SessionID: req.SessionID, Transcript: transcript}, spokenText(timeoutMessage))
+				return
+			}
+			respondSuccess(w, ChatResponse{Response: speech.Render(timeoutMessage, format), SessionID: req.SessionID, Transcript: transcript})
+			return
+		}
+		if stream != nil {
+			// Headers may already be sent, so the error is reported as an event
+			stream.send("error", ChatResponse{Error: "Failed to get response from AI"})
 			return
 		}
 		respondError(w, "Failed to get response from AI", http.StatusInternalServerError)
 		return
 	}
 
+	response := result.Text
 	if response == "" {
 		response = "Desculpe, não consegui processar sua solicitação. Pode repetir?"
+		if stream != nil {
+			stream.send("chunk", StreamChunk{Text: speech.Render(response, format)})
+		}
 	}
 
-	// Log successful request
-	responseTime := time.Since(startTime)
-	log.Printf("[%s] Response generated: Length=%d, Time=%v", requestID, len(response), responseTime)
-	// Log sanitized message (original stored separately if needed for audit)
-	s.logRequest(requestID, r, sanitizedMessage, response, route.Model, string(route.Category), responseTime, "success", "")
+	log.Printf("[%s] Response generated: Length=%d, Time=%v", requestID, len(response), time.Since(startTime))
+
+	// Record the exchange so the next question in this session has context
+	// Store the answer as spoken (without URLs) to keep follow-up prompts clean
+	if conversation.ID != "" {
+		now := time.Now()
+		conversation.Turns = append(conversation.Turns,
+			session.Turn{Role: session.RoleUser, Content: sanitizedMessage, Timestamp: now},
+			session.Turn{Role: session.RoleAssistant, Content: removeURLsFromText(response), Timestamp: now},
+		)
+		conversation.LastCategory = string(route.Category)
+		if err := s.sessions.Save(r.Context(), conversation); err != nil {
+			log.Printf("[%s] Failed to save session: %v", requestID, err)
+		}
+	}
 
-	respondSuccess(w, response)
+	switch {
+	case stream != nil:
+		// Final event carries the full answer (URLs removed) for clients that also want the whole text
+		stream.send("done", ChatResponse{Response: formatAnswer(response, format), SessionID: req.SessionID, Transcript: transcript})
+	case audioMode != "":
+		s.respondAudio(w, requestID, b, audioMode, ChatResponse{Response: formatAnswer(response, format), SessionID: req.SessionID, Transcript: transcript}, spokenText(response))
+	default:
+		respondSuccess(w, ChatResponse{Response: formatAnswer(response, format), SessionID: req.SessionID, Transcript: transcript})
+	}
+
+	// Log successful request once answered, so the response time and stage timings include synthesis
+	// Log sanitized message (original stored separately if needed for audit)
+	s.logUsage(requestID, r, sanitizedMessage, response, model, string(route.Category), time.Since(startTime), "success", "", usage)
+	s.chargeQuota(r.Context(), requestID, route, usage)
 }
 
 // logRequest adds a structured log entry with full input/output for Cloud Logging
 func (s *Server) logRequest(requestID string, r *http.Request, input, output, model, category string, responseTime time.Duration, status, errorMsg string) {
+	s.logger.Add(s.logEntry(requestID, r, input, output, model, category, responseTime, status, errorMsg))
+}
+
+// logUsage adds the log entry of a request that reached a model, with its token usage and cost
+func (s *Server) logUsage(requestID string, r *http.Request, input, output, model, category string, responseTime time.Duration, status, errorMsg string, usage requestUsage) {
+	entry := s.logEntry(requestID, r, input, output, model, category, responseTime, status, errorMsg)
+	entry.InputTokens = usage.InputTokens
+	entry.OutputTokens = usage.OutputTokens
+	entry.ReasoningTokens = usage.ReasoningTokens
+	entry.UsageEstimated = usage.Estimated
+	entry.SearchCalls = usage.SearchCalls
+	entry.PerplexityCalls = usage.PerplexityCalls
+	entry.Cost = usage.Cost
+	entry.Cache = usage.Cache
+	entry.CacheSimilarity = usage.CacheSimilarity
+	entry.FallbackFrom = usage.FallbackFrom
+	entry.Fallbacks = usage.Fallbacks
+	entry.Hedged = usage.Hedged
+	entry.HedgeWinner = usage.HedgeWinner
+	s.logger.Add(entry)
+}
+
+// logEntry builds the log entry of a request, applying PII redaction and the content logging setting
+func (s *Server) logEntry(requestID string, r *http.Request, input, output, model, category string, responseTime time.Duration, status, errorMsg string) logging.LogEntry {
 	// Apply PII redaction if enabled
 	loggedInput := input
 	loggedOutput := output
@@ -649,10 +1186,14 @@ func (s *Server) logRequest(requestID string, r *http.Request, input, output, mo
 		finalOutput = ""
 	}
 
-	entry := logging.LogEntry{
+	identity, _ := auth.GetIdentity(r.Context())
+	return logging.LogEntry{
 		ID:            requestID,
 		Timestamp:     time.Now(),
 		IPHash:        hashIP(r.RemoteAddr),
+		KeyID:         identity.KeyID,
+		KeyGeneration: identity.Generation,
+		AuthMethod:    identity.Method,
 		MessageLength: len(input), // Always log original length, even if content is redacted
 		Model:         model,
 		Category:      category,
@@ -662,8 +1203,8 @@ func (s *Server) logRequest(requestID string, r *http.Request, input, output, mo
 		ErrorMessage:  errorMsg,
 		Input:         finalInput,
 		Output:        finalOutput,
+		Stages:        budget.FromContext(r.Context()).Timings(),
 	}
-	s.logger.Add(entry)
 }
 
 var (
@@ -704,14 +1245,31 @@ func removeURLsFromText(text string) string {
 	return strings.TrimSpace(text)
 }
 
-func respondSuccess(w http.ResponseWriter, response string) {
+// formatAnswer removes URLs and renders the answer in the format requested by the client
+// Paragraphs are cleaned one at a time because removeURLsFromText collapses newlines,
+// which SSML needs to place pauses between paragraphs
+func formatAnswer(text string, format speech.Format) string {
+	if format == speech.FormatText {
+		return removeURLsFromText(text)
+	}
+
+	var paragraphs []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		if cleaned := removeURLsFromText(paragraph); cleaned != "" {
+			paragraphs = append(paragraphs, cleaned)
+		}
+	}
+	return speech.Render(strings.Join(paragraphs, "\n"), format)
+}
+
+func respondSuccess(w http.ResponseWriter, response ChatResponse) {
 	// Remove any URLs that might have escaped the system prompt
-	response = removeURLsFromText(response)
+	response.Response = removeURLsFromText(response.Response)
 
 	w.Header().Set("Content-Type", "application/json")
 	// CORS restricted to Apple Shortcuts origin for security
 	setCORSHeaders(w)
-	json.NewEncoder(w).Encode(ChatResponse{Response: response})
+	json.NewEncoder(w).Encode(response)
 }
 
 func respondError(w http.ResponseWriter, message string, statusCode int) {
@@ -733,7 +1291,7 @@ func setCORSHeaders(w http.ResponseWriter) {
 	}
 	w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
 	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-API-Key")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-API-Key, X-Clotilde-Signature, X-Clotilde-Timestamp, X-Clotilde-Nonce")
 	w.Header().Set("Access-Control-Max-Age", "3600")
 }
 
@@ -774,6 +1332,16 @@ func getIPHashSalt() string {
 	return ipHashSalt
 }
 
+// historyName derives the config history document name from the config document name
+func historyName(name string) string {
+	return strings.TrimSuffix(name, ".json") + "-history.json"
+}
+
+// keysName derives the API key document name, in the same directory as the config document
+func keysName(name string) string {
+	return strings.TrimSuffix(name, path.Base(name)) + "api-keys.json"
+}
+
 func hashIP(ip string) string {
 	// Cryptographically secure IP hashing using SHA-256 with salt
 	// This prevents rainbow table attacks and makes it difficult to reverse hashes
@@ -789,14 +1357,19 @@ func hashIP(ip string) string {
 	return fmt.Sprintf("ip_%s", hex.EncodeToString(hash[:16])) // Use first 16 bytes (128 bits) for shorter hash
 }
 
-// getCurrentBrazilTime returns current date and time in Brazil/São Paulo timezone
-func getCurrentBrazilTime() string {
+// brazilNow returns the current time in Brazil/São Paulo timezone
+func brazilNow() time.Time {
 	loc, err := time.LoadLocation(timezoneBR)
 	if err != nil {
 		// Fallback to UTC if timezone loading fails
 		loc = time.UTC
 	}
-	now := time.Now().In(loc)
+	return time.Now().In(loc)
+}
+
+// getCurrentBrazilTime returns current date and time in Brazil/São Paulo timezone
+func getCurrentBrazilTime() string {
+	now := brazilNow()
 
 	// Format date in Portuguese
 	months := map[time.Month]string{
@@ -832,6 +1405,9 @@ type PerplexitySearchResponse struct {
 	Results []PerplexitySearchResult `json:"results"`
 }
 
+// perplexityBreaker is the name of the circuit breaker of the Perplexity Search API
+const perplexityBreaker = "perplexity"
+
 // PerplexitySearchResult represents a single search result from Perplexity
 type PerplexitySearchResult struct {
 	Title       string `json:"title"`
@@ -841,11 +1417,44 @@ type PerplexitySearchResult struct {
 	LastUpdated string `json:"last_updated,omitempty"`
 }
 
+// perplexityClient sends the trace context along with Perplexity searches
+var perplexityClient = &http.Client{Transport: tracing.Transport(nil)}
+
 // performPerplexitySearch calls the Perplexity Search API to get web search results
-func (s *Server) performPerplexitySearch(ctx context.Context, query string) ([]PerplexitySearchResult, error) {
+// While Perplexity keeps failing its breaker is open and the search fails at once, so the
+// native web search is used without waiting for the timeout
+func (s *Server) performPerplexitySearch(ctx context.Context, query string) (results []PerplexitySearchResult, err error) {
 	if s.perplexityAPIKey == "" {
 		return nil, fmt.Errorf("Perplexity API key not configured")
 	}
+	ctx, span := tracing.Start(ctx, "perplexity.search")
+	defer func() {
+		span.SetAttributes(attribute.Int("clotilde.search_results", len(results)))
+		tracing.End(span, err)
+	}()
+
+	// The search gets what is left once generation (and synthesis) have their share of the budget
+	b := budget.FromContext(ctx)
+	allot := b.Allot(budget.Search, maxSearchTime)
+	if allot < minSearchTime {
+		return nil, fmt.Errorf("search skipped: only %v left in the request budget", allot.Round(time.Millisecond))
+	}
+	ctx, cancel := context.WithTimeout(ctx, allot)
+	defer cancel()
+	defer b.Track(budget.Search)()
+
+	done, err := breaker.Get(perplexityBreaker).Allow()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		// Running out of a shortened search budget says nothing about Perplexity's health
+		if allot < maxSearchTime && errors.Is(err, context.DeadlineExceeded) {
+			done(breaker.Ignored)
+			return
+		}
+		done(upstreamResult(err))
+	}()
 
 	// Build request body
 	reqBody := PerplexitySearchRequest{
@@ -876,14 +1485,14 @@ func (s *Server) performPerplexitySearch(ctx context.Context, query string) ([]P
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.perplexityAPIKey))
 
-	// Make HTTP request
-	// Use 8s timeout for Perplexity to leave time for OpenAI call within 25s total budget
-	client := &http.Client{Timeout: 8 * time.Second}
-	resp, err := client.Do(httpReq)
+	// Make HTTP request (bounded by the search budget through ctx)
+	resp, err := perplexityClient.Do(httpReq)
 	if err != nil {
+		observeUpstream(perplexityBreaker, err)
 		return nil, fmt.Errorf("failed to make Perplexity request: %w", err)
 	}
 	defer resp.Body.Close()
+	metrics.UpstreamResponses.WithLabelValues(perplexityBreaker, strconv.Itoa(resp.StatusCode)).Inc()
 
 	// Read response body
 	body, err := io.ReadAll(resp.Body)
@@ -894,7 +1503,7 @@ func (s *Server) performPerplexitySearch(ctx context.Context, query string) ([]P
 	// Check HTTP status
 	if resp.StatusCode != http.StatusOK {
 		log.Printf("Perplexity API returned status %d: %s", resp.StatusCode, string(body))
-		return nil, fmt.Errorf("Perplexity API returned status %d", resp.StatusCode)
+		return nil, &provider.Error{Message: fmt.Sprintf("Perplexity API returned status %d", resp.StatusCode), StatusCode: resp.StatusCode}
 	}
 
 	// Parse response
@@ -930,317 +1539,107 @@ func formatPerplexityResults(results []PerplexitySearchResult) string {
 	return builder.String()
 }
 
-// createResponse routes to the appropriate AI provider (Claude or OpenAI)
+// createResponse routes the request to the provider that serves the selected model
 // Claude models are preferred for speed-critical CarPlay scenarios
 // OpenAI Responses API has native web_search support for real-time information
-func (s *Server) createResponse(ctx context.Context, route RouteDecision, instructions, input string) (string, error) {
+// history holds previous turns of the conversation (empty for single-shot requests)
+func (s *Server) createResponse(ctx context.Context, route RouteDecision, instructions, input string, history []provider.Message) (answer, error) {
+	p, req, perplexityCalls, err := s.prepareRequest(ctx, route, instructions, input, history)
+	if err != nil {
+		return answer{Request: req, PerplexityCalls: perplexityCalls}, err
+	}
+	ctx, done := generationContext(ctx)
+	defer done()
+
+	var hedge hedgeOutcome
+	result, err := s.generateWithFallback(ctx, route, p, req, perplexityCalls, func(ctx context.Context, p provider.Provider, req provider.Request) (provider.Response, bool, error) {
+		log.Printf("Using %s provider: model=%s", p.Name(), req.Model)
+		// Only the routed model is hedged; fallback models already replace a failed one
+		if route.Hedge != nil && req.Model == route.Model {
+			resp, outcome, err := generateHedged(ctx, p, req, *route.Hedge, perplexityCalls)
+			hedge = outcome
+			return resp, false, err
+		}
+		resp, err := generateTimed(ctx, p, req)
+		return resp, false, err
+	})
+	result.Hedged = hedge.Hedged
+	result.HedgeWinner = hedge.Winner
+	result.Discarded = append(result.Discarded, hedge.Discarded...)
+	if hedge.Winner == logging.HedgeSecondary {
+		result.Model = hedge.Request.Model
+		result.Request = hedge.Request
+	}
+	return result, err
+}
+
+// prepareRequest selects the provider for the model and builds the request,
+// running the Perplexity search first when web search is needed
+// perplexityCalls is the number of Perplexity queries made (they are paid even if generation fails)
+func (s *Server) prepareRequest(ctx context.Context, route RouteDecision, instructions, input string, history []provider.Message) (provider.Provider, provider.Request, int, error) {
+	p, ok := provider.ForModel(route.Model)
+	if !ok {
+		return nil, provider.Request{}, 0, fmt.Errorf("no provider configured for model %s", route.Model)
+	}
+	caps := provider.CapabilitiesFor(route.Model)
+
 	// Get current config to check Perplexity setting
 	config := admin.GetConfig()
 
-	// Check if this is a Claude model
-	if isClaudeModel(route.Model) && s.claudeAPIKey != "" {
-		// CRITICAL: If web search is needed, use Perplexity first to get real-time data
-		// We should NEVER rely on training data for recent information
-		if route.WebSearch {
-			if config.PerplexityEnabled && s.perplexityAPIKey != "" {
-				log.Printf("Using Perplexity Search API with Claude for web search (real-time data required)")
-				perplexityResults, err := s.performPerplexitySearch(ctx, input)
-				if err != nil {
-					log.Printf("Perplexity search failed: %v, using Claude without web search (WARNING: may be outdated)", err)
-					// Continue to Claude without web search results - user will get training data only
-				} else {
-					// Format Perplexity results and append to instructions
-					formattedResults := formatPerplexityResults(perplexityResults)
-					if formattedResults != "" {
-						instructions = fmt.Sprintf("%s\n\n%s", instructions, formattedResults)
-						log.Printf("Perplexity results appended to Claude instructions for real-time data")
-					}
-				}
-			} else {
-				log.Printf("WARNING: Web search needed but Perplexity not configured - Claude will use training data only (may be outdated)")
-			}
-		}
-		log.Printf("Using Claude API for fast response: model=%s", route.Model)
-		return s.makeClaudeRequest(ctx, route.Model, instructions, input)
+	req := provider.Request{
+		Model:           route.Model,
+		Instructions:    instructions,
+		History:         history,
+		Input:           input,
+		ReasoningEffort: route.ReasoningEffort,
 	}
 
-	// Build request body for Responses API
-	store := true // Enable logging so usage appears in OpenAI logs
-
-	// Handle web search: use Perplexity if enabled, otherwise use OpenAI's web_search tool
+	perplexityCalls := 0
+	// Handle web search: use Perplexity if enabled, otherwise use the provider's native web_search tool
+	// CRITICAL: We should NEVER rely on training data for recent information
 	if route.WebSearch {
 		if config.PerplexityEnabled && s.perplexityAPIKey != "" {
-			// Use Perplexity Search API
-			log.Printf("Using Perplexity Search API for web search")
-			perplexityResults, err := s.performPerplexitySearch(ctx, input)
+			log.Printf("Using Perplexity Search API for web search (provider=%s)", p.Name())
+			perplexityResults, err := s.performPerplexitySearch(ctx, searchQuery(history, input))
 			if err != nil {
-				log.Printf("Perplexity search failed: %v, falling back to OpenAI web_search", err)
-				// Fallback to OpenAI web_search on error
-				webSearchTool := WebSearchTool{Type: "web_search"}
-				reqBody := ResponsesAPIRequest{
-					Model:        route.Model,
-					Input:        input,
-					Instructions: instructions,
-					Store:        &store,
-					Tools:        []interface{}{webSearchTool},
-				}
-				return s.makeOpenAIRequest(ctx, reqBody, route)
-			}
-
-			// Format Perplexity results and append to instructions
-			formattedResults := formatPerplexityResults(perplexityResults)
-			enhancedInstructions := instructions
-			if formattedResults != "" {
-				enhancedInstructions = fmt.Sprintf("%s\n\n%s", instructions, formattedResults)
-			}
-
-			// Create request without web_search tool (using Perplexity results in instructions)
-			reqBody := ResponsesAPIRequest{
-				Model:        route.Model,
-				Input:        input,
-				Instructions: enhancedInstructions,
-				Store:        &store,
-			}
-			return s.makeOpenAIRequest(ctx, reqBody, route)
-		} else {
-			// Use OpenAI's native web_search tool
-			log.Printf("Using OpenAI web_search tool for web search")
-			webSearchTool := WebSearchTool{Type: "web_search"}
-			reqBody := ResponsesAPIRequest{
-				Model:        route.Model,
-				Input:        input,
-				Instructions: instructions,
-				Store:        &store,
-				Tools:        []interface{}{webSearchTool},
-			}
-			return s.makeOpenAIRequest(ctx, reqBody, route)
-		}
-	}
-
-	// No web search needed - create standard request
-	reqBody := ResponsesAPIRequest{
-		Model:        route.Model,
-		Input:        input,
-		Instructions: instructions,
-		Store:        &store,
-	}
-	return s.makeOpenAIRequest(ctx, reqBody, route)
-}
-
-// makeOpenAIRequest makes the actual HTTP request to OpenAI Responses API
-func (s *Server) makeOpenAIRequest(ctx context.Context, reqBody ResponsesAPIRequest, route RouteDecision) (string, error) {
-	// Set reasoning effort only for models that support it (o1, o3, gpt-5 series)
-	// Models like gpt-4o, gpt-4-turbo don't support reasoning parameter
-	// IMPORTANT: gpt-5 requires reasoning >= "low" for web search to work
-	// According to OpenAI docs: "Web search is currently not supported in gpt-5 with minimal reasoning"
-	// Note: This only applies when using OpenAI's web_search tool, not when using Perplexity
-	if modelSupportsReasoning(route.Model) {
-		reasoningEffort := route.ReasoningEffort
-		// Check if web_search tool is being used (not Perplexity)
-		usingWebSearchTool := false
-		if len(reqBody.Tools) > 0 {
-			// Check if any tool is a web_search tool
-			for _, tool := range reqBody.Tools {
-				if toolMap, ok := tool.(map[string]interface{}); ok {
-					if toolType, ok := toolMap["type"].(string); ok && toolType == "web_search" {
-						usingWebSearchTool = true
-						break
-					}
-				} else if toolStruct, ok := tool.(WebSearchTool); ok && toolStruct.Type == "web_search" {
-					usingWebSearchTool = true
-					break
+				if caps.WebSearch {
+					log.Printf("Perplexity search failed: %v, falling back to native web_search", err)
+					metrics.PerplexityFallbacks.WithLabelValues("native_search").Inc()
+					req.WebSearch = true
+				} else {
+					metrics.PerplexityFallbacks.WithLabelValues("none").Inc()
+					// Continue without web search results - user will get training data only
+					log.Printf("Perplexity search failed: %v, using %s without web search (WARNING: may be outdated)", err, route.Model)
 				}
-			}
-		}
-
-		// If using gpt-5 with OpenAI's web_search tool, must use at least "medium" reasoning
-		if strings.HasPrefix(route.Model, "gpt-5") && route.WebSearch && usingWebSearchTool {
-			if reasoningEffort == "" || reasoningEffort == "none" {
-				reasoningEffort = "medium" // Minimum required for web search
-				log.Printf("gpt-5 with web search: using reasoning='medium' (minimum required)")
-			}
-		}
-		if reasoningEffort != "" && reasoningEffort != "none" {
-			reqBody.Reasoning = &ReasoningConfig{Effort: reasoningEffort}
-			log.Printf("Reasoning effort: %s", reasoningEffort)
-		}
-	}
-
-	// Ensure Store is always set to true for logging
-	if reqBody.Store == nil {
-		store := true
-		reqBody.Store = &store
-	} else {
-		*reqBody.Store = true // Force to true to ensure logging is enabled
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// Log request details (without sensitive data) for debugging
-	log.Printf("OpenAI Responses API request: model=%s, store=%v, has_tools=%v",
-		reqBody.Model, reqBody.Store != nil && *reqBody.Store, len(reqBody.Tools) > 0)
-
-	// Create HTTP request to Responses API
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/responses", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.openaiAPIKey))
-
-	// Make HTTP request
-	// Use 20s timeout for OpenAI to fit within 25s total budget (leaves buffer for processing)
-	client := &http.Client{Timeout: 20 * time.Second}
-	resp, err := client.Do(httpReq)
-	if err != nil {
-		return "", fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
-	}
-
-	// Check HTTP status
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("OpenAI API returned status %d: %s", resp.StatusCode, string(body))
-		return "", fmt.Errorf("API returned status %d", resp.StatusCode)
-	}
-
-	// Parse response
-	var apiResp ResponsesAPIResponse
-	if err := json.Unmarshal(body, &apiResp); err != nil {
-		log.Printf("Failed to parse response body: %s", string(body))
-		return "", fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	// Check for API-level errors
-	if apiResp.Error != nil {
-		return "", fmt.Errorf("API error: %s (type: %s)", apiResp.Error.Message, apiResp.Error.Type)
-	}
-
-	// Responses API returns output as an array of items
-	// Structure: output[0].content[0].text (for message type items)
-	if apiResp.Output != nil {
-		if outputArr, ok := apiResp.Output.([]interface{}); ok {
-			for _, item := range outputArr {
-				if itemMap, ok := item.(map[string]interface{}); ok {
-					// Look for message type items
-					if itemType, ok := itemMap["type"].(string); ok && itemType == "message" {
-						// Content is an array of content items
-						if contentArr, ok := itemMap["content"].([]interface{}); ok {
-							for _, contentItem := range contentArr {
-								if contentMap, ok := contentItem.(map[string]interface{}); ok {
-									// Look for output_text type content
-									if contentType, ok := contentMap["type"].(string); ok && contentType == "output_text" {
-										if text, ok := contentMap["text"].(string); ok && text != "" {
-											return text, nil
-										}
-									}
-								}
-							}
-						}
-					}
+			} else {
+				perplexityCalls++
+				if formattedResults := formatPerplexityResults(perplexityResults); formattedResults != "" {
+					// Append Perplexity results to instructions (no web_search tool needed)
+					req.Instructions = fmt.Sprintf("%s\n\n%s", instructions, formattedResults)
+					log.Printf("Perplexity results appended to instructions for real-time data")
 				}
 			}
+		} else if caps.WebSearch {
+			log.Printf("Using native web_search tool for web search (provider=%s)", p.Name())
+			req.WebSearch = true
+		} else {
+			log.Printf("WARNING: Web search needed but Perplexity not configured - %s will use training data only (may be outdated)", route.Model)
 		}
 	}
 
-	// Fallback: try output_text field (SDK-only convenience property, may not be in raw API response)
-	if apiResp.OutputText != "" {
-		return apiResp.OutputText, nil
-	}
-
-	log.Printf("Empty response from API. Full response: %s", string(body))
-	return "", fmt.Errorf("empty response from API")
+	return p, req, perplexityCalls, nil
 }
 
-// makeClaudeRequest makes a request to Claude Messages API (Anthropic)
-// Claude Haiku 4.5 is extremely fast (~1-3s) and ideal for CarPlay where speed is critical
-func (s *Server) makeClaudeRequest(ctx context.Context, model, systemPrompt, userMessage string) (string, error) {
-	if s.claudeAPIKey == "" {
-		return "", fmt.Errorf("Claude API key not configured")
-	}
-
-	// Build request body for Claude Messages API
-	reqBody := ClaudeRequest{
-		Model:     model,
-		MaxTokens: 500, // Keep responses concise for CarPlay
-		System:    systemPrompt,
-		Messages: []ClaudeMessage{
-			{Role: "user", Content: userMessage},
-		},
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal Claude request: %w", err)
-	}
-
-	log.Printf("Claude API request: model=%s, max_tokens=%d", model, reqBody.MaxTokens)
-
-	// Create HTTP request to Claude Messages API
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("failed to create Claude request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("x-api-key", s.claudeAPIKey)
-	httpReq.Header.Set("anthropic-version", "2023-06-01")
-
-	// Use 15s timeout for Claude (it's very fast, Haiku typically responds in 1-3s)
-	client := &http.Client{Timeout: 15 * time.Second}
-	resp, err := client.Do(httpReq)
-	if err != nil {
-		return "", fmt.Errorf("failed to make Claude request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read Claude response: %w", err)
-	}
-
-	// Check HTTP status
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Claude API returned status %d: %s", resp.StatusCode, string(body))
-		return "", fmt.Errorf("Claude API returned status %d", resp.StatusCode)
-	}
-
-	// Parse response
-	var claudeResp ClaudeResponse
-	if err := json.Unmarshal(body, &claudeResp); err != nil {
-		log.Printf("Failed to parse Claude response body: %s", string(body))
-		return "", fmt.Errorf("failed to parse Claude response: %w", err)
-	}
-
-	// Check for API-level errors
-	if claudeResp.Error != nil {
-		return "", fmt.Errorf("Claude API error: %s (type: %s)", claudeResp.Error.Message, claudeResp.Error.Type)
-	}
-
-	// Extract text from response content
-	for _, content := range claudeResp.Content {
-		if content.Type == "text" && content.Text != "" {
-			return content.Text, nil
+// searchQuery builds the Perplexity query for a question
+// Follow-ups like "e em Curitiba?" are meaningless on their own, so the previous
+// user question is prepended to give the search engine the missing context
+func searchQuery(history []provider.Message, input string) string {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Role == provider.RoleUser {
+			return history[i].Content + " " + input
 		}
 	}
-
-	log.Printf("Empty response from Claude. Full response: %s", string(body))
-	return "", fmt.Errorf("empty response from Claude")
-}
-
-// isClaudeModel checks if the model name is a Claude model
-func isClaudeModel(model string) bool {
-	return strings.HasPrefix(model, "claude-")
+	return input
 }
 
 // handleConfigAPI handles GET and POST requests for /api/config endpoint
@@ -1273,6 +1672,7 @@ func (s *Server) handleGetConfigAPI(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	setCORSHeaders(w)
+	admin.SetETag(w, config.Version)
 	if err := json.NewEncoder(w).Encode(config); err != nil {
 		log.Printf("Error encoding config: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -1317,6 +1717,9 @@ func (s *Server) handleSetConfigAPI(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid JSON"})
 		return
 	}
+	if version := admin.IfMatchVersion(r); version != "" {
+		newConfig.Version = version
+	}
 
 	// Validate base system prompt size (prefer BaseSystemPrompt, fallback to SystemPrompt for legacy)
 	basePrompt := newConfig.BaseSystemPrompt
@@ -1344,12 +1747,13 @@ func (s *Server) handleSetConfigAPI(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Update config using admin.SetConfig (includes model validation, prompt format validation, etc.)
-	if err := admin.SetConfig(newConfig); err != nil {
+	// Update config using admin.UpdateConfig (includes model validation, prompt format validation, etc.)
+	origin := admin.ConfigOrigin{Actor: auth.GetKeyID(r.Context()), Source: admin.SourceAPI}
+	if err := admin.UpdateConfig(newConfig, origin); err != nil {
 		log.Printf("Error setting config via API: %v", err)
 		w.Header().Set("Content-Type", "application/json")
 		setCORSHeaders(w)
-		w.WriteHeader(http.StatusBadRequest)
+		w.WriteHeader(admin.ConfigErrorStatus(err))
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
@@ -1357,11 +1761,13 @@ func (s *Server) handleSetConfigAPI(w http.ResponseWriter, r *http.Request) {
 	// Log successful config update
 	log.Printf("Config updated via API: standard_model=%s premium_model=%s", newConfig.StandardModel, newConfig.PremiumModel)
 
-	// Return updated config
+	// Return updated config (with its new version)
+	config := admin.GetConfig()
 	w.Header().Set("Content-Type", "application/json")
 	setCORSHeaders(w)
+	admin.SetETag(w, config.Version)
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(newConfig)
+	json.NewEncoder(w).Encode(config)
 }
 
 // buildSystemPrompt constructs the system prompt using specialized category prompts
@@ -1402,20 +1808,3 @@ func (s *Server) buildSystemPrompt(config admin.RuntimeConfig, category router.C
 	// Category prompts are self-contained and include %s for date/time
 	return fmt.Sprintf(categoryPrompt, currentTime)
 }
-
-// modelSupportsReasoning checks if a model supports the reasoning parameter
-// Only o-series and gpt-5 series models support reasoning configuration
-func modelSupportsReasoning(model string) bool {
-	reasoningModels := []string{
-		"o1", "o1-mini", "o1-pro",
-		"o3", "o3-mini",
-		"o4-mini",
-		"gpt-5", "gpt-5-mini", "gpt-5-nano", "gpt-5-pro", "gpt-5.1",
-	}
-	for _, m := range reasoningModels {
-		if strings.HasPrefix(model, m) {
-			return true
-		}
-	}
-	return false
-}
@@ -10,8 +10,11 @@ import (
 	"testing"
 
 	"github.com/clotilde/carplay-assistant/internal/admin"
+	"github.com/clotilde/carplay-assistant/internal/breaker"
 	"github.com/clotilde/carplay-assistant/internal/logging"
+	"github.com/clotilde/carplay-assistant/internal/provider"
 	"github.com/clotilde/carplay-assistant/internal/router"
+	"github.com/clotilde/carplay-assistant/internal/session"
 )
 
 // TestHealthEndpoint tests the health check endpoint
@@ -50,9 +53,7 @@ func TestHandleChat_OPTIONS(t *testing.T) {
 		os.Unsetenv("API_KEY_SECRET_NAME")
 	}()
 
-	server := &Server{
-		apiKeySecret: "test-api-key",
-	}
+	server := &Server{}
 	req := httptest.NewRequest("OPTIONS", "/chat", nil)
 	rr := httptest.NewRecorder()
 
@@ -75,9 +76,7 @@ func TestHandleChat_MethodNotAllowed(t *testing.T) {
 		os.Unsetenv("API_KEY_SECRET_NAME")
 	}()
 
-	server := &Server{
-		apiKeySecret: "test-api-key",
-	}
+	server := &Server{}
 
 	methods := []string{"GET", "PUT", "DELETE", "PATCH"}
 	for _, method := range methods {
@@ -108,8 +107,7 @@ func TestHandleChat_RequestStructure(t *testing.T) {
 	// Initialize logger
 	logger := logging.GetLogger()
 	server := &Server{
-		apiKeySecret: "test-api-key",
-		logger:       logger,
+		logger: logger,
 	}
 
 	// Test with valid JSON
@@ -379,3 +377,122 @@ func TestPromptHallucinationPrevention(t *testing.T) {
 // 2. Mocking or test API setup
 // 3. Actual test cases for each edge case category
 // These would be added in a separate integration test file or with proper test infrastructure
+
+// useLocalTestModel points the standard and premium models at a fake OpenAI-compatible server
+func useLocalTestModel(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	upstream := httptest.NewServer(handler)
+	t.Cleanup(upstream.Close)
+
+	provider.Register(provider.NewLocal(upstream.URL, "", []string{"test"}))
+	breaker.Get("local").Reset()
+	t.Cleanup(breaker.Get("local").Reset)
+	admin.SetDefaultConfig(clotildeBaseSystemPromptTemplate)
+	previous := admin.GetConfig()
+	t.Cleanup(func() { admin.SetConfig(previous) })
+	config := admin.GetConfig()
+	config.StandardModel = "local:test"
+	config.PremiumModel = "local:test"
+	if err := admin.SetConfig(config); err != nil {
+		t.Fatalf("Failed to set config: %v", err)
+	}
+}
+
+// localAnswer returns a fake Chat Completions handler that always answers with text
+func localAnswer(text string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": text}}},
+		})
+	}
+}
+
+// TestHandleChat_SessionHistory verifies that a session_id carries previous turns to the model
+func TestHandleChat_SessionHistory(t *testing.T) {
+	var lastMessages []map[string]string
+	useLocalTestModel(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []map[string]string `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		lastMessages = body.Messages
+		localAnswer("Resposta")(w, r)
+	})
+
+	server := &Server{
+		logger:   logging.GetLogger(),
+		sessions: session.NewMemoryStore(session.DefaultMemoryConfig()),
+	}
+
+	send := func(message, sessionID string) ChatResponse {
+		bodyBytes, _ := json.Marshal(ChatRequest{Message: message, SessionID: sessionID})
+		rr := httptest.NewRecorder()
+		server.handleChat(rr, httptest.NewRequest("POST", "/chat", bytes.NewReader(bodyBytes)))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var resp ChatResponse
+		json.NewDecoder(rr.Body).Decode(&resp)
+		return resp
+	}
+
+	send("Oi, tudo bem?", "trip-1")
+	resp := send("E você?", "trip-1")
+	if resp.SessionID != "trip-1" {
+		t.Errorf("Expected session_id to be echoed, got %q", resp.SessionID)
+	}
+	// system + previous user/assistant + current question
+	if len(lastMessages) != 4 || lastMessages[1]["content"] != "Oi, tudo bem?" || lastMessages[2]["role"] != "assistant" {
+		t.Errorf("Expected previous turns in the upstream request, got %+v", lastMessages)
+	}
+
+	// Requests without session_id stay single-shot
+	send("E você?", "")
+	if len(lastMessages) != 2 {
+		t.Errorf("Expected no history without session_id, got %+v", lastMessages)
+	}
+
+	// Invalid session IDs are rejected
+	bodyBytes, _ := json.Marshal(ChatRequest{Message: "Oi", SessionID: "../bad id"})
+	rr := httptest.NewRecorder()
+	server.handleChat(rr, httptest.NewRequest("POST", "/chat", bytes.NewReader(bodyBytes)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid session_id, got %d", rr.Code)
+	}
+}
+
+// TestHandleChat_Format verifies the ssml and speech output formats
+func TestHandleChat_Format(t *testing.T) {
+	useLocalTestModel(t, localAnswer("Custa R$ 10,50 em https://loja.com.br hoje.\n\nAbre às 9h."))
+	server := &Server{logger: logging.GetLogger()}
+
+	tests := []struct {
+		format string
+		status int
+		want   string
+	}{
+		{"", http.StatusOK, "Custa R$ 10,50 em hoje. Abre às 9h."},
+		{"speech", http.StatusOK, "Custa dez reais e cinquenta centavos em hoje. Abre às nove horas."},
+		{"ssml", http.StatusOK, `<speak>Custa R$ 10,50 em hoje.<break time="600ms"/>Abre às 9h.</speak>`},
+		{"mp3", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run("format="+tt.format, func(t *testing.T) {
+			bodyBytes, _ := json.Marshal(ChatRequest{Message: "Quanto custa?", Format: tt.format})
+			rr := httptest.NewRecorder()
+			server.handleChat(rr, httptest.NewRequest("POST", "/chat", bytes.NewReader(bodyBytes)))
+			if rr.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rr.Code, rr.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var resp ChatResponse
+			json.NewDecoder(rr.Body).Decode(&resp)
+			if resp.Response != tt.want {
+				t.Errorf("Response = %q, want %q", resp.Response, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+
+	"github.com/clotilde/carplay-assistant/internal/logging"
+	"github.com/clotilde/carplay-assistant/internal/metrics"
+	"github.com/clotilde/carplay-assistant/internal/provider"
+)
+
+// cacheProvider labels the latency of answers served from the response cache
+const cacheProvider = "cache"
+
+// providerName returns the provider label of an answer from model
+func (a answer) providerName(model string) string {
+	if a.Cache == logging.CacheHit || a.Cache == logging.CacheSemanticHit {
+		return cacheProvider
+	}
+	if p, ok := provider.ForModel(model); ok {
+		return p.Name()
+	}
+	return "none"
+}
+
+// observeUpstream counts a call to an upstream by the HTTP status it answered with
+func observeUpstream(upstream string, err error) {
+	metrics.UpstreamResponses.WithLabelValues(upstream, upstreamStatus(err)).Inc()
+}
+
+// upstreamStatus returns the status label of an upstream call: the HTTP status when there was one,
+// otherwise timeout, canceled or error
+func upstreamStatus(err error) string {
+	var providerErr *provider.Error
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return "200"
+	case errors.As(err, &providerErr) && providerErr.StatusCode > 0:
+		return strconv.Itoa(providerErr.StatusCode)
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	}
+	return "error"
+}
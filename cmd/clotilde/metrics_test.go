@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clotilde/carplay-assistant/internal/logging"
+	"github.com/clotilde/carplay-assistant/internal/metrics"
+	"github.com/clotilde/carplay-assistant/internal/provider"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// observations returns the number of latencies observed in a RequestDuration series
+func observations(t *testing.T, labels ...string) uint64 {
+	t.Helper()
+	var m dto.Metric
+	if err := metrics.RequestDuration.WithLabelValues(labels...).(prometheus.Metric).Write(&m); err != nil {
+		t.Fatalf("Failed to read the histogram: %v", err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestUpstreamStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"success", nil, "200"},
+		{"status", fmt.Errorf("call failed: %w", &provider.Error{Message: "overloaded", StatusCode: 529}), "529"},
+		{"error in stream", &provider.Error{Message: "overloaded", Type: "overloaded_error"}, "error"},
+		{"deadline", fmt.Errorf("request failed: %w", context.DeadlineExceeded), "timeout"},
+		{"client hung up", context.Canceled, "canceled"},
+		{"other", errors.New("connection refused"), "error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := upstreamStatus(tt.err); got != tt.want {
+				t.Errorf("upstreamStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleChat_Metrics(t *testing.T) {
+	failing := true
+	useLocalTestModel(t, func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		localAnswer("Tudo bem!")(w, r)
+	})
+	server := &Server{logger: logging.GetLogger()}
+	handler := metrics.Middleware(http.HandlerFunc(server.handleChat))
+	ask := func() int {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("POST", "/chat", strings.NewReader(`{"message":"Oi, tudo bem?"}`)))
+		return rr.Code
+	}
+
+	failedBefore := testutil.ToFloat64(metrics.UpstreamResponses.WithLabelValues("local", "400"))
+	errorsBefore := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("/chat", "500"))
+	if code := ask(); code != http.StatusInternalServerError {
+		t.Fatalf("Expected 500, got %d", code)
+	}
+	if testutil.ToFloat64(metrics.UpstreamResponses.WithLabelValues("local", "400")) != failedBefore+1 || testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("/chat", "500")) != errorsBefore+1 {
+		t.Error("Expected the failed upstream call and the error response counted")
+	}
+
+	failing = false
+	okBefore := testutil.ToFloat64(metrics.UpstreamResponses.WithLabelValues("local", "200"))
+	latencyBefore := observations(t, "simple", "local:test", "local")
+	if code := ask(); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if testutil.ToFloat64(metrics.UpstreamResponses.WithLabelValues("local", "200")) != okBefore+1 {
+		t.Error("Expected the successful upstream call counted")
+	}
+	if observations(t, "simple", "local:test", "local") != latencyBefore+1 {
+		t.Error("Expected the latency observed by category, model and provider")
+	}
+}
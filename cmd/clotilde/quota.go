@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/clotilde/carplay-assistant/internal/admin"
+	"github.com/clotilde/carplay-assistant/internal/auth"
+	"github.com/clotilde/carplay-assistant/internal/quota"
+	"github.com/clotilde/carplay-assistant/internal/router"
+)
+
+// quotaBudgets returns the budgets of the caller: its own budget (unset fields from the default
+// budget) and the budget of the category
+func quotaBudgets(config admin.RuntimeConfig, identity auth.Identity, category string) (quota.Budget, quota.Budget) {
+	return identity.Budget.Or(config.QuotaDefault), config.QuotaCategories[category]
+}
+
+// checkQuota applies the caller's budgets before the model is called
+// When a budget is used up the route is moved to the standard model (action "downgrade") or
+// rejected: ok is false and reason names the exhausted budget
+// Usage is counted per key ID, so requests without an authenticated key are not limited
+func (s *Server) checkQuota(ctx context.Context, requestID string, route router.RouteDecision) (decision router.RouteDecision, reason string, ok bool) {
+	identity, _ := auth.GetIdentity(ctx)
+	if s.quotas == nil || identity.KeyID == "" {
+		return route, "", true
+	}
+
+	config := admin.GetConfig()
+	budget, categoryBudget := quotaBudgets(config, identity, string(route.Category))
+	reason, err := s.quotas.Check(ctx, identity.KeyID, string(route.Category), budget, categoryBudget)
+	if err != nil {
+		// Budgets protect spending, not availability: answer when usage cannot be read
+		log.Printf("[%s] Quota check failed, allowing request: %v", requestID, err)
+		return route, "", true
+	}
+	if reason == "" {
+		return route, "", true
+	}
+
+	if config.QuotaAction == quota.ActionDowngrade {
+		downgraded := router.Downgrade(route)
+		log.Printf("[%s] Quota: %s exhausted for %s, downgrading %s -> %s", requestID, reason, identity.KeyID, route.Model, downgraded.Model)
+		return downgraded, reason, true
+	}
+	log.Printf("[%s] Quota: %s exhausted for %s, rejecting request", requestID, reason, identity.KeyID)
+	return route, reason, false
+}
+
+// chargeQuota adds the usage of a request to the caller's counters
+func (s *Server) chargeQuota(ctx context.Context, requestID string, route router.RouteDecision, usage requestUsage) {
+	keyID := auth.GetKeyID(ctx)
+	if s.quotas == nil || keyID == "" {
+		return
+	}
+	if usage.InputTokens == 0 && usage.OutputTokens == 0 && usage.Cost == 0 {
+		return
+	}
+
+	charge := quota.Usage{Tokens: usage.InputTokens + usage.OutputTokens, Cost: usage.Cost}
+	if err := s.quotas.Charge(ctx, keyID, string(route.Category), charge); err != nil {
+		log.Printf("[%s] Failed to record quota usage: %v", requestID, err)
+	}
+}
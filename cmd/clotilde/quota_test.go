@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clotilde/carplay-assistant/internal/admin"
+	"github.com/clotilde/carplay-assistant/internal/auth"
+	"github.com/clotilde/carplay-assistant/internal/logging"
+	"github.com/clotilde/carplay-assistant/internal/quota"
+	"github.com/clotilde/carplay-assistant/internal/router"
+)
+
+// setQuotaConfig applies quota settings on top of the current config for one test
+func setQuotaConfig(t *testing.T, update func(*admin.RuntimeConfig)) {
+	t.Helper()
+	admin.SetDefaultConfig(clotildeBaseSystemPromptTemplate)
+	previous := admin.GetConfig()
+	t.Cleanup(func() { admin.SetConfig(previous) })
+	config := admin.GetConfig()
+	update(&config)
+	if err := admin.SetConfig(config); err != nil {
+		t.Fatalf("Failed to set config: %v", err)
+	}
+}
+
+// TestHandleChat_QuotaRejects verifies that a key over its budget is refused after being charged
+func TestHandleChat_QuotaRejects(t *testing.T) {
+	useLocalTestModel(t, localAnswer("Resposta"))
+	setQuotaConfig(t, func(config *admin.RuntimeConfig) {
+		config.QuotaDefault = quota.Budget{DailyTokens: 10}
+		config.QuotaAction = quota.ActionReject
+	})
+
+	tracker := quota.NewTracker(nil, nil)
+	server := &Server{logger: logging.GetLogger(), quotas: tracker}
+
+	send := func(keyID string) *httptest.ResponseRecorder {
+		bodyBytes, _ := json.Marshal(ChatRequest{Message: "Oi, tudo bem?"})
+		req := httptest.NewRequest("POST", "/chat", bytes.NewReader(bodyBytes))
+		req = req.WithContext(auth.WithIdentity(req.Context(), auth.Identity{KeyID: keyID}))
+		rr := httptest.NewRecorder()
+		server.handleChat(rr, req)
+		return rr
+	}
+
+	if rr := send("key_quota"); rr.Code != http.StatusOK {
+		t.Fatalf("First request should be answered, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	report, _ := tracker.Report(context.Background(), "key_quota", quota.Budget{}, nil)
+	if report.Day.Tokens <= 10 {
+		t.Fatalf("Expected the answer (system prompt included) to be charged, got %+v", report.Day)
+	}
+
+	if rr := send("key_quota"); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 once the daily token budget is used, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := send("key_other"); rr.Code != http.StatusOK {
+		t.Errorf("Other keys keep their own budget, got %d", rr.Code)
+	}
+}
+
+func TestCheckQuota_Downgrade(t *testing.T) {
+	setQuotaConfig(t, func(config *admin.RuntimeConfig) {
+		config.StandardModel = "gpt-4o-mini"
+		config.PremiumModel = "gpt-4o"
+		config.QuotaCategories = map[string]quota.Budget{"complex": {DailyCost: 0.01}}
+		config.QuotaAction = quota.ActionDowngrade
+	})
+
+	tracker := quota.NewTracker(nil, nil)
+	server := &Server{quotas: tracker}
+	ctx := auth.WithIdentity(context.Background(), auth.Identity{KeyID: "key_downgrade"})
+	route := router.RouteDecision{Category: router.CategoryComplex, Model: "gpt-4o"}
+
+	decision, reason, ok := server.checkQuota(ctx, "test", route)
+	if !ok || reason != "" || decision.Model != "gpt-4o" {
+		t.Fatalf("Expected the routed model within budget, got %+v %q %v", decision, reason, ok)
+	}
+
+	tracker.Charge(ctx, "key_downgrade", "complex", quota.Usage{Cost: 0.02})
+	decision, reason, ok = server.checkQuota(ctx, "test", route)
+	if !ok || decision.Model != "gpt-4o-mini" || reason != "daily cost budget for complex" {
+		t.Errorf("Expected downgrade to the standard model, got %+v %q %v", decision, reason, ok)
+	}
+
+	// Requests without an authenticated key are not limited
+	decision, _, ok = server.checkQuota(context.Background(), "test", route)
+	if !ok || decision.Model != "gpt-4o" {
+		t.Errorf("Expected unauthenticated request unchanged, got %+v", decision)
+	}
+}
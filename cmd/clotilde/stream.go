@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/clotilde/carplay-assistant/internal/provider"
+	"github.com/clotilde/carplay-assistant/internal/tracing"
+)
+
+// minSentenceLength avoids emitting tiny chunks (e.g. "Sr." or "1.") that sound choppy when spoken
+const minSentenceLength = 20
+
+// StreamChunk is the payload of a "chunk" event: one sentence of the answer, ready to be spoken
+type StreamChunk struct {
+	Text string `json:"text"`
+}
+
+// wantsEventStream reports whether the client asked for a Server-Sent Events response
+// Streaming is selected with the /chat/stream endpoint or an "Accept: text/event-stream" header on /chat
+func wantsEventStream(r *http.Request) bool {
+	return r.URL.Path == "/chat/stream" || strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// sseWriter writes Server-Sent Events to the client, flushing after each event
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	started bool
+}
+
+func newSSEWriter(w http.ResponseWriter) *sseWriter {
+	flusher, _ := w.(http.Flusher)
+	return &sseWriter{w: w, flusher: flusher}
+}
+
+// send writes one event with a JSON payload, sending the stream headers first if needed
+func (s *sseWriter) send(event string, payload interface{}) error {
+	if !s.started {
+		s.started = true
+		s.w.Header().Set("Content-Type", "text/event-stream")
+		s.w.Header().Set("Cache-Control", "no-cache")
+		s.w.Header().Set("X-Accel-Buffering", "no") // Disable proxy buffering
+		setCORSHeaders(s.w)
+		s.w.WriteHeader(http.StatusOK)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+	return nil
+}
+
+// sentenceChunker buffers streamed text and emits complete sentences with URLs removed
+// A sentence ends at ".", "!", "?" or a newline followed by whitespace. Since URLs never
+// contain whitespace, and we never split inside an open markdown link, every URL is
+// entirely inside one emitted sentence and removeURLsFromText can strip it
+type sentenceChunker struct {
+	buf  strings.Builder
+	emit func(sentence string) error
+}
+
+func newSentenceChunker(emit func(sentence string) error) *sentenceChunker {
+	return &sentenceChunker{emit: emit}
+}
+
+// Write adds streamed text and emits any complete sentences
+func (c *sentenceChunker) Write(text string) error {
+	c.buf.WriteString(text)
+
+	pending := c.buf.String()
+	cut := sentenceBoundary(pending)
+	if cut <= 0 {
+		return nil
+	}
+
+	c.buf.Reset()
+	c.buf.WriteString(pending[cut:])
+	return c.send(pending[:cut])
+}
+
+// Flush emits whatever text remains at the end of the stream
+func (c *sentenceChunker) Flush() error {
+	rest := c.buf.String()
+	c.buf.Reset()
+	return c.send(rest)
+}
+
+func (c *sentenceChunker) send(text string) error {
+	if cleaned := removeURLsFromText(text); cleaned != "" {
+		return c.emit(cleaned)
+	}
+	return nil
+}
+
+// sentenceBoundary returns the index just after the last safe sentence end in text,
+// or 0 if no complete sentence of at least minSentenceLength is available yet
+func sentenceBoundary(text string) int {
+	cut := 0
+	openBrackets, openParens := 0, 0
+	for i := 0; i < len(text)-1; i++ {
+		switch text[i] {
+		case '[':
+			openBrackets++
+		case ']':
+			if openBrackets > 0 {
+				openBrackets--
+			}
+		case '(':
+			openParens++
+		case ')':
+			if openParens > 0 {
+				openParens--
+			}
+		case '.', '!', '?', '\n':
+			next := text[i+1]
+			if (next == ' ' || next == '\n' || next == '\t') && openBrackets == 0 && openParens == 0 && i+1 >= minSentenceLength {
+				cut = i + 1
+			}
+		}
+	}
+	return cut
+}
+
+// streamResponse generates the answer and passes it to onSentence one sentence at a time
+// Providers without streaming support are called normally and their answer is split afterwards
+func (s *Server) streamResponse(ctx context.Context, route RouteDecision, instructions, input string, history []provider.Message, onSentence func(sentence string) error) (answer, error) {
+	p, req, perplexityCalls, err := s.prepareRequest(ctx, route, instructions, input, history)
+	if err != nil {
+		return answer{Request: req, PerplexityCalls: perplexityCalls}, err
+	}
+	ctx, done := generationContext(ctx)
+	defer done()
+
+	// Once a sentence reached the client, a failure cannot be answered by another model
+	delivered := false
+	send := func(sentence string) error {
+		delivered = true
+		return onSentence(sentence)
+	}
+	return s.generateWithFallback(ctx, route, p, req, perplexityCalls, func(ctx context.Context, p provider.Provider, req provider.Request) (provider.Response, bool, error) {
+		chunker := newSentenceChunker(send)
+		ctx, span := providerSpan(ctx, "provider.stream", p, req)
+		var resp provider.Response
+		var err error
+		if sp, ok := p.(provider.StreamingProvider); ok {
+			log.Printf("Using %s provider (streaming): model=%s", p.Name(), req.Model)
+			resp, err = sp.Stream(ctx, req, chunker.Write)
+		} else {
+			log.Printf("Using %s provider (no streaming support): model=%s", p.Name(), req.Model)
+			resp, err = p.Generate(ctx, req)
+			if err == nil {
+				err = chunker.Write(resp.Text)
+			}
+		}
+		tracing.End(span, err)
+		observeUpstream(p.Name(), err)
+		if err == nil {
+			err = chunker.Flush()
+		}
+		return resp, delivered, err
+	})
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clotilde/carplay-assistant/internal/logging"
+)
+
+func TestSentenceChunker(t *testing.T) {
+	var sentences []string
+	chunker := newSentenceChunker(func(s string) error {
+		sentences = append(sentences, s)
+		return nil
+	})
+
+	// Deltas split words and a URL across writes, as providers do
+	deltas := []string{
+		"A previsão para São Paulo é de chuva à tarde. Mais detalhes em https://cli",
+		"matempo.com.br/sp agora. O valor é 3.5 graus acima",
+		" da média",
+	}
+	for _, d := range deltas {
+		if err := chunker.Write(d); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := chunker.Flush(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []string{
+		"A previsão para São Paulo é de chuva à tarde.",
+		"Mais detalhes em agora.",
+		"O valor é 3.5 graus acima da média",
+	}
+	if len(sentences) != len(want) {
+		t.Fatalf("Expected %d sentences, got %q", len(want), sentences)
+	}
+	for i := range want {
+		if sentences[i] != want[i] {
+			t.Errorf("Sentence %d = %q, want %q", i, sentences[i], want[i])
+		}
+		if strings.Contains(sentences[i], "http") || strings.Contains(sentences[i], ".com") {
+			t.Errorf("URL leaked into streamed sentence: %q", sentences[i])
+		}
+	}
+}
+
+func TestSentenceBoundary(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"no boundary yet", "A previsão para São Paulo", 0},
+		{"too short", "Sim. Vai chover", 0},
+		{"sentence end followed by space", "Vai chover em São Paulo hoje. Amanhã", len("Vai chover em São Paulo hoje.")},
+		{"decimal point is not a boundary", "A temperatura máxima será 3.5 graus", 0},
+		{"inside markdown link", "Veja a notícia completa [Fonte. G1](https://g1", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sentenceBoundary(tt.text); got != tt.want {
+				t.Errorf("sentenceBoundary(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleChat_EventStream(t *testing.T) {
+	useLocalTestModel(t, localAnswer("Primeira frase da resposta. Segunda frase, veja em www.exemplo.com.br agora."))
+
+	server := &Server{logger: logging.GetLogger()}
+
+	bodyBytes, _ := json.Marshal(ChatRequest{Message: "Oi, tudo bem?"})
+	req := httptest.NewRequest("POST", "/chat/stream", bytes.NewReader(bodyBytes))
+	rr := httptest.NewRecorder()
+	server.handleChat(rr, req)
+
+	if ct := rr.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream, got %q (body: %s)", ct, rr.Body.String())
+	}
+
+	body := rr.Body.String()
+	if strings.Count(body, "event: chunk") != 2 {
+		t.Errorf("Expected 2 chunk events, got body:\n%s", body)
+	}
+	if !strings.Contains(body, "event: done") {
+		t.Errorf("Expected done event, got body:\n%s", body)
+	}
+	if strings.Contains(body, "exemplo.com") {
+		t.Errorf("URL leaked into stream:\n%s", body)
+	}
+}
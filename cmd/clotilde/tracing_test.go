@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clotilde/carplay-assistant/internal/logging"
+	"github.com/clotilde/carplay-assistant/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// TestHandleChat_Tracing verifies that a chat request continues the caller's trace through routing
+// and the model call, and sends it on to the provider
+func TestHandleChat_Tracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	var upstreamTraceparent string
+	useLocalTestModel(t, func(w http.ResponseWriter, r *http.Request) {
+		upstreamTraceparent = r.Header.Get("traceparent")
+		localAnswer("Tudo bem!")(w, r)
+	})
+
+	server := &Server{logger: logging.GetLogger()}
+	handler := tracing.Middleware(logging.RequestIDMiddleware(http.HandlerFunc(server.handleChat)))
+	req := httptest.NewRequest("POST", "/chat", strings.NewReader(`{"message":"Oi, tudo bem?"}`))
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	if !strings.Contains(upstreamTraceparent, traceID) {
+		t.Errorf("Expected the provider call to carry trace %s, got %q", traceID, upstreamTraceparent)
+	}
+	names := make(map[string]bool)
+	for _, span := range exporter.GetSpans() {
+		if span.SpanContext.TraceID().String() != traceID {
+			t.Errorf("Span %s is not part of the caller's trace", span.Name)
+		}
+		names[span.Name] = true
+	}
+	for _, name := range []string{"HTTP POST", "router.Route", "provider.generate"} {
+		if !names[name] {
+			t.Errorf("Expected a %s span, got %v", name, names)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"github.com/clotilde/carplay-assistant/internal/logging"
+	"github.com/clotilde/carplay-assistant/internal/provider"
+	"github.com/clotilde/carplay-assistant/internal/quota"
+)
+
+// answer is the result of a generation request with what it consumed
+type answer struct {
+	Text            string
+	Request         provider.Request // As sent, including Perplexity results in the instructions
+	Usage           provider.Usage   // As reported by the provider
+	PerplexityCalls int              // Perplexity queries made before the model was called
+	Cache           string           // logging.CacheHit, CacheSemanticHit or CacheMiss for cacheable questions
+	CacheSimilarity float64          // Similarity of the closest cached question (semantic cache)
+	Model           string           // Model that answered, when a fallback or hedge model answered instead of the routed one
+	FallbackFrom    string           // Routed model that failed
+	Fallbacks       int              // Models that failed with a retryable error before the answer
+	Hedged          bool             // The hedge model was asked too
+	HedgeWinner     string           // logging.HedgePrimary or logging.HedgeSecondary
+	Discarded       []discardedCall  // Model calls whose answer was not used, charged too
+}
+
+// discardedCall is the usage of a model call whose answer was not used (e.g. the hedge that lost)
+type discardedCall struct {
+	Model     string
+	Usage     provider.Usage
+	Estimated bool // Input tokens estimated from the request; the call was cancelled before reporting usage
+}
+
+// estimateInputTokens estimates the input tokens of a request from its text (~4 characters per token)
+func estimateInputTokens(req provider.Request) int64 {
+	tokens := quota.EstimateTokens(req.Instructions) + quota.EstimateTokens(req.Input)
+	for _, message := range req.History {
+		tokens += quota.EstimateTokens(message.Content)
+	}
+	return tokens
+}
+
+// model returns the model that answered (or was last tried) for a request routed to routed
+func (a answer) model(routed string) string {
+	if a.Model != "" {
+		return a.Model
+	}
+	return routed
+}
+
+// requestUsage is the token usage and cost of a request, as logged and charged to quotas
+type requestUsage struct {
+	InputTokens     int64
+	OutputTokens    int64
+	ReasoningTokens int64
+	Estimated       bool // Token counts (partly) estimated from the text; the provider reported none
+	SearchCalls     int
+	PerplexityCalls int
+	Cost            float64
+	Cache           string  // logging.CacheHit or CacheSemanticHit (nothing consumed), or CacheMiss
+	CacheSimilarity float64 // Similarity of the closest cached question (semantic cache)
+	FallbackFrom    string  // Routed model that failed, when a fallback model was used
+	Fallbacks       int
+	Hedged          bool
+	HedgeWinner     string
+}
+
+// usage returns the usage and cost of the answer from model, priced with the current model prices
+// Providers that report no usage are estimated from the text sent and received (~4 characters
+// per token); failed requests only cost the searches and the model calls already made, and cached
+// answers cost nothing. Discarded calls are added, each priced with its own model
+func (a answer) usage(model string) requestUsage {
+	if a.Cache == logging.CacheHit || a.Cache == logging.CacheSemanticHit {
+		return requestUsage{Cache: a.Cache, CacheSimilarity: a.CacheSimilarity}
+	}
+	usage := requestUsage{
+		InputTokens:     a.Usage.InputTokens,
+		OutputTokens:    a.Usage.OutputTokens,
+		ReasoningTokens: a.Usage.ReasoningTokens,
+		SearchCalls:     a.Usage.WebSearchCalls,
+		PerplexityCalls: a.PerplexityCalls,
+		Cache:           a.Cache,
+		CacheSimilarity: a.CacheSimilarity,
+		FallbackFrom:    a.FallbackFrom,
+		Fallbacks:       a.Fallbacks,
+		Hedged:          a.Hedged,
+		HedgeWinner:     a.HedgeWinner,
+	}
+	if !a.Usage.Reported() && a.Text != "" {
+		usage.InputTokens = estimateInputTokens(a.Request)
+		usage.OutputTokens = quota.EstimateTokens(a.Text)
+		usage.Estimated = true
+	}
+	usage.Cost = quota.Cost(model, usage.InputTokens, usage.OutputTokens, usage.SearchCalls+usage.PerplexityCalls)
+	for _, call := range a.Discarded {
+		usage.InputTokens += call.Usage.InputTokens
+		usage.OutputTokens += call.Usage.OutputTokens
+		usage.ReasoningTokens += call.Usage.ReasoningTokens
+		usage.SearchCalls += call.Usage.WebSearchCalls
+		usage.Estimated = usage.Estimated || call.Estimated
+		usage.Cost += quota.Cost(call.Model, call.Usage.InputTokens, call.Usage.OutputTokens, call.Usage.WebSearchCalls)
+	}
+	return usage
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clotilde/carplay-assistant/internal/admin"
+	"github.com/clotilde/carplay-assistant/internal/logging"
+	"github.com/clotilde/carplay-assistant/internal/provider"
+	"github.com/clotilde/carplay-assistant/internal/quota"
+)
+
+func TestAnswerUsage(t *testing.T) {
+	reported := answer{
+		Text:            "Resposta",
+		Usage:           provider.Usage{InputTokens: 1_000_000, OutputTokens: 1_000_000, ReasoningTokens: 400_000, WebSearchCalls: 1},
+		PerplexityCalls: 1,
+	}
+	usage := reported.usage("gpt-4o-mini")
+	if usage.Estimated || usage.InputTokens != 1_000_000 || usage.ReasoningTokens != 400_000 {
+		t.Errorf("Expected the reported token counts, got %+v", usage)
+	}
+	if want := quota.Cost("gpt-4o-mini", 1_000_000, 1_000_000, 2); math.Abs(usage.Cost-want) > 1e-9 {
+		t.Errorf("Expected cost %v (tokens and both searches), got %v", want, usage.Cost)
+	}
+
+	// Servers that report no usage are estimated from the text
+	estimated := answer{
+		Text:    "12345678",
+		Request: provider.Request{Instructions: "1234", Input: "1234", History: []provider.Message{{Role: provider.RoleUser, Content: "1234"}}},
+	}.usage("local:llama3")
+	if !estimated.Estimated || estimated.InputTokens != 3 || estimated.OutputTokens != 2 || estimated.Cost != 0 {
+		t.Errorf("Unexpected estimated usage: %+v", estimated)
+	}
+
+	// A failed request only costs the searches already made
+	failed := answer{PerplexityCalls: 1, Request: provider.Request{Input: "Oi"}}.usage("gpt-4o")
+	if failed.InputTokens != 0 || failed.Estimated || failed.Cost != quota.Cost("gpt-4o", 0, 0, 1) {
+		t.Errorf("Unexpected usage for a failed request: %+v", failed)
+	}
+
+	// Discarded calls are added, each priced with its own model
+	hedged := answer{
+		Text:      "Resposta",
+		Usage:     provider.Usage{InputTokens: 1_000_000, OutputTokens: 1_000_000},
+		Discarded: []discardedCall{{Model: "gpt-4o", Usage: provider.Usage{InputTokens: 1_000_000}, Estimated: true}},
+	}.usage("gpt-4o-mini")
+	want := quota.Cost("gpt-4o-mini", 1_000_000, 1_000_000, 0) + quota.Cost("gpt-4o", 1_000_000, 0, 0)
+	if hedged.InputTokens != 2_000_000 || hedged.OutputTokens != 1_000_000 || !hedged.Estimated || math.Abs(hedged.Cost-want) > 1e-9 {
+		t.Errorf("Expected the discarded call charged, got %+v (want cost %v)", hedged, want)
+	}
+}
+
+// TestHandleChat_LogsReportedUsage verifies that the provider's usage block ends up in the request log
+func TestHandleChat_LogsReportedUsage(t *testing.T) {
+	useLocalTestModel(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": "Resposta"}}},
+			"usage":   map[string]int{"prompt_tokens": 321, "completion_tokens": 12},
+		})
+	})
+	setQuotaConfig(t, func(config *admin.RuntimeConfig) {
+		config.ModelPrices = map[string]quota.Price{"local:test": {Input: 1, Output: 2}}
+	})
+	quota.SetPriceSource(func() quota.Prices { return admin.GetConfig().Prices() })
+	t.Cleanup(func() { quota.SetPriceSource(nil) })
+
+	server := &Server{logger: logging.GetLogger()}
+	bodyBytes, _ := json.Marshal(ChatRequest{Message: "Oi, tudo bem?"})
+	rr := httptest.NewRecorder()
+	server.handleChat(rr, httptest.NewRequest("POST", "/chat", bytes.NewReader(bodyBytes)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	entry := server.logger.GetEntries(1, 0)[0]
+	if entry.InputTokens != 321 || entry.OutputTokens != 12 || entry.UsageEstimated {
+		t.Errorf("Expected reported token counts in the log, got %+v", entry)
+	}
+	if want := (321*1.0 + 12*2.0) / 1e6; math.Abs(entry.Cost-want) > 1e-12 {
+		t.Errorf("Expected cost %v from the configured price, got %v", want, entry.Cost)
+	}
+}
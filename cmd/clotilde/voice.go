@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/clotilde/carplay-assistant/internal/admin"
+	"github.com/clotilde/carplay-assistant/internal/budget"
+	"github.com/clotilde/carplay-assistant/internal/speech"
+	"github.com/clotilde/carplay-assistant/internal/tts"
+)
+
+// Audio response modes (ChatRequest.AudioResponse)
+const (
+	audioBase64 = "base64" // JSON response with the audio in the "audio" field
+	audioBinary = "binary" // Raw audio body (Content-Type audio/mpeg or audio/wav)
+)
+
+// synthesisReserve is the part of the request budget kept for text-to-speech when audio is requested
+// Generation gets a shorter deadline so the answer can still be spoken in time
+const synthesisReserve = 5 * time.Second
+
+// minSynthesisTime is the least time worth attempting synthesis with; below it the text answer is returned
+const minSynthesisTime = 1 * time.Second
+
+// audioResponseMode returns the requested audio mode ("" for text only)
+// Clients ask for audio with "audio_response" in the body, or with Accept: audio/mpeg for a raw body
+func audioResponseMode(r *http.Request, req ChatRequest) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(req.AudioResponse)) {
+	case "":
+		if strings.Contains(r.Header.Get("Accept"), "audio/mpeg") {
+			return audioBinary, true
+		}
+		return "", true
+	case audioBase64:
+		return audioBase64, true
+	case audioBinary:
+		return audioBinary, true
+	}
+	return "", false
+}
+
+// respondAudio speaks the answer with the configured synthesizer and writes it in the requested mode
+// If synthesis fails or the budget is exhausted, the JSON text answer is returned instead so the
+// client can fall back to on-device speech rather than staying silent
+func (s *Server) respondAudio(w http.ResponseWriter, requestID string, b *budget.Budget, mode string, resp ChatResponse, spoken string) {
+	timeout := b.Allot(budget.Synthesis, 0)
+	if timeout < minSynthesisTime {
+		log.Printf("[%s] Skipping speech synthesis: budget exhausted", requestID)
+		respondSuccess(w, resp)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	config := admin.GetConfig()
+	synthesisStart := time.Now()
+	stop := b.Track(budget.Synthesis)
+	audio, err := s.synthesizer.Synthesize(ctx, spoken, tts.Options{Voice: config.TTSVoice, Speed: config.TTSSpeed})
+	stop()
+	if err != nil {
+		log.Printf("[%s] Speech synthesis error (%s): %v", requestID, s.synthesizer.Name(), err)
+		respondSuccess(w, resp)
+		return
+	}
+	log.Printf("[%s] Speech synthesized: size=%d, time=%v, total=%v",
+		requestID, len(audio.Data), time.Since(synthesisStart), time.Since(b.Start()))
+
+	if mode == audioBinary {
+		w.Header().Set("Content-Type", audio.ContentType)
+		if resp.SessionID != "" {
+			w.Header().Set("X-Session-ID", resp.SessionID)
+		}
+		setCORSHeaders(w)
+		w.Write(audio.Data)
+		return
+	}
+
+	resp.Audio = base64.StdEncoding.EncodeToString(audio.Data)
+	resp.AudioContentType = audio.ContentType
+	respondSuccess(w, resp)
+}
+
+// spokenText returns the answer as it should be read aloud by the synthesizer
+func spokenText(text string) string {
+	return formatAnswer(text, speech.FormatSpeech)
+}
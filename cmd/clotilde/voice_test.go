@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clotilde/carplay-assistant/internal/logging"
+	"github.com/clotilde/carplay-assistant/internal/tts"
+)
+
+// fakeSynthesizer returns fixed audio (or an error) and records the text it was asked to speak
+type fakeSynthesizer struct {
+	err  error
+	text string
+}
+
+func (f *fakeSynthesizer) Name() string { return "fake" }
+func (f *fakeSynthesizer) Synthesize(ctx context.Context, text string, opts tts.Options) (tts.Audio, error) {
+	f.text = text
+	if f.err != nil {
+		return tts.Audio{}, f.err
+	}
+	return tts.Audio{Data: []byte("fake-mp3"), ContentType: "audio/mpeg"}, nil
+}
+
+func TestAudioResponseMode(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		accept string
+		want   string
+		ok     bool
+	}{
+		{"text only", "", "", "", true},
+		{"base64", "base64", "", audioBase64, true},
+		{"binary", "BINARY", "", audioBinary, true},
+		{"accept header", "", "audio/mpeg", audioBinary, true},
+		{"invalid", "wav", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/chat", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			got, ok := audioResponseMode(req, ChatRequest{AudioResponse: tt.value})
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("audioResponseMode() = (%q, %v), want (%q, %v)", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestHandleChat_AudioResponse(t *testing.T) {
+	useLocalTestModel(t, localAnswer("São 3 km até o posto."))
+	synthesizer := &fakeSynthesizer{}
+	server := &Server{logger: logging.GetLogger(), synthesizer: synthesizer}
+
+	// base64: JSON with text and audio
+	req := httptest.NewRequest("POST", "/chat", strings.NewReader(`{"message":"Onde tem posto?","audio_response":"base64"}`))
+	rr := httptest.NewRecorder()
+	server.handleChat(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp ChatResponse
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if resp.Response != "São 3 km até o posto." || resp.AudioContentType != "audio/mpeg" {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+	if audio, _ := base64.StdEncoding.DecodeString(resp.Audio); string(audio) != "fake-mp3" {
+		t.Errorf("Unexpected audio: %q", resp.Audio)
+	}
+	// The synthesizer gets the speech-normalized text
+	if synthesizer.text != "São três quilômetros até o posto." {
+		t.Errorf("Unexpected spoken text: %q", synthesizer.text)
+	}
+
+	// binary: raw audio body
+	req = httptest.NewRequest("POST", "/chat", strings.NewReader(`{"message":"Onde tem posto?","session_id":"trip-1"}`))
+	req.Header.Set("Accept", "audio/mpeg")
+	rr = httptest.NewRecorder()
+	server.handleChat(rr, req)
+
+	if rr.Header().Get("Content-Type") != "audio/mpeg" || rr.Body.String() != "fake-mp3" {
+		t.Errorf("Expected raw audio, got %s: %q", rr.Header().Get("Content-Type"), rr.Body.String())
+	}
+	if rr.Header().Get("X-Session-ID") != "trip-1" {
+		t.Errorf("Expected X-Session-ID header, got %q", rr.Header().Get("X-Session-ID"))
+	}
+}
+
+func TestHandleChat_AudioResponseFallback(t *testing.T) {
+	useLocalTestModel(t, localAnswer("Resposta em texto."))
+	server := &Server{logger: logging.GetLogger(), synthesizer: &fakeSynthesizer{err: errors.New("backend down")}}
+
+	req := httptest.NewRequest("POST", "/chat", strings.NewReader(`{"message":"Oi","audio_response":"binary"}`))
+	rr := httptest.NewRecorder()
+	server.handleChat(rr, req)
+
+	// A synthesis failure still answers, as JSON text
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Expected JSON fallback, got %d %s", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	var resp ChatResponse
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if resp.Response != "Resposta em texto." || resp.Audio != "" {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+}
+
+func TestHandleChat_AudioResponseErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		body        string
+		synthesizer tts.Synthesizer
+		wantStatus  int
+	}{
+		{"not configured", "/chat", `{"message":"Oi","audio_response":"base64"}`, nil, http.StatusServiceUnavailable},
+		{"invalid mode", "/chat", `{"message":"Oi","audio_response":"wav"}`, &fakeSynthesizer{}, http.StatusBadRequest},
+		{"streaming", "/chat/stream", `{"message":"Oi","audio_response":"base64"}`, &fakeSynthesizer{}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &Server{logger: logging.GetLogger(), synthesizer: tt.synthesizer}
+			req := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			server.handleChat(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
@@ -19,7 +19,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/clotilde/carplay-assistant/internal/auth"
+	"github.com/clotilde/carplay-assistant/internal/cache"
 	"github.com/clotilde/carplay-assistant/internal/logging"
+	"github.com/clotilde/carplay-assistant/internal/quota"
 )
 
 //go:embed dashboard.js
@@ -75,6 +78,11 @@ type Handler struct {
 	csrfTokensByIP map[string]map[string]bool // IP -> set of tokens
 	csrfMutex      sync.RWMutex
 	rateLimiter    *adminRateLimiter
+	keys           *auth.Registry  // API key registry (nil disables key management)
+	sharedKeys     *auth.KeySet    // Shared API key generations (nil hides rotation stats)
+	quotas         *quota.Tracker  // Budget consumption per API key (nil hides budgets)
+	responseCache  *cache.Cache    // Cached answers (nil hides cache controls)
+	semanticCache  *cache.Semantic // Cached answers searched by similarity (nil when semantic matching is off)
 }
 
 // NewHandler creates a new admin handler
@@ -771,7 +779,14 @@ func (h *Handler) HandleStats(w http.ResponseWriter, r *http.Request) {
 	ip := getClientIP(r)
 	h.logAdminAction("stats_view", ip, "")
 
-	stats := h.logger.GetStats()
+	stats := struct {
+		logging.Stats
+		// Requests per shared key generation, to tell when an old key can be retired
+		KeyGenerations []auth.GenerationUsage `json:"key_generations,omitempty"`
+	}{
+		Stats:          h.logger.GetStats(),
+		KeyGenerations: h.sharedKeys.Usage(),
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(stats)
@@ -790,6 +805,7 @@ func (h *Handler) HandleGetConfig(w http.ResponseWriter, r *http.Request) {
 	config := GetConfig()
 
 	w.Header().Set("Content-Type", "application/json")
+	SetETag(w, config.Version)
 	if err := json.NewEncoder(w).Encode(config); err != nil {
 		log.Printf("Error encoding config: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -837,6 +853,9 @@ func (h *Handler) HandleSetConfig(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
+	if version := IfMatchVersion(r); version != "" {
+		newConfig.Version = version
+	}
 
 	// Validate base system prompt size (prefer BaseSystemPrompt, fallback to SystemPrompt for legacy)
 	basePrompt := newConfig.BaseSystemPrompt
@@ -858,10 +877,11 @@ func (h *Handler) HandleSetConfig(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if err := SetConfig(newConfig); err != nil {
+	username, _, _ := r.BasicAuth()
+	if err := UpdateConfig(newConfig, ConfigOrigin{Actor: username, Source: SourceAdminDashboard}); err != nil {
 		h.logAdminAction("config_update_failed", ip, err.Error())
 		log.Printf("Error setting config: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), ConfigErrorStatus(err))
 		return
 	}
 
@@ -869,10 +889,182 @@ func (h *Handler) HandleSetConfig(w http.ResponseWriter, r *http.Request) {
 	h.logAdminAction("config_updated", ip, fmt.Sprintf("standard_model=%s premium_model=%s prompt_len=%d",
 		newConfig.StandardModel, newConfig.PremiumModel, len(newConfig.SystemPrompt)))
 
-	// Return updated config
+	// Return updated config (with its new version)
+	config := GetConfig()
 	w.Header().Set("Content-Type", "application/json")
+	SetETag(w, config.Version)
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(newConfig)
+	json.NewEncoder(w).Encode(config)
+}
+
+// historySummary is a history entry without the full snapshot, for listing
+type historySummary struct {
+	ID            int       `json:"id"`
+	Timestamp     time.Time `json:"timestamp"`
+	Actor         string    `json:"actor,omitempty"`
+	Source        string    `json:"source"`
+	RollbackOf    int       `json:"rollback_of,omitempty"`
+	Version       string    `json:"version,omitempty"`
+	StandardModel string    `json:"standard_model"`
+	PremiumModel  string    `json:"premium_model"`
+}
+
+// HandleConfigHistory lists applied configurations, newest first
+// With ?id=N, returns that entry with its full snapshot
+func (h *Handler) HandleConfigHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ip := getClientIP(r)
+	h.logAdminAction("config_history_view", ip, r.URL.RawQuery)
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if idParam := r.URL.Query().Get("id"); idParam != "" {
+		id, err := strconv.Atoi(idParam)
+		if err != nil {
+			http.Error(w, "Invalid id", http.StatusBadRequest)
+			return
+		}
+		entry, err := ConfigHistoryEntry(r.Context(), id)
+		if err != nil {
+			http.Error(w, err.Error(), ConfigErrorStatus(err))
+			return
+		}
+		json.NewEncoder(w).Encode(entry)
+		return
+	}
+
+	entries, err := ConfigHistory(r.Context())
+	if err != nil {
+		log.Printf("Error reading config history: %v", err)
+		http.Error(w, "Failed to read config history", http.StatusInternalServerError)
+		return
+	}
+
+	summaries := make([]historySummary, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		summaries = append(summaries, historySummary{
+			ID:            entry.ID,
+			Timestamp:     entry.Timestamp,
+			Actor:         entry.Actor,
+			Source:        entry.Source,
+			RollbackOf:    entry.RollbackOf,
+			Version:       entry.Config.Version,
+			StandardModel: entry.Config.StandardModel,
+			PremiumModel:  entry.Config.PremiumModel,
+		})
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"entries": summaries})
+}
+
+// HandleConfigDiff compares two history entries (?from=&to=)
+// Without "to" the latest entry is used; without "from" the entry before "to"
+func (h *Handler) HandleConfigDiff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ip := getClientIP(r)
+	h.logAdminAction("config_diff_view", ip, r.URL.RawQuery)
+
+	entries, err := ConfigHistory(r.Context())
+	if err != nil {
+		log.Printf("Error reading config history: %v", err)
+		http.Error(w, "Failed to read config history", http.StatusInternalServerError)
+		return
+	}
+	if len(entries) == 0 {
+		http.Error(w, ErrHistoryNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	// Resolve entry IDs to positions in the history
+	find := func(param string, fallback int) (int, bool) {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			return fallback, fallback >= 0
+		}
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, false
+		}
+		for i, entry := range entries {
+			if entry.ID == id {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+
+	to, ok := find("to", len(entries)-1)
+	if !ok {
+		http.Error(w, "Invalid or unknown 'to' entry", http.StatusNotFound)
+		return
+	}
+	from, ok := find("from", to-1)
+	if !ok {
+		http.Error(w, "Invalid or unknown 'from' entry", http.StatusNotFound)
+		return
+	}
+
+	changes := DiffConfigs(entries[from].Config, entries[to].Config)
+	if changes == nil {
+		changes = []ConfigDiff{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"from":    entries[from].ID,
+		"to":      entries[to].ID,
+		"changes": changes,
+	})
+}
+
+// HandleConfigRollback re-applies a previous configuration from the history ({"id": N})
+func (h *Handler) HandleConfigRollback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ip := getClientIP(r)
+
+	// Validate CSRF token
+	csrfToken := r.Header.Get("X-CSRF-Token")
+	if !h.validateCSRFToken(csrfToken, r) {
+		h.logAdminAction("config_rollback_failed", ip, "Invalid CSRF token")
+		http.Error(w, "Invalid or missing CSRF token", http.StatusForbidden)
+		return
+	}
+
+	var req struct {
+		ID int `json:"id"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1024)).Decode(&req); err != nil || req.ID <= 0 {
+		h.logAdminAction("config_rollback_failed", ip, "Invalid request")
+		http.Error(w, "Invalid request (expected {\"id\": N})", http.StatusBadRequest)
+		return
+	}
+
+	username, _, _ := r.BasicAuth()
+	if err := RollbackConfig(r.Context(), req.ID, ConfigOrigin{Actor: username, Source: SourceRollback}); err != nil {
+		h.logAdminAction("config_rollback_failed", ip, err.Error())
+		log.Printf("Error rolling back config: %v", err)
+		http.Error(w, err.Error(), ConfigErrorStatus(err))
+		return
+	}
+
+	h.logAdminAction("config_rolled_back", ip, fmt.Sprintf("id=%d", req.ID))
+
+	config := GetConfig()
+	w.Header().Set("Content-Type", "application/json")
+	SetETag(w, config.Version)
+	json.NewEncoder(w).Encode(config)
 }
 
 // RegisterRoutes registers admin routes on the given mux
@@ -900,4 +1092,12 @@ func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	}))
+	mux.HandleFunc("/admin/config/history", h.BasicAuthMiddleware(h.HandleConfigHistory))
+	mux.HandleFunc("/admin/config/diff", h.BasicAuthMiddleware(h.HandleConfigDiff))
+	mux.HandleFunc("/admin/config/rollback", h.BasicAuthMiddleware(h.HandleConfigRollback))
+	mux.HandleFunc("/admin/keys", h.BasicAuthMiddleware(h.HandleKeys))
+	mux.HandleFunc("/admin/keys/revoke", h.BasicAuthMiddleware(h.HandleRevokeKey))
+	mux.HandleFunc("/admin/quotas", h.BasicAuthMiddleware(h.HandleQuotas))
+	mux.HandleFunc("/admin/cache", h.BasicAuthMiddleware(h.HandleCache))
+	mux.HandleFunc("/admin/breakers", h.BasicAuthMiddleware(h.HandleBreakers))
 }
@@ -0,0 +1,58 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/clotilde/carplay-assistant/internal/breaker"
+)
+
+// LogBreakerTransition writes a circuit breaker state change to the audit log
+func (h *Handler) LogBreakerTransition(t breaker.Transition) {
+	details := fmt.Sprintf("%s %s -> %s", t.Name, t.From, t.To)
+	if t.To == breaker.Open {
+		details += fmt.Sprintf(" (%.0f%% of recent calls failed)", t.FailureRate*100)
+	}
+	h.logAdminAction("breaker_"+string(t.To), "-", details)
+}
+
+// HandleBreakers reports the circuit breaker of every upstream (GET) or closes one (POST)
+// Closing a breaker by hand lets traffic through at once after an upstream is known to be back
+func (h *Handler) HandleBreakers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		ip := getClientIP(r)
+		if !h.validateCSRFToken(r.Header.Get("X-CSRF-Token"), r) {
+			h.logAdminAction("breaker_reset_failed", ip, "Invalid CSRF token")
+			http.Error(w, "Invalid or missing CSRF token", http.StatusForbidden)
+			return
+		}
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1024)).Decode(&req); err != nil || req.Name == "" {
+			h.logAdminAction("breaker_reset_failed", ip, "Invalid request")
+			http.Error(w, "Invalid request (expected {\"name\": \"perplexity\"})", http.StatusBadRequest)
+			return
+		}
+		b, ok := breaker.Lookup(req.Name)
+		if !ok {
+			h.logAdminAction("breaker_reset_failed", ip, "Unknown breaker "+req.Name)
+			http.Error(w, "Unknown circuit breaker", http.StatusNotFound)
+			return
+		}
+		b.Reset()
+		h.logAdminAction("breaker_reset", ip, req.Name)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"breakers": breaker.Statuses(),
+	})
+}
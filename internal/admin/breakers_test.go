@@ -0,0 +1,63 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clotilde/carplay-assistant/internal/breaker"
+)
+
+func TestHandleBreakers_Reset(t *testing.T) {
+	h := NewHandler(nil)
+	b := breaker.Get("admin-test")
+	t.Cleanup(b.Reset)
+	for i := 0; i < 3; i++ {
+		done, err := b.Allow()
+		if err != nil {
+			t.Fatalf("Unexpected refusal: %v", err)
+		}
+		done(breaker.Failure)
+	}
+
+	rr := httptest.NewRecorder()
+	h.HandleBreakers(rr, httptest.NewRequest("GET", "/admin/breakers", nil))
+	var body struct {
+		Breakers []breaker.Status `json:"breakers"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Invalid response: %v", err)
+	}
+	found := false
+	for _, status := range body.Breakers {
+		if status.Name == "admin-test" {
+			found = status.State == breaker.Open
+		}
+	}
+	if !found {
+		t.Fatalf("Expected the open breaker in the report, got %+v", body.Breakers)
+	}
+
+	// Closing a breaker requires a CSRF token
+	rr = httptest.NewRecorder()
+	h.HandleBreakers(rr, httptest.NewRequest("POST", "/admin/breakers", bytes.NewBufferString(`{"name": "admin-test"}`)))
+	if rr.Code != http.StatusForbidden || b.Status().State != breaker.Open {
+		t.Fatalf("Expected 403 without CSRF token, got %d", rr.Code)
+	}
+
+	post := func(body string) int {
+		req := httptest.NewRequest("POST", "/admin/breakers", bytes.NewBufferString(body))
+		req.Header.Set("X-CSRF-Token", h.generateCSRFToken(req))
+		rr := httptest.NewRecorder()
+		h.HandleBreakers(rr, req)
+		return rr.Code
+	}
+	if code := post(`{"name": "unknown"}`); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown breaker, got %d", code)
+	}
+	if code := post(`{"name": "admin-test"}`); code != http.StatusOK || b.Status().State != breaker.Closed {
+		t.Errorf("Expected the breaker closed, got %d in state %s", code, b.Status().State)
+	}
+}
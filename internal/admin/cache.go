@@ -0,0 +1,57 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/clotilde/carplay-assistant/internal/cache"
+)
+
+// SetResponseCache enables the response cache controls in the dashboard
+func (h *Handler) SetResponseCache(c *cache.Cache) {
+	h.responseCache = c
+}
+
+// SetSemanticCache adds the semantic cache to the size report and the purge
+func (h *Handler) SetSemanticCache(s *cache.Semantic) {
+	h.semanticCache = s
+}
+
+// HandleCache reports the size of the response cache (GET) or purges it (POST or DELETE)
+// Only this instance's caches are purged; config changes purge every instance's caches on their own
+func (h *Handler) HandleCache(w http.ResponseWriter, r *http.Request) {
+	if h.responseCache == nil {
+		http.Error(w, "Response cache not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost, http.MethodDelete:
+		ip := getClientIP(r)
+		if !h.validateCSRFToken(r.Header.Get("X-CSRF-Token"), r) {
+			h.logAdminAction("cache_purge_failed", ip, "Invalid CSRF token")
+			http.Error(w, "Invalid or missing CSRF token", http.StatusForbidden)
+			return
+		}
+		purged := h.responseCache.Purge()
+		if h.semanticCache != nil {
+			purged += h.semanticCache.Purge()
+		}
+		h.logAdminAction("cache_purged", ip, fmt.Sprintf("%d answers", purged))
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report := map[string]interface{}{
+		"entries":     h.responseCache.Len(),
+		"max_entries": h.responseCache.MaxEntries(),
+	}
+	if h.semanticCache != nil {
+		report["semantic_entries"] = h.semanticCache.Len()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
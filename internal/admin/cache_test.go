@@ -0,0 +1,67 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clotilde/carplay-assistant/internal/cache"
+)
+
+func TestHandleCache_Purge(t *testing.T) {
+	h := NewHandler(nil)
+	c := cache.New(cache.DefaultConfig())
+	h.SetResponseCache(c)
+	semantic := cache.NewSemantic(cache.DefaultConfig())
+	h.SetSemanticCache(semantic)
+	c.Set(cache.Key{Question: "qual capital australia", Category: "factual", Model: "gpt-4o-mini"}, "Camberra")
+	semantic.Add(cache.SemanticKey{Category: "factual", Model: "gpt-4o-mini"}, []float32{1, 0}, "Camberra")
+
+	rr := httptest.NewRecorder()
+	h.HandleCache(rr, httptest.NewRequest("GET", "/admin/cache", nil))
+	var body struct {
+		Entries         int `json:"entries"`
+		MaxEntries      int `json:"max_entries"`
+		SemanticEntries int `json:"semantic_entries"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Invalid response: %v", err)
+	}
+	if body.Entries != 1 || body.MaxEntries != 1000 || body.SemanticEntries != 1 {
+		t.Errorf("Unexpected cache size: %+v", body)
+	}
+
+	// Purging requires a CSRF token
+	rr = httptest.NewRecorder()
+	h.HandleCache(rr, httptest.NewRequest("POST", "/admin/cache", nil))
+	if rr.Code != http.StatusForbidden || c.Len() != 1 {
+		t.Fatalf("Expected 403 without CSRF token, got %d", rr.Code)
+	}
+
+	req := httptest.NewRequest("POST", "/admin/cache", nil)
+	req.Header.Set("X-CSRF-Token", h.generateCSRFToken(req))
+	rr = httptest.NewRecorder()
+	h.HandleCache(rr, req)
+	if rr.Code != http.StatusOK || c.Len() != 0 || semantic.Len() != 0 {
+		t.Errorf("Expected both caches purged, got %d with %d and %d entries", rr.Code, c.Len(), semantic.Len())
+	}
+
+	c.Set(cache.Key{Question: "qual capital australia", Category: "factual", Model: "gpt-4o-mini"}, "Camberra")
+	req = httptest.NewRequest("DELETE", "/admin/cache", nil)
+	req.Header.Set("X-CSRF-Token", h.generateCSRFToken(req))
+	rr = httptest.NewRecorder()
+	h.HandleCache(rr, req)
+	if rr.Code != http.StatusOK || c.Len() != 0 {
+		t.Errorf("Expected DELETE to purge too, got %d with %d entries", rr.Code, c.Len())
+	}
+}
+
+func TestHandleCache_NotConfigured(t *testing.T) {
+	h := NewHandler(nil)
+	rr := httptest.NewRecorder()
+	h.HandleCache(rr, httptest.NewRequest("GET", "/admin/cache", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without a cache, got %d", rr.Code)
+	}
+}
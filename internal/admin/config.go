@@ -6,6 +6,11 @@ import (
 	"strings"
 	"sync"
 	"unicode/utf8"
+
+	"github.com/clotilde/carplay-assistant/internal/cache"
+	"github.com/clotilde/carplay-assistant/internal/provider"
+	"github.com/clotilde/carplay-assistant/internal/quota"
+	"github.com/clotilde/carplay-assistant/internal/tts"
 )
 
 // RuntimeConfig holds runtime configuration that can be changed via admin UI
@@ -16,6 +21,38 @@ type RuntimeConfig struct {
 	PremiumModel      string            `json:"premium_model"`      // Powerful model (default: gpt-4.1-mini, also supports gpt-4.1, o3)
 	CategoryModels    map[string]string `json:"category_models"`    // category -> model override (optional)
 	PerplexityEnabled bool              `json:"perplexity_enabled"` // Enable Perplexity Search API for web search (default: true)
+	TTSVoice          string            `json:"tts_voice"`          // Text-to-speech voice (empty uses the backend default)
+	TTSSpeed          float64           `json:"tts_speed"`          // Text-to-speech speaking rate, 0.5-2.0 (0 means 1.0)
+	Version           string            `json:"version,omitempty"`  // Version in the config store (sent back on update to detect concurrent edits)
+
+	// Rate limits (0 uses the built-in default)
+	RateLimitPerMinute        int `json:"rate_limit_per_minute"`          // Sustained requests per minute per API key (default 10)
+	RateLimitPerHour          int `json:"rate_limit_per_hour"`            // Requests per hour per API key (default 100)
+	RateLimitBurst            int `json:"rate_limit_burst"`               // Requests allowed back to back (default: per-minute limit)
+	PreAuthRateLimitPerMinute int `json:"pre_auth_rate_limit_per_minute"` // Requests per minute per IP before authentication (default 5)
+	PreAuthRateLimitPerHour   int `json:"pre_auth_rate_limit_per_hour"`   // Requests per hour per IP before authentication (default 20)
+
+	// Cost quotas, charged per API key with the cost of each request (zero fields are unlimited)
+	QuotaDefault    quota.Budget            `json:"quota_default"`              // For keys without their own budget (including the shared key)
+	QuotaCategories map[string]quota.Budget `json:"quota_categories,omitempty"` // category -> budget per key within that category
+	QuotaAction     string                  `json:"quota_action,omitempty"`     // "reject" (default) or "downgrade" to the standard model
+
+	// Prices used for request costs and quotas (empty uses the built-in list prices)
+	ModelPrices map[string]quota.Price `json:"model_prices,omitempty"` // model -> USD per million input/output tokens
+	SearchPrice float64                `json:"search_price,omitempty"` // USD per web search, native or Perplexity (0 uses $0.01)
+
+	// Models tried in order when the routed model fails with a retryable error (overloaded, rate
+	// limited, server error, timeout): category -> chain; "default" applies to categories without one
+	FallbackModels map[string][]string `json:"fallback_models,omitempty"`
+
+	// Hedged requests: when the routed model has not answered within the hedge delay, the question
+	// is also sent to HedgeModel and the first answer is used (empty disables hedging)
+	HedgeModel   string `json:"hedge_model,omitempty"`
+	HedgeDelayMs int    `json:"hedge_delay_ms,omitempty"` // 0 uses the observed p90 response time of the routed model
+
+	// Semantic cache: category -> minimum similarity (0-1) for a paraphrase to get a cached answer
+	// Categories not listed use the built-in thresholds; 0 turns semantic matching off for the category
+	SemanticThresholds map[string]float64 `json:"semantic_thresholds,omitempty"`
 
 	// Legacy field for backward compatibility
 	SystemPrompt string `json:"system_prompt,omitempty"`
@@ -33,8 +70,29 @@ var (
 	}
 	defaultCategoryPrompts = make(map[string]string) // Store default category prompts
 	initialized            = false
+	// configApplied is called after a new configuration takes effect (nil when nothing depends on it)
+	configApplied func()
 )
 
+// OnConfigApplied registers fn to run after each new configuration takes effect: changes from the
+// dashboard or the API, rollbacks and configs saved by other instances
+// Call it once at startup; fn must not change the configuration
+func OnConfigApplied(fn func()) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	configApplied = fn
+}
+
+// notifyConfigApplied runs the OnConfigApplied callback, if any
+func notifyConfigApplied() {
+	configMutex.RLock()
+	fn := configApplied
+	configMutex.RUnlock()
+	if fn != nil {
+		fn()
+	}
+}
+
 // SetDefaultConfig initializes the config with default values from main.go
 // This should be called once at startup with the default system prompt template
 func SetDefaultConfig(defaultSystemPrompt string) {
@@ -89,6 +147,38 @@ func GetConfig() RuntimeConfig {
 		categoryModels[k] = v
 	}
 
+	var quotaCategories map[string]quota.Budget
+	if len(runtimeConfig.QuotaCategories) > 0 {
+		quotaCategories = make(map[string]quota.Budget, len(runtimeConfig.QuotaCategories))
+		for k, v := range runtimeConfig.QuotaCategories {
+			quotaCategories[k] = v
+		}
+	}
+
+	var modelPrices map[string]quota.Price
+	if len(runtimeConfig.ModelPrices) > 0 {
+		modelPrices = make(map[string]quota.Price, len(runtimeConfig.ModelPrices))
+		for k, v := range runtimeConfig.ModelPrices {
+			modelPrices[k] = v
+		}
+	}
+
+	var semanticThresholds map[string]float64
+	if len(runtimeConfig.SemanticThresholds) > 0 {
+		semanticThresholds = make(map[string]float64, len(runtimeConfig.SemanticThresholds))
+		for k, v := range runtimeConfig.SemanticThresholds {
+			semanticThresholds[k] = v
+		}
+	}
+
+	var fallbackModels map[string][]string
+	if len(runtimeConfig.FallbackModels) > 0 {
+		fallbackModels = make(map[string][]string, len(runtimeConfig.FallbackModels))
+		for k, v := range runtimeConfig.FallbackModels {
+			fallbackModels[k] = append([]string(nil), v...)
+		}
+	}
+
 	return RuntimeConfig{
 		BaseSystemPrompt:  runtimeConfig.BaseSystemPrompt,
 		CategoryPrompts:   categoryPrompts,
@@ -96,6 +186,27 @@ func GetConfig() RuntimeConfig {
 		StandardModel:     runtimeConfig.StandardModel,
 		PremiumModel:      runtimeConfig.PremiumModel,
 		PerplexityEnabled: runtimeConfig.PerplexityEnabled,
+		TTSVoice:          runtimeConfig.TTSVoice,
+		TTSSpeed:          runtimeConfig.TTSSpeed,
+		Version:           runtimeConfig.Version,
+
+		RateLimitPerMinute:        runtimeConfig.RateLimitPerMinute,
+		RateLimitPerHour:          runtimeConfig.RateLimitPerHour,
+		RateLimitBurst:            runtimeConfig.RateLimitBurst,
+		PreAuthRateLimitPerMinute: runtimeConfig.PreAuthRateLimitPerMinute,
+		PreAuthRateLimitPerHour:   runtimeConfig.PreAuthRateLimitPerHour,
+
+		QuotaDefault:    runtimeConfig.QuotaDefault,
+		QuotaCategories: quotaCategories,
+		QuotaAction:     runtimeConfig.QuotaAction,
+
+		ModelPrices: modelPrices,
+		SearchPrice: runtimeConfig.SearchPrice,
+
+		FallbackModels:     fallbackModels,
+		HedgeModel:         runtimeConfig.HedgeModel,
+		HedgeDelayMs:       runtimeConfig.HedgeDelayMs,
+		SemanticThresholds: semanticThresholds,
 		// Legacy support
 		SystemPrompt: runtimeConfig.BaseSystemPrompt,
 	}
@@ -139,53 +250,182 @@ func validateSystemPrompt(prompt string) error {
 	return nil
 }
 
+// validateTTS validates the text-to-speech voice and speed
+// Voices are backend-specific (e.g. "nova" for OpenAI, "pt_BR-faber-medium" for Piper), so only the charset is checked
+func validateTTS(voice string, speed float64) error {
+	if len(voice) > 64 {
+		return &ConfigError{Field: "tts_voice", Message: "TTS voice name too long"}
+	}
+	for _, r := range voice {
+		if !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && !(r >= '0' && r <= '9') && r != '-' && r != '_' && r != '.' {
+			return &ConfigError{Field: "tts_voice", Message: "TTS voice contains invalid characters"}
+		}
+	}
+
+	// 0 means "use the default speed"
+	if speed != 0 && (speed < tts.MinSpeed || speed > tts.MaxSpeed) {
+		return &ConfigError{Field: "tts_speed", Message: fmt.Sprintf("TTS speed must be between %.1f and %.1f", tts.MinSpeed, tts.MaxSpeed)}
+	}
+
+	return nil
+}
+
+// maxRateLimit bounds each rate limit setting
+const maxRateLimit = 100000
+
+// validateRateLimits checks that every limit is between 0 (default) and maxRateLimit
+func validateRateLimits(config RuntimeConfig) error {
+	limits := []struct {
+		field string
+		value int
+	}{
+		{"rate_limit_per_minute", config.RateLimitPerMinute},
+		{"rate_limit_per_hour", config.RateLimitPerHour},
+		{"rate_limit_burst", config.RateLimitBurst},
+		{"pre_auth_rate_limit_per_minute", config.PreAuthRateLimitPerMinute},
+		{"pre_auth_rate_limit_per_hour", config.PreAuthRateLimitPerHour},
+	}
+	for _, limit := range limits {
+		if limit.value < 0 || limit.value > maxRateLimit {
+			return &ConfigError{Field: limit.field, Message: fmt.Sprintf("Rate limit must be between 0 (default) and %d", maxRateLimit)}
+		}
+	}
+	return nil
+}
+
+// validateQuotas checks that budgets are not negative and the exhaustion action is known
+func validateQuotas(config RuntimeConfig) error {
+	if err := config.QuotaDefault.Validate(); err != nil {
+		return &ConfigError{Field: "quota_default", Message: "Budget limits must not be negative"}
+	}
+	for category, budget := range config.QuotaCategories {
+		if err := budget.Validate(); err != nil {
+			return &ConfigError{Field: "quota_categories." + category, Message: "Budget limits must not be negative"}
+		}
+	}
+	switch config.QuotaAction {
+	case "", quota.ActionReject, quota.ActionDowngrade:
+	default:
+		return &ConfigError{Field: "quota_action", Message: "Quota action must be reject or downgrade"}
+	}
+	if err := config.Prices().Validate(); err != nil {
+		return &ConfigError{Field: "model_prices", Message: "Invalid prices: " + err.Error()}
+	}
+	return nil
+}
+
+// maxFallbackModels bounds a fallback chain: each step needs a few seconds of the request deadline
+const maxFallbackModels = 3
+
+// validateFallbackModels checks that fallback chains are short and only name known models
+func validateFallbackModels(config RuntimeConfig) error {
+	for category, chain := range config.FallbackModels {
+		if len(chain) > maxFallbackModels {
+			return &ConfigError{Field: "fallback_models." + category, Message: fmt.Sprintf("At most %d fallback models per category", maxFallbackModels)}
+		}
+		for _, model := range chain {
+			if !provider.IsKnownModel(model) {
+				return &ConfigError{Field: "fallback_models." + category, Message: "Invalid fallback model: " + model}
+			}
+		}
+	}
+	return nil
+}
+
+// FallbackChain returns the models to try, in order, when the routed model of a category fails
+func (c RuntimeConfig) FallbackChain(category string) []string {
+	if chain, ok := c.FallbackModels[category]; ok {
+		return chain
+	}
+	return c.FallbackModels["default"]
+}
+
+// maxHedgeDelayMs keeps the hedge delay well within the request deadline, so the hedge model has
+// time to answer
+const maxHedgeDelayMs = 15000
+
+// validateHedge checks that the hedge model is known and the delay leaves it time to answer
+func validateHedge(config RuntimeConfig) error {
+	if config.HedgeModel != "" && !provider.IsKnownModel(config.HedgeModel) {
+		return &ConfigError{Field: "hedge_model", Message: "Invalid hedge model: " + config.HedgeModel}
+	}
+	if config.HedgeDelayMs < 0 || config.HedgeDelayMs > maxHedgeDelayMs {
+		return &ConfigError{Field: "hedge_delay_ms", Message: fmt.Sprintf("Hedge delay must be between 0 (observed p90) and %d ms", maxHedgeDelayMs)}
+	}
+	return nil
+}
+
+// validateSemanticThresholds checks that similarity thresholds are between 0 and 1
+func validateSemanticThresholds(config RuntimeConfig) error {
+	for category, threshold := range config.SemanticThresholds {
+		if threshold < 0 || threshold > 1 {
+			return &ConfigError{Field: "semantic_thresholds." + category, Message: "Similarity threshold must be between 0 (off) and 1"}
+		}
+	}
+	return nil
+}
+
+// SemanticThreshold returns the minimum similarity for a paraphrase of a question in category to
+// get a cached answer (0 when semantic matching is off for the category)
+func (c RuntimeConfig) SemanticThreshold(category string) float64 {
+	if threshold, ok := c.SemanticThresholds[category]; ok {
+		return threshold
+	}
+	return cache.DefaultSemanticThresholds()[category]
+}
+
+// Prices returns the configured price overrides
+func (c RuntimeConfig) Prices() quota.Prices {
+	return quota.Prices{Models: c.ModelPrices, SearchCall: c.SearchPrice}
+}
+
 // SetConfig updates the runtime configuration
 // Returns error if validation fails
+// When a config store is set, the new configuration is persisted before it is applied and
+// ErrConfigConflict is returned if newConfig.Version is not the current version
 func SetConfig(newConfig RuntimeConfig) error {
-	// All models that can be used - OpenAI and Claude (Anthropic)
-	validModels := map[string]bool{
-		// GPT-4o series (confirmed working)
-		"gpt-4o":            true,
-		"gpt-4o-mini":       true,
-		"gpt-4o-2024-08-06": true,
-		"chatgpt-4o-latest": true,
-		// GPT-4 series
-		"gpt-4-turbo":   true,
-		"gpt-3.5-turbo": true,
-		// GPT-4.1 series (may require specific API access)
-		"gpt-4.1":      true,
-		"gpt-4.1-mini": true,
-		"gpt-4.1-nano": true,
-		// GPT-5 series (may require specific API access)
-		"gpt-5":      true,
-		"gpt-5.1":    true,
-		"gpt-5-mini": true,
-		"gpt-5-nano": true,
-		"gpt-5-pro":  true,
-		// O-series reasoning models
-		"o1":      true,
-		"o1-mini": true,
-		"o1-pro":  true,
-		"o3":      true,
-		"o3-mini": true,
-		"o4-mini": true,
-		// Claude models (Anthropic) - FAST, ideal for CarPlay
-		// Claude Haiku 4.5 is extremely fast (~1-3s), best for CarPlay
-		"claude-haiku-4-5-20251001": true, // Latest Haiku 4.5 - fastest, best for CarPlay
-		// Older Claude models (for backward compatibility)
-		"claude-3-5-haiku-20241022":  true,
-		"claude-3-5-haiku-latest":    true,
-		"claude-3-5-sonnet-20241022": true,
-		"claude-3-5-sonnet-latest":   true,
-		"claude-sonnet-4-20250514":   true, // Claude Sonnet 4
-		"claude-3-opus-20240229":     true, // Most capable but slower
-	}
-
-	if !validModels[newConfig.StandardModel] {
+	return UpdateConfig(newConfig, ConfigOrigin{Source: SourceInternal})
+}
+
+// UpdateConfig is SetConfig with the author of the change, which is recorded in the config history
+func UpdateConfig(newConfig RuntimeConfig, origin ConfigOrigin) error {
+	return updateConfig(newConfig, origin, 0)
+}
+
+// updateConfig validates, persists and applies a configuration, then records it in the history
+func updateConfig(newConfig RuntimeConfig, origin ConfigOrigin, rollbackOf int) error {
+	if err := validateConfig(newConfig); err != nil {
+		return err
+	}
+
+	var applied RuntimeConfig
+	if configStore != nil {
+		var err error
+		if applied, err = persistConfig(newConfig); err != nil {
+			return err
+		}
+	} else {
+		configMutex.Lock()
+		applyConfig(&runtimeConfig, newConfig)
+		applied = cloneConfig(runtimeConfig)
+		configMutex.Unlock()
+	}
+
+	notifyConfigApplied()
+	recordHistory(applied, origin, rollbackOf)
+	return nil
+}
+
+// validateConfig checks models, prompts, voice settings, rate limits and quotas
+func validateConfig(newConfig RuntimeConfig) error {
+	// All models that can be used are declared by the registered providers (OpenAI, Claude, ...)
+	validModels := provider.IsKnownModel
+
+	if !validModels(newConfig.StandardModel) {
 		return &ConfigError{Field: "standard_model", Message: "Invalid standard model"}
 	}
 
-	if !validModels[newConfig.PremiumModel] {
+	if !validModels(newConfig.PremiumModel) {
 		return &ConfigError{Field: "premium_model", Message: "Invalid premium model"}
 	}
 
@@ -221,58 +461,117 @@ func SetConfig(newConfig RuntimeConfig) error {
 
 	// Validate category models if provided
 	for category, model := range newConfig.CategoryModels {
-		if !validModels[model] {
+		if !validModels(model) {
 			return &ConfigError{Field: "category_models." + category, Message: "Invalid model for category"}
 		}
 	}
 
-	configMutex.Lock()
-	defer configMutex.Unlock()
+	if err := validateTTS(newConfig.TTSVoice, newConfig.TTSSpeed); err != nil {
+		return err
+	}
+
+	if err := validateRateLimits(newConfig); err != nil {
+		return err
+	}
+	if err := validateQuotas(newConfig); err != nil {
+		return err
+	}
+	if err := validateFallbackModels(newConfig); err != nil {
+		return err
+	}
+	if err := validateHedge(newConfig); err != nil {
+		return err
+	}
+	return validateSemanticThresholds(newConfig)
+}
 
+// applyConfig merges a validated configuration into dst (caller holds configMutex when dst is runtimeConfig)
+func applyConfig(dst *RuntimeConfig, newConfig RuntimeConfig) {
 	// Update base prompt (prefer BaseSystemPrompt, fallback to SystemPrompt for legacy)
 	if newConfig.BaseSystemPrompt != "" {
-		runtimeConfig.BaseSystemPrompt = newConfig.BaseSystemPrompt
-		runtimeConfig.SystemPrompt = newConfig.BaseSystemPrompt // Legacy support
+		dst.BaseSystemPrompt = newConfig.BaseSystemPrompt
+		dst.SystemPrompt = newConfig.BaseSystemPrompt // Legacy support
 	} else if newConfig.SystemPrompt != "" {
-		runtimeConfig.BaseSystemPrompt = newConfig.SystemPrompt
-		runtimeConfig.SystemPrompt = newConfig.SystemPrompt
+		dst.BaseSystemPrompt = newConfig.SystemPrompt
+		dst.SystemPrompt = newConfig.SystemPrompt
 	}
 
 	// Update category prompts
 	if newConfig.CategoryPrompts != nil && len(newConfig.CategoryPrompts) > 0 {
-		runtimeConfig.CategoryPrompts = make(map[string]string)
+		dst.CategoryPrompts = make(map[string]string)
 		for k, v := range newConfig.CategoryPrompts {
-			runtimeConfig.CategoryPrompts[k] = v
+			dst.CategoryPrompts[k] = v
 		}
 	} else {
 		// If nil or empty, set to nil to use defaults
-		runtimeConfig.CategoryPrompts = nil
+		dst.CategoryPrompts = nil
 	}
 
 	// Update category models
 	if newConfig.CategoryModels != nil {
-		runtimeConfig.CategoryModels = make(map[string]string)
+		dst.CategoryModels = make(map[string]string)
 		for k, v := range newConfig.CategoryModels {
-			runtimeConfig.CategoryModels[k] = v
+			dst.CategoryModels[k] = v
 		}
 	}
 
-	runtimeConfig.StandardModel = newConfig.StandardModel
-	runtimeConfig.PremiumModel = newConfig.PremiumModel
+	dst.StandardModel = newConfig.StandardModel
+	dst.PremiumModel = newConfig.PremiumModel
+	dst.TTSVoice = newConfig.TTSVoice
+	dst.TTSSpeed = newConfig.TTSSpeed
+	dst.RateLimitPerMinute = newConfig.RateLimitPerMinute
+	dst.RateLimitPerHour = newConfig.RateLimitPerHour
+	dst.RateLimitBurst = newConfig.RateLimitBurst
+	dst.PreAuthRateLimitPerMinute = newConfig.PreAuthRateLimitPerMinute
+	dst.PreAuthRateLimitPerHour = newConfig.PreAuthRateLimitPerHour
+	dst.QuotaDefault = newConfig.QuotaDefault
+	dst.QuotaAction = newConfig.QuotaAction
+	dst.QuotaCategories = nil
+	for k, v := range newConfig.QuotaCategories {
+		if v.IsZero() {
+			continue
+		}
+		if dst.QuotaCategories == nil {
+			dst.QuotaCategories = make(map[string]quota.Budget)
+		}
+		dst.QuotaCategories[k] = v
+	}
+	dst.ModelPrices = nil
+	for k, v := range newConfig.ModelPrices {
+		if dst.ModelPrices == nil {
+			dst.ModelPrices = make(map[string]quota.Price)
+		}
+		dst.ModelPrices[k] = v
+	}
+	dst.SearchPrice = newConfig.SearchPrice
+	dst.FallbackModels = nil
+	for k, v := range newConfig.FallbackModels {
+		if dst.FallbackModels == nil {
+			dst.FallbackModels = make(map[string][]string)
+		}
+		dst.FallbackModels[k] = append([]string(nil), v...)
+	}
+	dst.HedgeModel = newConfig.HedgeModel
+	dst.HedgeDelayMs = newConfig.HedgeDelayMs
+	dst.SemanticThresholds = nil
+	for k, v := range newConfig.SemanticThresholds {
+		if dst.SemanticThresholds == nil {
+			dst.SemanticThresholds = make(map[string]float64)
+		}
+		dst.SemanticThresholds[k] = v
+	}
 
 	// Update PerplexityEnabled (always update if provided, default to true on first init)
 	if initialized {
-		runtimeConfig.PerplexityEnabled = newConfig.PerplexityEnabled
+		dst.PerplexityEnabled = newConfig.PerplexityEnabled
 	} else {
 		// On first initialization, use provided value or default to true
 		if newConfig.PerplexityEnabled {
-			runtimeConfig.PerplexityEnabled = true
+			dst.PerplexityEnabled = true
 		} else {
-			runtimeConfig.PerplexityEnabled = false
+			dst.PerplexityEnabled = false
 		}
 	}
-
-	return nil
 }
 
 // ConfigError represents a configuration validation error
@@ -3,6 +3,10 @@ package admin
 import (
 	"sync"
 	"testing"
+
+	"github.com/clotilde/carplay-assistant/internal/cache"
+	"github.com/clotilde/carplay-assistant/internal/provider"
+	"github.com/clotilde/carplay-assistant/internal/quota"
 )
 
 func TestSetDefaultConfig_InitializesOnce(t *testing.T) {
@@ -78,6 +82,7 @@ func TestSetConfig_ValidModels(t *testing.T) {
 		{"gpt-4.1 series", "gpt-4.1-mini", "gpt-4.1", true},
 		{"gpt-5 series", "gpt-5-mini", "gpt-5.1", true},
 		{"o-series", "o1-mini", "o3", true},
+		{"gemini series", "gemini-2.5-flash", "gemini-2.5-pro", true},
 		{"invalid standard", "invalid-model", "gpt-4o", false},
 		{"invalid premium", "gpt-4o-mini", "invalid-model", false},
 		{"both invalid", "invalid-1", "invalid-2", false},
@@ -112,6 +117,186 @@ func TestSetConfig_ValidModels(t *testing.T) {
 	}
 }
 
+func TestSetConfig_LocalModels(t *testing.T) {
+	// Reset state
+	configMutex.Lock()
+	runtimeConfig = RuntimeConfig{
+		SystemPrompt:  "Test: %s",
+		StandardModel: "gpt-4o-mini",
+		PremiumModel:  "gpt-4o",
+	}
+	initialized = true
+	configMutex.Unlock()
+
+	localConfig := RuntimeConfig{
+		SystemPrompt:   "Test prompt: %s",
+		StandardModel:  "local:llama3",
+		PremiumModel:   "gpt-4o",
+		CategoryModels: map[string]string{"creative": "local:mistral"},
+	}
+
+	// Local models are rejected until a local provider is registered
+	if err := SetConfig(localConfig); err == nil {
+		t.Error("Expected local model to be rejected without a local provider")
+	}
+
+	provider.Register(provider.NewLocal("http://localhost:11434/v1", "", nil))
+	if err := SetConfig(localConfig); err != nil {
+		t.Errorf("Expected local model to be accepted, got error: %v", err)
+	}
+	if config := GetConfig(); config.StandardModel != "local:llama3" {
+		t.Errorf("Expected standard model local:llama3, got %q", config.StandardModel)
+	}
+}
+
+func TestSetConfig_TTSValidation(t *testing.T) {
+	// Reset state
+	configMutex.Lock()
+	runtimeConfig = RuntimeConfig{
+		SystemPrompt:  "Test: %s",
+		StandardModel: "gpt-4o-mini",
+		PremiumModel:  "gpt-4o",
+	}
+	initialized = true
+	configMutex.Unlock()
+
+	tests := []struct {
+		name    string
+		voice   string
+		speed   float64
+		wantErr bool
+	}{
+		{"defaults", "", 0, false},
+		{"openai voice", "nova", 1.25, false},
+		{"piper voice", "pt_BR-faber-medium", 0.5, false},
+		{"too slow", "nova", 0.25, true},
+		{"too fast", "nova", 2.5, true},
+		{"invalid voice", "nova; rm -rf", 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := SetConfig(RuntimeConfig{
+				SystemPrompt:  "Test prompt: %s",
+				StandardModel: "gpt-4o-mini",
+				PremiumModel:  "gpt-4o",
+				TTSVoice:      tt.voice,
+				TTSSpeed:      tt.speed,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				if config := GetConfig(); config.TTSVoice != tt.voice || config.TTSSpeed != tt.speed {
+					t.Errorf("Expected voice %q speed %v, got %q %v", tt.voice, tt.speed, config.TTSVoice, config.TTSSpeed)
+				}
+			}
+		})
+	}
+}
+
+func TestSetConfig_RateLimitValidation(t *testing.T) {
+	// Reset state
+	configMutex.Lock()
+	runtimeConfig = RuntimeConfig{
+		SystemPrompt:  "Test: %s",
+		StandardModel: "gpt-4o-mini",
+		PremiumModel:  "gpt-4o",
+	}
+	initialized = true
+	configMutex.Unlock()
+
+	tests := []struct {
+		name    string
+		config  RuntimeConfig
+		wantErr bool
+	}{
+		{"defaults", RuntimeConfig{}, false},
+		{"custom limits", RuntimeConfig{RateLimitPerMinute: 30, RateLimitPerHour: 500, RateLimitBurst: 5}, false},
+		{"pre-auth limits", RuntimeConfig{PreAuthRateLimitPerMinute: 10, PreAuthRateLimitPerHour: 50}, false},
+		{"negative", RuntimeConfig{RateLimitPerMinute: -1}, true},
+		{"too large", RuntimeConfig{PreAuthRateLimitPerHour: maxRateLimit + 1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config
+			cfg.SystemPrompt = "Test prompt: %s"
+			cfg.StandardModel = "gpt-4o-mini"
+			cfg.PremiumModel = "gpt-4o"
+			err := SetConfig(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if _, ok := err.(*ConfigError); !ok {
+					t.Errorf("Expected *ConfigError, got %T", err)
+				}
+				return
+			}
+			if config := GetConfig(); config.RateLimitPerMinute != cfg.RateLimitPerMinute || config.RateLimitBurst != cfg.RateLimitBurst || config.PreAuthRateLimitPerHour != cfg.PreAuthRateLimitPerHour {
+				t.Errorf("Rate limits not applied: %+v", config)
+			}
+		})
+	}
+}
+
+func TestSetConfig_QuotaValidation(t *testing.T) {
+	// Reset state
+	configMutex.Lock()
+	runtimeConfig = RuntimeConfig{
+		SystemPrompt:  "Test: %s",
+		StandardModel: "gpt-4o-mini",
+		PremiumModel:  "gpt-4o",
+	}
+	initialized = true
+	configMutex.Unlock()
+
+	tests := []struct {
+		name    string
+		config  RuntimeConfig
+		wantErr bool
+	}{
+		{"unlimited", RuntimeConfig{}, false},
+		{"default budget", RuntimeConfig{QuotaDefault: quota.Budget{DailyCost: 1, MonthlyTokens: 1000000}, QuotaAction: quota.ActionDowngrade}, false},
+		{"category budget", RuntimeConfig{QuotaCategories: map[string]quota.Budget{"complex": {DailyCost: 0.5}}}, false},
+		{"negative budget", RuntimeConfig{QuotaDefault: quota.Budget{DailyCost: -1}}, true},
+		{"negative category budget", RuntimeConfig{QuotaCategories: map[string]quota.Budget{"complex": {MonthlyCost: -1}}}, true},
+		{"unknown action", RuntimeConfig{QuotaAction: "block"}, true},
+		{"model price", RuntimeConfig{ModelPrices: map[string]quota.Price{"local:llama3": {Input: 0.1, Output: 0.1}}, SearchPrice: 0.005}, false},
+		{"negative model price", RuntimeConfig{ModelPrices: map[string]quota.Price{"gpt-4o": {Output: -1}}}, true},
+		{"negative search price", RuntimeConfig{SearchPrice: -0.01}, true},
+		{"semantic thresholds", RuntimeConfig{SemanticThresholds: map[string]float64{"factual": 0.95, "web_search": 0}}, false},
+		{"semantic threshold above 1", RuntimeConfig{SemanticThresholds: map[string]float64{"factual": 1.5}}, true},
+		{"fallback chain", RuntimeConfig{FallbackModels: map[string][]string{"default": {"gpt-4o-mini", "gpt-4.1-nano"}}}, false},
+		{"unknown fallback model", RuntimeConfig{FallbackModels: map[string][]string{"complex": {"gpt-99"}}}, true},
+		{"fallback chain too long", RuntimeConfig{FallbackModels: map[string][]string{"default": {"gpt-4o-mini", "gpt-4o", "gpt-4.1", "gpt-4.1-nano"}}}, true},
+		{"hedge model", RuntimeConfig{HedgeModel: "gpt-4.1-nano", HedgeDelayMs: 3000}, false},
+		{"unknown hedge model", RuntimeConfig{HedgeModel: "gpt-99"}, true},
+		{"hedge delay too long", RuntimeConfig{HedgeModel: "gpt-4.1-nano", HedgeDelayMs: 20000}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config
+			cfg.SystemPrompt = "Test prompt: %s"
+			cfg.StandardModel = "gpt-4o-mini"
+			cfg.PremiumModel = "gpt-4o"
+			err := SetConfig(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			config := GetConfig()
+			if config.QuotaDefault != cfg.QuotaDefault || config.QuotaAction != cfg.QuotaAction || len(config.QuotaCategories) != len(cfg.QuotaCategories) {
+				t.Errorf("Quotas not applied: %+v", config)
+			}
+		})
+	}
+}
+
 func TestSetConfig_SystemPromptValidation(t *testing.T) {
 	// Reset state
 	configMutex.Lock()
@@ -263,3 +448,19 @@ func containsMiddle(s, substr string) bool {
 	return false
 }
 
+
+func TestRuntimeConfig_SemanticThreshold(t *testing.T) {
+	config := RuntimeConfig{SemanticThresholds: map[string]float64{"factual": 0.97, "web_search": 0}}
+	if got := config.SemanticThreshold("factual"); got != 0.97 {
+		t.Errorf("Expected the configured threshold, got %v", got)
+	}
+	if got := config.SemanticThreshold("web_search"); got != 0 {
+		t.Errorf("Expected semantic matching off for web_search, got %v", got)
+	}
+	if got := config.SemanticThreshold("simple"); got != cache.DefaultSemanticThresholds()["simple"] {
+		t.Errorf("Expected the default threshold for simple, got %v", got)
+	}
+	if got := config.SemanticThreshold("mathematical"); got != 0 {
+		t.Errorf("Expected no semantic matching for calculations by default, got %v", got)
+	}
+}
@@ -488,6 +488,22 @@ const dashboardHTML = `<!DOCTYPE html>
         }
 
         /* Settings Styles */
+        .history-diff {
+            font-family: 'SF Mono', Monaco, monospace;
+            font-size: 12px;
+            white-space: pre-wrap;
+            word-break: break-word;
+            margin-top: 8px;
+        }
+
+        .history-diff .diff-add {
+            color: var(--accent-green);
+        }
+
+        .history-diff .diff-remove {
+            color: var(--accent-red);
+        }
+
         .settings-card {
             background: var(--bg-secondary);
             border: 1px solid var(--border-color);
@@ -637,7 +653,8 @@ const dashboardHTML = `<!DOCTYPE html>
             <div class="stat-card">
                 <div class="stat-label">Avg Response Time</div>
                 <div class="stat-value" id="avgResponseTime">-</div>
-                <div class="stat-subtitle">milliseconds</div>
+                <div class="stat-subtitle" id="responseTimeTail">milliseconds</div>
+                <div class="stat-subtitle" id="stageAverages"></div>
             </div>
             <div class="stat-card">
                 <div class="stat-label">Error Rate</div>
@@ -654,6 +671,27 @@ const dashboardHTML = `<!DOCTYPE html>
                 <div class="stat-value" id="modelPremium">-</div>
                 <div class="stat-subtitle">Powerful (Sonnet, GPT-4o)</div>
             </div>
+            <div class="stat-card">
+                <div class="stat-label">Cost Today</div>
+                <div class="stat-value" id="costToday">-</div>
+                <div class="stat-subtitle" id="costTotal">USD since startup</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-label">Cache Hit Rate</div>
+                <div class="stat-value" id="cacheHitRate">-</div>
+                <div class="stat-subtitle" id="cacheEntries">Answers served from the cache</div>
+                <button class="btn btn-secondary" style="margin-top: 8px;" onclick="purgeCache()">Purge Cache</button>
+            </div>
+            <div class="stat-card">
+                <div class="stat-label">Fallbacks</div>
+                <div class="stat-value" id="fallbacks">-</div>
+                <div class="stat-subtitle" id="fallbackDetail">Answered by a fallback model</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-label">Clients on Old Key</div>
+                <div class="stat-value" id="oldKeyClients">-</div>
+                <div class="stat-subtitle" id="keyGenerations">Shared API key generations</div>
+            </div>
         </div>
 
         <div class="settings-card">
@@ -676,6 +714,11 @@ const dashboardHTML = `<!DOCTYPE html>
                             <option value="claude-3-5-haiku-20241022">Claude 3.5 Haiku</option>
                             <option value="claude-3-5-haiku-latest">Claude 3.5 Haiku (Latest)</option>
                         </optgroup>
+                        <optgroup label="Google Gemini - Search Grounding 🔍">
+                            <option value="gemini-2.5-flash">Gemini 2.5 Flash (Fast, native web search)</option>
+                            <option value="gemini-2.5-flash-lite">Gemini 2.5 Flash-Lite</option>
+                            <option value="gemini-2.0-flash">Gemini 2.0 Flash</option>
+                        </optgroup>
                         <optgroup label="OpenAI - GPT-4o">
                             <option value="gpt-4o-mini">gpt-4o-mini</option>
                             <option value="gpt-4o">gpt-4o</option>
@@ -711,6 +754,10 @@ const dashboardHTML = `<!DOCTYPE html>
                             <option value="claude-sonnet-4-20250514">Claude Sonnet 4 (Most Capable)</option>
                             <option value="claude-3-opus-20240229">Claude 3 Opus (Highest Quality)</option>
                         </optgroup>
+                        <optgroup label="Google Gemini - Search Grounding 🔍">
+                            <option value="gemini-2.5-pro">Gemini 2.5 Pro (Most Capable)</option>
+                            <option value="gemini-2.5-flash">Gemini 2.5 Flash (Fast, native web search)</option>
+                        </optgroup>
                         <optgroup label="OpenAI - GPT-4o">
                             <option value="gpt-4o">gpt-4o</option>
                             <option value="gpt-4o-mini">gpt-4o-mini</option>
@@ -748,7 +795,189 @@ const dashboardHTML = `<!DOCTYPE html>
                     <span>Enable Perplexity Search API for Web Search</span>
                 </label>
                 <div class="stat-subtitle" style="margin-top: 8px; margin-left: 32px;">
-                    When enabled, uses Perplexity AI Search API instead of OpenAI's web_search tool for web search queries. Enabled by default.
+                    When enabled, uses Perplexity AI Search API instead of the native web search tools (OpenAI web_search, Gemini Google Search grounding) for web search queries. Claude models require it for web search. Enabled by default.
+                </div>
+            </div>
+
+            <div style="display: grid; grid-template-columns: 1fr 1fr; gap: 16px; margin-bottom: 24px;">
+                <div class="form-group">
+                    <label class="form-label">Voice (Text-to-Speech)</label>
+                    <input type="text" class="form-control" id="ttsVoice" placeholder="nova">
+                    <div class="stat-subtitle" style="margin-top: 8px;">
+                        Backend voice name (e.g. nova, shimmer for OpenAI; pt_BR-faber-medium for Piper). Leave empty for the default.
+                    </div>
+                </div>
+                <div class="form-group">
+                    <label class="form-label">Speech Speed</label>
+                    <input type="number" class="form-control" id="ttsSpeed" min="0.5" max="2" step="0.05" placeholder="1.0">
+                    <div class="stat-subtitle" style="margin-top: 8px;">
+                        Speaking rate between 0.5 and 2.0. Leave empty for normal speed.
+                    </div>
+                </div>
+            </div>
+
+            <div class="form-group" style="margin-bottom: 24px;">
+                <label class="form-label">Rate Limits</label>
+                <div style="display: grid; grid-template-columns: repeat(5, 1fr); gap: 16px;">
+                    <div class="form-group">
+                        <label class="form-label">Per Minute (per key)</label>
+                        <input type="number" class="form-control" id="rateLimitPerMinute" min="0" max="100000" placeholder="10">
+                    </div>
+                    <div class="form-group">
+                        <label class="form-label">Per Hour (per key)</label>
+                        <input type="number" class="form-control" id="rateLimitPerHour" min="0" max="100000" placeholder="100">
+                    </div>
+                    <div class="form-group">
+                        <label class="form-label">Burst</label>
+                        <input type="number" class="form-control" id="rateLimitBurst" min="0" max="100000" placeholder="per-minute">
+                    </div>
+                    <div class="form-group">
+                        <label class="form-label">Per Minute (per IP, pre-auth)</label>
+                        <input type="number" class="form-control" id="preAuthRateLimitPerMinute" min="0" max="100000" placeholder="5">
+                    </div>
+                    <div class="form-group">
+                        <label class="form-label">Per Hour (per IP, pre-auth)</label>
+                        <input type="number" class="form-control" id="preAuthRateLimitPerHour" min="0" max="100000" placeholder="20">
+                    </div>
+                </div>
+                <div class="stat-subtitle" style="margin-top: 8px;">
+                    Token bucket limits applied immediately to new requests. Burst is how many requests a key can make back to back before the per-minute rate applies. Leave empty for the defaults. API keys with their own limits override the per-key values.
+                </div>
+            </div>
+
+            <div class="form-group">
+                <label class="form-label">Cost Quotas (per API key)</label>
+                <div style="display: grid; grid-template-columns: repeat(5, 1fr); gap: 16px;">
+                    <div class="form-group">
+                        <label class="form-label">Daily Budget (USD)</label>
+                        <input type="number" class="form-control" id="quotaDailyCost" min="0" step="0.01" placeholder="unlimited">
+                    </div>
+                    <div class="form-group">
+                        <label class="form-label">Monthly Budget (USD)</label>
+                        <input type="number" class="form-control" id="quotaMonthlyCost" min="0" step="0.01" placeholder="unlimited">
+                    </div>
+                    <div class="form-group">
+                        <label class="form-label">Daily Tokens</label>
+                        <input type="number" class="form-control" id="quotaDailyTokens" min="0" placeholder="unlimited">
+                    </div>
+                    <div class="form-group">
+                        <label class="form-label">Monthly Tokens</label>
+                        <input type="number" class="form-control" id="quotaMonthlyTokens" min="0" placeholder="unlimited">
+                    </div>
+                    <div class="form-group">
+                        <label class="form-label">When Exhausted</label>
+                        <select class="form-control" id="quotaAction">
+                            <option value="reject">Reject (429)</option>
+                            <option value="downgrade">Use standard model</option>
+                        </select>
+                    </div>
+                </div>
+                <label class="form-label" style="margin-top: 8px;">Budget per Category (per API key)</label>
+                <div style="display: grid; grid-template-columns: repeat(6, 1fr); gap: 16px;">
+                    <div class="form-group">
+                        <label class="form-label">Simple</label>
+                        <input type="number" class="form-control quota-category" data-category="simple" data-field="daily_cost" id="quotaCategorySimpleDaily" min="0" step="0.01" placeholder="USD/day">
+                        <input type="number" class="form-control quota-category" data-category="simple" data-field="monthly_cost" id="quotaCategorySimpleMonthly" min="0" step="0.01" placeholder="USD/month" style="margin-top: 8px;">
+                    </div>
+                    <div class="form-group">
+                        <label class="form-label">Factual</label>
+                        <input type="number" class="form-control quota-category" data-category="factual" data-field="daily_cost" id="quotaCategoryFactualDaily" min="0" step="0.01" placeholder="USD/day">
+                        <input type="number" class="form-control quota-category" data-category="factual" data-field="monthly_cost" id="quotaCategoryFactualMonthly" min="0" step="0.01" placeholder="USD/month" style="margin-top: 8px;">
+                    </div>
+                    <div class="form-group">
+                        <label class="form-label">Web Search</label>
+                        <input type="number" class="form-control quota-category" data-category="web_search" data-field="daily_cost" id="quotaCategoryWebSearchDaily" min="0" step="0.01" placeholder="USD/day">
+                        <input type="number" class="form-control quota-category" data-category="web_search" data-field="monthly_cost" id="quotaCategoryWebSearchMonthly" min="0" step="0.01" placeholder="USD/month" style="margin-top: 8px;">
+                    </div>
+                    <div class="form-group">
+                        <label class="form-label">Mathematical</label>
+                        <input type="number" class="form-control quota-category" data-category="mathematical" data-field="daily_cost" id="quotaCategoryMathematicalDaily" min="0" step="0.01" placeholder="USD/day">
+                        <input type="number" class="form-control quota-category" data-category="mathematical" data-field="monthly_cost" id="quotaCategoryMathematicalMonthly" min="0" step="0.01" placeholder="USD/month" style="margin-top: 8px;">
+                    </div>
+                    <div class="form-group">
+                        <label class="form-label">Complex</label>
+                        <input type="number" class="form-control quota-category" data-category="complex" data-field="daily_cost" id="quotaCategoryComplexDaily" min="0" step="0.01" placeholder="USD/day">
+                        <input type="number" class="form-control quota-category" data-category="complex" data-field="monthly_cost" id="quotaCategoryComplexMonthly" min="0" step="0.01" placeholder="USD/month" style="margin-top: 8px;">
+                    </div>
+                    <div class="form-group">
+                        <label class="form-label">Creative</label>
+                        <input type="number" class="form-control quota-category" data-category="creative" data-field="daily_cost" id="quotaCategoryCreativeDaily" min="0" step="0.01" placeholder="USD/day">
+                        <input type="number" class="form-control quota-category" data-category="creative" data-field="monthly_cost" id="quotaCategoryCreativeMonthly" min="0" step="0.01" placeholder="USD/month" style="margin-top: 8px;">
+                    </div>
+                </div>
+                <div class="stat-subtitle" style="margin-top: 8px;">
+                    Each request is charged to its API key with the tokens reported by the provider and the model prices below (estimated from the text length when a server reports no usage). Days and months start at midnight in Brazil. Leave empty for no limit. API keys with their own budget override the per-key values.
+                </div>
+            </div>
+
+            <div class="form-group">
+                <label class="form-label">Model Prices</label>
+                <div style="display: grid; grid-template-columns: 1fr 3fr; gap: 16px;">
+                    <div class="form-group">
+                        <label class="form-label">Web Search (USD per search)</label>
+                        <input type="number" class="form-control" id="searchPrice" min="0" step="0.001" placeholder="0.01">
+                    </div>
+                    <div class="form-group">
+                        <label class="form-label">Price Overrides (USD per million tokens)</label>
+                        <textarea class="form-control" id="modelPrices" rows="4" spellcheck="false" placeholder='{"gpt-4o-mini": {"input": 0.15, "output": 0.6}}'></textarea>
+                    </div>
+                </div>
+                <div class="stat-subtitle" style="margin-top: 8px;">
+                    Used to compute the cost of each request and budget usage. Models without an override use the built-in list price; local models are free unless priced here. Searches cover native web search calls and Perplexity queries.
+                </div>
+            </div>
+
+            <div class="form-group">
+                <label class="form-label">Fallback Models</label>
+                <textarea class="form-control" id="fallbackModels" rows="4" spellcheck="false" placeholder='{"default": ["gpt-4o-mini", "gemini-2.5-flash"], "complex": ["gpt-4o"]}'></textarea>
+                <div class="stat-subtitle" style="margin-top: 8px;">
+                    Models tried in order when the routed model is overloaded, rate limited or times out (at most 3 per category). Categories without their own chain use "default". Leave empty to fail instead.
+                </div>
+            </div>
+
+            <div class="form-group">
+                <label class="form-label">Hedged Requests</label>
+                <div style="display: grid; grid-template-columns: 2fr 1fr; gap: 16px;">
+                    <div class="form-group">
+                        <label class="form-label">Hedge Model</label>
+                        <input type="text" class="form-control" id="hedgeModel" spellcheck="false" placeholder="gemini-2.5-flash-lite">
+                    </div>
+                    <div class="form-group">
+                        <label class="form-label">Hedge Delay (ms)</label>
+                        <input type="number" class="form-control" id="hedgeDelayMs" min="0" max="15000" step="100" placeholder="p90">
+                    </div>
+                </div>
+                <div class="stat-subtitle" style="margin-top: 8px;">
+                    When the routed model has not answered within the delay, the question is also sent to the hedge model and the first answer is used. Leave the delay empty to use each model's observed p90 response time. Leave the model empty to disable hedging. Streaming answers are not hedged.
+                </div>
+            </div>
+
+            <div class="form-group">
+                <label class="form-label">Semantic Cache Thresholds</label>
+                <div style="display: grid; grid-template-columns: repeat(5, 1fr); gap: 16px;">
+                    <div class="form-group">
+                        <label class="form-label">Simple</label>
+                        <input type="number" class="form-control semantic-threshold" data-category="simple" id="semanticThresholdSimple" min="0" max="1" step="0.01" placeholder="0.90">
+                    </div>
+                    <div class="form-group">
+                        <label class="form-label">Factual</label>
+                        <input type="number" class="form-control semantic-threshold" data-category="factual" id="semanticThresholdFactual" min="0" max="1" step="0.01" placeholder="0.92">
+                    </div>
+                    <div class="form-group">
+                        <label class="form-label">Web Search</label>
+                        <input type="number" class="form-control semantic-threshold" data-category="web_search" id="semanticThresholdWebSearch" min="0" max="1" step="0.01" placeholder="0.92">
+                    </div>
+                    <div class="form-group">
+                        <label class="form-label">Mathematical</label>
+                        <input type="number" class="form-control semantic-threshold" data-category="mathematical" id="semanticThresholdMathematical" min="0" max="1" step="0.01" placeholder="off">
+                    </div>
+                    <div class="form-group">
+                        <label class="form-label">Complex</label>
+                        <input type="number" class="form-control semantic-threshold" data-category="complex" id="semanticThresholdComplex" min="0" max="1" step="0.01" placeholder="0.92">
+                    </div>
+                </div>
+                <div class="stat-subtitle" style="margin-top: 8px;">
+                    Minimum similarity (0-1) between a question and a cached one for a paraphrase to get the cached answer. Higher is stricter; 0 turns semantic matching off for the category. Leave empty for the default. Questions with different numbers never share answers; creative answers are never cached.
                 </div>
             </div>
 
@@ -793,6 +1022,126 @@ const dashboardHTML = `<!DOCTYPE html>
             <input type="hidden" id="systemPrompt" value="">
         </div>
 
+        <div class="settings-card">
+            <div class="settings-header">
+                <div class="settings-title">
+                    🕘 Configuration History
+                </div>
+                <button class="btn btn-secondary" onclick="loadHistory()">Refresh</button>
+            </div>
+            <div class="stat-subtitle" style="margin-bottom: 16px;">
+                Every applied configuration, newest first. View the changes an entry introduced or roll back to it (the rollback is recorded as a new entry).
+            </div>
+            <div id="historyContainer">
+                <div class="loading">
+                    <div class="spinner"></div>
+                </div>
+            </div>
+            <div id="historyDiff"></div>
+        </div>
+
+        <div class="settings-card">
+            <div class="settings-header">
+                <div class="settings-title">
+                    🔑 API Keys
+                </div>
+                <button class="btn btn-secondary" onclick="loadKeys()">Refresh</button>
+            </div>
+            <div class="stat-subtitle" style="margin-bottom: 16px;">
+                Give each device or integration its own key. Keys are identified by ID in logs; the secret is shown only once, when the key is created. The shared key (API_KEY_SECRET_NAME) keeps working and appears as "legacy".
+            </div>
+            <div style="display: grid; grid-template-columns: 2fr 1fr 1fr 1fr 1fr; gap: 16px; margin-bottom: 16px;">
+                <div class="form-group">
+                    <label class="form-label">Owner</label>
+                    <input type="text" class="form-control" id="keyOwner" maxlength="64" placeholder="Ana's iPhone">
+                </div>
+                <div class="form-group">
+                    <label class="form-label">Requests / Minute</label>
+                    <input type="number" class="form-control" id="keyPerMinute" min="0" placeholder="default">
+                </div>
+                <div class="form-group">
+                    <label class="form-label">Requests / Hour</label>
+                    <input type="number" class="form-control" id="keyPerHour" min="0" placeholder="default">
+                </div>
+                <div class="form-group">
+                    <label class="form-label">Daily Budget (USD)</label>
+                    <input type="number" class="form-control" id="keyDailyCost" min="0" step="0.01" placeholder="default">
+                </div>
+                <div class="form-group">
+                    <label class="form-label">Monthly Budget (USD)</label>
+                    <input type="number" class="form-control" id="keyMonthlyCost" min="0" step="0.01" placeholder="default">
+                </div>
+            </div>
+            <div class="form-group" style="display: flex; align-items: center; gap: 24px; margin-bottom: 16px;">
+                <label class="form-label" style="display: flex; align-items: center; gap: 8px; cursor: pointer; margin: 0;">
+                    <input type="checkbox" class="key-scope" value="chat" checked> chat
+                </label>
+                <label class="form-label" style="display: flex; align-items: center; gap: 8px; cursor: pointer; margin: 0;">
+                    <input type="checkbox" class="key-scope" value="config:read"> config:read
+                </label>
+                <label class="form-label" style="display: flex; align-items: center; gap: 8px; cursor: pointer; margin: 0;">
+                    <input type="checkbox" class="key-scope" value="config:write"> config:write
+                </label>
+                <button class="btn" onclick="createKey()">Create Key</button>
+            </div>
+            <div id="newKeySecret"></div>
+            <div id="keysContainer">
+                <div class="loading">
+                    <div class="spinner"></div>
+                </div>
+            </div>
+        </div>
+
+        <div class="settings-card">
+            <div class="settings-header">
+                <div class="settings-title">
+                    💰 Budgets
+                </div>
+                <button class="btn btn-secondary" onclick="loadQuotas()">Refresh</button>
+            </div>
+            <div class="stat-subtitle" style="margin-bottom: 16px;">
+                Consumption of each active API key today and this month, against its budget.
+            </div>
+            <div id="quotasContainer">
+                <div class="loading">
+                    <div class="spinner"></div>
+                </div>
+            </div>
+        </div>
+
+        <div class="settings-card">
+            <div class="settings-header">
+                <div class="settings-title">
+                    ⚡ Circuit Breakers
+                </div>
+                <button class="btn btn-secondary" onclick="loadBreakers()">Refresh</button>
+            </div>
+            <div class="stat-subtitle" style="margin-bottom: 16px;">
+                Upstreams that keep failing are skipped at once (open) and probed again after a cooldown (half open). Counts cover the last 2 minutes.
+            </div>
+            <div id="breakersContainer">
+                <div class="loading">
+                    <div class="spinner"></div>
+                </div>
+            </div>
+        </div>
+
+        <div class="settings-card">
+            <div class="settings-header">
+                <div class="settings-title">
+                    💵 Costs
+                </div>
+            </div>
+            <div class="stat-subtitle" style="margin-bottom: 16px;">
+                Tokens, searches and cost of the requests served by this instance since startup, by day, model and category.
+            </div>
+            <div id="costsContainer">
+                <div class="loading">
+                    <div class="spinner"></div>
+                </div>
+            </div>
+        </div>
+
         <div id="toast" class="toast"></div>
 
         <div class="section">
@@ -812,6 +1161,12 @@ const dashboardHTML = `<!DOCTYPE html>
                             <option value="claude-sonnet-4-20250514">Claude Sonnet 4</option>
                             <option value="claude-3-opus-20240229">Claude 3 Opus</option>
                         </optgroup>
+                        <optgroup label="Google Gemini">
+                            <option value="gemini-2.5-flash">Gemini 2.5 Flash</option>
+                            <option value="gemini-2.5-flash-lite">Gemini 2.5 Flash-Lite</option>
+                            <option value="gemini-2.5-pro">Gemini 2.5 Pro</option>
+                            <option value="gemini-2.0-flash">Gemini 2.0 Flash</option>
+                        </optgroup>
                         <optgroup label="OpenAI">
                             <option value="gpt-4o-mini">gpt-4o-mini</option>
                             <option value="gpt-4o">gpt-4o</option>
@@ -10,12 +10,18 @@ const limit = 50;
 let totalEntries = 0;
 let autoRefreshInterval = null;
 let expandedRows = new Set();
+let configVersion = ''; // Version of the loaded config, sent back so concurrent edits are detected
+let quotaCategories = {}; // Loaded category budgets (fields without a dashboard input are kept on save)
 
 // Initialize
 document.addEventListener('DOMContentLoaded', () => {
     loadStats();
     loadLogs();
     loadConfig();
+    loadHistory();
+    loadKeys();
+    loadQuotas();
+    loadBreakers();
     setupAutoRefresh();
 });
 
@@ -26,6 +32,8 @@ function setupAutoRefresh() {
         if (autoRefreshInterval) clearInterval(autoRefreshInterval);
         autoRefreshInterval = setInterval(() => {
             loadStats();
+            loadQuotas();
+            loadBreakers();
             if (currentOffset === 0) loadLogs(); // Only refresh if on first page
         }, 10000);
     };
@@ -57,16 +65,163 @@ async function loadStats() {
         document.getElementById('requestsToday').textContent = stats.total_requests_today.toLocaleString();
         document.getElementById('totalRequests').textContent = stats.total_requests.toLocaleString();
         document.getElementById('avgResponseTime').textContent = stats.avg_response_time_ms.toFixed(0);
+        document.getElementById('responseTimeTail').textContent = stats.p90_response_time_ms
+            ? `ms · p90 ${stats.p90_response_time_ms.toLocaleString()} · p99 ${stats.p99_response_time_ms.toLocaleString()}`
+            : 'milliseconds';
+        const stageAverages = formatStages(stats.stage_avg_ms);
+        document.getElementById('stageAverages').textContent = stageAverages ? 'avg ' + stageAverages : '';
         document.getElementById('errorRate').textContent = stats.error_rate.toFixed(2) + '%';
         // Use new field names (standard/premium) with fallback to legacy (nano/full)
         document.getElementById('modelStandard').textContent = (stats.model_usage.standard || stats.model_usage.nano || 0).toLocaleString();
         document.getElementById('modelPremium').textContent = (stats.model_usage.premium || stats.model_usage.full || 0).toLocaleString();
+        document.getElementById('fallbacks').textContent = (stats.fallbacks || 0).toLocaleString();
+        document.getElementById('fallbackDetail').textContent = stats.hedges
+            ? `Answered by a fallback model · ${stats.hedges.toLocaleString()} hedged, ${(stats.hedge_wins || 0).toLocaleString()} won by the hedge`
+            : 'Answered by a fallback model';
         document.getElementById('uptime').textContent = 'Up: ' + stats.uptime;
+        renderKeyGenerations(stats.key_generations || []);
+        renderCosts(stats.costs || {});
+        renderCache(stats.cache || {});
     } catch (error) {
         console.error('Failed to load stats:', error);
     }
 }
 
+// renderCache fills the cache stat card with the hit rate and the current cache size
+async function renderCache(cache) {
+    const lookups = (cache.hits || 0) + (cache.misses || 0);
+    document.getElementById('cacheHitRate').textContent = lookups ? cache.hit_rate.toFixed(1) + '%' : '-';
+    const semantic = cache.semantic_hits ? ` (${cache.semantic_hit_rate.toFixed(1)}% semantic)` : '';
+    try {
+        const response = await fetch('/admin/cache');
+        if (!response.ok) throw new Error('Response cache not configured');
+        const data = await response.json();
+        document.getElementById('cacheEntries').textContent =
+            `${data.entries.toLocaleString()} / ${data.max_entries.toLocaleString()} answers cached${semantic}`;
+    } catch (error) {
+        document.getElementById('cacheEntries').textContent = 'Cache disabled';
+    }
+}
+
+async function purgeCache() {
+    if (!confirm('Purge all cached answers? Repeated questions will call the model again.')) return;
+    try {
+        const response = await fetch('/admin/cache', {
+            method: 'POST',
+            headers: { 'X-CSRF-Token': csrfToken }
+        });
+        if (!response.ok) throw new Error(await response.text());
+
+        showToast('Response cache purged', 'success');
+        loadStats();
+    } catch (error) {
+        console.error('Error purging cache:', error);
+        showToast('Failed to purge cache', 'error');
+    }
+}
+
+// formatCost renders a USD amount with more precision for small values
+function formatCost(value) {
+    value = value || 0;
+    return '$' + value.toFixed(value < 1 ? 4 : 2);
+}
+
+// stageOrder lists the request budget stages in the order they run
+const stageOrder = ['transcription', 'embedding', 'search', 'generation', 'synthesis'];
+
+// formatStages formats the time spent in each stage of a request (e.g. "search 1.2s · generation 3.4s")
+function formatStages(stages) {
+    if (!stages) return '';
+    return stageOrder
+        .filter(stage => stage in stages)
+        .map(stage => `${stage} ${(stages[stage] / 1000).toFixed(1)}s`)
+        .join(' · ');
+}
+
+// localDay returns today's date as used for the daily cost totals (YYYY-MM-DD, local time)
+function localDay() {
+    const now = new Date();
+    const pad = n => String(n).padStart(2, '0');
+    return `${now.getFullYear()}-${pad(now.getMonth() + 1)}-${pad(now.getDate())}`;
+}
+
+// renderCosts fills the cost stat card and the cost breakdown tables
+function renderCosts(costs) {
+    const byDay = costs.by_day || {};
+    document.getElementById('costToday').textContent = formatCost((byDay[localDay()] || {}).cost_usd);
+    document.getElementById('costTotal').textContent = `${formatCost(costs.cost_usd)} since startup`;
+
+    const container = document.getElementById('costsContainer');
+    if (!costs.requests) {
+        container.innerHTML = '<div class="empty-state"><div class="empty-state-icon">📭</div><div>No usage yet</div></div>';
+        return;
+    }
+
+    const table = (title, totals, sort) => {
+        const rows = Object.entries(totals || {}).sort(sort);
+        let html = `
+            <table class="logs-table" style="margin-bottom: 16px;">
+                <thead>
+                    <tr>
+                        <th>${title}</th>
+                        <th>Requests</th>
+                        <th>Input Tokens</th>
+                        <th>Output Tokens</th>
+                        <th>Reasoning Tokens</th>
+                        <th>Searches</th>
+                        <th>Perplexity</th>
+                        <th>Cost</th>
+                    </tr>
+                </thead>
+                <tbody>
+        `;
+        rows.forEach(([name, t]) => {
+            html += `
+                <tr>
+                    <td>${escapeHtml(title === 'Category' ? formatCategory(name) : name)}</td>
+                    <td>${t.requests.toLocaleString()}</td>
+                    <td>${t.input_tokens.toLocaleString()}</td>
+                    <td>${t.output_tokens.toLocaleString()}</td>
+                    <td>${t.reasoning_tokens.toLocaleString()}</td>
+                    <td>${t.search_calls.toLocaleString()}</td>
+                    <td>${t.perplexity_calls.toLocaleString()}</td>
+                    <td>${formatCost(t.cost_usd)}</td>
+                </tr>
+            `;
+        });
+        return html + '</tbody></table>';
+    };
+    const byCost = (a, b) => b[1].cost_usd - a[1].cost_usd;
+
+    container.innerHTML =
+        table('Day', byDay, (a, b) => b[0].localeCompare(a[0])) +
+        table('Model', costs.by_model, byCost) +
+        table('Category', costs.by_category, byCost);
+}
+
+// Shows how many clients still authenticate with a shared key generation other than the current one
+// When it reaches zero (and stays there), the old generation can be retired
+function renderKeyGenerations(generations) {
+    const value = document.getElementById('oldKeyClients');
+    const subtitle = document.getElementById('keyGenerations');
+    if (generations.length === 0) {
+        value.textContent = '-';
+        subtitle.textContent = 'Shared API key generations';
+        return;
+    }
+
+    const current = generations.find(g => g.current);
+    const old = generations.filter(g => !g.current && g.requests > 0);
+    value.textContent = old.reduce((sum, g) => sum + g.clients, 0).toLocaleString();
+
+    const parts = [current ? `${current.generation} current` : 'no active key'];
+    old.forEach(g => {
+        const expiry = g.not_after ? `, expires ${formatTime(g.not_after)}` : '';
+        parts.push(`${g.generation}: ${g.clients} client(s), last ${formatTime(g.last_used)}${expiry}`);
+    });
+    subtitle.textContent = parts.join(' · ');
+}
+
 async function loadLogs() {
     const container = document.getElementById('logsContainer');
     container.innerHTML = '<div class="loading"><div class="spinner"></div></div>';
@@ -133,12 +288,14 @@ function renderLogs(entries) {
         html += `
             <tr class="${isExpanded ? 'expanded' : ''}" data-id="${safeId}">
                 <td>${formatTime(entry.timestamp)}</td>
-                <td class="request-id">${safeId}</td>
+                <td class="request-id">${safeId}${entry.key_id ? `<br><small style="color: var(--text-secondary)">${escapeHtml(entry.key_id)}${entry.key_generation ? ` (${escapeHtml(entry.key_generation)})` : ''}${entry.auth_method === 'signature' ? ' · signed' : ''}</small>` : ''}</td>
                 <td>
                     ${entry.model ? `
                         <span class="badge badge-model ${entry.model.includes('mini') || entry.model.includes('nano') ? 'badge-nano' : 'badge-full'}" title="${escapeHtml(entry.model)}">
                             ${escapeHtml(entry.model)}
                         </span>
+                        ${entry.fallback_from ? `<br><small style="color: var(--text-secondary)" title="${entry.fallbacks} fallback(s)">↪ from ${escapeHtml(entry.fallback_from)}</small>` : ''}
+                        ${entry.hedged ? `<br><small style="color: var(--text-secondary)" title="Also sent to the hedge model">⇉ hedged${entry.hedge_winner ? ` (${escapeHtml(entry.hedge_winner)} won)` : ''}</small>` : ''}
                     ` : '<span style="color: var(--text-secondary); font-size: 11px;">N/A</span>'}
                 </td>
                 <td>
@@ -148,7 +305,11 @@ function renderLogs(entries) {
                         </span>
                     ` : '<span style="color: var(--text-secondary); font-size: 11px;">-</span>'}
                 </td>
-                <td>${entry.response_time_ms}ms</td>
+                <td>
+                    ${entry.response_time_ms}ms
+                    ${entry.stages_ms ? `<br><small style="color: var(--text-secondary)" title="Time spent in each stage">${escapeHtml(formatStages(entry.stages_ms))}</small>` : ''}
+                    ${entry.cost_usd || entry.input_tokens ? `<br><small style="color: var(--text-secondary)" title="Input / output tokens${entry.usage_estimated ? ' (estimated)' : ''}">${formatCost(entry.cost_usd)} · ${(entry.input_tokens || 0).toLocaleString()}/${(entry.output_tokens || 0).toLocaleString()} tok${entry.usage_estimated ? '*' : ''}</small>` : ''}
+                </td>
                 <td>
                     <span class="badge ${entry.status === 'success' ? 'badge-success' : 'badge-error'}">
                         ${entry.status}
@@ -282,6 +443,7 @@ async function loadConfig() {
         if (!response.ok) throw new Error('Failed to load config');
         
         const config = await response.json();
+        configVersion = config.version || '';
         
         // Load base system prompt (prefer base_system_prompt, fallback to system_prompt for legacy)
         const basePrompt = config.base_system_prompt || config.system_prompt || '';
@@ -322,16 +484,89 @@ async function loadConfig() {
             // Default to true if not set
             document.getElementById('perplexityEnabled').checked = true;
         }
+        document.getElementById('ttsVoice').value = config.tts_voice || '';
+        document.getElementById('ttsSpeed').value = config.tts_speed ? config.tts_speed : '';
+        document.getElementById('rateLimitPerMinute').value = config.rate_limit_per_minute || '';
+        document.getElementById('rateLimitPerHour').value = config.rate_limit_per_hour || '';
+        document.getElementById('rateLimitBurst').value = config.rate_limit_burst || '';
+        document.getElementById('preAuthRateLimitPerMinute').value = config.pre_auth_rate_limit_per_minute || '';
+        document.getElementById('preAuthRateLimitPerHour').value = config.pre_auth_rate_limit_per_hour || '';
+
+        const quotaDefault = config.quota_default || {};
+        document.getElementById('quotaDailyCost').value = quotaDefault.daily_cost || '';
+        document.getElementById('quotaMonthlyCost').value = quotaDefault.monthly_cost || '';
+        document.getElementById('quotaDailyTokens').value = quotaDefault.daily_tokens || '';
+        document.getElementById('quotaMonthlyTokens').value = quotaDefault.monthly_tokens || '';
+        document.getElementById('quotaAction').value = config.quota_action || 'reject';
+        document.getElementById('searchPrice').value = config.search_price || '';
+        const modelPrices = config.model_prices || {};
+        document.getElementById('modelPrices').value = Object.keys(modelPrices).length ? JSON.stringify(modelPrices, null, 2) : '';
+        const fallbackModels = config.fallback_models || {};
+        document.getElementById('fallbackModels').value = Object.keys(fallbackModels).length ? JSON.stringify(fallbackModels, null, 2) : '';
+        document.getElementById('hedgeModel').value = config.hedge_model || '';
+        document.getElementById('hedgeDelayMs').value = config.hedge_delay_ms || '';
+        const semanticThresholds = config.semantic_thresholds || {};
+        document.querySelectorAll('.semantic-threshold').forEach(input => {
+            const threshold = semanticThresholds[input.dataset.category];
+            input.value = threshold === undefined ? '' : threshold;
+        });
+        quotaCategories = config.quota_categories || {};
+        document.querySelectorAll('.quota-category').forEach(input => {
+            const budget = quotaCategories[input.dataset.category] || {};
+            input.value = budget[input.dataset.field] || '';
+        });
     } catch (error) {
         console.error('Error loading config:', error);
         // Don't show error toast on load to avoid annoyance if backend isn't ready
     }
 }
 
+// readQuotaCategories merges the category budget inputs into the loaded category budgets
+// readSemanticThresholds returns the thresholds that were filled in (empty fields use the default)
+function readSemanticThresholds() {
+    const thresholds = {};
+    document.querySelectorAll('.semantic-threshold').forEach(input => {
+        if (input.value.trim() !== '') thresholds[input.dataset.category] = parseFloat(input.value) || 0;
+    });
+    return thresholds;
+}
+
+function readQuotaCategories() {
+    const categories = JSON.parse(JSON.stringify(quotaCategories));
+    document.querySelectorAll('.quota-category').forEach(input => {
+        const category = input.dataset.category;
+        categories[category] = categories[category] || {};
+        categories[category][input.dataset.field] = parseFloat(input.value) || 0;
+    });
+    return categories;
+}
+
 async function saveConfig() {
     const btn = document.getElementById('saveConfigBtn');
     const btnText = btn.querySelector('.btn-text');
     const spinner = btn.querySelector('.spinner');
+
+    let modelPrices = {};
+    const modelPricesText = document.getElementById('modelPrices').value.trim();
+    if (modelPricesText) {
+        try {
+            modelPrices = JSON.parse(modelPricesText);
+        } catch (error) {
+            showToast('Model prices must be valid JSON', 'error');
+            return;
+        }
+    }
+
+    let fallbackModels = {};
+    const fallbackModelsText = document.getElementById('fallbackModels').value.trim();
+    if (fallbackModelsText) {
+        try {
+            fallbackModels = JSON.parse(fallbackModelsText);
+        } catch (error) {
+            showToast('Fallback models must be valid JSON', 'error');
+            return;
+        }
+    }
     
     // Lock UI
     btn.disabled = true;
@@ -363,6 +598,28 @@ async function saveConfig() {
         standard_model: document.getElementById('standardModel').value,
         premium_model: document.getElementById('premiumModel').value,
         perplexity_enabled: document.getElementById('perplexityEnabled').checked,
+        tts_voice: document.getElementById('ttsVoice').value.trim(),
+        tts_speed: parseFloat(document.getElementById('ttsSpeed').value) || 0,
+        rate_limit_per_minute: parseInt(document.getElementById('rateLimitPerMinute').value, 10) || 0,
+        rate_limit_per_hour: parseInt(document.getElementById('rateLimitPerHour').value, 10) || 0,
+        rate_limit_burst: parseInt(document.getElementById('rateLimitBurst').value, 10) || 0,
+        pre_auth_rate_limit_per_minute: parseInt(document.getElementById('preAuthRateLimitPerMinute').value, 10) || 0,
+        pre_auth_rate_limit_per_hour: parseInt(document.getElementById('preAuthRateLimitPerHour').value, 10) || 0,
+        quota_default: {
+            daily_cost: parseFloat(document.getElementById('quotaDailyCost').value) || 0,
+            monthly_cost: parseFloat(document.getElementById('quotaMonthlyCost').value) || 0,
+            daily_tokens: parseInt(document.getElementById('quotaDailyTokens').value, 10) || 0,
+            monthly_tokens: parseInt(document.getElementById('quotaMonthlyTokens').value, 10) || 0
+        },
+        quota_categories: readQuotaCategories(),
+        quota_action: document.getElementById('quotaAction').value,
+        model_prices: modelPrices,
+        search_price: parseFloat(document.getElementById('searchPrice').value) || 0,
+        semantic_thresholds: readSemanticThresholds(),
+        fallback_models: fallbackModels,
+        hedge_model: document.getElementById('hedgeModel').value.trim(),
+        hedge_delay_ms: parseInt(document.getElementById('hedgeDelayMs').value, 10) || 0,
+        version: configVersion,
         // Legacy support
         system_prompt: basePrompt
     };
@@ -377,9 +634,16 @@ async function saveConfig() {
             body: JSON.stringify(config)
         });
         
+        if (response.status === 409) {
+            showToast('Configuration was changed by someone else. Reload the page and reapply your edits.', 'error');
+            return;
+        }
         if (!response.ok) throw new Error('Failed to save config');
         
+        const saved = await response.json();
+        configVersion = saved.version || '';
         showToast('Configuration saved successfully', 'success');
+        loadHistory();
     } catch (error) {
         console.error('Error saving config:', error);
         showToast('Failed to save configuration', 'error');
@@ -391,6 +655,379 @@ async function saveConfig() {
     }
 }
 
+async function loadHistory() {
+    const container = document.getElementById('historyContainer');
+    try {
+        const response = await fetch('/admin/config/history');
+        if (!response.ok) throw new Error('Failed to load history');
+        const data = await response.json();
+        renderHistory(data.entries || []);
+    } catch (error) {
+        console.error('Error loading history:', error);
+        container.innerHTML = '<div class="empty-state"><div class="empty-state-icon">❌</div><div>Failed to load history</div></div>';
+    }
+}
+
+function renderHistory(entries) {
+    const container = document.getElementById('historyContainer');
+    if (entries.length === 0) {
+        container.innerHTML = '<div class="empty-state"><div class="empty-state-icon">📭</div><div>No configuration changes yet</div></div>';
+        return;
+    }
+
+    let html = `
+        <table class="logs-table">
+            <thead>
+                <tr>
+                    <th>#</th>
+                    <th>Time</th>
+                    <th>Who</th>
+                    <th>Source</th>
+                    <th>Models</th>
+                    <th>Actions</th>
+                </tr>
+            </thead>
+            <tbody>
+    `;
+    entries.forEach((entry, index) => {
+        const source = entry.rollback_of ? `rollback of #${entry.rollback_of}` : entry.source;
+        html += `
+            <tr>
+                <td>${entry.id}</td>
+                <td>${formatTime(entry.timestamp)}</td>
+                <td>${escapeHtml(entry.actor) || '-'}</td>
+                <td>${escapeHtml(source)}</td>
+                <td>${escapeHtml(entry.standard_model)} / ${escapeHtml(entry.premium_model)}</td>
+                <td>
+                    <button class="btn btn-secondary" onclick="showHistoryDiff(${entry.id})">Changes</button>
+                    ${index === 0 ? '' : `<button class="btn btn-secondary" onclick="rollbackConfig(${entry.id})">Roll back</button>`}
+                </td>
+            </tr>
+        `;
+    });
+    html += '</tbody></table>';
+    container.innerHTML = html;
+}
+
+async function showHistoryDiff(id) {
+    const target = document.getElementById('historyDiff');
+    try {
+        const response = await fetch('/admin/config/diff?to=' + encodeURIComponent(id));
+        if (response.status === 404) {
+            target.innerHTML = `<div class="detail-content">Entry #${id} is the first recorded configuration.</div>`;
+            return;
+        }
+        if (!response.ok) throw new Error('Failed to load diff');
+        const diff = await response.json();
+
+        let html = `<div class="detail-content"><div class="detail-label">Changes from #${diff.from} to #${diff.to}</div>`;
+        if (diff.changes.length === 0) {
+            html += '<div class="stat-subtitle">No changes</div>';
+        }
+        diff.changes.forEach(change => {
+            html += `<div class="detail-section"><div class="detail-label">${escapeHtml(change.field)}</div>`;
+            if (change.lines) {
+                html += '<div class="detail-text history-diff">';
+                change.lines.forEach(line => {
+                    const cls = line.startsWith('+ ') ? 'diff-add' : (line.startsWith('- ') ? 'diff-remove' : '');
+                    html += `<div class="${cls}">${escapeHtml(line) || '&nbsp;'}</div>`;
+                });
+                html += '</div>';
+            } else {
+                html += `<div class="detail-text history-diff"><span class="diff-remove">${escapeHtml(change.from) || '(default)'}</span> → <span class="diff-add">${escapeHtml(change.to) || '(default)'}</span></div>`;
+            }
+            html += '</div>';
+        });
+        html += '</div>';
+        target.innerHTML = html;
+    } catch (error) {
+        console.error('Error loading diff:', error);
+        showToast('Failed to load changes', 'error');
+    }
+}
+
+async function rollbackConfig(id) {
+    if (!confirm(`Roll back the configuration to entry #${id}?`)) return;
+    try {
+        const response = await fetch('/admin/config/rollback', {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+                'X-CSRF-Token': csrfToken
+            },
+            body: JSON.stringify({ id: id })
+        });
+        if (!response.ok) throw new Error(await response.text());
+
+        showToast(`Rolled back to #${id}`, 'success');
+        document.getElementById('historyDiff').innerHTML = '';
+        loadConfig();
+        loadHistory();
+    } catch (error) {
+        console.error('Error rolling back config:', error);
+        showToast('Failed to roll back configuration', 'error');
+    }
+}
+
+async function loadKeys() {
+    const container = document.getElementById('keysContainer');
+    try {
+        const response = await fetch('/admin/keys');
+        if (!response.ok) throw new Error('Failed to load keys');
+        const data = await response.json();
+        renderKeys(data.keys || []);
+    } catch (error) {
+        console.error('Error loading keys:', error);
+        container.innerHTML = '<div class="empty-state"><div class="empty-state-icon">❌</div><div>Failed to load API keys</div></div>';
+    }
+}
+
+function renderKeys(keys) {
+    const container = document.getElementById('keysContainer');
+    if (keys.length === 0) {
+        container.innerHTML = '<div class="empty-state"><div class="empty-state-icon">📭</div><div>No API keys yet</div></div>';
+        return;
+    }
+
+    let html = `
+        <table class="logs-table">
+            <thead>
+                <tr>
+                    <th>ID</th>
+                    <th>Owner</th>
+                    <th>Scopes</th>
+                    <th>Limits</th>
+                    <th>Created</th>
+                    <th>Status</th>
+                    <th>Actions</th>
+                </tr>
+            </thead>
+            <tbody>
+    `;
+    keys.forEach(key => {
+        const budget = key.budget || {};
+        let limits = `${key.requests_per_minute || 'default'}/min, ${key.requests_per_hour || 'default'}/h`;
+        if (budget.daily_cost || budget.monthly_cost) {
+            limits += `, $${budget.daily_cost || 'default'}/day, $${budget.monthly_cost || 'default'}/month`;
+        }
+        html += `
+            <tr>
+                <td class="request-id">${escapeHtml(key.id)}</td>
+                <td>${escapeHtml(key.owner)}</td>
+                <td>${escapeHtml((key.scopes || []).join(', '))}</td>
+                <td>${escapeHtml(limits)}</td>
+                <td>${formatTime(key.created_at)}</td>
+                <td>
+                    <span class="badge ${key.enabled ? 'badge-success' : 'badge-error'}">
+                        ${key.enabled ? 'active' : 'revoked'}
+                    </span>
+                </td>
+                <td>
+                    ${key.enabled ? `<button class="btn btn-secondary" onclick="revokeKey('${escapeHtml(key.id)}')">Revoke</button>` : ''}
+                </td>
+            </tr>
+        `;
+    });
+    html += '</tbody></table>';
+    container.innerHTML = html;
+}
+
+async function createKey() {
+    const scopes = Array.from(document.querySelectorAll('.key-scope:checked')).map(el => el.value);
+    const spec = {
+        owner: document.getElementById('keyOwner').value.trim(),
+        scopes: scopes,
+        requests_per_minute: parseInt(document.getElementById('keyPerMinute').value, 10) || 0,
+        requests_per_hour: parseInt(document.getElementById('keyPerHour').value, 10) || 0,
+        budget: {
+            daily_cost: parseFloat(document.getElementById('keyDailyCost').value) || 0,
+            monthly_cost: parseFloat(document.getElementById('keyMonthlyCost').value) || 0
+        }
+    };
+
+    try {
+        const response = await fetch('/admin/keys', {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+                'X-CSRF-Token': csrfToken
+            },
+            body: JSON.stringify(spec)
+        });
+        if (!response.ok) throw new Error(await response.text());
+        const data = await response.json();
+
+        document.getElementById('newKeySecret').innerHTML = `
+            <div class="detail-content" style="margin-bottom: 16px;">
+                <div class="detail-label">New key ${escapeHtml(data.key.id)} — copy it now, it will not be shown again</div>
+                <div class="detail-text history-diff">${escapeHtml(data.secret)}</div>
+            </div>
+        `;
+        document.getElementById('keyOwner').value = '';
+        showToast('API key created', 'success');
+        loadKeys();
+    } catch (error) {
+        console.error('Error creating key:', error);
+        showToast('Failed to create API key: ' + error.message, 'error');
+    }
+}
+
+async function revokeKey(id) {
+    if (!confirm(`Revoke API key ${id}? Clients using it will be rejected.`)) return;
+    try {
+        const response = await fetch('/admin/keys/revoke', {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+                'X-CSRF-Token': csrfToken
+            },
+            body: JSON.stringify({ id: id })
+        });
+        if (!response.ok) throw new Error(await response.text());
+
+        showToast(`Revoked ${id}`, 'success');
+        loadKeys();
+    } catch (error) {
+        console.error('Error revoking key:', error);
+        showToast('Failed to revoke API key', 'error');
+    }
+}
+
+async function loadQuotas() {
+    const container = document.getElementById('quotasContainer');
+    try {
+        const response = await fetch('/admin/quotas');
+        if (!response.ok) throw new Error('Failed to load quotas');
+        const data = await response.json();
+        renderQuotas(data.keys || []);
+    } catch (error) {
+        console.error('Error loading quotas:', error);
+        container.innerHTML = '<div class="empty-state"><div class="empty-state-icon">❌</div><div>Failed to load budgets</div></div>';
+    }
+}
+
+// formatQuota renders usage against a limit, e.g. "$0.0123 / $1.00 (1%)"
+function formatQuota(used, limit, money) {
+    const format = value => money ? '$' + value.toFixed(value < 1 ? 4 : 2) : value.toLocaleString();
+    if (!limit) return format(used || 0);
+    const percent = Math.round(((used || 0) / limit) * 100);
+    return `${format(used || 0)} / ${format(limit)} (${percent}%)`;
+}
+
+function renderQuotas(keys) {
+    const container = document.getElementById('quotasContainer');
+    if (keys.length === 0) {
+        container.innerHTML = '<div class="empty-state"><div class="empty-state-icon">📭</div><div>No active API keys</div></div>';
+        return;
+    }
+
+    let html = `
+        <table class="logs-table">
+            <thead>
+                <tr>
+                    <th>Key</th>
+                    <th>Owner</th>
+                    <th>Today</th>
+                    <th>This Month</th>
+                    <th>Tokens Today</th>
+                    <th>Tokens This Month</th>
+                    <th>Categories</th>
+                </tr>
+            </thead>
+            <tbody>
+    `;
+    keys.forEach(key => {
+        const budget = key.budget || {};
+        const categories = Object.entries(key.categories || {}).map(([category, report]) => {
+            const categoryBudget = report.budget || {};
+            return `${category}: ${formatQuota(report.day.cost, categoryBudget.daily_cost, true)} today, ${formatQuota(report.month.cost, categoryBudget.monthly_cost, true)} this month`;
+        });
+        html += `
+            <tr>
+                <td class="request-id">${escapeHtml(key.key_id)}</td>
+                <td>${escapeHtml(key.owner)}</td>
+                <td>${escapeHtml(formatQuota(key.day.cost, budget.daily_cost, true))}</td>
+                <td>${escapeHtml(formatQuota(key.month.cost, budget.monthly_cost, true))}</td>
+                <td>${escapeHtml(formatQuota(key.day.tokens, budget.daily_tokens, false))}</td>
+                <td>${escapeHtml(formatQuota(key.month.tokens, budget.monthly_tokens, false))}</td>
+                <td>${categories.map(escapeHtml).join('<br>') || '—'}</td>
+            </tr>
+        `;
+    });
+    html += '</tbody></table>';
+    container.innerHTML = html;
+}
+
+async function loadBreakers() {
+    const container = document.getElementById('breakersContainer');
+    try {
+        const response = await fetch('/admin/breakers');
+        if (!response.ok) throw new Error('Failed to load circuit breakers');
+        const data = await response.json();
+        renderBreakers(data.breakers || []);
+    } catch (error) {
+        console.error('Error loading circuit breakers:', error);
+        container.innerHTML = '<div class="empty-state"><div class="empty-state-icon">❌</div><div>Failed to load circuit breakers</div></div>';
+    }
+}
+
+function renderBreakers(breakers) {
+    const container = document.getElementById('breakersContainer');
+    if (breakers.length === 0) {
+        container.innerHTML = '<div class="empty-state"><div class="empty-state-icon">📭</div><div>No upstreams called yet</div></div>';
+        return;
+    }
+
+    const badges = { closed: 'badge-success', open: 'badge-error', half_open: 'badge-full' };
+    let html = `
+        <table class="logs-table">
+            <thead>
+                <tr>
+                    <th>Upstream</th>
+                    <th>State</th>
+                    <th>Failed Calls</th>
+                    <th>Retry In</th>
+                    <th></th>
+                </tr>
+            </thead>
+            <tbody>
+    `;
+    breakers.forEach(breaker => {
+        html += `
+            <tr>
+                <td class="request-id">${escapeHtml(breaker.name)}</td>
+                <td><span class="badge ${badges[breaker.state] || ''}">${escapeHtml(breaker.state.replace('_', ' '))}</span></td>
+                <td>${breaker.failures} / ${breaker.calls}${breaker.calls ? ` (${breaker.failure_rate.toFixed(0)}%)` : ''}</td>
+                <td>${breaker.retry_in_ms ? Math.ceil(breaker.retry_in_ms / 1000) + 's' : '—'}</td>
+                <td>${breaker.state !== 'closed' ? `<button class="btn btn-secondary" onclick="resetBreaker('${escapeHtml(breaker.name)}')">Close</button>` : ''}</td>
+            </tr>
+        `;
+    });
+    html += '</tbody></table>';
+    container.innerHTML = html;
+}
+
+async function resetBreaker(name) {
+    if (!confirm(`Close the ${name} circuit breaker? Requests will call it again right away.`)) return;
+    try {
+        const response = await fetch('/admin/breakers', {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+                'X-CSRF-Token': csrfToken
+            },
+            body: JSON.stringify({ name: name })
+        });
+        if (!response.ok) throw new Error(await response.text());
+
+        showToast(`Closed ${name} circuit breaker`, 'success');
+        loadBreakers();
+    } catch (error) {
+        console.error('Error closing circuit breaker:', error);
+        showToast('Failed to close circuit breaker', 'error');
+    }
+}
+
 function showToast(message, type) {
     const toast = document.getElementById('toast');
     toast.textContent = message;
@@ -0,0 +1,394 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clotilde/carplay-assistant/internal/configstore"
+	"github.com/clotilde/carplay-assistant/internal/quota"
+)
+
+// maxHistoryEntries bounds the history; the oldest entries are dropped beyond it
+const maxHistoryEntries = 200
+
+// historySaveAttempts is how many times an append is retried when another instance appended first
+const historySaveAttempts = 3
+
+// Sources of configuration changes recorded in the history
+const (
+	SourceAdminDashboard = "admin_dashboard"
+	SourceAPI            = "api"
+	SourceRollback       = "rollback"
+	SourceInternal       = "internal"
+)
+
+// ErrHistoryNotFound is returned when a history entry does not exist
+var ErrHistoryNotFound = errors.New("config history entry not found")
+
+// ConfigOrigin identifies who applied a configuration and through which endpoint
+type ConfigOrigin struct {
+	Actor  string // Admin username or API key ID
+	Source string // One of the Source* constants
+}
+
+// HistoryEntry is an applied configuration with its author
+// Entries are append-only: a rollback adds a new entry with the old snapshot
+type HistoryEntry struct {
+	ID         int           `json:"id"`
+	Timestamp  time.Time     `json:"timestamp"`
+	Actor      string        `json:"actor,omitempty"`
+	Source     string        `json:"source"`
+	RollbackOf int           `json:"rollback_of,omitempty"` // ID of the entry that was restored
+	Config     RuntimeConfig `json:"config"`                // Full snapshot after the change
+}
+
+var (
+	historyMutex   sync.Mutex
+	historyEntries []HistoryEntry    // In-memory history (used when no history store is set)
+	historyStore   configstore.Store // Shared history document (nil keeps history in memory)
+)
+
+// SetHistoryStore persists the config history so every instance sees the same entries
+func SetHistoryStore(store configstore.Store) {
+	historyMutex.Lock()
+	defer historyMutex.Unlock()
+	historyStore = store
+}
+
+// ConfigHistory returns every recorded configuration, oldest first
+func ConfigHistory(ctx context.Context) ([]HistoryEntry, error) {
+	historyMutex.Lock()
+	defer historyMutex.Unlock()
+
+	entries, _, err := readHistoryLocked(ctx)
+	return entries, err
+}
+
+// ConfigHistoryEntry returns the history entry with the given ID
+func ConfigHistoryEntry(ctx context.Context, id int) (HistoryEntry, error) {
+	entries, err := ConfigHistory(ctx)
+	if err != nil {
+		return HistoryEntry{}, err
+	}
+	for _, entry := range entries {
+		if entry.ID == id {
+			return entry, nil
+		}
+	}
+	return HistoryEntry{}, ErrHistoryNotFound
+}
+
+// RollbackConfig re-applies the snapshot of a history entry, recording the rollback as a new entry
+func RollbackConfig(ctx context.Context, id int, origin ConfigOrigin) error {
+	entry, err := ConfigHistoryEntry(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	snapshot := cloneConfig(entry.Config)
+	// The snapshot's store version is stale by definition; check against this instance's view instead
+	snapshot.Version = ""
+	// applyConfig keeps the current category models when the map is nil, so a snapshot without
+	// overrides must clear them explicitly
+	if snapshot.CategoryModels == nil {
+		snapshot.CategoryModels = map[string]string{}
+	}
+	return updateConfig(snapshot, origin, id)
+}
+
+// RecordInitialConfig records the configuration in effect at startup (defaults or the stored
+// config) when the history is empty, so the first change can be diffed against it and rolled back
+func RecordInitialConfig() {
+	configMutex.RLock()
+	config := cloneConfig(runtimeConfig)
+	configMutex.RUnlock()
+	appendHistory(config, ConfigOrigin{Source: SourceInternal}, 0, true)
+}
+
+// recordHistory appends an entry for an applied configuration
+// Failures are logged: the configuration is already in effect at this point
+func recordHistory(config RuntimeConfig, origin ConfigOrigin, rollbackOf int) {
+	appendHistory(config, origin, rollbackOf, false)
+}
+
+// appendHistory appends an entry to the history; with onlyIfEmpty nothing is appended once
+// another entry exists (e.g. another instance recorded its startup config first)
+func appendHistory(config RuntimeConfig, origin ConfigOrigin, rollbackOf int, onlyIfEmpty bool) {
+	historyMutex.Lock()
+	defer historyMutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	for attempt := 0; attempt < historySaveAttempts; attempt++ {
+		entries, version, err := readHistoryLocked(ctx)
+		if err != nil {
+			log.Printf("Failed to read config history: %v", err)
+			return
+		}
+		if onlyIfEmpty && len(entries) > 0 {
+			return
+		}
+
+		nextID := 1
+		if len(entries) > 0 {
+			nextID = entries[len(entries)-1].ID + 1
+		}
+		entries = append(entries, HistoryEntry{
+			ID:         nextID,
+			Timestamp:  time.Now(),
+			Actor:      origin.Actor,
+			Source:     origin.Source,
+			RollbackOf: rollbackOf,
+			Config:     config,
+		})
+		if len(entries) > maxHistoryEntries {
+			entries = entries[len(entries)-maxHistoryEntries:]
+		}
+
+		if historyStore == nil {
+			historyEntries = entries
+			return
+		}
+
+		data, err := json.Marshal(entries)
+		if err != nil {
+			log.Printf("Failed to encode config history: %v", err)
+			return
+		}
+		_, err = historyStore.Save(ctx, data, version)
+		if errors.Is(err, configstore.ErrConflict) {
+			// Another instance appended at the same time; re-read and try again
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to save config history: %v", err)
+		}
+		return
+	}
+	log.Printf("Failed to save config history: too many concurrent writers")
+}
+
+// readHistoryLocked returns the history and its store version (caller holds historyMutex)
+func readHistoryLocked(ctx context.Context) ([]HistoryEntry, string, error) {
+	if historyStore == nil {
+		entries := make([]HistoryEntry, len(historyEntries))
+		copy(entries, historyEntries)
+		return entries, "", nil
+	}
+
+	data, version, err := historyStore.Load(ctx)
+	if errors.Is(err, configstore.ErrNotFound) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	var entries []HistoryEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, "", fmt.Errorf("invalid config history: %w", err)
+	}
+	return entries, version, nil
+}
+
+// cloneConfig deep-copies a configuration so snapshots never share maps with the live config
+func cloneConfig(config RuntimeConfig) RuntimeConfig {
+	clone := config
+	if config.CategoryPrompts != nil {
+		clone.CategoryPrompts = make(map[string]string, len(config.CategoryPrompts))
+		for k, v := range config.CategoryPrompts {
+			clone.CategoryPrompts[k] = v
+		}
+	}
+	if config.CategoryModels != nil {
+		clone.CategoryModels = make(map[string]string, len(config.CategoryModels))
+		for k, v := range config.CategoryModels {
+			clone.CategoryModels[k] = v
+		}
+	}
+	if config.QuotaCategories != nil {
+		clone.QuotaCategories = make(map[string]quota.Budget, len(config.QuotaCategories))
+		for k, v := range config.QuotaCategories {
+			clone.QuotaCategories[k] = v
+		}
+	}
+	if config.ModelPrices != nil {
+		clone.ModelPrices = make(map[string]quota.Price, len(config.ModelPrices))
+		for k, v := range config.ModelPrices {
+			clone.ModelPrices[k] = v
+		}
+	}
+	if config.FallbackModels != nil {
+		clone.FallbackModels = make(map[string][]string, len(config.FallbackModels))
+		for k, v := range config.FallbackModels {
+			clone.FallbackModels[k] = append([]string(nil), v...)
+		}
+	}
+	if config.SemanticThresholds != nil {
+		clone.SemanticThresholds = make(map[string]float64, len(config.SemanticThresholds))
+		for k, v := range config.SemanticThresholds {
+			clone.SemanticThresholds[k] = v
+		}
+	}
+	return clone
+}
+
+// ConfigDiff is a changed field between two configurations
+type ConfigDiff struct {
+	Field string   `json:"field"`
+	From  string   `json:"from"`
+	To    string   `json:"to"`
+	Lines []string `json:"lines,omitempty"` // Line diff for prompts ("+ added", "- removed", "  unchanged")
+}
+
+// DiffConfigs lists the fields that differ between two configurations
+// Category prompts and models are compared per category; an empty value means the default
+func DiffConfigs(from, to RuntimeConfig) []ConfigDiff {
+	var diffs []ConfigDiff
+	add := func(field, a, b string, lines bool) {
+		if a == b {
+			return
+		}
+		diff := ConfigDiff{Field: field, From: a, To: b}
+		if lines {
+			diff.Lines = diffLines(a, b)
+		}
+		diffs = append(diffs, diff)
+	}
+
+	add("base_system_prompt", from.BaseSystemPrompt, to.BaseSystemPrompt, true)
+	add("standard_model", from.StandardModel, to.StandardModel, false)
+	add("premium_model", from.PremiumModel, to.PremiumModel, false)
+	add("perplexity_enabled", strconv.FormatBool(from.PerplexityEnabled), strconv.FormatBool(to.PerplexityEnabled), false)
+	add("tts_voice", from.TTSVoice, to.TTSVoice, false)
+	add("tts_speed", strconv.FormatFloat(from.TTSSpeed, 'g', -1, 64), strconv.FormatFloat(to.TTSSpeed, 'g', -1, 64), false)
+	add("rate_limit_per_minute", strconv.Itoa(from.RateLimitPerMinute), strconv.Itoa(to.RateLimitPerMinute), false)
+	add("rate_limit_per_hour", strconv.Itoa(from.RateLimitPerHour), strconv.Itoa(to.RateLimitPerHour), false)
+	add("rate_limit_burst", strconv.Itoa(from.RateLimitBurst), strconv.Itoa(to.RateLimitBurst), false)
+	add("pre_auth_rate_limit_per_minute", strconv.Itoa(from.PreAuthRateLimitPerMinute), strconv.Itoa(to.PreAuthRateLimitPerMinute), false)
+	add("pre_auth_rate_limit_per_hour", strconv.Itoa(from.PreAuthRateLimitPerHour), strconv.Itoa(to.PreAuthRateLimitPerHour), false)
+
+	for _, category := range mapKeys(from.CategoryPrompts, to.CategoryPrompts) {
+		add("category_prompts."+category, from.CategoryPrompts[category], to.CategoryPrompts[category], true)
+	}
+	for _, category := range mapKeys(from.CategoryModels, to.CategoryModels) {
+		add("category_models."+category, from.CategoryModels[category], to.CategoryModels[category], false)
+	}
+	add("quota_default", formatBudget(from.QuotaDefault), formatBudget(to.QuotaDefault), false)
+	for _, category := range mapKeys(from.QuotaCategories, to.QuotaCategories) {
+		add("quota_categories."+category, formatBudget(from.QuotaCategories[category]), formatBudget(to.QuotaCategories[category]), false)
+	}
+	add("quota_action", from.QuotaAction, to.QuotaAction, false)
+	for _, model := range mapKeys(from.ModelPrices, to.ModelPrices) {
+		add("model_prices."+model, formatPrice(from.ModelPrices, model), formatPrice(to.ModelPrices, model), false)
+	}
+	add("search_price", strconv.FormatFloat(from.SearchPrice, 'g', -1, 64), strconv.FormatFloat(to.SearchPrice, 'g', -1, 64), false)
+	for _, category := range mapKeys(from.FallbackModels, to.FallbackModels) {
+		add("fallback_models."+category, strings.Join(from.FallbackModels[category], " → "), strings.Join(to.FallbackModels[category], " → "), false)
+	}
+	add("hedge_model", from.HedgeModel, to.HedgeModel, false)
+	add("hedge_delay_ms", strconv.Itoa(from.HedgeDelayMs), strconv.Itoa(to.HedgeDelayMs), false)
+	for _, category := range mapKeys(from.SemanticThresholds, to.SemanticThresholds) {
+		add("semantic_thresholds."+category, formatThreshold(from.SemanticThresholds, category), formatThreshold(to.SemanticThresholds, category), false)
+	}
+
+	return diffs
+}
+
+// formatBudget renders a budget for the diff (as its JSON, "{}" when unlimited)
+func formatBudget(b quota.Budget) string {
+	data, _ := json.Marshal(b)
+	return string(data)
+}
+
+// formatPrice renders the configured price of a model for the diff (empty for the list price)
+func formatPrice(prices map[string]quota.Price, model string) string {
+	price, ok := prices[model]
+	if !ok {
+		return ""
+	}
+	data, _ := json.Marshal(price)
+	return string(data)
+}
+
+// formatThreshold renders a semantic cache threshold for the diff ("" when the default is used)
+func formatThreshold(thresholds map[string]float64, category string) string {
+	threshold, ok := thresholds[category]
+	if !ok {
+		return ""
+	}
+	return strconv.FormatFloat(threshold, 'g', -1, 64)
+}
+
+// mapKeys returns the sorted union of the keys of both maps
+func mapKeys[V any](a, b map[string]V) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range []map[string]V{a, b} {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// diffLines computes a line diff using the longest common subsequence
+// Prompts are at most 10KB, so the quadratic table stays small
+func diffLines(a, b string) []string {
+	from := strings.Split(a, "\n")
+	to := strings.Split(b, "\n")
+
+	// lcs[i][j] is the LCS length of from[i:] and to[j:]
+	lcs := make([][]int, len(from)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(to)+1)
+	}
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if from[i] == to[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []string
+	i, j := 0, 0
+	for i < len(from) && j < len(to) {
+		switch {
+		case from[i] == to[j]:
+			lines = append(lines, "  "+from[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, "- "+from[i])
+			i++
+		default:
+			lines = append(lines, "+ "+to[j])
+			j++
+		}
+	}
+	for ; i < len(from); i++ {
+		lines = append(lines, "- "+from[i])
+	}
+	for ; j < len(to); j++ {
+		lines = append(lines, "+ "+to[j])
+	}
+	return lines
+}
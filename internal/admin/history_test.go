@@ -0,0 +1,194 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/clotilde/carplay-assistant/internal/configstore"
+	"github.com/clotilde/carplay-assistant/internal/quota"
+)
+
+// resetHistory clears the config and the in-memory history
+func resetHistory(t *testing.T) {
+	t.Helper()
+	configMutex.Lock()
+	runtimeConfig = RuntimeConfig{
+		BaseSystemPrompt: "Default: %s",
+		StandardModel:    "gpt-4o-mini",
+		PremiumModel:     "gpt-4o",
+	}
+	initialized = true
+	configMutex.Unlock()
+
+	historyMutex.Lock()
+	historyEntries = nil
+	historyMutex.Unlock()
+}
+
+func TestUpdateConfig_RecordsHistory(t *testing.T) {
+	resetHistory(t)
+
+	if err := UpdateConfig(RuntimeConfig{BaseSystemPrompt: "One: %s", StandardModel: "gpt-4o", PremiumModel: "gpt-4o"},
+		ConfigOrigin{Actor: "alice", Source: SourceAdminDashboard}); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	// Invalid configs are not recorded
+	UpdateConfig(RuntimeConfig{BaseSystemPrompt: "no placeholder", StandardModel: "gpt-4o", PremiumModel: "gpt-4o"}, ConfigOrigin{Source: SourceAPI})
+	if err := UpdateConfig(RuntimeConfig{BaseSystemPrompt: "Two: %s", StandardModel: "gpt-4o-mini", PremiumModel: "gpt-4o"},
+		ConfigOrigin{Actor: "key_1234abcd", Source: SourceAPI}); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+
+	entries, err := ConfigHistory(context.Background())
+	if err != nil {
+		t.Fatalf("ConfigHistory failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].ID != 1 || entries[0].Actor != "alice" || entries[0].Source != SourceAdminDashboard || entries[0].Config.BaseSystemPrompt != "One: %s" {
+		t.Errorf("Unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].ID != 2 || entries[1].Source != SourceAPI || entries[1].Config.StandardModel != "gpt-4o-mini" {
+		t.Errorf("Unexpected second entry: %+v", entries[1])
+	}
+}
+
+func TestRollbackConfig(t *testing.T) {
+	resetHistory(t)
+
+	UpdateConfig(RuntimeConfig{
+		BaseSystemPrompt: "Good: %s",
+		StandardModel:    "gpt-4o",
+		PremiumModel:     "gpt-4o",
+		CategoryPrompts:  map[string]string{"creative": "Be playful"},
+	}, ConfigOrigin{Actor: "alice", Source: SourceAdminDashboard})
+	UpdateConfig(RuntimeConfig{
+		BaseSystemPrompt: "Bad: %s",
+		StandardModel:    "gpt-4o-mini",
+		PremiumModel:     "gpt-4o",
+		CategoryModels:   map[string]string{"creative": "gpt-4o"},
+	}, ConfigOrigin{Actor: "bob", Source: SourceAdminDashboard})
+
+	if err := RollbackConfig(context.Background(), 1, ConfigOrigin{Actor: "alice", Source: SourceRollback}); err != nil {
+		t.Fatalf("RollbackConfig failed: %v", err)
+	}
+
+	config := GetConfig()
+	if config.BaseSystemPrompt != "Good: %s" || config.StandardModel != "gpt-4o" || config.CategoryPrompts["creative"] != "Be playful" {
+		t.Errorf("Expected first config to be restored, got %+v", config)
+	}
+	if len(config.CategoryModels) != 0 {
+		t.Errorf("Expected category model overrides to be cleared, got %v", config.CategoryModels)
+	}
+
+	entries, _ := ConfigHistory(context.Background())
+	if len(entries) != 3 || entries[2].RollbackOf != 1 || entries[2].Source != SourceRollback {
+		t.Errorf("Expected rollback to be appended as entry 3, got %+v", entries)
+	}
+
+	if err := RollbackConfig(context.Background(), 99, ConfigOrigin{}); !errors.Is(err, ErrHistoryNotFound) {
+		t.Errorf("Expected ErrHistoryNotFound, got %v", err)
+	}
+}
+
+func TestRollbackConfig_FirstChange(t *testing.T) {
+	resetHistory(t)
+
+	// The config in effect at startup is recorded once
+	RecordInitialConfig()
+	RecordInitialConfig()
+	UpdateConfig(RuntimeConfig{BaseSystemPrompt: "Edited: %s", StandardModel: "gpt-4o", PremiumModel: "gpt-4o"},
+		ConfigOrigin{Actor: "alice", Source: SourceAdminDashboard})
+
+	entries, _ := ConfigHistory(context.Background())
+	if len(entries) != 2 || entries[0].Source != SourceInternal || entries[0].Config.BaseSystemPrompt != "Default: %s" {
+		t.Fatalf("Expected the startup config as the first entry, got %+v", entries)
+	}
+	if diffs := DiffConfigs(entries[0].Config, entries[1].Config); len(diffs) != 2 {
+		t.Errorf("Expected the first change diffable against the startup config, got %+v", diffs)
+	}
+
+	if err := RollbackConfig(context.Background(), entries[0].ID, ConfigOrigin{Actor: "alice", Source: SourceRollback}); err != nil {
+		t.Fatalf("RollbackConfig failed: %v", err)
+	}
+	if config := GetConfig(); config.BaseSystemPrompt != "Default: %s" || config.StandardModel != "gpt-4o-mini" {
+		t.Errorf("Expected the startup config to be restored, got %+v", config)
+	}
+}
+
+func TestHistory_SharedStore(t *testing.T) {
+	resetHistory(t)
+	store := configstore.NewFile(filepath.Join(t.TempDir(), "history.json"))
+	SetHistoryStore(store)
+	t.Cleanup(func() { SetHistoryStore(nil) })
+
+	UpdateConfig(RuntimeConfig{BaseSystemPrompt: "One: %s", StandardModel: "gpt-4o", PremiumModel: "gpt-4o"}, ConfigOrigin{Source: SourceAPI})
+
+	// Another instance appends to the same document
+	SetHistoryStore(configstore.NewFile(store.Path))
+	UpdateConfig(RuntimeConfig{BaseSystemPrompt: "Two: %s", StandardModel: "gpt-4o", PremiumModel: "gpt-4o"}, ConfigOrigin{Source: SourceAPI})
+
+	entries, err := ConfigHistory(context.Background())
+	if err != nil {
+		t.Fatalf("ConfigHistory failed: %v", err)
+	}
+	if len(entries) != 2 || entries[1].ID != 2 {
+		t.Errorf("Expected both instances' entries, got %+v", entries)
+	}
+}
+
+func TestDiffConfigs(t *testing.T) {
+	from := RuntimeConfig{
+		BaseSystemPrompt: "Line 1\nLine 2: %s",
+		StandardModel:    "gpt-4o-mini",
+		PremiumModel:     "gpt-4o",
+		CategoryPrompts:  map[string]string{"creative": "Old"},
+	}
+	to := RuntimeConfig{
+		BaseSystemPrompt: "Line 1\nLine 2 changed: %s",
+		StandardModel:    "gpt-4o-mini",
+		PremiumModel:     "gpt-4.1",
+		CategoryModels:   map[string]string{"web_search": "gemini-2.5-flash"},
+		TTSSpeed:         1.25,
+		QuotaCategories:  map[string]quota.Budget{"complex": {DailyCost: 0.5}},
+		ModelPrices:      map[string]quota.Price{"gpt-4.1": {Input: 2, Output: 8}},
+
+		FallbackModels:     map[string][]string{"default": {"gpt-4o-mini", "gpt-4.1-nano"}},
+		HedgeModel:         "gpt-4.1-nano",
+		SemanticThresholds: map[string]float64{"web_search": 0},
+	}
+
+	diffs := DiffConfigs(from, to)
+	fields := make([]string, len(diffs))
+	for i, d := range diffs {
+		fields[i] = d.Field
+	}
+	want := []string{"base_system_prompt", "premium_model", "tts_speed", "category_prompts.creative", "category_models.web_search", "quota_categories.complex", "model_prices.gpt-4.1", "fallback_models.default", "hedge_model", "semantic_thresholds.web_search"}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("Changed fields = %v, want %v", fields, want)
+	}
+
+	wantLines := []string{"  Line 1", "- Line 2: %s", "+ Line 2 changed: %s"}
+	if !reflect.DeepEqual(diffs[0].Lines, wantLines) {
+		t.Errorf("Prompt diff = %q, want %q", diffs[0].Lines, wantLines)
+	}
+	if diffs[1].Lines != nil {
+		t.Error("Expected no line diff for model fields")
+	}
+
+	if len(DiffConfigs(from, from)) != 0 {
+		t.Error("Expected no changes between identical configs")
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	got := diffLines("a\nb\nc", "a\nc\nd")
+	want := []string{"  a", "- b", "  c", "+ d"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffLines() = %q, want %q", got, want)
+	}
+}
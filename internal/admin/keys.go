@@ -0,0 +1,130 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/clotilde/carplay-assistant/internal/auth"
+)
+
+// SetKeyRegistry enables API key management from the dashboard
+func (h *Handler) SetKeyRegistry(registry *auth.Registry) {
+	h.keys = registry
+}
+
+// SetSharedKeys reports usage of the shared API key generations in the dashboard stats
+func (h *Handler) SetSharedKeys(keys *auth.KeySet) {
+	h.sharedKeys = keys
+}
+
+// HandleKeys lists API keys (GET) or creates one (POST)
+// The secret of a new key is only returned in the POST response
+func (h *Handler) HandleKeys(w http.ResponseWriter, r *http.Request) {
+	if h.keys == nil {
+		http.Error(w, "API key registry not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys":   h.keys.List(),
+			"scopes": auth.AllScopes,
+		})
+	case http.MethodPost:
+		h.handleCreateKey(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) handleCreateKey(w http.ResponseWriter, r *http.Request) {
+	ip := getClientIP(r)
+
+	// Validate CSRF token
+	csrfToken := r.Header.Get("X-CSRF-Token")
+	if !h.validateCSRFToken(csrfToken, r) {
+		h.logAdminAction("key_create_failed", ip, "Invalid CSRF token")
+		http.Error(w, "Invalid or missing CSRF token", http.StatusForbidden)
+		return
+	}
+
+	var spec auth.KeySpec
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&spec); err != nil {
+		h.logAdminAction("key_create_failed", ip, "Invalid JSON")
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	key, secret, err := h.keys.Create(r.Context(), spec)
+	if err != nil {
+		h.logAdminAction("key_create_failed", ip, err.Error())
+		if errors.Is(err, auth.ErrInvalidKeySpec) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Error creating API key: %v", err)
+		http.Error(w, "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+
+	h.logAdminAction("key_created", ip, key.ID+" owner="+key.Owner)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"key":    key,
+		"secret": secret,
+	})
+}
+
+// HandleRevokeKey disables an API key ({"id": "key_..."})
+func (h *Handler) HandleRevokeKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.keys == nil {
+		http.Error(w, "API key registry not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ip := getClientIP(r)
+
+	// Validate CSRF token
+	csrfToken := r.Header.Get("X-CSRF-Token")
+	if !h.validateCSRFToken(csrfToken, r) {
+		h.logAdminAction("key_revoke_failed", ip, "Invalid CSRF token")
+		http.Error(w, "Invalid or missing CSRF token", http.StatusForbidden)
+		return
+	}
+
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1024)).Decode(&req); err != nil || req.ID == "" {
+		h.logAdminAction("key_revoke_failed", ip, "Invalid request")
+		http.Error(w, "Invalid request (expected {\"id\": \"key_...\"})", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.keys.Revoke(r.Context(), req.ID); err != nil {
+		h.logAdminAction("key_revoke_failed", ip, err.Error())
+		if errors.Is(err, auth.ErrKeyNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("Error revoking API key: %v", err)
+		http.Error(w, "Failed to revoke API key", http.StatusInternalServerError)
+		return
+	}
+
+	h.logAdminAction("key_revoked", ip, req.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "revoked", "id": req.ID})
+}
@@ -0,0 +1,118 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clotilde/carplay-assistant/internal/auth"
+	"github.com/clotilde/carplay-assistant/internal/logging"
+)
+
+func TestHandleKeys_CreateListRevoke(t *testing.T) {
+	h := NewHandler(nil)
+	registry := auth.NewRegistry(nil)
+	h.SetKeyRegistry(registry)
+
+	newRequest := func(method, target, body string) *http.Request {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.RemoteAddr = "203.0.113.7:1234"
+		req.Header.Set("X-CSRF-Token", h.generateCSRFToken(req))
+		return req
+	}
+
+	// Create
+	rr := httptest.NewRecorder()
+	h.HandleKeys(rr, newRequest("POST", "/admin/keys", `{"owner":"car","scopes":["chat"]}`))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created struct {
+		Key    auth.Key `json:"key"`
+		Secret string   `json:"secret"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Invalid response: %v", err)
+	}
+	if created.Secret == "" || created.Key.ID == "" {
+		t.Fatalf("Expected key and secret, got %s", rr.Body.String())
+	}
+
+	// List never exposes the secret
+	rr = httptest.NewRecorder()
+	h.HandleKeys(rr, httptest.NewRequest("GET", "/admin/keys", nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), created.Key.ID) {
+		t.Fatalf("Expected key in list, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if strings.Contains(rr.Body.String(), created.Secret) || strings.Contains(rr.Body.String(), `"hash"`) {
+		t.Error("List must not expose the secret or its hash")
+	}
+
+	// Invalid spec
+	rr = httptest.NewRecorder()
+	h.HandleKeys(rr, newRequest("POST", "/admin/keys", `{"owner":"car","scopes":["root"]}`))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown scope, got %d", rr.Code)
+	}
+
+	// Revoke
+	rr = httptest.NewRecorder()
+	h.HandleRevokeKey(rr, newRequest("POST", "/admin/keys/revoke", `{"id":"`+created.Key.ID+`"}`))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if _, ok := registry.Lookup(created.Secret); ok {
+		t.Error("Revoked key must not authenticate")
+	}
+
+	rr = httptest.NewRecorder()
+	h.HandleRevokeKey(rr, newRequest("POST", "/admin/keys/revoke", `{"id":"key_missing"}`))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown key, got %d", rr.Code)
+	}
+}
+
+func TestHandleKeys_RequiresCSRF(t *testing.T) {
+	h := NewHandler(nil)
+	h.SetKeyRegistry(auth.NewRegistry(nil))
+
+	rr := httptest.NewRecorder()
+	h.HandleKeys(rr, httptest.NewRequest("POST", "/admin/keys", strings.NewReader(`{"owner":"car","scopes":["chat"]}`)))
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 without CSRF token, got %d", rr.Code)
+	}
+}
+
+func TestHandleStats_KeyGenerations(t *testing.T) {
+	h := NewHandler(logging.GetLogger())
+	shared := auth.NewKeySet(
+		auth.SharedKey{Generation: "v1", Key: "old-key", NotBefore: time.Now().Add(-48 * time.Hour), NotAfter: time.Now().Add(time.Hour)},
+		auth.SharedKey{Generation: "v2", Key: "new-key", NotBefore: time.Now().Add(-time.Hour)},
+	)
+	shared.Record("v1", "203.0.113.1")
+	h.SetSharedKeys(shared)
+
+	rr := httptest.NewRecorder()
+	h.HandleStats(rr, httptest.NewRequest("GET", "/admin/stats", nil))
+
+	var stats struct {
+		TotalRequests  int64                  `json:"total_requests"`
+		KeyGenerations []auth.GenerationUsage `json:"key_generations"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Invalid response: %v", err)
+	}
+	if len(stats.KeyGenerations) != 2 {
+		t.Fatalf("Expected 2 key generations, got %s", rr.Body.String())
+	}
+	old := stats.KeyGenerations[0]
+	if old.Generation != "v1" || old.Current || old.Clients != 1 {
+		t.Errorf("Expected v1 with one client still using it, got %+v", old)
+	}
+	if strings.Contains(rr.Body.String(), "old-key") {
+		t.Error("Stats must not expose key values")
+	}
+}
@@ -0,0 +1,61 @@
+package admin
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/clotilde/carplay-assistant/internal/auth"
+	"github.com/clotilde/carplay-assistant/internal/quota"
+)
+
+// KeyQuota is the budget consumption of one API key
+type KeyQuota struct {
+	KeyID string `json:"key_id"`
+	Owner string `json:"owner"`
+	quota.Report
+}
+
+// SetQuotas enables the budget report in the dashboard
+func (h *Handler) SetQuotas(tracker *quota.Tracker) {
+	h.quotas = tracker
+}
+
+// HandleQuotas reports today's and this month's consumption of every active API key
+// against its budgets
+func (h *Handler) HandleQuotas(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.quotas == nil {
+		http.Error(w, "Quotas not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	config := GetConfig()
+	keys := []auth.Key{{ID: auth.LegacyKeyID, Owner: "shared key", Enabled: true}}
+	if h.keys != nil {
+		keys = append(keys, h.keys.List()...)
+	}
+
+	reports := make([]KeyQuota, 0, len(keys))
+	for _, key := range keys {
+		if !key.Enabled {
+			continue
+		}
+		report, err := h.quotas.Report(r.Context(), key.ID, key.Budget.Or(config.QuotaDefault), config.QuotaCategories)
+		if err != nil {
+			log.Printf("Error reading quota usage: %v", err)
+			http.Error(w, "Failed to read quota usage", http.StatusInternalServerError)
+			return
+		}
+		reports = append(reports, KeyQuota{KeyID: key.ID, Owner: key.Owner, Report: report})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys":   reports,
+		"action": config.QuotaAction,
+	})
+}
@@ -0,0 +1,62 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clotilde/carplay-assistant/internal/auth"
+	"github.com/clotilde/carplay-assistant/internal/quota"
+)
+
+func TestHandleQuotas(t *testing.T) {
+	ctx := context.Background()
+	h := NewHandler(nil)
+	registry := auth.NewRegistry(nil)
+	h.SetKeyRegistry(registry)
+	tracker := quota.NewTracker(nil, nil)
+	h.SetQuotas(tracker)
+
+	key, _, err := registry.Create(ctx, auth.KeySpec{Owner: "car", Scopes: []string{auth.ScopeChat}, Budget: quota.Budget{DailyCost: 2}})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	revoked, _, _ := registry.Create(ctx, auth.KeySpec{Owner: "old", Scopes: []string{auth.ScopeChat}})
+	registry.Revoke(ctx, revoked.ID)
+
+	tracker.Charge(ctx, key.ID, "complex", quota.Usage{Tokens: 100, Cost: 0.5})
+	tracker.Charge(ctx, auth.LegacyKeyID, "simple", quota.Usage{Tokens: 10, Cost: 0.01})
+
+	rr := httptest.NewRecorder()
+	h.HandleQuotas(rr, httptest.NewRequest("GET", "/admin/quotas", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var body struct {
+		Keys []KeyQuota `json:"keys"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Invalid response: %v", err)
+	}
+	if len(body.Keys) != 2 {
+		t.Fatalf("Expected the shared key and one active key, got %+v", body.Keys)
+	}
+	if body.Keys[0].KeyID != auth.LegacyKeyID || body.Keys[0].Day.Tokens != 10 {
+		t.Errorf("Unexpected shared key report: %+v", body.Keys[0])
+	}
+	if body.Keys[1].KeyID != key.ID || body.Keys[1].Day.Cost != 0.5 || body.Keys[1].Budget.DailyCost != 2 {
+		t.Errorf("Unexpected key report: %+v", body.Keys[1])
+	}
+}
+
+func TestHandleQuotas_NotConfigured(t *testing.T) {
+	h := NewHandler(nil)
+	rr := httptest.NewRecorder()
+	h.HandleQuotas(rr, httptest.NewRequest("GET", "/admin/quotas", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without a tracker, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,179 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clotilde/carplay-assistant/internal/configstore"
+)
+
+// storeTimeout bounds each read or write to the config store
+const storeTimeout = 10 * time.Second
+
+// ErrConfigConflict is returned by SetConfig when the configuration was changed by someone else
+// since the caller read it (the caller should reload and reapply its edits)
+var ErrConfigConflict = errors.New("configuration was changed by someone else, reload and try again")
+
+var (
+	configStore configstore.Store
+	// persistMutex serializes writers so readers of runtimeConfig are never blocked on the store
+	persistMutex sync.Mutex
+)
+
+// SetConfigStore enables persistence of the runtime configuration
+// Call it once at startup, after SetDefaultConfig and before LoadConfig
+func SetConfigStore(store configstore.Store) {
+	persistMutex.Lock()
+	defer persistMutex.Unlock()
+	configStore = store
+}
+
+// LoadConfig applies the stored configuration if it differs from the current one
+// Called at startup and periodically so every instance converges on the last saved config
+// A missing document keeps the defaults; an invalid one is logged and ignored
+func LoadConfig(ctx context.Context) error {
+	persistMutex.Lock()
+	defer persistMutex.Unlock()
+	return loadConfigLocked(ctx)
+}
+
+// StartConfigReload reloads the stored configuration every interval until ctx is done
+func StartConfigReload(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := LoadConfig(ctx); err != nil {
+					log.Printf("Config reload failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// loadConfigLocked reads the store and applies a newer version (caller holds persistMutex)
+func loadConfigLocked(ctx context.Context) error {
+	if configStore == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, storeTimeout)
+	defer cancel()
+
+	data, version, err := configStore.Load(ctx)
+	if errors.Is(err, configstore.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	configMutex.RLock()
+	current := runtimeConfig.Version
+	configMutex.RUnlock()
+	if version == current {
+		return nil
+	}
+
+	var stored RuntimeConfig
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("invalid stored config (version %s): %w", version, err)
+	}
+	if err := validateConfig(stored); err != nil {
+		return fmt.Errorf("invalid stored config (version %s): %w", version, err)
+	}
+
+	configMutex.Lock()
+	applyConfig(&runtimeConfig, stored)
+	runtimeConfig.Version = version
+	configMutex.Unlock()
+	notifyConfigApplied()
+
+	log.Printf("Runtime config loaded from %s store (version %s)", configStore.Name(), version)
+	return nil
+}
+
+// persistConfig saves a validated configuration to the store, applies it on success and returns the applied config
+func persistConfig(newConfig RuntimeConfig) (RuntimeConfig, error) {
+	persistMutex.Lock()
+	defer persistMutex.Unlock()
+
+	// Build the resulting config on a copy so the store holds exactly what will be applied
+	configMutex.RLock()
+	candidate := runtimeConfig
+	configMutex.RUnlock()
+
+	if newConfig.Version != "" && newConfig.Version != candidate.Version {
+		return RuntimeConfig{}, ErrConfigConflict
+	}
+	currentVersion := candidate.Version
+
+	applyConfig(&candidate, newConfig)
+	candidate.Version = ""
+	data, err := json.MarshalIndent(candidate, "", "  ")
+	if err != nil {
+		return RuntimeConfig{}, fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	version, err := configStore.Save(ctx, data, currentVersion)
+	if errors.Is(err, configstore.ErrConflict) {
+		// Another instance saved first: pick up its version so the next read shows it
+		if loadErr := loadConfigLocked(ctx); loadErr != nil {
+			log.Printf("Config reload after conflict failed: %v", loadErr)
+		}
+		return RuntimeConfig{}, ErrConfigConflict
+	}
+	if err != nil {
+		return RuntimeConfig{}, fmt.Errorf("failed to persist config: %w", err)
+	}
+
+	configMutex.Lock()
+	applyConfig(&runtimeConfig, newConfig)
+	runtimeConfig.Version = version
+	applied := cloneConfig(runtimeConfig)
+	configMutex.Unlock()
+
+	return applied, nil
+}
+
+// ConfigErrorStatus maps a SetConfig error to an HTTP status code
+func ConfigErrorStatus(err error) int {
+	var configErr *ConfigError
+	switch {
+	case errors.As(err, &configErr):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrConfigConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrHistoryNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// SetETag exposes the config version as an ETag header (no-op when persistence is disabled)
+func SetETag(w http.ResponseWriter, version string) {
+	if version != "" {
+		w.Header().Set("ETag", `"`+version+`"`)
+	}
+}
+
+// IfMatchVersion returns the version from an If-Match header (empty if absent)
+func IfMatchVersion(r *http.Request) string {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	value = strings.TrimPrefix(value, "W/")
+	return strings.Trim(value, `"`)
+}
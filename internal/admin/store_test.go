@@ -0,0 +1,166 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/clotilde/carplay-assistant/internal/configstore"
+)
+
+// resetWithStore resets the runtime config and persists it in a temp file for the duration of the test
+func resetWithStore(t *testing.T) *configstore.File {
+	t.Helper()
+	configMutex.Lock()
+	runtimeConfig = RuntimeConfig{
+		BaseSystemPrompt: "Default: %s",
+		StandardModel:    "gpt-4o-mini",
+		PremiumModel:     "gpt-4o",
+	}
+	initialized = true
+	configMutex.Unlock()
+
+	store := configstore.NewFile(filepath.Join(t.TempDir(), "config.json"))
+	SetConfigStore(store)
+	t.Cleanup(func() { SetConfigStore(nil) })
+	return store
+}
+
+func TestSetConfig_PersistsToStore(t *testing.T) {
+	store := resetWithStore(t)
+
+	if err := SetConfig(RuntimeConfig{BaseSystemPrompt: "Saved: %s", StandardModel: "gpt-4o", PremiumModel: "gpt-4o"}); err != nil {
+		t.Fatalf("SetConfig failed: %v", err)
+	}
+
+	_, storedVersion, err := store.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Expected config to be persisted: %v", err)
+	}
+	if config := GetConfig(); config.Version != storedVersion {
+		t.Errorf("Expected version %q, got %q", storedVersion, config.Version)
+	}
+
+	// A fresh instance (defaults) picks up the stored config
+	configMutex.Lock()
+	runtimeConfig = RuntimeConfig{BaseSystemPrompt: "Default: %s", StandardModel: "gpt-4o-mini", PremiumModel: "gpt-4o"}
+	configMutex.Unlock()
+
+	if err := LoadConfig(context.Background()); err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	config := GetConfig()
+	if config.BaseSystemPrompt != "Saved: %s" || config.StandardModel != "gpt-4o" || config.Version != storedVersion {
+		t.Errorf("Expected stored config to be loaded, got %+v", config)
+	}
+}
+
+func TestSetConfig_VersionConflict(t *testing.T) {
+	resetWithStore(t)
+
+	if err := SetConfig(RuntimeConfig{BaseSystemPrompt: "First: %s", StandardModel: "gpt-4o", PremiumModel: "gpt-4o"}); err != nil {
+		t.Fatalf("SetConfig failed: %v", err)
+	}
+	staleVersion := GetConfig().Version
+
+	// Second admin saves on top of the version both read
+	if err := SetConfig(RuntimeConfig{BaseSystemPrompt: "Second: %s", StandardModel: "gpt-4o", PremiumModel: "gpt-4o", Version: staleVersion}); err != nil {
+		t.Fatalf("SetConfig failed: %v", err)
+	}
+
+	// First admin saves with the now stale version
+	err := SetConfig(RuntimeConfig{BaseSystemPrompt: "Third: %s", StandardModel: "gpt-4o", PremiumModel: "gpt-4o", Version: staleVersion})
+	if !errors.Is(err, ErrConfigConflict) {
+		t.Fatalf("Expected ErrConfigConflict, got %v", err)
+	}
+	if ConfigErrorStatus(err) != http.StatusConflict {
+		t.Errorf("Expected 409 for conflict, got %d", ConfigErrorStatus(err))
+	}
+	if config := GetConfig(); config.BaseSystemPrompt != "Second: %s" {
+		t.Errorf("Expected second admin's config to be kept, got %q", config.BaseSystemPrompt)
+	}
+}
+
+func TestSetConfig_ConflictWithOtherInstance(t *testing.T) {
+	store := resetWithStore(t)
+
+	if err := SetConfig(RuntimeConfig{BaseSystemPrompt: "Mine: %s", StandardModel: "gpt-4o", PremiumModel: "gpt-4o"}); err != nil {
+		t.Fatalf("SetConfig failed: %v", err)
+	}
+
+	// Another instance writes directly to the store
+	_, version, _ := store.Load(context.Background())
+	if _, err := store.Save(context.Background(), []byte(`{"base_system_prompt":"Theirs: %s","standard_model":"gpt-4o-mini","premium_model":"gpt-4o"}`), version); err != nil {
+		t.Fatalf("Store save failed: %v", err)
+	}
+
+	// Without an explicit version, this instance's view is used and the store rejects it
+	err := SetConfig(RuntimeConfig{BaseSystemPrompt: "Mine again: %s", StandardModel: "gpt-4o", PremiumModel: "gpt-4o"})
+	if !errors.Is(err, ErrConfigConflict) {
+		t.Fatalf("Expected ErrConfigConflict, got %v", err)
+	}
+
+	// The conflict reloads the other instance's config
+	if config := GetConfig(); config.BaseSystemPrompt != "Theirs: %s" {
+		t.Errorf("Expected reloaded config, got %q", config.BaseSystemPrompt)
+	}
+}
+
+func TestOnConfigApplied(t *testing.T) {
+	store := resetWithStore(t)
+	applied := 0
+	OnConfigApplied(func() { applied++ })
+	t.Cleanup(func() { OnConfigApplied(nil) })
+
+	if err := SetConfig(RuntimeConfig{BaseSystemPrompt: "no placeholder", StandardModel: "gpt-4o", PremiumModel: "gpt-4o"}); err == nil {
+		t.Fatal("Expected invalid config to be rejected")
+	}
+	if err := SetConfig(RuntimeConfig{BaseSystemPrompt: "Mine: %s", StandardModel: "gpt-4o", PremiumModel: "gpt-4o"}); err != nil {
+		t.Fatalf("SetConfig failed: %v", err)
+	}
+	if applied != 1 {
+		t.Fatalf("Expected one call for the applied config, got %d", applied)
+	}
+
+	// A config saved by another instance is picked up on the next load, an unchanged one is not
+	_, version, _ := store.Load(context.Background())
+	store.Save(context.Background(), []byte(`{"base_system_prompt":"Theirs: %s","standard_model":"gpt-4o-mini","premium_model":"gpt-4o"}`), version)
+	LoadConfig(context.Background())
+	LoadConfig(context.Background())
+	if applied != 2 {
+		t.Errorf("Expected a call for the reloaded config, got %d", applied)
+	}
+}
+
+func TestLoadConfig_IgnoresInvalidDocument(t *testing.T) {
+	store := resetWithStore(t)
+	store.Save(context.Background(), []byte(`{"base_system_prompt":"no placeholder","standard_model":"gpt-4o","premium_model":"gpt-4o"}`), "")
+
+	if err := LoadConfig(context.Background()); err == nil {
+		t.Error("Expected error for invalid stored config")
+	}
+	if config := GetConfig(); config.BaseSystemPrompt != "Default: %s" {
+		t.Errorf("Expected current config to be kept, got %q", config.BaseSystemPrompt)
+	}
+}
+
+func TestIfMatchVersion(t *testing.T) {
+	tests := map[string]string{
+		``:          "",
+		`"abc123"`:  "abc123",
+		`W/"17"`:    "17",
+		`plain-tag`: "plain-tag",
+	}
+	for header, want := range tests {
+		req := httptest.NewRequest("POST", "/api/config", nil)
+		if header != "" {
+			req.Header.Set("If-Match", header)
+		}
+		if got := IfMatchVersion(req); got != want {
+			t.Errorf("IfMatchVersion(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
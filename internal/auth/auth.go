@@ -2,31 +2,87 @@ package auth
 
 import (
 	"context"
-	"crypto/subtle"
 	"net/http"
 	"strings"
+
+	"github.com/clotilde/carplay-assistant/internal/quota"
 )
 
-// Context key for validated API key
+// Context key for the authenticated identity
 type contextKey string
 
-const validatedAPIKeyKey contextKey = "validatedAPIKey"
+const identityKey contextKey = "identity"
+
+// LegacyKeyID identifies requests made with the shared key (any generation) from API_KEY_SECRET_NAME
+// It stays the same across rotations so sessions and rate limits survive a key change
+const LegacyKeyID = "legacy"
+
+// DefaultGeneration labels the shared key when it is configured as a single value
+const DefaultGeneration = "default"
+
+// Identity is the authenticated caller of a request
+// It carries the key ID, never the key itself, so it is safe to log
+type Identity struct {
+	KeyID             string
+	Generation        string // Shared key generation used ("" for registry keys)
+	Method            string // MethodAPIKey or MethodSignature
+	Owner             string
+	Scopes            []string
+	RequestsPerMinute int          // 0 uses the default limit
+	RequestsPerHour   int          // 0 uses the default limit
+	Budget            quota.Budget // Unset fields use the default budget
+}
+
+// HasScope reports whether the identity was granted scope
+func (id Identity) HasScope(scope string) bool {
+	for _, s := range id.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// WithIdentity adds the authenticated identity to the context
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey, id)
+}
+
+// GetIdentity retrieves the authenticated identity from context
+func GetIdentity(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey).(Identity)
+	return id, ok
+}
 
-// WithValidatedAPIKey adds the validated API key to the context
-func WithValidatedAPIKey(ctx context.Context, apiKey string) context.Context {
-	return context.WithValue(ctx, validatedAPIKeyKey, apiKey)
+// GetKeyID returns the ID of the key that authenticated the request ("" if unauthenticated)
+func GetKeyID(ctx context.Context) string {
+	id, _ := GetIdentity(ctx)
+	return id.KeyID
 }
 
-// GetValidatedAPIKey retrieves the validated API key from context
-func GetValidatedAPIKey(ctx context.Context) string {
-	if key, ok := ctx.Value(validatedAPIKeyKey).(string); ok {
-		return key
+// RequiredScope returns the scope needed to call the endpoint of r
+func RequiredScope(r *http.Request) string {
+	if r.URL.Path == "/api/config" {
+		if r.Method == http.MethodGet || r.Method == http.MethodOptions {
+			return ScopeConfigRead
+		}
+		return ScopeConfigWrite
 	}
-	return ""
+	return ScopeChat
 }
 
 // Middleware validates the X-API-Key header against the expected API key
 func Middleware(expectedAPIKey string) func(http.Handler) http.Handler {
+	return MiddlewareWithKeys(NewKeySet(SharedKey{Generation: DefaultGeneration, Key: expectedAPIKey}), nil)
+}
+
+// MiddlewareWithKeys validates the X-API-Key header against the active generations of the shared
+// key and the keys of registry (nil for the shared key only), then checks that the key has the
+// endpoint's scope
+// Requests with an X-Clotilde-Signature header are instead verified as HMAC-signed with the shared
+// key, so devices can move to signing one at a time
+// The shared key keeps every scope so existing clients are unaffected
+func MiddlewareWithKeys(shared *KeySet, registry *Registry) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Skip auth for health check and admin routes (admin has its own Basic Auth)
@@ -35,22 +91,66 @@ func Middleware(expectedAPIKey string) func(http.Handler) http.Handler {
 				return
 			}
 
+			if r.Header.Get(SignatureHeader) != "" {
+				sharedKey, err := verifySignature(r, shared)
+				if err != nil {
+					http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+					return
+				}
+				shared.Record(sharedKey.Generation, clientAddress(r))
+				identity := Identity{
+					KeyID:      LegacyKeyID,
+					Generation: sharedKey.Generation,
+					Method:     MethodSignature,
+					Owner:      "shared key",
+					Scopes:     AllScopes,
+				}
+				next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+				return
+			}
+
 			apiKey := r.Header.Get("X-API-Key")
 			if apiKey == "" {
 				http.Error(w, `{"error":"API key required"}`, http.StatusUnauthorized)
 				return
 			}
 
-			// Use constant-time comparison to prevent timing attacks
-			if subtle.ConstantTimeCompare([]byte(apiKey), []byte(expectedAPIKey)) != 1 {
+			var identity Identity
+			// Shared keys are compared in constant time to prevent timing attacks
+			if sharedKey, ok := shared.Match(apiKey); ok {
+				identity = Identity{KeyID: LegacyKeyID, Generation: sharedKey.Generation, Method: MethodAPIKey, Owner: "shared key", Scopes: AllScopes}
+				shared.Record(sharedKey.Generation, clientAddress(r))
+			} else if key, ok := lookup(registry, apiKey); ok {
+				identity = Identity{
+					KeyID:             key.ID,
+					Method:            MethodAPIKey,
+					Owner:             key.Owner,
+					Scopes:            key.Scopes,
+					RequestsPerMinute: key.RequestsPerMinute,
+					RequestsPerHour:   key.RequestsPerHour,
+					Budget:            key.Budget,
+				}
+			} else {
 				http.Error(w, `{"error":"Invalid API key"}`, http.StatusUnauthorized)
 				return
 			}
 
-			// API key is validated - add to context for rate limiter
-			ctx := WithValidatedAPIKey(r.Context(), apiKey)
+			if !identity.HasScope(RequiredScope(r)) {
+				http.Error(w, `{"error":"API key not allowed for this endpoint"}`, http.StatusForbidden)
+				return
+			}
+
+			// API key is validated - add its identity to context for rate limiter and logging
+			ctx := WithIdentity(r.Context(), identity)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// lookup finds a registry key (false when there is no registry)
+func lookup(registry *Registry, apiKey string) (Key, bool) {
+	if registry == nil {
+		return Key{}, false
+	}
+	return registry.Lookup(apiKey)
+}
@@ -1,10 +1,12 @@
 package auth
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestMiddleware_ValidAPIKey(t *testing.T) {
@@ -147,3 +149,139 @@ func TestMiddleware_ConstantTimeComparison(t *testing.T) {
 	}
 }
 
+
+func TestMiddlewareWithKeys_RegistryKeyIdentityAndScopes(t *testing.T) {
+	registry := NewRegistry(nil)
+	_, chatSecret, err := registry.Create(context.Background(), KeySpec{Owner: "car", Scopes: []string{ScopeChat}, RequestsPerMinute: 3})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	_, readSecret, err := registry.Create(context.Background(), KeySpec{Owner: "script", Scopes: []string{ScopeConfigRead}})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	var got Identity
+	handler := MiddlewareWithKeys(NewKeySet(SharedKey{Generation: DefaultGeneration, Key: "shared-key"}), registry)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = GetIdentity(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	testCases := []struct {
+		name     string
+		method   string
+		path     string
+		apiKey   string
+		expected int
+		keyID    string
+	}{
+		{"shared key has every scope", "POST", "/api/config", "shared-key", http.StatusOK, LegacyKeyID},
+		{"chat key on chat", "POST", "/chat", chatSecret, http.StatusOK, ""},
+		{"chat key on config read", "GET", "/api/config", chatSecret, http.StatusForbidden, ""},
+		{"read key on config read", "GET", "/api/config", readSecret, http.StatusOK, ""},
+		{"read key on config write", "POST", "/api/config", readSecret, http.StatusForbidden, ""},
+		{"read key on chat", "POST", "/chat", readSecret, http.StatusForbidden, ""},
+		{"unknown key", "POST", "/chat", "clt_unknown", http.StatusUnauthorized, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got = Identity{}
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.Header.Set("X-API-Key", tc.apiKey)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expected {
+				t.Fatalf("Expected status %d, got %d", tc.expected, rr.Code)
+			}
+			if tc.keyID != "" && got.KeyID != tc.keyID {
+				t.Errorf("Expected key ID %q, got %q", tc.keyID, got.KeyID)
+			}
+			if tc.expected == http.StatusOK && strings.Contains(got.KeyID, tc.apiKey) {
+				t.Errorf("Key ID %q must not contain the secret", got.KeyID)
+			}
+		})
+	}
+
+	// Per-key limits travel with the identity
+	req := httptest.NewRequest("POST", "/chat", nil)
+	req.Header.Set("X-API-Key", chatSecret)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if got.RequestsPerMinute != 3 || got.Owner != "car" {
+		t.Errorf("Expected owner car with 3 requests/minute, got %+v", got)
+	}
+}
+
+func TestMiddlewareWithKeys_RegistryRevokedKey(t *testing.T) {
+	registry := NewRegistry(nil)
+	key, secret, err := registry.Create(context.Background(), KeySpec{Owner: "old phone", Scopes: []string{ScopeChat}})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := registry.Revoke(context.Background(), key.ID); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+
+	handler := MiddlewareWithKeys(nil, registry)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called")
+	}))
+	req := httptest.NewRequest("POST", "/chat", nil)
+	req.Header.Set("X-API-Key", secret)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for revoked key, got %d", rr.Code)
+	}
+}
+
+func TestMiddlewareWithKeys_RotationWindows(t *testing.T) {
+	now := time.Now()
+	shared := NewKeySet(
+		SharedKey{Generation: "v1", Key: "old-key", NotAfter: now.Add(time.Hour)},
+		SharedKey{Generation: "v2", Key: "new-key", NotBefore: now.Add(-time.Minute)},
+		SharedKey{Generation: "v0", Key: "expired-key", NotAfter: now.Add(-time.Hour)},
+		SharedKey{Generation: "v3", Key: "future-key", NotBefore: now.Add(time.Hour)},
+	)
+
+	var got Identity
+	handler := MiddlewareWithKeys(shared, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = GetIdentity(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	testCases := []struct {
+		apiKey     string
+		expected   int
+		generation string
+	}{
+		{"old-key", http.StatusOK, "v1"},
+		{"new-key", http.StatusOK, "v2"},
+		{"expired-key", http.StatusUnauthorized, ""},
+		{"future-key", http.StatusUnauthorized, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.apiKey, func(t *testing.T) {
+			got = Identity{}
+			req := httptest.NewRequest("POST", "/chat", nil)
+			req.Header.Set("X-API-Key", tc.apiKey)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expected {
+				t.Fatalf("Expected status %d, got %d", tc.expected, rr.Code)
+			}
+			if got.Generation != tc.generation {
+				t.Errorf("Expected generation %q, got %q", tc.generation, got.Generation)
+			}
+			if tc.expected == http.StatusOK && got.KeyID != LegacyKeyID {
+				t.Errorf("Every shared key generation should map to %q, got %q", LegacyKeyID, got.KeyID)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxTrackedClients bounds the distinct clients remembered per key generation
+const maxTrackedClients = 1000
+
+// SharedKey is one generation of the shared API key
+// Several generations can be valid at once so clients can move to a new key before the old one expires
+type SharedKey struct {
+	Generation string    `json:"generation"` // Label reported in logs and stats (e.g. a Secret Manager version)
+	Key        string    `json:"key"`
+	NotBefore  time.Time `json:"not_before,omitempty"` // Zero means valid immediately
+	NotAfter   time.Time `json:"not_after,omitempty"`  // Zero means no expiry
+}
+
+// activeAt reports whether the key is within its validity window at t
+func (k SharedKey) activeAt(t time.Time) bool {
+	if !k.NotBefore.IsZero() && t.Before(k.NotBefore) {
+		return false
+	}
+	if !k.NotAfter.IsZero() && !t.Before(k.NotAfter) {
+		return false
+	}
+	return true
+}
+
+// GenerationUsage reports how a shared key generation is being used on this instance
+type GenerationUsage struct {
+	Generation string     `json:"generation"`
+	NotBefore  *time.Time `json:"not_before,omitempty"`
+	NotAfter   *time.Time `json:"not_after,omitempty"`
+	Active     bool       `json:"active"`  // Within its validity window
+	Current    bool       `json:"current"` // Newest active generation (the one clients should use)
+	Requests   int64      `json:"requests"`
+	Clients    int        `json:"clients"` // Distinct client addresses seen
+	LastUsed   *time.Time `json:"last_used,omitempty"`
+}
+
+type generationStats struct {
+	requests int64
+	clients  map[string]bool // Hashed client addresses
+	lastUsed time.Time
+}
+
+// KeySet holds the generations of the shared API key and counts requests per generation
+type KeySet struct {
+	mu    sync.RWMutex
+	keys  []SharedKey
+	stats map[string]*generationStats
+	now   func() time.Time
+}
+
+// NewKeySet creates a key set (keys with an empty value are ignored)
+func NewKeySet(keys ...SharedKey) *KeySet {
+	ks := &KeySet{stats: make(map[string]*generationStats), now: time.Now}
+	ks.Set(keys)
+	return ks
+}
+
+// Set replaces the key generations; usage counters of remaining generations are kept
+func (ks *KeySet) Set(keys []SharedKey) {
+	valid := make([]SharedKey, 0, len(keys))
+	for _, key := range keys {
+		if key.Key != "" {
+			valid = append(valid, key)
+		}
+	}
+	// Oldest first, so the last active key is the current one
+	sort.SliceStable(valid, func(i, j int) bool {
+		return valid[i].NotBefore.Before(valid[j].NotBefore)
+	})
+
+	ks.mu.Lock()
+	ks.keys = valid
+	ks.mu.Unlock()
+}
+
+// StartReload refreshes the keys from load every interval until ctx is done
+// A failed load keeps the current keys
+func (ks *KeySet) StartReload(ctx context.Context, interval time.Duration, load func(context.Context) ([]SharedKey, error)) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				keys, err := load(ctx)
+				if err != nil {
+					log.Printf("API key reload failed: %v", err)
+					continue
+				}
+				ks.Set(keys)
+			}
+		}
+	}()
+}
+
+// Match returns the active generation matching apiKey
+// Every active key is compared in constant time, so timing does not reveal which generation matched
+func (ks *KeySet) Match(apiKey string) (SharedKey, bool) {
+	if ks == nil {
+		return SharedKey{}, false
+	}
+
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	now := ks.now()
+	var matched SharedKey
+	found := false
+	for _, key := range ks.keys {
+		if key.activeAt(now) && subtle.ConstantTimeCompare([]byte(apiKey), []byte(key.Key)) == 1 && !found {
+			matched = key
+			found = true
+		}
+	}
+	return matched, found
+}
+
+// Record counts a request authenticated with generation from client
+func (ks *KeySet) Record(generation, client string) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	stats, ok := ks.stats[generation]
+	if !ok {
+		stats = &generationStats{clients: make(map[string]bool)}
+		ks.stats[generation] = stats
+	}
+	stats.requests++
+	stats.lastUsed = ks.now()
+	if len(stats.clients) < maxTrackedClients {
+		sum := sha256.Sum256([]byte(client))
+		stats.clients[hex.EncodeToString(sum[:8])] = true
+	}
+}
+
+// Usage reports every configured generation, oldest first
+// Any traffic on a generation other than the current one comes from clients that still need the new key
+func (ks *KeySet) Usage() []GenerationUsage {
+	if ks == nil {
+		return nil
+	}
+
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	now := ks.now()
+	current := ""
+	for _, key := range ks.keys {
+		if key.activeAt(now) {
+			current = key.Generation
+		}
+	}
+
+	usage := make([]GenerationUsage, 0, len(ks.keys))
+	for _, key := range ks.keys {
+		u := GenerationUsage{
+			Generation: key.Generation,
+			NotBefore:  timePtr(key.NotBefore),
+			NotAfter:   timePtr(key.NotAfter),
+			Active:     key.activeAt(now),
+			Current:    key.Generation == current,
+		}
+		if stats, ok := ks.stats[key.Generation]; ok {
+			u.Requests = stats.requests
+			u.Clients = len(stats.clients)
+			u.LastUsed = timePtr(stats.lastUsed)
+		}
+		usage = append(usage, u)
+	}
+	return usage
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// LoadKeyFile reads shared key generations from a JSON file:
+// [{"generation": "2026-10", "key": "...", "not_before": "2026-10-01T00:00:00Z", "not_after": "2026-11-01T00:00:00Z"}]
+func LoadKeyFile(path string) ([]SharedKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API key file: %w", err)
+	}
+
+	var keys []SharedKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("invalid API key file: %w", err)
+	}
+	for i, key := range keys {
+		if key.Key == "" {
+			return nil, fmt.Errorf("invalid API key file: entry %d has no key", i)
+		}
+		if key.Generation == "" {
+			keys[i].Generation = fmt.Sprintf("gen-%d", i+1)
+		}
+		if !key.NotAfter.IsZero() && !key.NotBefore.IsZero() && !key.NotAfter.After(key.NotBefore) {
+			return nil, fmt.Errorf("invalid API key file: generation %s expires before it starts", keys[i].Generation)
+		}
+	}
+	return keys, nil
+}
+
+// clientAddress identifies the caller for usage stats (X-Real-IP on Cloud Run, else the remote address)
+func clientAddress(r *http.Request) string {
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		return realIP
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeySet_Usage(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	ks := NewKeySet(
+		SharedKey{Generation: "v2", Key: "new-key", NotBefore: now.Add(-time.Hour)},
+		SharedKey{Generation: "v1", Key: "old-key", NotBefore: now.Add(-30 * 24 * time.Hour), NotAfter: now.Add(24 * time.Hour)},
+		SharedKey{Generation: "empty", Key: ""},
+	)
+	ks.now = func() time.Time { return now }
+
+	ks.Record("v1", "203.0.113.1")
+	ks.Record("v1", "203.0.113.1")
+	ks.Record("v1", "203.0.113.2")
+	ks.Record("v2", "203.0.113.3")
+
+	usage := ks.Usage()
+	if len(usage) != 2 {
+		t.Fatalf("Expected 2 generations (empty keys ignored), got %d", len(usage))
+	}
+
+	old, current := usage[0], usage[1]
+	if old.Generation != "v1" || current.Generation != "v2" {
+		t.Fatalf("Expected generations ordered oldest first, got %s, %s", old.Generation, current.Generation)
+	}
+	if old.Current || !current.Current {
+		t.Errorf("Expected v2 to be current, got v1=%v v2=%v", old.Current, current.Current)
+	}
+	if old.Requests != 3 || old.Clients != 2 {
+		t.Errorf("Expected 3 requests from 2 clients on v1, got %d from %d", old.Requests, old.Clients)
+	}
+	if old.NotAfter == nil || current.NotAfter != nil {
+		t.Error("Expected only v1 to report an expiry")
+	}
+
+	// Once v1 expires it stops matching but its usage is still reported
+	ks.now = func() time.Time { return now.Add(48 * time.Hour) }
+	if _, ok := ks.Match("old-key"); ok {
+		t.Error("Expired generation must not match")
+	}
+	if generation, ok := ks.Match("new-key"); !ok || generation.Generation != "v2" {
+		t.Error("Current generation should match")
+	}
+	if usage := ks.Usage(); usage[0].Active || usage[0].Requests != 3 {
+		t.Errorf("Expected inactive v1 with its usage kept, got %+v", usage[0])
+	}
+}
+
+func TestLoadKeyFile(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "keys.json")
+	data := `[
+		{"generation": "2026-09", "key": "old", "not_after": "2026-10-15T00:00:00Z"},
+		{"key": "new", "not_before": "2026-10-01T00:00:00Z"}
+	]`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := LoadKeyFile(path)
+	if err != nil {
+		t.Fatalf("LoadKeyFile failed: %v", err)
+	}
+	if len(keys) != 2 || keys[0].Generation != "2026-09" || keys[1].Generation != "gen-2" {
+		t.Errorf("Unexpected keys: %+v", keys)
+	}
+	if keys[0].NotAfter.IsZero() || keys[1].NotBefore.IsZero() {
+		t.Error("Expected validity windows to be parsed")
+	}
+
+	invalid := map[string]string{
+		"missing key":  `[{"generation": "a"}]`,
+		"bad window":   `[{"key": "k", "not_before": "2026-10-02T00:00:00Z", "not_after": "2026-10-01T00:00:00Z"}]`,
+		"invalid json": `{`,
+	}
+	for name, content := range invalid {
+		path := filepath.Join(dir, name+".json")
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadKeyFile(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
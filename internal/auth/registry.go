@@ -0,0 +1,333 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/clotilde/carplay-assistant/internal/configstore"
+	"github.com/clotilde/carplay-assistant/internal/quota"
+)
+
+// Scopes granted to API keys
+const (
+	ScopeChat        = "chat"         // /chat, /chat/stream, /chat/audio
+	ScopeConfigRead  = "config:read"  // GET /api/config
+	ScopeConfigWrite = "config:write" // POST /api/config
+)
+
+// AllScopes lists every scope, in display order
+var AllScopes = []string{ScopeChat, ScopeConfigRead, ScopeConfigWrite}
+
+// keySecretPrefix makes registry keys recognizable (e.g. in secret scanners)
+const keySecretPrefix = "clt_"
+
+// maxOwnerLength bounds the owner label shown in the dashboard
+const maxOwnerLength = 64
+
+// registrySaveAttempts is how many times a change is retried when another instance saved first
+const registrySaveAttempts = 3
+
+var (
+	// ErrKeyNotFound is returned when a key ID does not exist
+	ErrKeyNotFound = errors.New("API key not found")
+	// ErrInvalidKeySpec is returned when a key request has an invalid owner, scope or limit
+	ErrInvalidKeySpec = errors.New("invalid API key specification")
+)
+
+// Key is a registered API key
+// Only a SHA-256 hash of the secret is kept; the secret is shown once, when the key is created
+type Key struct {
+	ID                string       `json:"id"`
+	Owner             string       `json:"owner"` // Who uses the key (e.g. "Ana's iPhone")
+	Scopes            []string     `json:"scopes"`
+	RequestsPerMinute int          `json:"requests_per_minute,omitempty"` // 0 uses the default limit
+	RequestsPerHour   int          `json:"requests_per_hour,omitempty"`   // 0 uses the default limit
+	Budget            quota.Budget `json:"budget"`                        // Cost/token budget (unset fields use the default budget)
+	Enabled           bool         `json:"enabled"`
+	CreatedAt         time.Time    `json:"created_at"`
+	RevokedAt         *time.Time   `json:"revoked_at,omitempty"`
+	Hash              string       `json:"hash,omitempty"` // SHA-256 of the secret (never returned by List)
+}
+
+// KeySpec describes a key to create
+type KeySpec struct {
+	Owner             string       `json:"owner"`
+	Scopes            []string     `json:"scopes"`
+	RequestsPerMinute int          `json:"requests_per_minute"`
+	RequestsPerHour   int          `json:"requests_per_hour"`
+	Budget            quota.Budget `json:"budget"`
+}
+
+// Registry holds the API keys, optionally persisted in a configstore document shared by all instances
+type Registry struct {
+	store   configstore.Store
+	mu      sync.RWMutex
+	keys    []Key          // In creation order
+	byHash  map[string]int // Secret hash -> index in keys
+	version string         // Store version of keys
+	writeMu sync.Mutex     // Serializes changes
+}
+
+// NewRegistry creates a key registry (store may be nil to keep keys in memory)
+func NewRegistry(store configstore.Store) *Registry {
+	return &Registry{store: store, byHash: make(map[string]int)}
+}
+
+// Load reads the keys from the store (a missing document means no keys yet)
+func (r *Registry) Load(ctx context.Context) error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+	return r.load(ctx)
+}
+
+// StartReload reloads the keys every interval until ctx is done, so revocations reach every instance
+func (r *Registry) StartReload(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.Load(ctx); err != nil {
+					log.Printf("API key registry reload failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Lookup returns the enabled key matching secret
+func (r *Registry) Lookup(secret string) (Key, bool) {
+	hash := hashSecret(secret)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// The map is keyed by hash, so lookup time does not depend on how much of the secret matches
+	index, ok := r.byHash[hash]
+	if !ok || !r.keys[index].Enabled {
+		return Key{}, false
+	}
+	return r.keys[index], true
+}
+
+// List returns every key (including revoked ones) without secret hashes
+func (r *Registry) List() []Key {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]Key, len(r.keys))
+	for i, key := range r.keys {
+		key.Hash = ""
+		key.Scopes = append([]string(nil), key.Scopes...)
+		keys[i] = key
+	}
+	return keys
+}
+
+// Create registers a new key and returns it with its secret (the only time the secret is available)
+func (r *Registry) Create(ctx context.Context, spec KeySpec) (Key, string, error) {
+	if err := validateKeySpec(spec); err != nil {
+		return Key{}, "", err
+	}
+
+	secret, err := randomString(32)
+	if err != nil {
+		return Key{}, "", err
+	}
+	secret = keySecretPrefix + secret
+	id, err := randomHex(6)
+	if err != nil {
+		return Key{}, "", err
+	}
+
+	key := Key{
+		ID:                "key_" + id,
+		Owner:             spec.Owner,
+		Scopes:            append([]string(nil), spec.Scopes...),
+		RequestsPerMinute: spec.RequestsPerMinute,
+		RequestsPerHour:   spec.RequestsPerHour,
+		Budget:            spec.Budget,
+		Enabled:           true,
+		CreatedAt:         time.Now().UTC(),
+		Hash:              hashSecret(secret),
+	}
+
+	err = r.update(ctx, func(keys []Key) ([]Key, error) {
+		return append(keys, key), nil
+	})
+	if err != nil {
+		return Key{}, "", err
+	}
+
+	key.Hash = ""
+	return key, secret, nil
+}
+
+// Revoke disables a key; it stays listed so its usage in logs can still be attributed
+func (r *Registry) Revoke(ctx context.Context, id string) error {
+	return r.update(ctx, func(keys []Key) ([]Key, error) {
+		for i := range keys {
+			if keys[i].ID == id {
+				if keys[i].Enabled {
+					now := time.Now().UTC()
+					keys[i].Enabled = false
+					keys[i].RevokedAt = &now
+				}
+				return keys, nil
+			}
+		}
+		return nil, ErrKeyNotFound
+	})
+}
+
+// update applies a change to a copy of the keys, persists it and swaps it in
+// On a store conflict the keys are reloaded and the change is applied again
+func (r *Registry) update(ctx context.Context, change func([]Key) ([]Key, error)) error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	for attempt := 0; attempt < registrySaveAttempts; attempt++ {
+		r.mu.RLock()
+		keys := make([]Key, len(r.keys))
+		copy(keys, r.keys)
+		version := r.version
+		r.mu.RUnlock()
+
+		keys, err := change(keys)
+		if err != nil {
+			return err
+		}
+
+		if r.store == nil {
+			r.set(keys, "")
+			return nil
+		}
+
+		data, err := json.MarshalIndent(keys, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode API keys: %w", err)
+		}
+		newVersion, err := r.store.Save(ctx, data, version)
+		if errors.Is(err, configstore.ErrConflict) {
+			if err := r.load(ctx); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save API keys: %w", err)
+		}
+		r.set(keys, newVersion)
+		return nil
+	}
+	return fmt.Errorf("failed to save API keys: too many concurrent writers")
+}
+
+// load replaces the keys with the stored document (caller holds writeMu)
+func (r *Registry) load(ctx context.Context) error {
+	if r.store == nil {
+		return nil
+	}
+
+	data, version, err := r.store.Load(ctx)
+	if errors.Is(err, configstore.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load API keys: %w", err)
+	}
+
+	r.mu.RLock()
+	unchanged := version == r.version
+	r.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	var keys []Key
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return fmt.Errorf("invalid API key document: %w", err)
+	}
+	r.set(keys, version)
+	return nil
+}
+
+// set swaps in a new key list and rebuilds the hash index
+func (r *Registry) set(keys []Key, version string) {
+	byHash := make(map[string]int, len(keys))
+	for i, key := range keys {
+		byHash[key.Hash] = i
+	}
+
+	r.mu.Lock()
+	r.keys = keys
+	r.byHash = byHash
+	r.version = version
+	r.mu.Unlock()
+}
+
+// validateKeySpec checks the owner label, scopes and limits of a new key
+func validateKeySpec(spec KeySpec) error {
+	if spec.Owner == "" || len(spec.Owner) > maxOwnerLength || !utf8.ValidString(spec.Owner) {
+		return fmt.Errorf("%w: owner must be 1-%d characters", ErrInvalidKeySpec, maxOwnerLength)
+	}
+	if len(spec.Scopes) == 0 {
+		return fmt.Errorf("%w: at least one scope is required", ErrInvalidKeySpec)
+	}
+	for _, scope := range spec.Scopes {
+		if !validScope(scope) {
+			return fmt.Errorf("%w: unknown scope %q", ErrInvalidKeySpec, scope)
+		}
+	}
+	if spec.RequestsPerMinute < 0 || spec.RequestsPerHour < 0 {
+		return fmt.Errorf("%w: rate limits cannot be negative", ErrInvalidKeySpec)
+	}
+	if err := spec.Budget.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidKeySpec, err)
+	}
+	return nil
+}
+
+func validScope(scope string) bool {
+	for _, s := range AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// hashSecret returns the hex SHA-256 of a key secret
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate key ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
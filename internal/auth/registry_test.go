@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/clotilde/carplay-assistant/internal/configstore"
+)
+
+func TestRegistry_CreateAndLookup(t *testing.T) {
+	registry := NewRegistry(nil)
+
+	key, secret, err := registry.Create(context.Background(), KeySpec{Owner: "Ana's iPhone", Scopes: []string{ScopeChat}})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if !strings.HasPrefix(key.ID, "key_") || !strings.HasPrefix(secret, keySecretPrefix) {
+		t.Errorf("Unexpected key ID %q or secret prefix", key.ID)
+	}
+	if key.Hash != "" {
+		t.Error("Create must not return the secret hash")
+	}
+
+	found, ok := registry.Lookup(secret)
+	if !ok || found.ID != key.ID {
+		t.Fatalf("Lookup failed: ok=%v id=%q", ok, found.ID)
+	}
+	if _, ok := registry.Lookup(secret + "x"); ok {
+		t.Error("Lookup must not match a different secret")
+	}
+
+	for _, listed := range registry.List() {
+		if listed.Hash != "" {
+			t.Error("List must not return secret hashes")
+		}
+	}
+}
+
+func TestRegistry_CreateValidation(t *testing.T) {
+	registry := NewRegistry(nil)
+
+	specs := []KeySpec{
+		{Owner: "", Scopes: []string{ScopeChat}},
+		{Owner: strings.Repeat("a", maxOwnerLength+1), Scopes: []string{ScopeChat}},
+		{Owner: "car", Scopes: nil},
+		{Owner: "car", Scopes: []string{"admin"}},
+		{Owner: "car", Scopes: []string{ScopeChat}, RequestsPerMinute: -1},
+	}
+	for _, spec := range specs {
+		if _, _, err := registry.Create(context.Background(), spec); !errors.Is(err, ErrInvalidKeySpec) {
+			t.Errorf("Expected ErrInvalidKeySpec for %+v, got %v", spec, err)
+		}
+	}
+	if len(registry.List()) != 0 {
+		t.Error("Invalid specs must not create keys")
+	}
+}
+
+func TestRegistry_Revoke(t *testing.T) {
+	registry := NewRegistry(nil)
+	key, secret, err := registry.Create(context.Background(), KeySpec{Owner: "car", Scopes: []string{ScopeChat}})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if err := registry.Revoke(context.Background(), key.ID); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if _, ok := registry.Lookup(secret); ok {
+		t.Error("Revoked key must not authenticate")
+	}
+
+	keys := registry.List()
+	if len(keys) != 1 || keys[0].Enabled || keys[0].RevokedAt == nil {
+		t.Errorf("Revoked key should stay listed as disabled, got %+v", keys)
+	}
+
+	if err := registry.Revoke(context.Background(), "key_missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestRegistry_SharedStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api-keys.json")
+	first := NewRegistry(configstore.NewFile(path))
+	second := NewRegistry(configstore.NewFile(path))
+
+	key, secret, err := first.Create(context.Background(), KeySpec{Owner: "car", Scopes: []string{ScopeChat}})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// The second instance sees the key after a reload
+	if err := second.Load(context.Background()); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if _, ok := second.Lookup(secret); !ok {
+		t.Fatal("Key created by another instance should authenticate after reload")
+	}
+
+	// A stale write from the first instance is retried on top of the second one's change
+	if _, _, err := second.Create(context.Background(), KeySpec{Owner: "script", Scopes: []string{ScopeConfigRead}}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := first.Revoke(context.Background(), key.ID); err != nil {
+		t.Fatalf("Revoke after concurrent change failed: %v", err)
+	}
+
+	if err := second.Load(context.Background()); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	keys := second.List()
+	if len(keys) != 2 {
+		t.Fatalf("Expected both keys to be kept, got %d", len(keys))
+	}
+	if _, ok := second.Lookup(secret); ok {
+		t.Error("Revocation should reach the other instance")
+	}
+}
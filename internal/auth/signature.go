@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Signed request headers
+// The signature is hex(HMAC-SHA256(shared key, canonical request)), see CanonicalRequest
+const (
+	SignatureHeader = "X-Clotilde-Signature"
+	TimestampHeader = "X-Clotilde-Timestamp" // Unix seconds
+	NonceHeader     = "X-Clotilde-Nonce"     // Unique per request (e.g. a UUID)
+)
+
+// Authentication methods reported in Identity.Method
+const (
+	MethodAPIKey    = "api_key"
+	MethodSignature = "signature"
+)
+
+const (
+	// defaultMaxSkew is how far a request timestamp may be from the server clock
+	defaultMaxSkew = 5 * time.Minute
+	// maxSignedBodySize bounds the body read to verify a signature (the validator already limits it further)
+	maxSignedBodySize = 4 * 1024 * 1024
+	// maxNonces bounds the replay cache; when full, new signed requests are rejected until entries expire
+	maxNonces      = 100000
+	minNonceLength = 8
+	maxNonceLength = 128
+)
+
+var (
+	errSignatureTimestamp = errors.New("request timestamp outside allowed window")
+	errSignatureNonce     = errors.New("missing or invalid nonce")
+	errSignatureInvalid   = errors.New("invalid signature")
+	errSignatureReplay    = errors.New("replayed request")
+)
+
+var (
+	maxSkewMu sync.RWMutex
+	maxSkew   = defaultMaxSkew
+
+	// Nonces of accepted signed requests, kept until their timestamp leaves the skew window
+	signatureNonces = &nonceCache{seen: make(map[string]time.Time)}
+)
+
+// SetMaxSignatureSkew sets the allowed clock difference for signed requests (default 5 minutes)
+func SetMaxSignatureSkew(skew time.Duration) {
+	maxSkewMu.Lock()
+	defer maxSkewMu.Unlock()
+	if skew > 0 {
+		maxSkew = skew
+	}
+}
+
+func getMaxSkew() time.Duration {
+	maxSkewMu.RLock()
+	defer maxSkewMu.RUnlock()
+	return maxSkew
+}
+
+// CanonicalRequest builds the string clients sign:
+// METHOD \n PATH?QUERY \n TIMESTAMP \n NONCE \n hex(SHA-256(body))
+func CanonicalRequest(method, requestURI, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	return strings.Join([]string{
+		strings.ToUpper(method),
+		requestURI,
+		timestamp,
+		nonce,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+}
+
+// Sign returns the hex HMAC-SHA256 of a canonical request with key
+func Sign(key, canonical string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifySignature checks a signed request against the active shared key generations
+// The body is read and restored so handlers can still decode it
+func verifySignature(r *http.Request, shared *KeySet) (SharedKey, error) {
+	timestamp := r.Header.Get(TimestampHeader)
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return SharedKey{}, errSignatureTimestamp
+	}
+	signedAt := time.Unix(seconds, 0)
+	skew := getMaxSkew()
+	if diff := time.Since(signedAt); diff > skew || diff < -skew {
+		return SharedKey{}, errSignatureTimestamp
+	}
+
+	nonce := r.Header.Get(NonceHeader)
+	if len(nonce) < minNonceLength || len(nonce) > maxNonceLength {
+		return SharedKey{}, errSignatureNonce
+	}
+
+	signature, err := hex.DecodeString(strings.TrimSpace(r.Header.Get(SignatureHeader)))
+	if err != nil || len(signature) != sha256.Size {
+		return SharedKey{}, errSignatureInvalid
+	}
+
+	var body []byte
+	if r.Body != nil {
+		body, err = io.ReadAll(io.LimitReader(r.Body, maxSignedBodySize))
+		if err != nil {
+			return SharedKey{}, errSignatureInvalid
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	canonical := CanonicalRequest(r.Method, r.URL.RequestURI(), timestamp, nonce, body)
+	key, ok := shared.MatchSignature(canonical, signature)
+	if !ok {
+		return SharedKey{}, errSignatureInvalid
+	}
+
+	// Only remember nonces of authentic requests so forged ones cannot fill the cache
+	if !signatureNonces.add(key.Generation+"|"+nonce, signedAt.Add(skew)) {
+		return SharedKey{}, errSignatureReplay
+	}
+	return key, nil
+}
+
+// MatchSignature returns the active generation whose HMAC of canonical equals signature
+// Every active key is checked so timing does not reveal which generation matched
+func (ks *KeySet) MatchSignature(canonical string, signature []byte) (SharedKey, bool) {
+	if ks == nil {
+		return SharedKey{}, false
+	}
+
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	now := ks.now()
+	var matched SharedKey
+	found := false
+	for _, key := range ks.keys {
+		if !key.activeAt(now) {
+			continue
+		}
+		mac := hmac.New(sha256.New, []byte(key.Key))
+		mac.Write([]byte(canonical))
+		if hmac.Equal(mac.Sum(nil), signature) && !found {
+			matched = key
+			found = true
+		}
+	}
+	return matched, found
+}
+
+// nonceCache remembers nonces until they expire
+type nonceCache struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time // Nonce -> expiry
+	lastSweep time.Time
+}
+
+// add records a nonce and reports whether it was unseen
+func (c *nonceCache) add(nonce string, expires time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if expiry, ok := c.seen[nonce]; ok && now.Before(expiry) {
+		return false
+	}
+	if len(c.seen) >= maxNonces || now.Sub(c.lastSweep) > time.Minute {
+		c.sweep(now)
+		if len(c.seen) >= maxNonces {
+			// Refuse rather than forget live nonces, which would reopen a replay window
+			return false
+		}
+	}
+	c.seen[nonce] = expires
+	return true
+}
+
+// sweep drops expired nonces (caller holds mu)
+func (c *nonceCache) sweep(now time.Time) {
+	for nonce, expiry := range c.seen {
+		if !now.Before(expiry) {
+			delete(c.seen, nonce)
+		}
+	}
+	c.lastSweep = now
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signedRequest builds a request signed with key, as a client would
+func signedRequest(key, method, target, body, nonce string, signedAt time.Time) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	canonical := CanonicalRequest(method, req.URL.RequestURI(), timestamp, nonce, []byte(body))
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(NonceHeader, nonce)
+	req.Header.Set(SignatureHeader, Sign(key, canonical))
+	return req
+}
+
+func TestMiddleware_SignedRequest(t *testing.T) {
+	shared := NewKeySet(SharedKey{Generation: "v1", Key: "signing-secret"})
+
+	var got Identity
+	var gotBody string
+	handler := MiddlewareWithKeys(shared, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = GetIdentity(r.Context())
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	body := `{"message":"Qual a previsão do tempo?"}`
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, signedRequest("signing-secret", "POST", "/chat", body, "nonce-0001", time.Now()))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if got.Method != MethodSignature || got.KeyID != LegacyKeyID || got.Generation != "v1" {
+		t.Errorf("Unexpected identity %+v", got)
+	}
+	if gotBody != body {
+		t.Errorf("Handler should receive the original body, got %q", gotBody)
+	}
+
+	// The same signed request again is a replay
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, signedRequest("signing-secret", "POST", "/chat", body, "nonce-0001", time.Now()))
+	if rr.Code != http.StatusUnauthorized || !strings.Contains(rr.Body.String(), "replayed") {
+		t.Errorf("Expected replay to be rejected, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestMiddleware_SignedRequestRejections(t *testing.T) {
+	shared := NewKeySet(SharedKey{Generation: "v1", Key: "signing-secret"})
+	handler := MiddlewareWithKeys(shared, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called")
+	}))
+
+	tampered := signedRequest("signing-secret", "POST", "/chat", `{"message":"a"}`, "nonce-tamper", time.Now())
+	tampered.Body = io.NopCloser(strings.NewReader(`{"message":"b"}`))
+
+	otherPath := signedRequest("signing-secret", "POST", "/chat", `{}`, "nonce-path", time.Now())
+	otherPath.URL.Path = "/api/config"
+
+	testCases := []struct {
+		name string
+		req  *http.Request
+	}{
+		{"wrong secret", signedRequest("other-secret", "POST", "/chat", `{}`, "nonce-wrong", time.Now())},
+		{"tampered body", tampered},
+		{"different path", otherPath},
+		{"old timestamp", signedRequest("signing-secret", "POST", "/chat", `{}`, "nonce-old", time.Now().Add(-10*time.Minute))},
+		{"future timestamp", signedRequest("signing-secret", "POST", "/chat", `{}`, "nonce-future", time.Now().Add(10*time.Minute))},
+		{"short nonce", signedRequest("signing-secret", "POST", "/chat", `{}`, "n", time.Now())},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, tc.req)
+			if rr.Code != http.StatusUnauthorized {
+				t.Errorf("Expected status 401, got %d", rr.Code)
+			}
+		})
+	}
+}
+
+func TestMiddleware_SignatureCoexistsWithAPIKey(t *testing.T) {
+	shared := NewKeySet(SharedKey{Generation: "v1", Key: "shared-secret"})
+	var got Identity
+	handler := MiddlewareWithKeys(shared, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = GetIdentity(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("POST", "/chat", strings.NewReader(`{}`))
+	req.Header.Set("X-API-Key", "shared-secret")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK || got.Method != MethodAPIKey {
+		t.Errorf("Static key should still work, got %d (%s)", rr.Code, got.Method)
+	}
+}
+
+func TestNonceCache_Expiry(t *testing.T) {
+	cache := &nonceCache{seen: make(map[string]time.Time)}
+
+	if !cache.add("a", time.Now().Add(time.Minute)) {
+		t.Fatal("First use of a nonce should be accepted")
+	}
+	if cache.add("a", time.Now().Add(time.Minute)) {
+		t.Error("Second use of a live nonce should be rejected")
+	}
+	if !cache.add("b", time.Now().Add(-time.Second)) || !cache.add("b", time.Now().Add(time.Minute)) {
+		t.Error("An expired nonce no longer needs to be remembered")
+	}
+}
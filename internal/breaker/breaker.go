@@ -0,0 +1,289 @@
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// State is the state of a circuit breaker
+type State string
+
+const (
+	Closed   State = "closed"    // Calls go through; failures are counted
+	Open     State = "open"      // Calls fail immediately until the cooldown ends
+	HalfOpen State = "half_open" // One probe call decides whether to close or open again
+)
+
+// Result is the outcome of a call as seen by the breaker
+type Result int
+
+const (
+	Success Result = iota // The upstream answered (including errors caused by the request itself)
+	Failure               // The upstream is unhealthy: server error, overload, rate limit, timeout, connection failure
+	Ignored               // Says nothing about the upstream (e.g. the client hung up)
+)
+
+// ErrOpen is returned (wrapped with the breaker name) when a call is refused by an open breaker
+var ErrOpen = errors.New("circuit breaker open")
+
+// Config sets when a breaker opens and for how long
+type Config struct {
+	Window      time.Duration // Only calls finished within the window are counted
+	MaxCalls    int           // Most recent calls kept in the window
+	MinCalls    int           // Calls needed in the window before the failure rate is considered
+	FailureRate float64       // Share of failed calls (0-1) that opens the breaker
+	OpenFor     time.Duration // How long an open breaker refuses calls before letting a probe through
+}
+
+// DefaultConfig opens a breaker when half of at least 3 calls in the last 2 minutes failed, and
+// probes the upstream again after 30s
+// The thresholds are low because traffic is low: a handful of drivers, so waiting for dozens of
+// failures would mean minutes of slow answers
+func DefaultConfig() Config {
+	return Config{
+		Window:      2 * time.Minute,
+		MaxCalls:    20,
+		MinCalls:    3,
+		FailureRate: 0.5,
+		OpenFor:     30 * time.Second,
+	}
+}
+
+// Transition is a change of state of a breaker
+type Transition struct {
+	Name        string
+	From        State
+	To          State
+	FailureRate float64 // Share of failed calls in the window when the breaker opened (0-1)
+}
+
+// Status is a snapshot of a breaker for /health and the dashboard
+type Status struct {
+	Name        string  `json:"name"`
+	State       State   `json:"state"`
+	Calls       int     `json:"calls"`        // Calls in the window
+	Failures    int     `json:"failures"`     // Failed calls in the window
+	FailureRate float64 `json:"failure_rate"` // Percentage of failed calls in the window
+	RetryInMs   int64   `json:"retry_in_ms,omitempty"`
+}
+
+// outcome is a finished call in the window
+type outcome struct {
+	at     time.Time
+	failed bool
+}
+
+// Breaker stops calling an upstream that keeps failing, so requests skip it immediately instead
+// of waiting for its timeout
+type Breaker struct {
+	name   string
+	config Config
+	now    func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	outcomes []outcome // Oldest first; only kept while closed
+	openedAt time.Time
+	probing  bool // A half-open probe is in flight
+}
+
+// New creates a closed breaker
+func New(name string, config Config) *Breaker {
+	return &Breaker{name: name, config: config, now: time.Now, state: Closed}
+}
+
+// Name returns the upstream guarded by the breaker
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// Allow reports whether a call may go to the upstream
+// On success the returned function must be called with the result of the call
+func (b *Breaker) Allow() (func(Result), error) {
+	b.mu.Lock()
+	var transition *Transition
+	probe := false
+	switch b.state {
+	case Open:
+		if b.now().Sub(b.openedAt) < b.config.OpenFor {
+			b.mu.Unlock()
+			return nil, fmt.Errorf("%s: %w", b.name, ErrOpen)
+		}
+		transition = b.setState(HalfOpen, 0)
+		fallthrough
+	case HalfOpen:
+		if b.probing {
+			b.mu.Unlock()
+			return nil, fmt.Errorf("%s: %w", b.name, ErrOpen)
+		}
+		b.probing = true
+		probe = true
+	}
+	b.mu.Unlock()
+	notify(transition)
+
+	return func(result Result) { b.done(probe, result) }, nil
+}
+
+// done records the result of a call allowed by Allow
+func (b *Breaker) done(probe bool, result Result) {
+	b.mu.Lock()
+	var transition *Transition
+	switch {
+	case probe:
+		b.probing = false
+		switch result {
+		case Success:
+			transition = b.setState(Closed, 0)
+		case Failure:
+			transition = b.setState(Open, 1)
+		}
+	case b.state == Closed && result != Ignored:
+		now := b.now()
+		b.outcomes = append(b.prune(now), outcome{at: now, failed: result == Failure})
+		if calls, failures := b.counts(); calls >= b.config.MinCalls {
+			if rate := float64(failures) / float64(calls); rate >= b.config.FailureRate {
+				transition = b.setState(Open, rate)
+			}
+		}
+	}
+	b.mu.Unlock()
+	notify(transition)
+}
+
+// setState moves the breaker to state and returns the transition (nil if unchanged)
+// The caller must hold b.mu
+func (b *Breaker) setState(state State, failureRate float64) *Transition {
+	if b.state == state {
+		return nil
+	}
+	transition := &Transition{Name: b.name, From: b.state, To: state, FailureRate: failureRate}
+	b.state = state
+	b.outcomes = nil
+	if state == Open {
+		b.openedAt = b.now()
+	}
+	return transition
+}
+
+// prune drops the outcomes that left the window or exceed MaxCalls
+// The caller must hold b.mu
+func (b *Breaker) prune(now time.Time) []outcome {
+	start := 0
+	for start < len(b.outcomes) && now.Sub(b.outcomes[start].at) > b.config.Window {
+		start++
+	}
+	if excess := len(b.outcomes) - start - (b.config.MaxCalls - 1); excess > 0 {
+		start += excess
+	}
+	return b.outcomes[start:]
+}
+
+// counts returns the calls and failures in the window
+// The caller must hold b.mu
+func (b *Breaker) counts() (calls, failures int) {
+	for _, o := range b.outcomes {
+		if o.failed {
+			failures++
+		}
+	}
+	return len(b.outcomes), failures
+}
+
+// Reset closes the breaker and forgets the recent calls
+func (b *Breaker) Reset() {
+	b.mu.Lock()
+	transition := b.setState(Closed, 0)
+	b.outcomes = nil // Also when already closed
+	b.probing = false
+	b.mu.Unlock()
+	notify(transition)
+}
+
+// Status returns a snapshot of the breaker
+func (b *Breaker) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.outcomes = b.prune(now)
+	calls, failures := b.counts()
+	status := Status{Name: b.name, State: b.state, Calls: calls, Failures: failures}
+	if calls > 0 {
+		status.FailureRate = float64(failures) / float64(calls) * 100
+	}
+	if b.state == Open {
+		if retryIn := b.config.OpenFor - now.Sub(b.openedAt); retryIn > 0 {
+			status.RetryInMs = retryIn.Milliseconds()
+		}
+	}
+	return status
+}
+
+// registry holds the process-wide breakers, one per upstream
+var registry = struct {
+	mu           sync.Mutex
+	breakers     map[string]*Breaker
+	onTransition func(Transition)
+}{breakers: make(map[string]*Breaker)}
+
+// Get returns the breaker of an upstream, creating it with the default config on first use
+func Get(name string) *Breaker {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	b, ok := registry.breakers[name]
+	if !ok {
+		b = New(name, DefaultConfig())
+		registry.breakers[name] = b
+	}
+	return b
+}
+
+// Lookup returns the breaker of an upstream if it exists
+func Lookup(name string) (*Breaker, bool) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	b, ok := registry.breakers[name]
+	return b, ok
+}
+
+// Statuses returns a snapshot of every breaker, sorted by name
+func Statuses() []Status {
+	registry.mu.Lock()
+	breakers := make([]*Breaker, 0, len(registry.breakers))
+	for _, b := range registry.breakers {
+		breakers = append(breakers, b)
+	}
+	registry.mu.Unlock()
+
+	statuses := make([]Status, 0, len(breakers))
+	for _, b := range breakers {
+		statuses = append(statuses, b.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// SetTransitionHook sets the function called on every state change (e.g. to write the audit log)
+func SetTransitionHook(fn func(Transition)) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.onTransition = fn
+}
+
+// notify passes a transition to the hook
+func notify(transition *Transition) {
+	if transition == nil {
+		return
+	}
+	registry.mu.Lock()
+	fn := registry.onTransition
+	registry.mu.Unlock()
+	if fn != nil {
+		fn(*transition)
+	}
+}
@@ -0,0 +1,160 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestBreaker(now *time.Time) *Breaker {
+	b := New("test", DefaultConfig())
+	b.now = func() time.Time { return *now }
+	return b
+}
+
+// call makes one call through the breaker with the given result
+func call(t *testing.T, b *Breaker, result Result) {
+	t.Helper()
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Expected the call to be allowed, got %v", err)
+	}
+	done(result)
+}
+
+func TestBreaker_OpensOnFailureRate(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	b := newTestBreaker(&now)
+
+	call(t, b, Success)
+	call(t, b, Failure)
+	if b.Status().State != Closed {
+		t.Fatal("Expected the breaker closed below the minimum number of calls")
+	}
+	call(t, b, Failure)
+
+	status := b.Status()
+	if status.State != Open || status.Calls != 0 || status.RetryInMs != 30000 {
+		t.Fatalf("Expected the breaker open after 2 of 3 calls failed, got %+v", status)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("Expected ErrOpen while open, got %v", err)
+	}
+}
+
+func TestBreaker_WindowForgetsOldCalls(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	b := newTestBreaker(&now)
+
+	for i := 0; i < 5; i++ {
+		call(t, b, Success)
+	}
+	now = now.Add(3 * time.Minute)
+	for i := 0; i < 3; i++ {
+		call(t, b, Failure)
+	}
+	if b.Status().State != Open {
+		t.Error("Expected successes from before the window not to keep the breaker closed")
+	}
+}
+
+func TestBreaker_IgnoredCallsNotCounted(t *testing.T) {
+	now := time.Now()
+	b := newTestBreaker(&now)
+
+	call(t, b, Failure)
+	call(t, b, Ignored)
+	call(t, b, Ignored)
+	if status := b.Status(); status.State != Closed || status.Calls != 1 {
+		t.Errorf("Expected only the failure counted, got %+v", status)
+	}
+}
+
+func TestBreaker_HalfOpenProbe(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	b := newTestBreaker(&now)
+	for i := 0; i < 3; i++ {
+		call(t, b, Failure)
+	}
+
+	// After the cooldown a single probe goes through
+	now = now.Add(31 * time.Second)
+	done, err := b.Allow()
+	if err != nil || b.Status().State != HalfOpen {
+		t.Fatalf("Expected a half-open probe, got %v in state %s", err, b.Status().State)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("Expected other calls refused during the probe, got %v", err)
+	}
+
+	// A failed probe opens the breaker for another cooldown
+	done(Failure)
+	if status := b.Status(); status.State != Open || status.RetryInMs != 30000 {
+		t.Fatalf("Expected the breaker open again, got %+v", status)
+	}
+
+	now = now.Add(31 * time.Second)
+	call(t, b, Success)
+	if b.Status().State != Closed {
+		t.Errorf("Expected a successful probe to close the breaker, got %s", b.Status().State)
+	}
+}
+
+func TestBreaker_IgnoredProbeReleased(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	b := newTestBreaker(&now)
+	for i := 0; i < 3; i++ {
+		call(t, b, Failure)
+	}
+	now = now.Add(31 * time.Second)
+
+	// A probe cancelled by the client lets the next call probe
+	call(t, b, Ignored)
+	call(t, b, Success)
+	if b.Status().State != Closed {
+		t.Errorf("Expected the breaker closed, got %s", b.Status().State)
+	}
+}
+
+func TestBreaker_TransitionHook(t *testing.T) {
+	var transitions []Transition
+	SetTransitionHook(func(tr Transition) { transitions = append(transitions, tr) })
+	t.Cleanup(func() { SetTransitionHook(nil) })
+
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	b := newTestBreaker(&now)
+	call(t, b, Success)
+	call(t, b, Failure)
+	call(t, b, Failure)
+	now = now.Add(31 * time.Second)
+	call(t, b, Success)
+	b.Reset()
+
+	if len(transitions) != 3 {
+		t.Fatalf("Expected 3 transitions, got %+v", transitions)
+	}
+	if tr := transitions[0]; tr.Name != "test" || tr.From != Closed || tr.To != Open || tr.FailureRate < 0.66 || tr.FailureRate > 0.67 {
+		t.Errorf("Unexpected opening transition: %+v", tr)
+	}
+	if transitions[1].To != HalfOpen || transitions[2].To != Closed {
+		t.Errorf("Expected half-open then closed, got %+v", transitions[1:])
+	}
+}
+
+func TestStatuses(t *testing.T) {
+	Get("zeta")
+	Get("alpha")
+	Get("alpha").Reset()
+
+	statuses := Statuses()
+	var names []string
+	for _, status := range statuses {
+		names = append(names, status.Name)
+	}
+	if len(names) < 2 || names[0] != "alpha" || names[len(names)-1] != "zeta" {
+		t.Errorf("Expected breakers sorted by name, got %v", names)
+	}
+	if Get("alpha") != Get("alpha") {
+		t.Error("Expected one breaker per name")
+	}
+}
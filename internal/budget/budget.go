@@ -0,0 +1,145 @@
+package budget
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Stages of a chat request, in the order they run
+const (
+	Transcription = "transcription" // Speech-to-text of an audio question
+	Embedding     = "embedding"     // Semantic cache lookup
+	Search        = "search"        // Perplexity search
+	Generation    = "generation"    // Model call, including fallbacks and hedging
+	Synthesis     = "synthesis"     // Text-to-speech of the answer
+)
+
+// Budget splits the time left before a request's deadline between its stages
+// Later stages reserve time up front so earlier ones cannot use it all: a slow search is cut short
+// (or skipped) to leave generation enough time, and generation stops early to leave time for speech
+// A nil Budget allots every stage its maximum and records nothing
+type Budget struct {
+	start    time.Time
+	deadline time.Time
+	now      func() time.Time
+
+	mu       sync.Mutex
+	reserved map[string]time.Duration // Kept for stages that have not finished
+	timings  map[string]time.Duration // Time spent in each finished stage
+}
+
+// New creates the budget of a request that started at start and must finish within total
+func New(start time.Time, total time.Duration) *Budget {
+	return &Budget{
+		start:    start,
+		deadline: start.Add(total),
+		now:      time.Now,
+		reserved: make(map[string]time.Duration),
+		timings:  make(map[string]time.Duration),
+	}
+}
+
+// Start returns when the request started
+func (b *Budget) Start() time.Time {
+	return b.start
+}
+
+// Deadline returns when the request must be answered
+func (b *Budget) Deadline() time.Time {
+	return b.deadline
+}
+
+// Remaining returns the time left before the deadline
+func (b *Budget) Remaining() time.Duration {
+	return b.deadline.Sub(b.now())
+}
+
+// Reserve keeps d of the remaining time for stage; other stages are allotted what is left
+// The reservation ends when the stage is recorded
+func (b *Budget) Reserve(stage string, d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reserved[stage] = d
+}
+
+// Allot returns how long stage may run: the time left minus the time reserved for the other
+// stages, capped at max (no cap when max is 0); it is 0 or less when nothing is left
+func (b *Budget) Allot(stage string, max time.Duration) time.Duration {
+	if b == nil {
+		return max
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	allot := b.Remaining()
+	for other, d := range b.reserved {
+		if other != stage {
+			allot -= d
+		}
+	}
+	if max > 0 && allot > max {
+		allot = max
+	}
+	return allot
+}
+
+// Record adds the time spent in stage and ends its reservation
+func (b *Budget) Record(stage string, d time.Duration) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.timings[stage] += d
+	delete(b.reserved, stage)
+}
+
+// Release ends the reservation of a stage that will not run
+func (b *Budget) Release(stage string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.reserved, stage)
+}
+
+// Track starts timing stage; the returned function records it
+func (b *Budget) Track(stage string) func() {
+	if b == nil {
+		return func() {}
+	}
+	start := b.now()
+	return func() { b.Record(stage, b.now().Sub(start)) }
+}
+
+// Timings returns the time spent in each stage, in milliseconds (nil when none ran)
+func (b *Budget) Timings() map[string]int64 {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.timings) == 0 {
+		return nil
+	}
+	timings := make(map[string]int64, len(b.timings))
+	for stage, d := range b.timings {
+		timings[stage] = d.Milliseconds()
+	}
+	return timings
+}
+
+type contextKey struct{}
+
+// NewContext returns ctx carrying the budget, for stages that run deep in the request
+func NewContext(ctx context.Context, b *Budget) context.Context {
+	return context.WithValue(ctx, contextKey{}, b)
+}
+
+// FromContext returns the budget carried by ctx (nil if none)
+func FromContext(ctx context.Context) *Budget {
+	b, _ := ctx.Value(contextKey{}).(*Budget)
+	return b
+}
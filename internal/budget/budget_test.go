@@ -0,0 +1,91 @@
+package budget
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestBudget(now *time.Time) *Budget {
+	b := New(*now, 25*time.Second)
+	b.now = func() time.Time { return *now }
+	return b
+}
+
+func TestBudget_AllotKeepsReservations(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	b := newTestBudget(&now)
+	b.Reserve(Generation, 10*time.Second)
+	b.Reserve(Synthesis, 5*time.Second)
+
+	if got := b.Allot(Search, 8*time.Second); got != 8*time.Second {
+		t.Errorf("Expected the search capped at 8s, got %v", got)
+	}
+
+	// Transcription took 12s: the search only gets what generation and synthesis do not need
+	now = now.Add(12 * time.Second)
+	if got := b.Allot(Search, 8*time.Second); got != -2*time.Second {
+		t.Errorf("Expected no time left for search, got %v", got)
+	}
+	if got := b.Allot(Generation, 0); got != 8*time.Second {
+		t.Errorf("Expected generation to get all but the synthesis reserve, got %v", got)
+	}
+
+	// Once generation is recorded its reservation ends
+	b.Record(Generation, 6*time.Second)
+	now = now.Add(6 * time.Second)
+	if got := b.Allot(Synthesis, 0); got != 7*time.Second || b.Remaining() != 7*time.Second {
+		t.Errorf("Expected synthesis to get the rest of the budget, got %v", got)
+	}
+}
+
+func TestBudget_Release(t *testing.T) {
+	now := time.Now()
+	b := newTestBudget(&now)
+	b.Reserve(Generation, 10*time.Second)
+	b.Release(Generation)
+	if got := b.Allot(Synthesis, 0); got != 25*time.Second {
+		t.Errorf("Expected a released reservation to be available, got %v", got)
+	}
+}
+
+func TestBudget_Timings(t *testing.T) {
+	now := time.Now()
+	b := newTestBudget(&now)
+	if b.Timings() != nil {
+		t.Error("Expected no timings before any stage")
+	}
+
+	stop := b.Track(Search)
+	now = now.Add(1500 * time.Millisecond)
+	stop()
+	b.Record(Generation, time.Second)
+	b.Record(Generation, 500*time.Millisecond) // A second model after a fallback
+
+	timings := b.Timings()
+	if timings[Search] != 1500 || timings[Generation] != 1500 || len(timings) != 2 {
+		t.Errorf("Unexpected timings: %v", timings)
+	}
+}
+
+func TestBudget_Nil(t *testing.T) {
+	var b *Budget
+	if got := b.Allot(Embedding, 2*time.Second); got != 2*time.Second {
+		t.Errorf("Expected a nil budget to allot the maximum, got %v", got)
+	}
+	b.Track(Search)()
+	b.Release(Generation)
+	if b.Timings() != nil {
+		t.Error("Expected a nil budget to record nothing")
+	}
+}
+
+func TestContext(t *testing.T) {
+	if FromContext(context.Background()) != nil {
+		t.Error("Expected no budget in an empty context")
+	}
+	b := New(time.Now(), time.Second)
+	if FromContext(NewContext(context.Background(), b)) != b {
+		t.Error("Expected the budget carried by the context")
+	}
+}
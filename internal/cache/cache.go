@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Key identifies a cached answer
+type Key struct {
+	Question string // Normalized question (router.Normalize), so case, accents and punctuation do not matter
+	Category string
+	Model    string
+	Prompt   string // Version of the system prompt the answer was generated with
+}
+
+// Config bounds the response cache
+type Config struct {
+	MaxEntries int                      // Answers kept per instance (least recently used evicted first)
+	TTLs       map[string]time.Duration // Category -> how long an answer stays valid; categories without a TTL are not cached
+}
+
+// DefaultConfig returns TTLs that follow how fast answers go stale: web search answers (quotes,
+// scores, weather) for a few minutes, facts and calculations for a day
+// Creative answers are not cached so a repeated request gets a new story or joke
+func DefaultConfig() Config {
+	return Config{
+		MaxEntries: 1000,
+		TTLs: map[string]time.Duration{
+			"web_search":   5 * time.Minute,
+			"simple":       time.Hour,
+			"complex":      6 * time.Hour,
+			"factual":      24 * time.Hour,
+			"mathematical": 24 * time.Hour,
+		},
+	}
+}
+
+// entry is a cached answer in the LRU list
+type entry struct {
+	key     Key
+	answer  string
+	expires time.Time
+}
+
+// Cache is an in-process LRU cache of answers with per-category TTLs
+// Answers are per instance: on Cloud Run each instance warms its own cache
+type Cache struct {
+	config  Config
+	mu      sync.Mutex
+	entries map[Key]*list.Element
+	order   *list.List // Most recently used first
+	now     func() time.Time
+}
+
+// New creates a response cache (MaxEntries <= 0 uses the default size)
+func New(config Config) *Cache {
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = DefaultConfig().MaxEntries
+	}
+	return &Cache{
+		config:  config,
+		entries: make(map[Key]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// Cacheable reports whether answers of the category are cached
+func (c *Cache) Cacheable(category string) bool {
+	return c.config.TTLs[category] > 0
+}
+
+// Get returns the cached answer for key if it has not expired
+func (c *Cache) Get(key Key) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	e := elem.Value.(*entry)
+	if !c.now().Before(e.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return "", false
+	}
+	c.order.MoveToFront(elem)
+	return e.answer, true
+}
+
+// Set stores the answer for key with the TTL of its category (no-op for categories that are not cached)
+func (c *Cache) Set(key Key, answer string) {
+	ttl := c.config.TTLs[key.Category]
+	if ttl <= 0 || answer == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(ttl)
+	if elem, ok := c.entries[key]; ok {
+		e := elem.Value.(*entry)
+		e.answer = answer
+		e.expires = expires
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	for len(c.entries) >= c.config.MaxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).key)
+	}
+	c.entries[key] = c.order.PushFront(&entry{key: key, answer: answer, expires: expires})
+}
+
+// Purge removes every cached answer and returns how many were removed
+func (c *Cache) Purge() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := len(c.entries)
+	c.entries = make(map[Key]*list.Element)
+	c.order.Init()
+	return n
+}
+
+// Len returns the number of cached answers (including expired ones not yet evicted)
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// MaxEntries returns the size bound of the cache
+func (c *Cache) MaxEntries() int {
+	return c.config.MaxEntries
+}
@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestCache(maxEntries int, now *time.Time) *Cache {
+	config := DefaultConfig()
+	config.MaxEntries = maxEntries
+	c := New(config)
+	c.now = func() time.Time { return *now }
+	return c
+}
+
+func TestCache_CategoryTTLs(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	c := newTestCache(10, &now)
+
+	dollar := Key{Question: "qual cotaca dolar", Category: "web_search", Model: "gpt-4o-mini"}
+	capital := Key{Question: "qual capital australia", Category: "factual", Model: "gpt-4o-mini"}
+	c.Set(dollar, "R$ 5,40")
+	c.Set(capital, "Camberra")
+
+	if answer, ok := c.Get(dollar); !ok || answer != "R$ 5,40" {
+		t.Fatalf("Expected cached web search answer, got %q %v", answer, ok)
+	}
+
+	now = now.Add(10 * time.Minute)
+	if _, ok := c.Get(dollar); ok {
+		t.Error("Web search answers should expire after minutes")
+	}
+	if _, ok := c.Get(capital); !ok {
+		t.Error("Factual answers should still be cached")
+	}
+
+	now = now.Add(24 * time.Hour)
+	if _, ok := c.Get(capital); ok {
+		t.Error("Factual answers should expire after a day")
+	}
+}
+
+func TestCache_KeyIncludesCategoryAndModel(t *testing.T) {
+	now := time.Now()
+	c := newTestCache(10, &now)
+
+	c.Set(Key{Question: "oi", Category: "simple", Model: "gpt-4o-mini"}, "Olá!")
+	if _, ok := c.Get(Key{Question: "oi", Category: "simple", Model: "gpt-4o"}); ok {
+		t.Error("Answers from another model should not be served")
+	}
+	if _, ok := c.Get(Key{Question: "oi", Category: "factual", Model: "gpt-4o-mini"}); ok {
+		t.Error("Answers from another category should not be served")
+	}
+}
+
+func TestCache_CreativeNotCached(t *testing.T) {
+	now := time.Now()
+	c := newTestCache(10, &now)
+
+	key := Key{Question: "cont piad", Category: "creative", Model: "gpt-4o-mini"}
+	if c.Cacheable("creative") {
+		t.Error("Creative answers should not be cacheable")
+	}
+	c.Set(key, "Era uma vez...")
+	if _, ok := c.Get(key); ok || c.Len() != 0 {
+		t.Error("Creative answers should not be stored")
+	}
+}
+
+func TestCache_LRUEviction(t *testing.T) {
+	now := time.Now()
+	c := newTestCache(2, &now)
+
+	a := Key{Question: "a", Category: "factual"}
+	b := Key{Question: "b", Category: "factual"}
+	d := Key{Question: "d", Category: "factual"}
+	c.Set(a, "A")
+	c.Set(b, "B")
+	c.Get(a) // a is now the most recently used
+	c.Set(d, "D")
+
+	if c.Len() != 2 {
+		t.Fatalf("Expected the size bound to hold, got %d entries", c.Len())
+	}
+	if _, ok := c.Get(b); ok {
+		t.Error("Expected the least recently used answer to be evicted")
+	}
+	if _, ok := c.Get(a); !ok {
+		t.Error("Expected the recently used answer to be kept")
+	}
+}
+
+func TestCache_Purge(t *testing.T) {
+	now := time.Now()
+	c := newTestCache(10, &now)
+	c.Set(Key{Question: "a", Category: "factual"}, "A")
+	c.Set(Key{Question: "b", Category: "simple"}, "B")
+
+	if n := c.Purge(); n != 2 {
+		t.Errorf("Expected 2 answers purged, got %d", n)
+	}
+	if _, ok := c.Get(Key{Question: "a", Category: "factual"}); ok || c.Len() != 0 {
+		t.Error("Expected an empty cache after purge")
+	}
+}
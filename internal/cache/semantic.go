@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/clotilde/carplay-assistant/internal/embedding"
+)
+
+// SemanticKey scopes semantic matches: an answer is only reused for the same category and model,
+// and for questions with the same numbers ("15% de 80" and "15% de 90" are close in meaning, not in answer)
+type SemanticKey struct {
+	Category string
+	Model    string
+	Numbers  string // Numbers and math operators of the question, in order
+	Prompt   string // Version of the system prompt the answer was generated with
+}
+
+// DefaultSemanticThresholds returns the minimum similarity for a paraphrase to get a cached answer
+// Calculations are left out: their questions differ in the numbers more than in the wording
+func DefaultSemanticThresholds() map[string]float64 {
+	return map[string]float64{
+		"web_search": 0.92,
+		"simple":     0.90,
+		"complex":    0.92,
+		"factual":    0.92,
+	}
+}
+
+// semanticEntry is a cached answer with the embedding of its question
+type semanticEntry struct {
+	key     SemanticKey
+	vector  []float32
+	answer  string
+	expires time.Time
+}
+
+// Semantic is a bounded in-memory vector index of answers, searched by embedding similarity
+// Lookups scan every entry: with the default 1000 answers of 512 dimensions that is well under a millisecond
+type Semantic struct {
+	config  Config
+	mu      sync.Mutex
+	entries *list.List // Most recently used first
+	now     func() time.Time
+}
+
+// NewSemantic creates a semantic cache with the same size bound and TTLs as the exact cache
+func NewSemantic(config Config) *Semantic {
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = DefaultConfig().MaxEntries
+	}
+	return &Semantic{config: config, entries: list.New(), now: time.Now}
+}
+
+// Lookup returns the answer of the most similar question with the same key, if its similarity
+// reaches threshold
+func (s *Semantic) Lookup(key SemanticKey, vector []float32, threshold float64) (string, float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var best *list.Element
+	var bestSimilarity float64
+	for elem := s.entries.Front(); elem != nil; {
+		next := elem.Next()
+		e := elem.Value.(*semanticEntry)
+		if !now.Before(e.expires) {
+			s.entries.Remove(elem)
+		} else if e.key == key {
+			if similarity := embedding.Cosine(vector, e.vector); similarity > bestSimilarity {
+				best, bestSimilarity = elem, similarity
+			}
+		}
+		elem = next
+	}
+	if best == nil || bestSimilarity < threshold {
+		return "", bestSimilarity, false
+	}
+	s.entries.MoveToFront(best)
+	return best.Value.(*semanticEntry).answer, bestSimilarity, true
+}
+
+// Add stores the answer with the embedding of its question (no-op for categories that are not cached)
+func (s *Semantic) Add(key SemanticKey, vector []float32, answer string) {
+	ttl := s.config.TTLs[key.Category]
+	if ttl <= 0 || answer == "" || len(vector) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for s.entries.Len() >= s.config.MaxEntries {
+		s.entries.Remove(s.entries.Back())
+	}
+	s.entries.PushFront(&semanticEntry{key: key, vector: vector, answer: answer, expires: s.now().Add(ttl)})
+}
+
+// Purge removes every cached answer and returns how many were removed
+func (s *Semantic) Purge() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := s.entries.Len()
+	s.entries.Init()
+	return n
+}
+
+// Len returns the number of cached answers (including expired ones not yet evicted)
+func (s *Semantic) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.entries.Len()
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestSemantic(maxEntries int, now *time.Time) *Semantic {
+	config := DefaultConfig()
+	config.MaxEntries = maxEntries
+	s := NewSemantic(config)
+	s.now = func() time.Time { return *now }
+	return s
+}
+
+func TestSemantic_Lookup(t *testing.T) {
+	now := time.Now()
+	s := newTestSemantic(10, &now)
+	key := SemanticKey{Category: "web_search", Model: "gpt-4o-mini"}
+
+	s.Add(key, []float32{1, 0, 0}, "R$ 5,40")
+	s.Add(key, []float32{0, 1, 0}, "€ 6,10")
+
+	answer, similarity, ok := s.Lookup(key, []float32{0.95, 0.1, 0}, 0.9)
+	if !ok || answer != "R$ 5,40" || similarity < 0.99 {
+		t.Errorf("Expected the closest answer, got %q %v %v", answer, similarity, ok)
+	}
+	if _, similarity, ok := s.Lookup(key, []float32{1, 1, 0}, 0.9); ok || similarity < 0.7 {
+		t.Errorf("Expected no answer below the threshold, got similarity %v", similarity)
+	}
+	if _, _, ok := s.Lookup(SemanticKey{Category: "web_search", Model: "gpt-4o"}, []float32{1, 0, 0}, 0.9); ok {
+		t.Error("Answers from another model should not be served")
+	}
+	if _, _, ok := s.Lookup(SemanticKey{Category: "web_search", Model: "gpt-4o-mini", Numbers: "2"}, []float32{1, 0, 0}, 0.9); ok {
+		t.Error("Answers to questions with other numbers should not be served")
+	}
+
+	now = now.Add(10 * time.Minute)
+	if _, _, ok := s.Lookup(key, []float32{1, 0, 0}, 0.9); ok || s.Len() != 0 {
+		t.Error("Expected expired answers to be dropped")
+	}
+}
+
+func TestSemantic_Bounded(t *testing.T) {
+	now := time.Now()
+	s := newTestSemantic(2, &now)
+	key := SemanticKey{Category: "factual"}
+
+	s.Add(key, []float32{1, 0}, "A")
+	s.Add(key, []float32{0, 1}, "B")
+	s.Lookup(key, []float32{1, 0}, 0.9) // A is now the most recently used
+	s.Add(key, []float32{-1, 0}, "C")
+
+	if s.Len() != 2 {
+		t.Fatalf("Expected the size bound to hold, got %d entries", s.Len())
+	}
+	if _, _, ok := s.Lookup(key, []float32{0, 1}, 0.9); ok {
+		t.Error("Expected the least recently used answer to be evicted")
+	}
+	if answer, _, ok := s.Lookup(key, []float32{1, 0}, 0.9); !ok || answer != "A" {
+		t.Error("Expected the recently used answer to be kept")
+	}
+
+	s.Add(SemanticKey{Category: "creative"}, []float32{1, 0}, "Era uma vez")
+	if n := s.Purge(); n != 2 || s.Len() != 0 {
+		t.Errorf("Expected 2 answers purged (creative is not cached), got %d", n)
+	}
+}
@@ -0,0 +1,25 @@
+package configstore
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrNotFound is returned by Load when nothing has been saved yet
+	ErrNotFound = errors.New("config not found")
+	// ErrConflict is returned by Save when the stored document changed since it was read
+	ErrConflict = errors.New("config was modified by another writer")
+)
+
+// Store persists the runtime configuration document so it survives restarts and is shared by instances
+// Every saved document has an opaque version used for optimistic concurrency
+type Store interface {
+	// Name returns a short identifier for the backend (e.g. "file")
+	Name() string
+	// Load returns the stored document and its version (ErrNotFound if never saved)
+	Load(ctx context.Context) (data []byte, version string, err error)
+	// Save replaces the document only if the stored version still equals ifVersion
+	// ("" means the document must not exist yet) and returns the new version (ErrConflict otherwise)
+	Save(ctx context.Context, data []byte, ifVersion string) (version string, err error)
+}
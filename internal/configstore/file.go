@@ -0,0 +1,93 @@
+package configstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// File stores the configuration in a local JSON file
+// The version is a hash of the content, so edits made outside the service are also detected
+// Suitable for a single instance or a shared volume; use GCS when running several Cloud Run instances
+type File struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// NewFile creates a file-backed store
+func NewFile(path string) *File {
+	return &File{Path: path}
+}
+
+// Name returns the backend identifier
+func (f *File) Name() string {
+	return "file"
+}
+
+// Load reads the file
+func (f *File) Load(ctx context.Context) ([]byte, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.read()
+}
+
+// Save writes the file atomically (temp file + rename) if its content still matches ifVersion
+func (f *File) Save(ctx context.Context, data []byte, ifVersion string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, current, err := f.read()
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return "", err
+	}
+	if current != ifVersion {
+		return "", ErrConflict
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.Path), ".config-*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write config: %w", err)
+	}
+	// Prompts may be sensitive, so the file is only readable by the service user
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return "", fmt.Errorf("failed to set config permissions: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.Path); err != nil {
+		return "", fmt.Errorf("failed to replace config: %w", err)
+	}
+
+	return contentVersion(data), nil
+}
+
+// read returns the file content and version (caller holds mu)
+func (f *File) read() ([]byte, string, error) {
+	data, err := os.ReadFile(f.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, "", ErrNotFound
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read config: %w", err)
+	}
+	return data, contentVersion(data), nil
+}
+
+// contentVersion derives a short version string from the document content
+func contentVersion(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,72 @@
+package configstore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFile_SaveAndLoad(t *testing.T) {
+	ctx := context.Background()
+	store := NewFile(filepath.Join(t.TempDir(), "config.json"))
+
+	if _, _, err := store.Load(ctx); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound before first save, got %v", err)
+	}
+
+	v1, err := store.Save(ctx, []byte(`{"standard_model":"a"}`), "")
+	if err != nil {
+		t.Fatalf("First save failed: %v", err)
+	}
+
+	data, version, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if string(data) != `{"standard_model":"a"}` || version != v1 {
+		t.Errorf("Unexpected load: %s (version %s, want %s)", data, version, v1)
+	}
+
+	info, err := os.Stat(store.Path)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	v2, err := store.Save(ctx, []byte(`{"standard_model":"b"}`), v1)
+	if err != nil {
+		t.Fatalf("Second save failed: %v", err)
+	}
+	if v2 == v1 {
+		t.Error("Expected a new version after changing the content")
+	}
+}
+
+func TestFile_Conflict(t *testing.T) {
+	ctx := context.Background()
+	store := NewFile(filepath.Join(t.TempDir(), "config.json"))
+
+	v1, _ := store.Save(ctx, []byte(`{"n":1}`), "")
+
+	// The document already exists, so creating it again is a conflict
+	if _, err := store.Save(ctx, []byte(`{"n":2}`), ""); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict when creating over an existing file, got %v", err)
+	}
+
+	// Another writer updates the file
+	if _, err := store.Save(ctx, []byte(`{"n":3}`), v1); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// A stale version is rejected and the file keeps the other writer's content
+	if _, err := store.Save(ctx, []byte(`{"n":4}`), v1); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict for stale version, got %v", err)
+	}
+	if data, _, _ := store.Load(ctx); string(data) != `{"n":3}` {
+		t.Errorf("Expected content to be preserved, got %s", data)
+	}
+}
@@ -0,0 +1,89 @@
+package configstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	storage "google.golang.org/api/storage/v1"
+)
+
+// GCS stores the configuration as an object in a Cloud Storage bucket, shared by all instances
+// The object generation is the version, and writes use ifGenerationMatch so concurrent
+// updates from two admins cannot silently overwrite each other
+type GCS struct {
+	Bucket  string
+	Object  string
+	service *storage.Service
+}
+
+// NewGCS creates a Cloud Storage store using Application Default Credentials
+// (the Cloud Run service account needs roles/storage.objectAdmin on the bucket)
+func NewGCS(ctx context.Context, bucket, object string, opts ...option.ClientOption) (*GCS, error) {
+	service, err := storage.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+	return &GCS{Bucket: bucket, Object: object, service: service}, nil
+}
+
+// Name returns the backend identifier
+func (g *GCS) Name() string {
+	return "gcs"
+}
+
+// Load downloads the object and returns its generation as the version
+func (g *GCS) Load(ctx context.Context) ([]byte, string, error) {
+	resp, err := g.service.Objects.Get(g.Bucket, g.Object).Context(ctx).Download()
+	if err != nil {
+		if isStatus(err, http.StatusNotFound) {
+			return nil, "", ErrNotFound
+		}
+		return nil, "", fmt.Errorf("failed to download config: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read config: %w", err)
+	}
+	return data, resp.Header.Get("X-Goog-Generation"), nil
+}
+
+// Save uploads the object only if its generation still equals ifVersion
+func (g *GCS) Save(ctx context.Context, data []byte, ifVersion string) (string, error) {
+	// Generation 0 means "the object must not exist yet"
+	var generation int64
+	if ifVersion != "" {
+		parsed, err := strconv.ParseInt(ifVersion, 10, 64)
+		if err != nil {
+			return "", ErrConflict
+		}
+		generation = parsed
+	}
+
+	obj, err := g.service.Objects.Insert(g.Bucket, &storage.Object{Name: g.Object, ContentType: "application/json"}).
+		Media(bytes.NewReader(data), googleapi.ContentType("application/json")).
+		IfGenerationMatch(generation).
+		Context(ctx).
+		Do()
+	if err != nil {
+		if isStatus(err, http.StatusPreconditionFailed) {
+			return "", ErrConflict
+		}
+		return "", fmt.Errorf("failed to upload config: %w", err)
+	}
+	return strconv.FormatInt(obj.Generation, 10), nil
+}
+
+// isStatus reports whether err is a Google API error with the given HTTP status
+func isStatus(err error, status int) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == status
+}
@@ -0,0 +1,117 @@
+package configstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/api/option"
+)
+
+// fakeGCS implements the object download and multipart upload endpoints with generation preconditions
+type fakeGCS struct {
+	mu         sync.Mutex
+	data       []byte
+	generation int64
+}
+
+func (f *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/storage/v1/b/bucket/o/"):
+		if f.generation == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":404,"message":"No such object"}}`))
+			return
+		}
+		w.Header().Set("X-Goog-Generation", strconv.FormatInt(f.generation, 10))
+		w.Write(f.data)
+
+	case r.Method == "POST" && r.URL.Path == "/upload/storage/v1/b/bucket/o":
+		if r.URL.Query().Get("ifGenerationMatch") != strconv.FormatInt(f.generation, 10) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			w.Write([]byte(`{"error":{"code":412,"message":"Precondition Failed"}}`))
+			return
+		}
+		// Multipart upload: the first part is metadata, the second the content
+		_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		reader := multipart.NewReader(r.Body, params["boundary"])
+		reader.NextPart()
+		part, err := reader.NextPart()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.data, _ = io.ReadAll(part)
+		f.generation++
+		fmt.Fprintf(w, `{"name":"config.json","generation":"%d"}`, f.generation)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestGCS(t *testing.T) *GCS {
+	server := httptest.NewServer(&fakeGCS{})
+	t.Cleanup(server.Close)
+
+	store, err := NewGCS(context.Background(), "bucket", "config.json",
+		option.WithEndpoint(server.URL+"/storage/v1/"), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("NewGCS failed: %v", err)
+	}
+	return store
+}
+
+func TestGCS_SaveAndLoad(t *testing.T) {
+	ctx := context.Background()
+	store := newTestGCS(t)
+
+	if _, _, err := store.Load(ctx); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound before first save, got %v", err)
+	}
+
+	v1, err := store.Save(ctx, []byte(`{"n":1}`), "")
+	if err != nil {
+		t.Fatalf("First save failed: %v", err)
+	}
+	if v1 != "1" {
+		t.Errorf("Expected generation 1, got %q", v1)
+	}
+
+	data, version, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if string(data) != `{"n":1}` || version != v1 {
+		t.Errorf("Unexpected load: %s (version %s)", data, version)
+	}
+}
+
+func TestGCS_Conflict(t *testing.T) {
+	ctx := context.Background()
+	store := newTestGCS(t)
+
+	v1, _ := store.Save(ctx, []byte(`{"n":1}`), "")
+	if _, err := store.Save(ctx, []byte(`{"n":2}`), v1); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	if _, err := store.Save(ctx, []byte(`{"n":3}`), v1); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict for stale generation, got %v", err)
+	}
+	if _, err := store.Save(ctx, []byte(`{"n":3}`), "not-a-generation"); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict for invalid version, got %v", err)
+	}
+}
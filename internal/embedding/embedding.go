@@ -0,0 +1,31 @@
+package embedding
+
+import (
+	"context"
+	"math"
+)
+
+// Embedder turns text into a vector; texts with close meanings get vectors with a high cosine similarity
+type Embedder interface {
+	// Name returns a short identifier for the backend (e.g. "openai")
+	Name() string
+	// Embed returns the embedding of text
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+// Cosine returns the cosine similarity of two vectors (0 when their sizes differ or one is zero)
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
@@ -0,0 +1,81 @@
+package embedding
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+const hashingDefaultDimensions = 256
+
+// Hashing is a deterministic embedder that runs in process: words and their character trigrams
+// are hashed into a fixed-size vector
+// It only sees spelling, not meaning ("cotação do dólar" and "quanto tá o dólar" share little), so
+// it suits tests and deployments without an embeddings API
+type Hashing struct {
+	Dimensions int
+}
+
+// NewHashing creates a hashing embedder (dimensions <= 0 uses 256)
+func NewHashing(dimensions int) *Hashing {
+	if dimensions <= 0 {
+		dimensions = hashingDefaultDimensions
+	}
+	return &Hashing{Dimensions: dimensions}
+}
+
+// Name returns the backend identifier
+func (e *Hashing) Name() string {
+	return "hashing"
+}
+
+// Embed returns the normalized feature-hashed vector of text
+func (e *Hashing) Embed(ctx context.Context, text string) ([]float32, error) {
+	vector := make([]float32, e.Dimensions)
+	for _, word := range words(text) {
+		e.add(vector, "w:"+word, 1)
+		padded := []rune(" " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			e.add(vector, "t:"+string(padded[i:i+3]), 0.5)
+		}
+	}
+
+	var squares float64
+	for _, v := range vector {
+		squares += float64(v) * float64(v)
+	}
+	if squares > 0 {
+		scale := float32(1 / math.Sqrt(squares))
+		for i := range vector {
+			vector[i] *= scale
+		}
+	}
+	return vector, nil
+}
+
+// add hashes a feature into one dimension, with a sign from the hash so collisions tend to cancel out
+func (e *Hashing) add(vector []float32, feature string, weight float32) {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	sum := h.Sum64()
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	vector[sum%uint64(len(vector))] += weight
+}
+
+// words lowercases text, removes accents and splits it into letters and digits
+func words(text string) []string {
+	t := transform.Chain(norm.NFD, transform.RemoveFunc(func(r rune) bool { return unicode.Is(unicode.Mn, r) }), norm.NFC)
+	if stripped, _, err := transform.String(t, text); err == nil {
+		text = stripped
+	}
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
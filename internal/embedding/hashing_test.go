@@ -0,0 +1,36 @@
+package embedding
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestHashing_Embed(t *testing.T) {
+	e := NewHashing(0)
+	ctx := context.Background()
+	embed := func(text string) []float32 {
+		vector, err := e.Embed(ctx, text)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return vector
+	}
+
+	a := embed("Qual é a cotação do dólar hoje?")
+	if len(a) != hashingDefaultDimensions {
+		t.Fatalf("Expected %d dimensions, got %d", hashingDefaultDimensions, len(a))
+	}
+	if got := Cosine(a, embed("Qual é a cotação do dólar hoje?")); math.Abs(got-1) > 1e-6 {
+		t.Errorf("Expected the same vector for the same text, got similarity %v", got)
+	}
+	if got := Cosine(a, embed("qual e a cotacao do dolar hoje")); math.Abs(got-1) > 1e-6 {
+		t.Errorf("Expected case, accents and punctuation to be ignored, got similarity %v", got)
+	}
+
+	similar := Cosine(a, embed("qual a cotação do dólar agora"))
+	unrelated := Cosine(a, embed("Conte uma história sobre dragões"))
+	if similar < 0.6 || unrelated > 0.3 || similar <= unrelated {
+		t.Errorf("Expected similar wording to score higher, got %v (similar) and %v (unrelated)", similar, unrelated)
+	}
+}
@@ -0,0 +1,109 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/clotilde/carplay-assistant/internal/tracing"
+)
+
+const (
+	openAIDefaultBaseURL    = "https://api.openai.com/v1"
+	openAIDefaultModel      = "text-embedding-3-small"
+	openAIDefaultDimensions = 512 // Enough to tell paraphrases apart, a third of the memory of the full 1536
+)
+
+// OpenAI computes embeddings with the OpenAI embeddings API
+type OpenAI struct {
+	APIKey     string
+	BaseURL    string // Defaults to https://api.openai.com/v1 (overridable for tests)
+	Model      string // Defaults to text-embedding-3-small
+	Dimensions int    // Vector size requested from text-embedding-3 models
+	client     *http.Client
+}
+
+// openAIEmbeddingRequest is the body of POST /embeddings
+type openAIEmbeddingRequest struct {
+	Model      string `json:"model"`
+	Input      string `json:"input"`
+	Dimensions int    `json:"dimensions,omitempty"`
+}
+
+// openAIEmbeddingResponse is the response of POST /embeddings
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// NewOpenAI creates an OpenAI embedder
+func NewOpenAI(apiKey, model string) *OpenAI {
+	if model == "" {
+		model = openAIDefaultModel
+	}
+	return &OpenAI{
+		APIKey:     apiKey,
+		BaseURL:    openAIDefaultBaseURL,
+		Model:      model,
+		Dimensions: openAIDefaultDimensions,
+		// Embeddings are on the request path: a slow answer is worse than a cache miss
+		client: &http.Client{Timeout: 3 * time.Second, Transport: tracing.Transport(nil)},
+	}
+}
+
+// Name returns the backend identifier
+func (e *OpenAI) Name() string {
+	return "openai"
+}
+
+// Embed returns the embedding of text
+func (e *OpenAI) Embed(ctx context.Context, text string) ([]float32, error) {
+	if e.APIKey == "" {
+		return nil, fmt.Errorf("OpenAI API key not configured")
+	}
+
+	body, err := json.Marshal(openAIEmbeddingRequest{Model: e.Model, Input: text, Dimensions: e.Dimensions})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", e.BaseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+e.APIKey)
+
+	resp, err := e.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make embedding request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedding response: %w", err)
+	}
+
+	var result openAIEmbeddingResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse embedding response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if result.Error != nil {
+			return nil, fmt.Errorf("embedding API error (status %d): %s", resp.StatusCode, result.Error.Message)
+		}
+		return nil, fmt.Errorf("embedding API error (status %d)", resp.StatusCode)
+	}
+	if len(result.Data) == 0 || len(result.Data[0].Embedding) == 0 {
+		return nil, fmt.Errorf("no embedding in response")
+	}
+	return result.Data[0].Embedding, nil
+}
@@ -0,0 +1,61 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAI_Embed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			t.Errorf("Expected path /embeddings, got %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("Expected bearer token, got %q", r.Header.Get("Authorization"))
+		}
+		var body openAIEmbeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("Expected JSON body: %v", err)
+		}
+		if body.Model != "text-embedding-3-small" || body.Dimensions != 512 || body.Input != "cotação do dólar" {
+			t.Errorf("Unexpected request: %+v", body)
+		}
+		w.Write([]byte(`{"data": [{"embedding": [0.1, -0.2, 0.3]}], "usage": {"prompt_tokens": 4}}`))
+	}))
+	defer server.Close()
+
+	e := NewOpenAI("test-key", "")
+	e.BaseURL = server.URL
+
+	vector, err := e.Embed(context.Background(), "cotação do dólar")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(vector) != 3 || vector[1] != -0.2 {
+		t.Errorf("Unexpected embedding: %v", vector)
+	}
+}
+
+func TestOpenAI_EmbedError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": {"message": "Incorrect API key provided"}}`))
+	}))
+	defer server.Close()
+
+	e := NewOpenAI("bad-key", "")
+	e.BaseURL = server.URL
+
+	_, err := e.Embed(context.Background(), "oi")
+	if err == nil || !strings.Contains(err.Error(), "Incorrect API key") {
+		t.Errorf("Expected the API error message, got %v", err)
+	}
+
+	if _, err := NewOpenAI("", "").Embed(context.Background(), "oi"); err == nil {
+		t.Error("Expected an error without API key")
+	}
+}
@@ -120,6 +120,41 @@ func (cl *CloudLogger) Log(entry LogEntry) {
 	if entry.ErrorMessage != "" {
 		payload["error_message"] = entry.ErrorMessage
 	}
+	if entry.KeyID != "" {
+		payload["key_id"] = entry.KeyID
+	}
+	if entry.KeyGeneration != "" {
+		payload["key_generation"] = entry.KeyGeneration
+	}
+	if entry.hasUsage() {
+		payload["input_tokens"] = entry.InputTokens
+		payload["output_tokens"] = entry.OutputTokens
+		payload["reasoning_tokens"] = entry.ReasoningTokens
+		payload["usage_estimated"] = entry.UsageEstimated
+		payload["search_calls"] = entry.SearchCalls
+		payload["perplexity_calls"] = entry.PerplexityCalls
+		payload["cost_usd"] = entry.Cost
+	}
+	if entry.Cache != "" {
+		payload["cache"] = entry.Cache
+	}
+	if entry.CacheSimilarity > 0 {
+		payload["cache_similarity"] = entry.CacheSimilarity
+	}
+	if entry.Fallbacks > 0 {
+		payload["fallback_from"] = entry.FallbackFrom
+		payload["fallbacks"] = entry.Fallbacks
+	}
+	if entry.Hedged {
+		payload["hedged"] = true
+		payload["hedge_winner"] = entry.HedgeWinner
+	}
+	if len(entry.Stages) > 0 {
+		payload["stages_ms"] = entry.Stages
+	}
+	if entry.AuthMethod != "" {
+		payload["auth_method"] = entry.AuthMethod
+	}
 
 	// Determine severity based on status
 	severity := logging.Info
@@ -134,6 +134,15 @@ func convertCloudLogEntry(cloudEntry *loggingpb.LogEntry) *LogEntry {
 	if ipHash, ok := payload["ip_hash"].(string); ok {
 		entry.IPHash = ipHash
 	}
+	if keyID, ok := payload["key_id"].(string); ok {
+		entry.KeyID = keyID
+	}
+	if generation, ok := payload["key_generation"].(string); ok {
+		entry.KeyGeneration = generation
+	}
+	if method, ok := payload["auth_method"].(string); ok {
+		entry.AuthMethod = method
+	}
 	if msgLen, ok := payload["message_length"].(float64); ok {
 		entry.MessageLength = int(msgLen)
 	}
@@ -149,6 +158,53 @@ func convertCloudLogEntry(cloudEntry *loggingpb.LogEntry) *LogEntry {
 	if tokenEst, ok := payload["token_estimate"].(float64); ok {
 		entry.TokenEstimate = int(tokenEst)
 	}
+	if inputTokens, ok := payload["input_tokens"].(float64); ok {
+		entry.InputTokens = int64(inputTokens)
+	}
+	if outputTokens, ok := payload["output_tokens"].(float64); ok {
+		entry.OutputTokens = int64(outputTokens)
+	}
+	if reasoningTokens, ok := payload["reasoning_tokens"].(float64); ok {
+		entry.ReasoningTokens = int64(reasoningTokens)
+	}
+	if estimated, ok := payload["usage_estimated"].(bool); ok {
+		entry.UsageEstimated = estimated
+	}
+	if searchCalls, ok := payload["search_calls"].(float64); ok {
+		entry.SearchCalls = int(searchCalls)
+	}
+	if perplexityCalls, ok := payload["perplexity_calls"].(float64); ok {
+		entry.PerplexityCalls = int(perplexityCalls)
+	}
+	if cost, ok := payload["cost_usd"].(float64); ok {
+		entry.Cost = cost
+	}
+	if cache, ok := payload["cache"].(string); ok {
+		entry.Cache = cache
+	}
+	if similarity, ok := payload["cache_similarity"].(float64); ok {
+		entry.CacheSimilarity = similarity
+	}
+	if fallbackFrom, ok := payload["fallback_from"].(string); ok {
+		entry.FallbackFrom = fallbackFrom
+	}
+	if fallbacks, ok := payload["fallbacks"].(float64); ok {
+		entry.Fallbacks = int(fallbacks)
+	}
+	if hedged, ok := payload["hedged"].(bool); ok {
+		entry.Hedged = hedged
+	}
+	if winner, ok := payload["hedge_winner"].(string); ok {
+		entry.HedgeWinner = winner
+	}
+	if stages, ok := payload["stages_ms"].(map[string]interface{}); ok {
+		entry.Stages = make(map[string]int64, len(stages))
+		for stage, ms := range stages {
+			if ms, ok := ms.(float64); ok {
+				entry.Stages[stage] = int64(ms)
+			}
+		}
+	}
 	if status, ok := payload["status"].(string); ok {
 		entry.Status = status
 	}
@@ -174,6 +230,12 @@ func extractValue(v *structpb.Value) interface{} {
 		return v.NumberValue
 	case *structpb.Value_BoolValue:
 		return v.BoolValue
+	case *structpb.Value_StructValue:
+		fields := make(map[string]interface{}, len(v.StructValue.Fields))
+		for key, value := range v.StructValue.Fields {
+			fields[key] = extractValue(value)
+		}
+		return fields
 	default:
 		return nil
 	}
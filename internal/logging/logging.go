@@ -1,7 +1,9 @@
 package logging
 
 import (
+	"math"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -13,6 +15,9 @@ type LogEntry struct {
 	ID            string    `json:"id"`
 	Timestamp     time.Time `json:"timestamp"`
 	IPHash        string    `json:"ip_hash"`
+	KeyID         string    `json:"key_id,omitempty"`         // ID of the API key used (never the key itself)
+	KeyGeneration string    `json:"key_generation,omitempty"` // Shared key generation used (e.g. Secret Manager version)
+	AuthMethod    string    `json:"auth_method,omitempty"`    // "api_key" or "signature"
 	MessageLength int       `json:"message_length"`
 	Model         string    `json:"model"`
 	Category      string    `json:"category,omitempty"` // Router category (web_search, complex, factual, etc.)
@@ -22,24 +27,144 @@ type LogEntry struct {
 	ErrorMessage  string    `json:"error_message,omitempty"`
 	Input         string    `json:"input,omitempty"`  // Full user input (question)
 	Output        string    `json:"output,omitempty"` // Full AI response
+
+	// Usage reported by the provider (estimated from the text length when it reports none)
+	InputTokens     int64   `json:"input_tokens,omitempty"`
+	OutputTokens    int64   `json:"output_tokens,omitempty"` // Includes reasoning tokens
+	ReasoningTokens int64   `json:"reasoning_tokens,omitempty"`
+	UsageEstimated  bool    `json:"usage_estimated,omitempty"`
+	SearchCalls     int     `json:"search_calls,omitempty"`     // Native web search tool calls
+	PerplexityCalls int     `json:"perplexity_calls,omitempty"` // Perplexity Search API queries
+	Cost            float64 `json:"cost_usd,omitempty"`         // From the configured model and search prices
+	Cache           string  `json:"cache,omitempty"`            // "hit", "semantic_hit" or "miss" for cacheable questions
+	CacheSimilarity float64 `json:"cache_similarity,omitempty"` // Similarity of the closest cached question (semantic cache)
+	FallbackFrom    string  `json:"fallback_from,omitempty"`    // Routed model that failed; Model is the fallback that answered
+	Fallbacks       int     `json:"fallbacks,omitempty"`        // Models that failed with a retryable error before Model
+	Hedged          bool    `json:"hedged,omitempty"`           // The question was also sent to the hedge model after the hedge delay
+	HedgeWinner     string  `json:"hedge_winner,omitempty"`     // HedgePrimary or HedgeSecondary: the leg that answered first
+
+	// Time spent in each stage of the request budget (transcription, embedding, search, generation, synthesis)
+	Stages map[string]int64 `json:"stages_ms,omitempty"`
+}
+
+// Response cache outcomes recorded in LogEntry.Cache
+const (
+	CacheHit         = "hit"
+	CacheSemanticHit = "semantic_hit" // A paraphrase of a cached question
+	CacheMiss        = "miss"
+)
+
+// Legs of a hedged request recorded in LogEntry.HedgeWinner
+const (
+	HedgePrimary   = "primary" // The routed model
+	HedgeSecondary = "hedge"   // The hedge model; LogEntry.Model is the hedge model
+)
+
+// hasUsage reports whether the request consumed tokens or searches
+func (e LogEntry) hasUsage() bool {
+	return e.InputTokens > 0 || e.OutputTokens > 0 || e.SearchCalls > 0 || e.PerplexityCalls > 0 || e.Cost > 0
 }
 
 // Stats represents aggregated statistics
 type Stats struct {
-	TotalRequests      int64     `json:"total_requests"`
-	TotalRequestsToday int64     `json:"total_requests_today"`
-	AvgResponseTimeMs  float64   `json:"avg_response_time_ms"`
-	ErrorRate          float64   `json:"error_rate"`
-	ModelUsage         ModelUsage `json:"model_usage"`
-	Uptime             string    `json:"uptime"`
-	LastRequestTime    *time.Time `json:"last_request_time,omitempty"`
+	TotalRequests      int64            `json:"total_requests"`
+	TotalRequestsToday int64            `json:"total_requests_today"`
+	AvgResponseTimeMs  float64          `json:"avg_response_time_ms"`
+	ErrorRate          float64          `json:"error_rate"`
+	ModelUsage         ModelUsage       `json:"model_usage"`
+	Uptime             string           `json:"uptime"`
+	LastRequestTime    *time.Time       `json:"last_request_time,omitempty"`
+	Costs              CostStats        `json:"costs"`
+	Cache              CacheStats       `json:"cache"`
+	Fallbacks          int64            `json:"fallbacks"`            // Requests answered (or last attempted) by a fallback model
+	P90ResponseTimeMs  int64            `json:"p90_response_time_ms"` // Over the buffered entries
+	P99ResponseTimeMs  int64            `json:"p99_response_time_ms"`
+	Hedges             int64            `json:"hedges"`                 // Requests also sent to the hedge model
+	HedgeWins          int64            `json:"hedge_wins"`             // Hedged requests answered by the hedge model
+	StageAvgMs         map[string]int64 `json:"stage_avg_ms,omitempty"` // Average time of each stage over the buffered entries that ran it
+}
+
+// CacheStats counts response cache lookups since startup
+type CacheStats struct {
+	Hits            int64   `json:"hits"`
+	SemanticHits    int64   `json:"semantic_hits"`
+	Misses          int64   `json:"misses"`
+	HitRate         float64 `json:"hit_rate"`          // Percentage of lookups answered from the cache (exact or semantic)
+	SemanticHitRate float64 `json:"semantic_hit_rate"` // Percentage of lookups answered with a paraphrase's answer
+}
+
+// CostTotals are the usage and cost of a group of requests
+type CostTotals struct {
+	Requests        int64   `json:"requests"`
+	InputTokens     int64   `json:"input_tokens"`
+	OutputTokens    int64   `json:"output_tokens"`
+	ReasoningTokens int64   `json:"reasoning_tokens"`
+	SearchCalls     int64   `json:"search_calls"`
+	PerplexityCalls int64   `json:"perplexity_calls"`
+	Cost            float64 `json:"cost_usd"`
+}
+
+// add counts the usage of one request
+func (t *CostTotals) add(e LogEntry) {
+	t.Requests++
+	t.InputTokens += e.InputTokens
+	t.OutputTokens += e.OutputTokens
+	t.ReasoningTokens += e.ReasoningTokens
+	t.SearchCalls += int64(e.SearchCalls)
+	t.PerplexityCalls += int64(e.PerplexityCalls)
+	t.Cost += e.Cost
+}
+
+// CostStats breaks down the cost of the requests served since startup
+type CostStats struct {
+	CostTotals
+	ByModel    map[string]CostTotals `json:"by_model"`
+	ByCategory map[string]CostTotals `json:"by_category"`
+	ByDay      map[string]CostTotals `json:"by_day"` // "2006-01-02" in local time, last costDays days
+}
+
+// costDays is how many days of daily cost totals are kept
+const costDays = 31
+
+// pruneDays drops the oldest daily totals beyond costDays
+func (c *CostStats) pruneDays() {
+	if len(c.ByDay) <= costDays {
+		return
+	}
+	days := make([]string, 0, len(c.ByDay))
+	for day := range c.ByDay {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	for _, day := range days[:len(days)-costDays] {
+		delete(c.ByDay, day)
+	}
+}
+
+// addCost counts the usage of one request in the grand total and its model, category and day
+func addCost(totals map[string]CostTotals, key string, e LogEntry) {
+	if key == "" {
+		return
+	}
+	t := totals[key]
+	t.add(e)
+	totals[key] = t
+}
+
+// copyTotals returns a copy of a cost breakdown so it can be read without the lock
+func copyTotals(totals map[string]CostTotals) map[string]CostTotals {
+	result := make(map[string]CostTotals, len(totals))
+	for k, v := range totals {
+		result[k] = v
+	}
+	return result
 }
 
 // ModelUsage tracks usage by model
 type ModelUsage struct {
 	Standard int64 `json:"standard"` // Fast/cheap models (gpt-4o-mini, Claude Haiku, etc.)
 	Premium  int64 `json:"premium"`  // Powerful/expensive models (gpt-4o, Claude Sonnet, etc.)
-	
+
 	// Legacy fields for backward compatibility
 	Nano int64 `json:"nano"` // Deprecated: use Standard
 	Full int64 `json:"full"` // Deprecated: use Premium
@@ -58,6 +183,13 @@ type Logger struct {
 	totalTime     int64
 	standardCount int64 // Fast/cheap models
 	premiumCount  int64 // Powerful/expensive models
+	costs         CostStats
+	cacheHits     int64
+	semanticHits  int64
+	fallbacks     int64
+	cacheMisses   int64
+	hedges        int64
+	hedgeWins     int64
 }
 
 var (
@@ -74,15 +206,25 @@ func GetLogger() *Logger {
 				capacity = size
 			}
 		}
-		globalLogger = &Logger{
-			entries:   make([]LogEntry, capacity),
-			capacity:  capacity,
-			startTime: time.Now(),
-		}
+		globalLogger = newLogger(capacity)
 	})
 	return globalLogger
 }
 
+// newLogger creates a logger keeping the last capacity entries
+func newLogger(capacity int) *Logger {
+	return &Logger{
+		entries:   make([]LogEntry, capacity),
+		capacity:  capacity,
+		startTime: time.Now(),
+		costs: CostStats{
+			ByModel:    make(map[string]CostTotals),
+			ByCategory: make(map[string]CostTotals),
+			ByDay:      make(map[string]CostTotals),
+		},
+	}
+}
+
 // Add adds a new log entry to the ring buffer and Cloud Logging
 func (l *Logger) Add(entry LogEntry) {
 	l.mu.Lock()
@@ -110,13 +252,40 @@ func (l *Logger) Add(entry LogEntry) {
 		strings.Contains(modelLower, "haiku") ||
 		strings.Contains(modelLower, "nano") ||
 		strings.Contains(modelLower, "3.5-turbo")
-	
+
 	if isStandard {
 		l.standardCount++
 	} else {
 		l.premiumCount++
 	}
 
+	if entry.Fallbacks > 0 {
+		l.fallbacks++
+	}
+	if entry.Hedged {
+		l.hedges++
+		if entry.HedgeWinner == HedgeSecondary {
+			l.hedgeWins++
+		}
+	}
+	switch entry.Cache {
+	case CacheHit:
+		l.cacheHits++
+	case CacheSemanticHit:
+		l.semanticHits++
+	case CacheMiss:
+		l.cacheMisses++
+	}
+
+	// Track what the request cost, including errors that still consumed searches
+	if entry.hasUsage() {
+		l.costs.add(entry)
+		addCost(l.costs.ByModel, entry.Model, entry)
+		addCost(l.costs.ByCategory, entry.Category, entry)
+		addCost(l.costs.ByDay, entry.Timestamp.Local().Format("2006-01-02"), entry)
+		l.costs.pruneDays()
+	}
+
 	// Also send to Cloud Logging for persistence
 	go func(e LogEntry) {
 		cloudLogger := GetCloudLogger()
@@ -146,10 +315,10 @@ func (l *Logger) GetEntries(limit, offset int) []LogEntry {
 	}
 
 	result := make([]LogEntry, limit)
-	
+
 	// Start from most recent entry and go backwards
 	startIdx := (l.head - 1 - offset + l.capacity) % l.capacity
-	
+
 	for i := 0; i < limit; i++ {
 		idx := (startIdx - i + l.capacity) % l.capacity
 		result[i] = l.entries[idx]
@@ -168,19 +337,19 @@ func (l *Logger) GetEntriesFiltered(limit, offset int, model, status string, sta
 	}
 
 	var filtered []LogEntry
-	
+
 	// Start from most recent entry and go backwards
 	startIdx := (l.head - 1 + l.capacity) % l.capacity
-	
+
 	for i := 0; i < l.count; i++ {
 		idx := (startIdx - i + l.capacity) % l.capacity
 		entry := l.entries[idx]
-		
+
 		// Skip entries with zero timestamp (empty slots in ring buffer)
 		if entry.Timestamp.IsZero() {
 			continue
 		}
-		
+
 		// Apply filters
 		if model != "" && entry.Model != model {
 			continue
@@ -194,7 +363,7 @@ func (l *Logger) GetEntriesFiltered(limit, offset int, model, status string, sta
 		if endDate != nil && entry.Timestamp.After(*endDate) {
 			continue
 		}
-		
+
 		filtered = append(filtered, entry)
 	}
 
@@ -202,7 +371,7 @@ func (l *Logger) GetEntriesFiltered(limit, offset int, model, status string, sta
 	if offset >= len(filtered) {
 		return []LogEntry{}
 	}
-	
+
 	filtered = filtered[offset:]
 	if limit > 0 && limit < len(filtered) {
 		filtered = filtered[:limit]
@@ -226,12 +395,26 @@ func (l *Logger) GetStats() Stats {
 			Full: l.premiumCount,
 		},
 		Uptime: time.Since(l.startTime).Round(time.Second).String(),
+		Costs: CostStats{
+			CostTotals: l.costs.CostTotals,
+			ByModel:    copyTotals(l.costs.ByModel),
+			ByCategory: copyTotals(l.costs.ByCategory),
+			ByDay:      copyTotals(l.costs.ByDay),
+		},
+		Cache:     CacheStats{Hits: l.cacheHits, SemanticHits: l.semanticHits, Misses: l.cacheMisses},
+		Fallbacks: l.fallbacks,
+		Hedges:    l.hedges,
+		HedgeWins: l.hedgeWins,
+	}
+	if lookups := l.cacheHits + l.semanticHits + l.cacheMisses; lookups > 0 {
+		stats.Cache.HitRate = float64(l.cacheHits+l.semanticHits) / float64(lookups) * 100
+		stats.Cache.SemanticHitRate = float64(l.semanticHits) / float64(lookups) * 100
 	}
 
 	// Calculate today's requests
 	now := time.Now()
 	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-	
+
 	for i := 0; i < l.count; i++ {
 		idx := (l.head - 1 - i + l.capacity) % l.capacity
 		entry := l.entries[idx]
@@ -249,6 +432,8 @@ func (l *Logger) GetStats() Stats {
 		stats.AvgResponseTimeMs = float64(l.totalTime) / float64(l.totalCount)
 		stats.ErrorRate = float64(l.errorCount) / float64(l.totalCount) * 100
 	}
+	stats.P90ResponseTimeMs, stats.P99ResponseTimeMs = l.responseTimePercentiles()
+	stats.StageAvgMs = l.stageAverages()
 
 	// Get last request time (find most recent valid entry)
 	if l.count > 0 {
@@ -265,10 +450,59 @@ func (l *Logger) GetStats() Stats {
 	return stats
 }
 
+// responseTimePercentiles returns the p90 and p99 response times of the buffered entries
+// A driver waits in silence for the slowest answers, so the tail matters more than the average
+// The caller must hold l.mu
+func (l *Logger) responseTimePercentiles() (p90, p99 int64) {
+	times := make([]int64, 0, l.count)
+	for i := 0; i < l.count; i++ {
+		if entry := l.entries[i]; !entry.Timestamp.IsZero() {
+			times = append(times, entry.ResponseTime)
+		}
+	}
+	if len(times) == 0 {
+		return 0, 0
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	return Percentile(times, 90), Percentile(times, 99)
+}
+
+// stageAverages returns the average time of each stage over the buffered entries that ran it
+// The caller must hold l.mu
+func (l *Logger) stageAverages() map[string]int64 {
+	totals := make(map[string]int64)
+	counts := make(map[string]int64)
+	for i := 0; i < l.count; i++ {
+		for stage, ms := range l.entries[i].Stages {
+			totals[stage] += ms
+			counts[stage]++
+		}
+	}
+	if len(totals) == 0 {
+		return nil
+	}
+	averages := make(map[string]int64, len(totals))
+	for stage, total := range totals {
+		averages[stage] = total / counts[stage]
+	}
+	return averages
+}
+
+// Percentile returns the nearest-rank percentile (0-100) of sorted values
+func Percentile(sorted []int64, percentile float64) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(percentile/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
 // GetCount returns the current number of entries
 func (l *Logger) GetCount() int {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 	return l.count
 }
-
@@ -0,0 +1,121 @@
+package logging
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLogger_CostStats(t *testing.T) {
+	l := newLogger(10)
+	day := time.Date(2026, 10, 17, 12, 0, 0, 0, time.Local)
+
+	l.Add(LogEntry{Timestamp: day, Model: "gpt-4o-mini", Category: "web_search", Status: "success",
+		InputTokens: 1000, OutputTokens: 200, ReasoningTokens: 50, PerplexityCalls: 1, Cost: 0.0104})
+	l.Add(LogEntry{Timestamp: day, Model: "gpt-4o", Category: "complex", Status: "success",
+		InputTokens: 500, OutputTokens: 100, SearchCalls: 2, Cost: 0.0233})
+	l.Add(LogEntry{Timestamp: day.Add(24 * time.Hour), Model: "gpt-4o-mini", Category: "simple", Status: "success",
+		InputTokens: 100, OutputTokens: 10, Cost: 0.0001})
+	// Rejected before any model call: nothing to charge
+	l.Add(LogEntry{Timestamp: day, Status: "error", ErrorMessage: "Invalid request body"})
+
+	costs := l.GetStats().Costs
+	if costs.Requests != 3 || costs.InputTokens != 1600 || costs.OutputTokens != 310 || costs.ReasoningTokens != 50 {
+		t.Errorf("Unexpected totals: %+v", costs.CostTotals)
+	}
+	if costs.SearchCalls != 2 || costs.PerplexityCalls != 1 {
+		t.Errorf("Expected 2 native searches and 1 Perplexity query, got %+v", costs.CostTotals)
+	}
+	if math.Abs(costs.Cost-0.0338) > 1e-9 {
+		t.Errorf("Expected $0.0338 in total, got %v", costs.Cost)
+	}
+
+	mini := costs.ByModel["gpt-4o-mini"]
+	if mini.Requests != 2 || math.Abs(mini.Cost-0.0105) > 1e-9 {
+		t.Errorf("Unexpected gpt-4o-mini totals: %+v", mini)
+	}
+	if costs.ByCategory["complex"].SearchCalls != 2 || len(costs.ByCategory) != 3 {
+		t.Errorf("Unexpected category totals: %+v", costs.ByCategory)
+	}
+	if costs.ByDay["2026-10-17"].Requests != 2 || costs.ByDay["2026-10-18"].Requests != 1 {
+		t.Errorf("Unexpected daily totals: %+v", costs.ByDay)
+	}
+}
+
+func TestLogger_CostDaysPruned(t *testing.T) {
+	l := newLogger(10)
+	start := time.Date(2026, 9, 1, 12, 0, 0, 0, time.Local)
+	for i := 0; i < costDays+5; i++ {
+		l.Add(LogEntry{Timestamp: start.AddDate(0, 0, i), Model: "gpt-4o-mini", Cost: 0.01})
+	}
+
+	costs := l.GetStats().Costs
+	if len(costs.ByDay) != costDays {
+		t.Errorf("Expected %d days kept, got %d", costDays, len(costs.ByDay))
+	}
+	if _, ok := costs.ByDay["2026-09-01"]; ok {
+		t.Error("Expected the oldest day to be dropped")
+	}
+	if costs.Requests != int64(costDays+5) {
+		t.Errorf("Pruning days should not change the totals, got %d requests", costs.Requests)
+	}
+}
+
+func TestLogger_FallbackStats(t *testing.T) {
+	l := newLogger(10)
+	l.Add(LogEntry{Timestamp: time.Now(), Model: "gpt-4o-mini", FallbackFrom: "claude-haiku-4-5-20251001", Fallbacks: 1, Status: "success"})
+	l.Add(LogEntry{Timestamp: time.Now(), Model: "claude-haiku-4-5-20251001", Status: "success"})
+
+	if got := l.GetStats().Fallbacks; got != 1 {
+		t.Errorf("Expected 1 request answered by a fallback model, got %d", got)
+	}
+}
+
+func TestLogger_CacheStats(t *testing.T) {
+	l := newLogger(10)
+	l.Add(LogEntry{Timestamp: time.Now(), Cache: CacheMiss, Cost: 0.01})
+	l.Add(LogEntry{Timestamp: time.Now(), Cache: CacheHit})
+	l.Add(LogEntry{Timestamp: time.Now(), Cache: CacheHit})
+	l.Add(LogEntry{Timestamp: time.Now(), Cache: CacheSemanticHit, CacheSimilarity: 0.95})
+	l.Add(LogEntry{Timestamp: time.Now()}) // Not cacheable
+
+	stats := l.GetStats().Cache
+	if stats.Hits != 2 || stats.SemanticHits != 1 || stats.Misses != 1 {
+		t.Errorf("Expected 2 hits, 1 semantic hit and 1 miss, got %+v", stats)
+	}
+	if math.Abs(stats.HitRate-75) > 1e-9 || math.Abs(stats.SemanticHitRate-25) > 1e-9 {
+		t.Errorf("Expected 75%% of lookups answered from the cache, 25%% by a paraphrase, got %+v", stats)
+	}
+}
+
+func TestLogger_ResponseTimePercentiles(t *testing.T) {
+	l := newLogger(200)
+	for i := 1; i <= 100; i++ {
+		l.Add(LogEntry{Timestamp: time.Now(), ResponseTime: int64(i * 10), Status: "success"})
+	}
+	l.Add(LogEntry{Timestamp: time.Now(), ResponseTime: 2000, Hedged: true, HedgeWinner: HedgeSecondary})
+	l.Add(LogEntry{Timestamp: time.Now(), ResponseTime: 900, Hedged: true, HedgeWinner: HedgePrimary})
+
+	stats := l.GetStats()
+	if stats.P90ResponseTimeMs != 910 || stats.P99ResponseTimeMs != 1000 {
+		t.Errorf("Expected p90 910ms and p99 1000ms, got %d and %d", stats.P90ResponseTimeMs, stats.P99ResponseTimeMs)
+	}
+	if stats.Hedges != 2 || stats.HedgeWins != 1 {
+		t.Errorf("Expected 2 hedges with 1 won by the hedge model, got %d and %d", stats.Hedges, stats.HedgeWins)
+	}
+}
+
+func TestLogger_StageAverages(t *testing.T) {
+	l := newLogger(10)
+	l.Add(LogEntry{Timestamp: time.Now(), Stages: map[string]int64{"search": 3000, "generation": 2000}})
+	l.Add(LogEntry{Timestamp: time.Now(), Stages: map[string]int64{"generation": 4000, "synthesis": 900}})
+	l.Add(LogEntry{Timestamp: time.Now()}) // Rejected before any stage
+
+	averages := l.GetStats().StageAvgMs
+	if averages["search"] != 3000 || averages["generation"] != 3000 || averages["synthesis"] != 900 || len(averages) != 3 {
+		t.Errorf("Expected each stage averaged over the requests that ran it, got %v", averages)
+	}
+	if newLogger(10).GetStats().StageAvgMs != nil {
+		t.Error("Expected no stage averages without entries")
+	}
+}
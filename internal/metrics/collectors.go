@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics exported by Clotilde, registered in the default registry and scraped from /metrics
+
+var (
+	// HTTPRequests counts responses by route and HTTP status (see Middleware)
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "clotilde_http_requests_total",
+		Help: "HTTP responses by route and status code.",
+	}, []string{"path", "status"})
+
+	// RequestDuration is the latency of answered chat requests, including transcription and synthesis
+	RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "clotilde_request_duration_seconds",
+		Help:    "Latency of chat requests that reached a model or the cache, by category, model and provider.",
+		Buckets: DefaultBuckets,
+	}, []string{"category", "model", "provider"})
+
+	// PromptInjections counts messages with a prompt injection attempt
+	PromptInjections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "clotilde_prompt_injections_total",
+		Help: "Prompt injection attempts, neutralized or rejected.",
+	}, []string{"action"})
+
+	// RateLimitRejections counts requests refused by the rate limiters
+	RateLimitRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "clotilde_rate_limit_rejections_total",
+		Help: "Requests rejected by the pre-auth (per IP) or client (per API key) rate limiter.",
+	}, []string{"limiter"})
+
+	// PerplexityFallbacks counts web searches that could not use Perplexity
+	PerplexityFallbacks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "clotilde_perplexity_fallbacks_total",
+		Help: "Web searches answered without Perplexity results, by what was used instead.",
+	}, []string{"fallback"})
+
+	// CacheLookups counts response cache lookups by outcome
+	CacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "clotilde_cache_lookups_total",
+		Help: "Response cache lookups by result (hit, semantic_hit or miss).",
+	}, []string{"result"})
+
+	// UpstreamResponses counts calls to model providers and Perplexity by outcome
+	UpstreamResponses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "clotilde_upstream_responses_total",
+		Help: "Upstream calls by upstream and HTTP status code (or timeout, canceled, error).",
+	}, []string{"upstream", "status"})
+)
+
+// Label values of PromptInjections
+const (
+	InjectionNeutralized = "neutralized"
+	InjectionRejected    = "rejected"
+)
+
+// Label values of RateLimitRejections
+const (
+	LimiterPreAuth = "pre_auth"
+	LimiterClient  = "client"
+)
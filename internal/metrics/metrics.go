@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// DefaultBuckets are latency buckets in seconds, from 100ms to the 25s request budget
+var DefaultBuckets = []float64{0.1, 0.25, 0.5, 1, 2, 3, 5, 8, 12, 18, 25}
+
+// Handler serves the metrics for Prometheus scrapes: Clotilde's collectors and the standard Go
+// runtime and process collectors of the default registry
+// When token is set, scrapes must send it as a bearer token
+func Handler(token string) http.Handler {
+	scrape := promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if token != "" && !validToken(r.Header.Get("Authorization"), token) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		scrape.ServeHTTP(w, r)
+	})
+}
+
+// validToken reports whether an Authorization header carries the bearer token
+func validToken(header, token string) bool {
+	presented, ok := strings.CutPrefix(header, "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1
+}
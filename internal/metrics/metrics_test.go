@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	CacheLookups.WithLabelValues("hit").Inc()
+
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		want   int
+	}{
+		{"no token configured", "", "", http.StatusOK},
+		{"valid token", "s3cret", "Bearer s3cret", http.StatusOK},
+		{"missing token", "s3cret", "", http.StatusUnauthorized},
+		{"wrong token", "s3cret", "Bearer guess", http.StatusUnauthorized},
+		{"basic auth", "s3cret", "Basic czNjcmV0", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/metrics", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rr := httptest.NewRecorder()
+			Handler(tt.token).ServeHTTP(rr, req)
+			if rr.Code != tt.want {
+				t.Fatalf("Expected %d, got %d", tt.want, rr.Code)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			for _, series := range []string{`clotilde_cache_lookups_total{result="hit"}`, "go_goroutines", "process_cpu_seconds_total"} {
+				if !strings.Contains(rr.Body.String(), series) {
+					t.Errorf("Expected %s in the scrape, got:\n%s", series, rr.Body.String())
+				}
+			}
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	Handler("").ServeHTTP(rr, httptest.NewRequest("POST", "/metrics", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// routes are the paths counted under their own name; everything else is "other" so scanners
+// probing random URLs cannot create unbounded series
+var routes = map[string]bool{
+	"/chat":        true,
+	"/chat/stream": true,
+	"/chat/audio":  true,
+	"/health":      true,
+	"/api/config":  true,
+}
+
+// routeLabel returns the path label of a request
+func routeLabel(path string) string {
+	switch {
+	case routes[path]:
+		return path
+	case path == "/admin" || strings.HasPrefix(path, "/admin/"):
+		return "/admin"
+	}
+	return "other"
+}
+
+// requestLabels are the labels of a chat request, known once it has been routed
+type requestLabels struct {
+	mu                        sync.Mutex
+	category, model, provider string
+	set                       bool
+}
+
+type contextKey struct{}
+
+// SetRequestLabels records the category, model and provider that served a chat request, so its
+// latency is observed in RequestDuration when the response is complete
+func SetRequestLabels(ctx context.Context, category, model, provider string) {
+	labels, ok := ctx.Value(contextKey{}).(*requestLabels)
+	if !ok {
+		return
+	}
+	labels.mu.Lock()
+	defer labels.mu.Unlock()
+	labels.category, labels.model, labels.provider, labels.set = category, model, provider, true
+}
+
+// statusWriter records the status code written by the handlers
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush keeps Server-Sent Events streaming through the wrapper
+func (w *statusWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Middleware counts every response by route and status, and observes the latency of chat
+// requests that were routed (see SetRequestLabels)
+// It must wrap the whole chain so rejections by the auth and rate limit middlewares are counted
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		labels := &requestLabels{}
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), contextKey{}, labels)))
+
+		status := sw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		HTTPRequests.WithLabelValues(routeLabel(r.URL.Path), strconv.Itoa(status)).Inc()
+
+		labels.mu.Lock()
+		defer labels.mu.Unlock()
+		if labels.set {
+			RequestDuration.WithLabelValues(labels.category, labels.model, labels.provider).Observe(time.Since(start).Seconds())
+		}
+	})
+}
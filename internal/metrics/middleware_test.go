@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// observations returns the number of latencies observed in a RequestDuration series
+func observations(t *testing.T, labels ...string) uint64 {
+	t.Helper()
+	var m dto.Metric
+	if err := RequestDuration.WithLabelValues(labels...).(prometheus.Metric).Write(&m); err != nil {
+		t.Fatalf("Failed to read the histogram: %v", err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestRouteLabel(t *testing.T) {
+	tests := map[string]string{
+		"/chat":          "/chat",
+		"/chat/audio":    "/chat/audio",
+		"/admin/stats":   "/admin",
+		"/administrator": "other",
+		"/wp-login.php":  "other",
+	}
+	for path, want := range tests {
+		if got := routeLabel(path); got != want {
+			t.Errorf("routeLabel(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("routed") != "" {
+			SetRequestLabels(r.Context(), "simple", "test-model", "test-provider")
+		}
+		if r.URL.Query().Get("reject") != "" {
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("Expected the response writer to keep supporting streaming")
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	before := testutil.ToFloat64(HTTPRequests.WithLabelValues("/chat", "429"))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/chat?reject=1", nil))
+	if got := testutil.ToFloat64(HTTPRequests.WithLabelValues("/chat", "429")); got != before+1 {
+		t.Errorf("Expected the rejection counted, got %v (was %v)", got, before)
+	}
+
+	// Only routed requests have a latency by category, model and provider
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/chat", nil))
+	if observations(t, "simple", "test-model", "test-provider") != 0 {
+		t.Error("Expected no latency observed for a request that was not routed")
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/chat?routed=1", nil))
+	if observations(t, "simple", "test-model", "test-provider") != 1 {
+		t.Error("Expected the latency of the routed request observed")
+	}
+}
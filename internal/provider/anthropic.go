@@ -0,0 +1,272 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/clotilde/carplay-assistant/internal/tracing"
+)
+
+const (
+	anthropicDefaultBaseURL = "https://api.anthropic.com/v1"
+	anthropicAPIVersion     = "2023-06-01"
+	anthropicMaxTokens      = 500 // Keep responses concise for CarPlay
+)
+
+// anthropicModels lists the Claude models that can be selected in the runtime config
+// Claude models have no native web search; web search is provided via Perplexity results
+var anthropicModels = catalog{
+	// Claude Haiku 4.5 is extremely fast (~1-3s), best for CarPlay
+	{ID: "claude-haiku-4-5-20251001", Capabilities: Capabilities{ExternalSearch: true}},
+	// Older Claude models (for backward compatibility)
+	{ID: "claude-3-5-haiku-20241022", Capabilities: Capabilities{ExternalSearch: true}},
+	{ID: "claude-3-5-haiku-latest", Capabilities: Capabilities{ExternalSearch: true}},
+	{ID: "claude-3-5-sonnet-20241022", Capabilities: Capabilities{ExternalSearch: true}},
+	{ID: "claude-3-5-sonnet-latest", Capabilities: Capabilities{ExternalSearch: true}},
+	{ID: "claude-sonnet-4-20250514", Capabilities: Capabilities{ExternalSearch: true}}, // Claude Sonnet 4
+	{ID: "claude-3-opus-20240229", Capabilities: Capabilities{ExternalSearch: true}},   // Most capable but slower
+}
+
+// Anthropic implements Provider using the Claude Messages API
+type Anthropic struct {
+	APIKey  string
+	BaseURL string // Defaults to https://api.anthropic.com/v1 (overridable for tests)
+	client  *http.Client
+}
+
+// NewAnthropic creates a Claude Messages API provider
+func NewAnthropic(apiKey string) *Anthropic {
+	return &Anthropic{
+		APIKey:  apiKey,
+		BaseURL: anthropicDefaultBaseURL,
+		client:  &http.Client{Timeout: ClientTimeout, Transport: tracing.Transport(nil)},
+	}
+}
+
+// claudeRequest represents the request body for Claude Messages API
+type claudeRequest struct {
+	Model     string          `json:"model"`
+	MaxTokens int             `json:"max_tokens"`
+	System    string          `json:"system,omitempty"`
+	Messages  []claudeMessage `json:"messages"`
+	Stream    bool            `json:"stream,omitempty"`
+}
+
+// claudeMessage represents a message in the Claude conversation
+type claudeMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// claudeResponse represents the response from Claude Messages API
+type claudeResponse struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Role    string `json:"role"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string      `json:"stop_reason"`
+	Usage      claudeUsage `json:"usage"`
+	Error      *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// claudeUsage is the token usage block of a Messages API response
+type claudeUsage struct {
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+}
+
+// Name returns the provider identifier
+func (p *Anthropic) Name() string {
+	return "anthropic"
+}
+
+// Models returns the Claude model catalog
+func (p *Anthropic) Models() []Model {
+	models := make([]Model, len(anthropicModels))
+	for i, m := range anthropicModels {
+		m.Provider = p.Name()
+		models[i] = m
+	}
+	return models
+}
+
+// Model reports whether the model is a Claude model
+func (p *Anthropic) Model(id string) (Model, bool) {
+	m, ok := anthropicModels.find(id)
+	m.Provider = p.Name()
+	return m, ok
+}
+
+// Generate makes a request to Claude Messages API (Anthropic)
+// Claude Haiku 4.5 is extremely fast (~1-3s) and ideal for CarPlay where speed is critical
+func (p *Anthropic) Generate(ctx context.Context, req Request) (Response, error) {
+	resp, err := p.send(ctx, p.buildRequest(req, false))
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+
+	// Read response body
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to read Claude response: %w", err)
+	}
+
+	// Check HTTP status
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Claude API returned status %d: %s", resp.StatusCode, string(body))
+		return Response{}, statusError("Claude API", resp.StatusCode)
+	}
+
+	// Parse response
+	var claudeResp claudeResponse
+	if err := json.Unmarshal(body, &claudeResp); err != nil {
+		log.Printf("Failed to parse Claude response body: %s", string(body))
+		return Response{}, fmt.Errorf("failed to parse Claude response: %w", err)
+	}
+
+	// Check for API-level errors
+	if claudeResp.Error != nil {
+		return Response{}, &Error{Message: fmt.Sprintf("Claude API error: %s (type: %s)", claudeResp.Error.Message, claudeResp.Error.Type), Type: claudeResp.Error.Type}
+	}
+
+	// Extract text from response content
+	for _, content := range claudeResp.Content {
+		if content.Type == "text" && content.Text != "" {
+			usage := Usage{InputTokens: claudeResp.Usage.InputTokens, OutputTokens: claudeResp.Usage.OutputTokens}
+			return Response{Text: content.Text, Usage: usage}, nil
+		}
+	}
+
+	log.Printf("Empty response from Claude. Full response: %s", string(body))
+	return Response{}, fmt.Errorf("empty response from Claude")
+}
+
+// claudeStreamEvent is the subset of a Messages API streaming event we use
+type claudeStreamEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+	Message *struct {
+		Usage claudeUsage `json:"usage"`
+	} `json:"message,omitempty"` // Set on "message_start" with the input tokens
+	Usage *claudeUsage `json:"usage,omitempty"` // Set on "message_delta" with the output tokens so far
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// Stream makes a streaming request to Claude Messages API, calling onDelta for each text delta
+func (p *Anthropic) Stream(ctx context.Context, req Request, onDelta func(text string) error) (Response, error) {
+	resp, err := p.send(ctx, p.buildRequest(req, true))
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		log.Printf("Claude API returned status %d: %s", resp.StatusCode, string(body))
+		return Response{}, statusError("Claude API", resp.StatusCode)
+	}
+
+	var text strings.Builder
+	var usage Usage
+	err = readSSE(resp.Body, func(event, data string) error {
+		var ev claudeStreamEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return nil // Ignore keep-alives and events we don't understand
+		}
+		switch ev.Type {
+		case "message_start":
+			if ev.Message != nil {
+				usage.InputTokens = ev.Message.Usage.InputTokens
+				usage.OutputTokens = ev.Message.Usage.OutputTokens
+			}
+		case "message_delta":
+			if ev.Usage != nil {
+				usage.OutputTokens = ev.Usage.OutputTokens // Cumulative
+			}
+		case "content_block_delta":
+			if ev.Delta.Type == "text_delta" && ev.Delta.Text != "" {
+				text.WriteString(ev.Delta.Text)
+				return onDelta(ev.Delta.Text)
+			}
+		case "error":
+			if ev.Error != nil {
+				return &Error{Message: fmt.Sprintf("Claude API error: %s (type: %s)", ev.Error.Message, ev.Error.Type), Type: ev.Error.Type}
+			}
+			return fmt.Errorf("Claude API stream error")
+		case "message_stop":
+			return errStreamDone
+		}
+		return nil
+	})
+	if err != nil {
+		return Response{Usage: usage}, err
+	}
+	if text.Len() == 0 {
+		return Response{Usage: usage}, fmt.Errorf("empty response from Claude")
+	}
+	return Response{Text: text.String(), Usage: usage}, nil
+}
+
+// buildRequest builds the Messages API body (history first, then the current question)
+func (p *Anthropic) buildRequest(req Request, stream bool) claudeRequest {
+	reqBody := claudeRequest{
+		Model:     req.Model,
+		MaxTokens: anthropicMaxTokens,
+		System:    req.Instructions,
+		Stream:    stream,
+	}
+	for _, m := range req.History {
+		reqBody.Messages = append(reqBody.Messages, claudeMessage{Role: m.Role, Content: m.Content})
+	}
+	reqBody.Messages = append(reqBody.Messages, claudeMessage{Role: RoleUser, Content: req.Input})
+	return reqBody
+}
+
+// send POSTs a request body to the Messages API; the caller closes the response body
+func (p *Anthropic) send(ctx context.Context, reqBody claudeRequest) (*http.Response, error) {
+	if p.APIKey == "" {
+		return nil, fmt.Errorf("Claude API key not configured")
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Claude request: %w", err)
+	}
+
+	log.Printf("Claude API request: model=%s, max_tokens=%d, stream=%v", reqBody.Model, reqBody.MaxTokens, reqBody.Stream)
+
+	// Create HTTP request to Claude Messages API
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.BaseURL+"/messages", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Claude request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", p.APIKey)
+	httpReq.Header.Set("anthropic-version", anthropicAPIVersion)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make Claude request: %w", err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,151 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAnthropic_Generate(t *testing.T) {
+	var captured claudeRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/messages" {
+			t.Errorf("Expected path /messages, got %s", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "test-key" {
+			t.Errorf("Expected x-api-key header, got %q", r.Header.Get("x-api-key"))
+		}
+		if r.Header.Get("anthropic-version") == "" {
+			t.Error("Expected anthropic-version header")
+		}
+		json.NewDecoder(r.Body).Decode(&captured)
+		w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"Bom dia!"}],"usage":{"input_tokens":42,"output_tokens":7}}`))
+	}))
+	defer server.Close()
+
+	p := NewAnthropic("test-key")
+	p.BaseURL = server.URL
+
+	resp, err := p.Generate(context.Background(), Request{
+		Model:        "claude-haiku-4-5-20251001",
+		Instructions: "Sistema",
+		Input:        "Oi",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.Text != "Bom dia!" {
+		t.Errorf("Expected 'Bom dia!', got %q", resp.Text)
+	}
+	if resp.Usage != (Usage{InputTokens: 42, OutputTokens: 7}) {
+		t.Errorf("Unexpected usage: %+v", resp.Usage)
+	}
+	if captured.System != "Sistema" {
+		t.Errorf("Expected system prompt to be sent, got %q", captured.System)
+	}
+	if len(captured.Messages) != 1 || captured.Messages[0].Role != "user" || captured.Messages[0].Content != "Oi" {
+		t.Errorf("Expected single user message, got %+v", captured.Messages)
+	}
+}
+
+func TestAnthropic_GenerateWithHistory(t *testing.T) {
+	var captured claudeRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&captured)
+		w.Write([]byte(`{"content":[{"type":"text","text":"Em Curitiba também."}]}`))
+	}))
+	defer server.Close()
+
+	p := NewAnthropic("test-key")
+	p.BaseURL = server.URL
+
+	_, err := p.Generate(context.Background(), Request{
+		Model: "claude-haiku-4-5-20251001",
+		History: []Message{
+			{Role: RoleUser, Content: "Vai chover em SP?"},
+			{Role: RoleAssistant, Content: "Sim, à tarde."},
+		},
+		Input: "E em Curitiba?",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(captured.Messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %+v", captured.Messages)
+	}
+	if captured.Messages[1].Role != "assistant" || captured.Messages[2].Content != "E em Curitiba?" {
+		t.Errorf("Expected history followed by the current question, got %+v", captured.Messages)
+	}
+}
+
+func TestAnthropic_Stream(t *testing.T) {
+	var captured claudeRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&captured)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":25,\"output_tokens\":1}}}\n\n" +
+			"event: ping\ndata: {\"type\":\"ping\"}\n\n" +
+			"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Bom \"}}\n\n" +
+			"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"dia!\"}}\n\n" +
+			"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":4}}\n\n" +
+			"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"))
+	}))
+	defer server.Close()
+
+	p := NewAnthropic("test-key")
+	p.BaseURL = server.URL
+
+	var deltas []string
+	resp, err := p.Stream(context.Background(), Request{Model: "claude-haiku-4-5-20251001", Input: "Oi"}, func(text string) error {
+		deltas = append(deltas, text)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !captured.Stream {
+		t.Error("Expected stream=true in request body")
+	}
+	if len(deltas) != 2 || resp.Text != "Bom dia!" {
+		t.Errorf("Unexpected stream result: deltas=%q text=%q", deltas, resp.Text)
+	}
+	if resp.Usage != (Usage{InputTokens: 25, OutputTokens: 4}) {
+		t.Errorf("Expected input tokens from message_start and output tokens from message_delta, got %+v", resp.Usage)
+	}
+}
+
+func TestAnthropic_StreamError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":25,\"output_tokens\":1}}}\n\n"))
+		w.Write([]byte("event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n"))
+	}))
+	defer server.Close()
+
+	p := NewAnthropic("test-key")
+	p.BaseURL = server.URL
+
+	resp, err := p.Stream(context.Background(), Request{Model: "claude-haiku-4-5-20251001", Input: "Oi"}, func(string) error { return nil })
+	if err == nil {
+		t.Error("Expected error for mid-stream error event")
+	}
+	if resp.Usage.InputTokens != 25 {
+		t.Errorf("Expected the usage reported before the error, got %+v", resp.Usage)
+	}
+}
+
+func TestAnthropic_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(529)
+		w.Write([]byte(`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`))
+	}))
+	defer server.Close()
+
+	p := NewAnthropic("test-key")
+	p.BaseURL = server.URL
+
+	if _, err := p.Generate(context.Background(), Request{Model: "claude-haiku-4-5-20251001", Input: "Oi"}); err == nil {
+		t.Error("Expected error for overloaded status")
+	}
+}
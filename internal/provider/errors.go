@@ -0,0 +1,75 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// Error is a failure reported by a backend, either as an HTTP status or as an error object in the
+// response (or stream)
+type Error struct {
+	Message    string
+	StatusCode int    // HTTP status (0 when the error came in a successful response or a stream)
+	Type       string // Backend error type or code (e.g. overloaded_error, server_error, UNAVAILABLE)
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// statusError is the error of a non-200 response from service
+func statusError(service string, statusCode int) error {
+	return &Error{Message: fmt.Sprintf("%s returned status %d", service, statusCode), StatusCode: statusCode}
+}
+
+// retryableStatus are HTTP statuses after which the same request may succeed on another attempt or model
+var retryableStatus = map[int]bool{
+	http.StatusRequestTimeout:      true,
+	http.StatusConflict:            true, // Anthropic uses 409 for transient lock contention
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+	529:                            true, // Anthropic: overloaded
+}
+
+// retryableTypes are backend error types and codes of transient failures
+var retryableTypes = map[string]bool{
+	"overloaded_error":    true, // Anthropic
+	"api_error":           true, // Anthropic: unexpected internal error
+	"rate_limit_error":    true, // Anthropic
+	"server_error":        true, // OpenAI
+	"rate_limit_exceeded": true, // OpenAI
+	"UNAVAILABLE":         true, // Gemini
+	"RESOURCE_EXHAUSTED":  true, // Gemini
+	"INTERNAL":            true, // Gemini
+	"DEADLINE_EXCEEDED":   true, // Gemini
+}
+
+// Retryable reports whether a generation error is transient (overload, rate limit, server error,
+// timeout, connection failure), so another model may answer the same request
+// Invalid requests, bad credentials and cancelled requests are not retryable
+func Retryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var backendErr *Error
+	if errors.As(err, &backendErr) {
+		return retryableStatus[backendErr.StatusCode] || retryableTypes[backendErr.Type]
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	// Transport failures (connection refused or reset, DNS) before the backend answered
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
@@ -0,0 +1,52 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"overloaded", statusError("Claude API", 529), true},
+		{"rate limited", statusError("API", http.StatusTooManyRequests), true},
+		{"server error", statusError("Gemini API", http.StatusServiceUnavailable), true},
+		{"bad request", statusError("API", http.StatusBadRequest), false},
+		{"unauthorized", statusError("Claude API", http.StatusUnauthorized), false},
+		{"overloaded in stream", &Error{Message: "Claude API error: Overloaded", Type: "overloaded_error"}, true},
+		{"invalid request in body", &Error{Message: "API error: bad", Type: "invalid_request_error"}, false},
+		{"timeout", fmt.Errorf("failed to make request: %w", context.DeadlineExceeded), true},
+		{"cancelled by the client", context.Canceled, false},
+		{"parse error", errors.New("failed to parse response"), false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Retryable(tt.err); got != tt.want {
+				t.Errorf("Retryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryable_ConnectionRefused(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	p := NewLocal(url, "", []string{"test"})
+	_, err := p.Generate(context.Background(), Request{Model: "local:test", Input: "Oi"})
+	if err == nil || !Retryable(err) {
+		t.Errorf("Expected a retryable connection error, got %v", err)
+	}
+	if err := statusError("local model server", 502); err.Error() != "local model server returned status 502" {
+		t.Errorf("Unexpected status error message: %q", err.Error())
+	}
+}
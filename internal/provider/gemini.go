@@ -0,0 +1,216 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/clotilde/carplay-assistant/internal/tracing"
+)
+
+const (
+	geminiDefaultBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+	geminiMaxTokens      = 500 // Keep responses concise for CarPlay
+)
+
+// geminiModels lists the Gemini models that can be selected in the runtime config
+// All of them support native Google Search grounding, so web search does not depend on Perplexity
+var geminiModels = catalog{
+	{ID: "gemini-2.5-flash", Capabilities: Capabilities{WebSearch: true}},      // Fast, recommended for CarPlay
+	{ID: "gemini-2.5-flash-lite", Capabilities: Capabilities{WebSearch: true}}, // Fastest/cheapest
+	{ID: "gemini-2.5-pro", Capabilities: Capabilities{WebSearch: true}},        // Most capable but slower
+	{ID: "gemini-2.0-flash", Capabilities: Capabilities{WebSearch: true}},
+}
+
+// Gemini implements Provider using the Google Gemini generateContent API
+type Gemini struct {
+	APIKey  string
+	BaseURL string // Defaults to https://generativelanguage.googleapis.com/v1beta (overridable for tests)
+	client  *http.Client
+}
+
+// NewGemini creates a Gemini generateContent provider
+func NewGemini(apiKey string) *Gemini {
+	return &Gemini{
+		APIKey:  apiKey,
+		BaseURL: geminiDefaultBaseURL,
+		client:  &http.Client{Timeout: ClientTimeout, Transport: tracing.Transport(nil)},
+	}
+}
+
+// geminiRequest represents the request body for generateContent
+type geminiRequest struct {
+	SystemInstruction *geminiContent        `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent       `json:"contents"`
+	Tools             []geminiTool          `json:"tools,omitempty"`
+	GenerationConfig  *geminiGenerationConf `json:"generationConfig,omitempty"`
+}
+
+// geminiContent represents a turn (or the system instruction) in a Gemini conversation
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+// geminiPart represents a text part of a Gemini content
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+// geminiTool enables a Gemini tool; GoogleSearch turns on search grounding
+type geminiTool struct {
+	GoogleSearch *struct{} `json:"google_search,omitempty"`
+}
+
+// geminiGenerationConf controls generation parameters
+type geminiGenerationConf struct {
+	MaxOutputTokens int `json:"maxOutputTokens,omitempty"`
+}
+
+// geminiResponse represents the response from generateContent
+type geminiResponse struct {
+	Candidates []struct {
+		Content           geminiContent `json:"content"`
+		FinishReason      string        `json:"finishReason"`
+		GroundingMetadata *struct {
+			WebSearchQueries []string `json:"webSearchQueries"`
+		} `json:"groundingMetadata,omitempty"`
+	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount     int64 `json:"promptTokenCount"`
+		CandidatesTokenCount int64 `json:"candidatesTokenCount"`
+		ThoughtsTokenCount   int64 `json:"thoughtsTokenCount"`
+	} `json:"usageMetadata"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error,omitempty"`
+}
+
+// Name returns the provider identifier
+func (p *Gemini) Name() string {
+	return "gemini"
+}
+
+// Models returns the Gemini model catalog
+func (p *Gemini) Models() []Model {
+	models := make([]Model, len(geminiModels))
+	for i, m := range geminiModels {
+		m.Provider = p.Name()
+		models[i] = m
+	}
+	return models
+}
+
+// Model reports whether the model is a Gemini model
+func (p *Gemini) Model(id string) (Model, bool) {
+	m, ok := geminiModels.find(id)
+	m.Provider = p.Name()
+	return m, ok
+}
+
+// Generate makes a request to the Gemini generateContent API
+// When req.WebSearch is set, the google_search tool grounds the answer in live search results
+func (p *Gemini) Generate(ctx context.Context, req Request) (Response, error) {
+	if p.APIKey == "" {
+		return Response{}, fmt.Errorf("Gemini API key not configured")
+	}
+
+	reqBody := geminiRequest{
+		GenerationConfig: &geminiGenerationConf{MaxOutputTokens: geminiMaxTokens},
+	}
+	for _, m := range req.History {
+		role := "user"
+		if m.Role == RoleAssistant {
+			role = "model" // Gemini calls the assistant role "model"
+		}
+		reqBody.Contents = append(reqBody.Contents, geminiContent{Role: role, Parts: []geminiPart{{Text: m.Content}}})
+	}
+	reqBody.Contents = append(reqBody.Contents, geminiContent{Role: "user", Parts: []geminiPart{{Text: req.Input}}})
+	if req.Instructions != "" {
+		reqBody.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: req.Instructions}}}
+	}
+	if req.WebSearch {
+		reqBody.Tools = []geminiTool{{GoogleSearch: &struct{}{}}}
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to marshal Gemini request: %w", err)
+	}
+
+	log.Printf("Gemini API request: model=%s, grounding=%v", req.Model, req.WebSearch)
+
+	endpoint := fmt.Sprintf("%s/models/%s:generateContent", p.BaseURL, url.PathEscape(req.Model))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to create Gemini request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-goog-api-key", p.APIKey)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to make Gemini request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to read Gemini response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Gemini API returned status %d: %s", resp.StatusCode, string(body))
+		return Response{}, statusError("Gemini API", resp.StatusCode)
+	}
+
+	var geminiResp geminiResponse
+	if err := json.Unmarshal(body, &geminiResp); err != nil {
+		log.Printf("Failed to parse Gemini response body: %s", string(body))
+		return Response{}, fmt.Errorf("failed to parse Gemini response: %w", err)
+	}
+
+	if geminiResp.Error != nil {
+		return Response{}, &Error{Message: fmt.Sprintf("Gemini API error: %s (status: %s)", geminiResp.Error.Message, geminiResp.Error.Status), Type: geminiResp.Error.Status}
+	}
+
+	// Grounded answers may be split across several text parts
+	for _, candidate := range geminiResp.Candidates {
+		var text string
+		for _, part := range candidate.Content.Parts {
+			text += part.Text
+		}
+		if text != "" {
+			return Response{Text: text, Usage: geminiUsage(geminiResp)}, nil
+		}
+	}
+
+	log.Printf("Empty response from Gemini. Full response: %s", string(body))
+	return Response{}, fmt.Errorf("empty response from Gemini")
+}
+
+// geminiUsage reads the token usage of a generateContent response
+// Thinking tokens are reported apart from the answer but billed as output; a grounded answer
+// counts as one search however many queries it ran
+func geminiUsage(resp geminiResponse) Usage {
+	usage := Usage{
+		InputTokens:     resp.UsageMetadata.PromptTokenCount,
+		OutputTokens:    resp.UsageMetadata.CandidatesTokenCount + resp.UsageMetadata.ThoughtsTokenCount,
+		ReasoningTokens: resp.UsageMetadata.ThoughtsTokenCount,
+	}
+	for _, candidate := range resp.Candidates {
+		if candidate.GroundingMetadata != nil && len(candidate.GroundingMetadata.WebSearchQueries) > 0 {
+			usage.WebSearchCalls = 1
+			break
+		}
+	}
+	return usage
+}
@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGemini_GenerateWithGrounding(t *testing.T) {
+	var captured map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-2.5-flash:generateContent" {
+			t.Errorf("Expected generateContent path, got %s", r.URL.Path)
+		}
+		if r.Header.Get("x-goog-api-key") != "test-key" {
+			t.Errorf("Expected x-goog-api-key header, got %q", r.Header.Get("x-goog-api-key"))
+		}
+		json.NewDecoder(r.Body).Decode(&captured)
+		w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"Segundo o G1, "},{"text":"o dólar fechou em alta."}]},"finishReason":"STOP",` +
+			`"groundingMetadata":{"webSearchQueries":["cotação do dólar hoje"]}}],"usageMetadata":{"promptTokenCount":90,"candidatesTokenCount":20,"thoughtsTokenCount":15}}`))
+	}))
+	defer server.Close()
+
+	p := NewGemini("test-key")
+	p.BaseURL = server.URL
+
+	resp, err := p.Generate(context.Background(), Request{
+		Model:        "gemini-2.5-flash",
+		Instructions: "Sistema",
+		Input:        "Cotação do dólar hoje",
+		WebSearch:    true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.Text != "Segundo o G1, o dólar fechou em alta." {
+		t.Errorf("Expected joined text parts, got %q", resp.Text)
+	}
+	if want := (Usage{InputTokens: 90, OutputTokens: 35, ReasoningTokens: 15, WebSearchCalls: 1}); resp.Usage != want {
+		t.Errorf("Expected usage %+v, got %+v", want, resp.Usage)
+	}
+
+	tools, ok := captured["tools"].([]interface{})
+	if !ok || len(tools) != 1 {
+		t.Fatalf("Expected google_search tool, got %v", captured["tools"])
+	}
+	if _, ok := tools[0].(map[string]interface{})["google_search"]; !ok {
+		t.Errorf("Expected google_search tool, got %v", tools[0])
+	}
+	if _, ok := captured["systemInstruction"]; !ok {
+		t.Error("Expected systemInstruction to be sent")
+	}
+}
+
+func TestGemini_NoGroundingWithoutWebSearch(t *testing.T) {
+	var captured map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&captured)
+		w.Write([]byte(`{"candidates":[{"content":{"parts":[{"text":"Paris"}]}}]}`))
+	}))
+	defer server.Close()
+
+	p := NewGemini("test-key")
+	p.BaseURL = server.URL
+
+	if _, err := p.Generate(context.Background(), Request{Model: "gemini-2.5-flash", Input: "Capital da França"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := captured["tools"]; ok {
+		t.Errorf("Expected no tools without web search, got %v", captured["tools"])
+	}
+}
+
+func TestGemini_HistoryUsesModelRole(t *testing.T) {
+	var captured geminiRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&captured)
+		w.Write([]byte(`{"candidates":[{"content":{"parts":[{"text":"Berlim"}]}}]}`))
+	}))
+	defer server.Close()
+
+	p := NewGemini("test-key")
+	p.BaseURL = server.URL
+
+	_, err := p.Generate(context.Background(), Request{
+		Model:   "gemini-2.5-flash",
+		History: []Message{{Role: RoleUser, Content: "Capital da França"}, {Role: RoleAssistant, Content: "Paris"}},
+		Input:   "E da Alemanha?",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(captured.Contents) != 3 {
+		t.Fatalf("Expected 3 contents, got %+v", captured.Contents)
+	}
+	if captured.Contents[1].Role != "model" || captured.Contents[2].Role != "user" {
+		t.Errorf("Expected assistant turn mapped to 'model', got %+v", captured.Contents)
+	}
+}
+
+func TestGemini_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":{"code":429,"message":"Resource exhausted","status":"RESOURCE_EXHAUSTED"}}`))
+	}))
+	defer server.Close()
+
+	p := NewGemini("test-key")
+	p.BaseURL = server.URL
+
+	if _, err := p.Generate(context.Background(), Request{Model: "gemini-2.5-flash", Input: "Oi"}); err == nil {
+		t.Error("Expected error for 429 status")
+	}
+}
@@ -0,0 +1,178 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/clotilde/carplay-assistant/internal/tracing"
+)
+
+const (
+	// LocalModelPrefix marks model IDs served by the self-hosted provider (e.g. "local:llama3")
+	LocalModelPrefix = "local:"
+	localMaxTokens   = 500 // Keep responses concise for CarPlay
+)
+
+// Local implements Provider for self-hosted models exposed through an OpenAI-compatible
+// Chat Completions endpoint (Ollama, llama.cpp server, vLLM)
+type Local struct {
+	BaseURL string   // e.g. http://localhost:11434/v1
+	APIKey  string   // Optional bearer token (vLLM --api-key)
+	names   []string // Advertised model names (without prefix), for UI display
+	client  *http.Client
+}
+
+// NewLocal creates a provider for a local OpenAI-compatible server
+// models is the optional list of model names advertised to the admin dashboard
+func NewLocal(baseURL, apiKey string, models []string) *Local {
+	return &Local{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		APIKey:  apiKey,
+		names:   models,
+		client:  &http.Client{Timeout: ClientTimeout, Transport: tracing.Transport(nil)},
+	}
+}
+
+// chatCompletionRequest represents the request body for an OpenAI-compatible Chat Completions API
+type chatCompletionRequest struct {
+	Model     string        `json:"model"`
+	Messages  []chatMessage `json:"messages"`
+	MaxTokens int           `json:"max_tokens,omitempty"`
+	Stream    bool          `json:"stream"`
+}
+
+// chatMessage represents a message in a Chat Completions conversation
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatCompletionResponse represents the response from a Chat Completions API
+type chatCompletionResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens            int64 `json:"prompt_tokens"`
+		CompletionTokens        int64 `json:"completion_tokens"`
+		CompletionTokensDetails struct {
+			ReasoningTokens int64 `json:"reasoning_tokens"`
+		} `json:"completion_tokens_details"`
+	} `json:"usage,omitempty"` // Not every server reports usage
+	Error *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error,omitempty"`
+}
+
+// localCapabilities are shared by every self-hosted model
+// Web search relies on Perplexity results, and requests are never rerouted to a cloud model
+var localCapabilities = Capabilities{ExternalSearch: true, Local: true}
+
+// Name returns the provider identifier
+func (p *Local) Name() string {
+	return "local"
+}
+
+// Models returns the advertised local models
+func (p *Local) Models() []Model {
+	models := make([]Model, 0, len(p.names))
+	for _, name := range p.names {
+		models = append(models, Model{ID: LocalModelPrefix + name, Provider: p.Name(), Capabilities: localCapabilities})
+	}
+	return models
+}
+
+// Model accepts any "local:<name>" model ID, since local servers can host arbitrary models
+func (p *Local) Model(id string) (Model, bool) {
+	name := strings.TrimPrefix(id, LocalModelPrefix)
+	if name == id || strings.TrimSpace(name) == "" {
+		return Model{}, false
+	}
+	return Model{ID: id, Provider: p.Name(), Capabilities: localCapabilities}, true
+}
+
+// Generate makes a request to the local Chat Completions endpoint
+func (p *Local) Generate(ctx context.Context, req Request) (Response, error) {
+	if p.BaseURL == "" {
+		return Response{}, fmt.Errorf("local model server not configured")
+	}
+
+	reqBody := chatCompletionRequest{
+		Model:     strings.TrimPrefix(req.Model, LocalModelPrefix),
+		MaxTokens: localMaxTokens,
+	}
+	if req.Instructions != "" {
+		reqBody.Messages = append(reqBody.Messages, chatMessage{Role: "system", Content: req.Instructions})
+	}
+	for _, m := range req.History {
+		reqBody.Messages = append(reqBody.Messages, chatMessage{Role: m.Role, Content: m.Content})
+	}
+	reqBody.Messages = append(reqBody.Messages, chatMessage{Role: RoleUser, Content: req.Input})
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to marshal local request: %w", err)
+	}
+
+	log.Printf("Local model request: model=%s, max_tokens=%d", reqBody.Model, reqBody.MaxTokens)
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.BaseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to create local request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.APIKey != "" {
+		httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.APIKey))
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to make local request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to read local response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Local model server returned status %d: %s", resp.StatusCode, string(body))
+		return Response{}, statusError("local model server", resp.StatusCode)
+	}
+
+	var chatResp chatCompletionResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil {
+		log.Printf("Failed to parse local response body: %s", string(body))
+		return Response{}, fmt.Errorf("failed to parse local response: %w", err)
+	}
+
+	if chatResp.Error != nil {
+		return Response{}, fmt.Errorf("local model error: %s (type: %s)", chatResp.Error.Message, chatResp.Error.Type)
+	}
+
+	var usage Usage
+	if chatResp.Usage != nil {
+		usage = Usage{
+			InputTokens:     chatResp.Usage.PromptTokens,
+			OutputTokens:    chatResp.Usage.CompletionTokens,
+			ReasoningTokens: chatResp.Usage.CompletionTokensDetails.ReasoningTokens,
+		}
+	}
+	for _, choice := range chatResp.Choices {
+		if choice.Message.Content != "" {
+			return Response{Text: choice.Message.Content, Usage: usage}, nil
+		}
+	}
+
+	log.Printf("Empty response from local model. Full response: %s", string(body))
+	return Response{}, fmt.Errorf("empty response from local model")
+}
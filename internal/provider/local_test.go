@@ -0,0 +1,79 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLocal_Model(t *testing.T) {
+	p := NewLocal("http://localhost:11434/v1", "", []string{"llama3"})
+
+	tests := []struct {
+		id string
+		ok bool
+	}{
+		{"local:llama3", true},
+		{"local:qwen2.5:7b", true},
+		{"local:", false},
+		{"llama3", false},
+		{"gpt-4o-mini", false},
+	}
+	for _, tt := range tests {
+		if _, ok := p.Model(tt.id); ok != tt.ok {
+			t.Errorf("Model(%q) ok = %v, want %v", tt.id, ok, tt.ok)
+		}
+	}
+
+	models := p.Models()
+	if len(models) != 1 || models[0].ID != "local:llama3" || !models[0].Local {
+		t.Errorf("Expected advertised local:llama3 model, got %+v", models)
+	}
+}
+
+func TestLocal_Generate(t *testing.T) {
+	var captured chatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("Expected path /v1/chat/completions, got %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("Expected no Authorization header without API key, got %q", r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&captured)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"Resposta local"}}],"usage":{"prompt_tokens":12,"completion_tokens":3}}`))
+	}))
+	defer server.Close()
+
+	p := NewLocal(server.URL+"/v1/", "", nil)
+
+	resp, err := p.Generate(context.Background(), Request{
+		Model:        "local:llama3",
+		Instructions: "Você é Clotilde",
+		Input:        "Oi",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.Text != "Resposta local" {
+		t.Errorf("Expected 'Resposta local', got %q", resp.Text)
+	}
+	if resp.Usage != (Usage{InputTokens: 12, OutputTokens: 3}) {
+		t.Errorf("Unexpected usage: %+v", resp.Usage)
+	}
+
+	if captured.Model != "llama3" {
+		t.Errorf("Expected prefix to be stripped from model name, got %q", captured.Model)
+	}
+	if len(captured.Messages) != 2 || captured.Messages[0].Role != "system" || captured.Messages[0].Content != "Você é Clotilde" {
+		t.Errorf("Expected system prompt followed by user message, got %+v", captured.Messages)
+	}
+}
+
+func TestLocal_NotConfigured(t *testing.T) {
+	if _, err := NewLocal("", "", nil).Generate(context.Background(), Request{Model: "local:llama3"}); err == nil {
+		t.Error("Expected error when base URL is not configured")
+	}
+}
@@ -0,0 +1,368 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/clotilde/carplay-assistant/internal/tracing"
+)
+
+const openAIDefaultBaseURL = "https://api.openai.com/v1"
+
+// openAIModels lists the OpenAI models that can be selected in the runtime config
+var openAIModels = catalog{
+	// GPT-4o series (confirmed working)
+	{ID: "gpt-4o", Capabilities: Capabilities{WebSearch: true}},
+	{ID: "gpt-4o-mini", Capabilities: Capabilities{WebSearch: true}},
+	{ID: "gpt-4o-2024-08-06", Capabilities: Capabilities{WebSearch: true}},
+	{ID: "chatgpt-4o-latest", Capabilities: Capabilities{WebSearch: true}},
+	// GPT-4 series
+	{ID: "gpt-4-turbo", Capabilities: Capabilities{WebSearch: true}},
+	{ID: "gpt-3.5-turbo"},
+	// GPT-4.1 series (may require specific API access)
+	{ID: "gpt-4.1", Capabilities: Capabilities{WebSearch: true}},
+	{ID: "gpt-4.1-mini", Capabilities: Capabilities{WebSearch: true}},
+	{ID: "gpt-4.1-nano"}, // Does NOT support web search
+	// GPT-5 series (may require specific API access)
+	// gpt-5 needs reasoning >= "low" for web search; we use "medium" to be safe
+	{ID: "gpt-5", Capabilities: Capabilities{WebSearch: true, Reasoning: true, WebSearchReasoning: "medium"}},
+	{ID: "gpt-5.1", Capabilities: Capabilities{WebSearch: true, Reasoning: true, WebSearchReasoning: "medium"}},
+	{ID: "gpt-5-mini", Capabilities: Capabilities{WebSearch: true, Reasoning: true, WebSearchReasoning: "medium"}},
+	{ID: "gpt-5-nano", Capabilities: Capabilities{Reasoning: true}},
+	{ID: "gpt-5-pro", Capabilities: Capabilities{WebSearch: true, Reasoning: true, WebSearchReasoning: "medium"}},
+	// O-series reasoning models
+	{ID: "o1", Capabilities: Capabilities{Reasoning: true}},
+	{ID: "o1-mini", Capabilities: Capabilities{Reasoning: true}},
+	{ID: "o1-pro", Capabilities: Capabilities{Reasoning: true}},
+	{ID: "o3", Capabilities: Capabilities{WebSearch: true, Reasoning: true}},
+	{ID: "o3-mini", Capabilities: Capabilities{WebSearch: true, Reasoning: true}},
+	{ID: "o4-mini", Capabilities: Capabilities{WebSearch: true, Reasoning: true}},
+}
+
+// OpenAI implements Provider using the OpenAI Responses API
+type OpenAI struct {
+	APIKey  string
+	BaseURL string // Defaults to https://api.openai.com/v1 (overridable for tests)
+	client  *http.Client
+}
+
+// NewOpenAI creates an OpenAI Responses API provider
+func NewOpenAI(apiKey string) *OpenAI {
+	return &OpenAI{
+		APIKey:  apiKey,
+		BaseURL: openAIDefaultBaseURL,
+		client:  &http.Client{Timeout: ClientTimeout, Transport: tracing.Transport(nil)},
+	}
+}
+
+// responsesAPIRequest represents the request body for Responses API
+type responsesAPIRequest struct {
+	Model        string           `json:"model"`
+	Input        interface{}      `json:"input"` // Can be string or []map[string]interface{}
+	Instructions string           `json:"instructions,omitempty"`
+	Store        *bool            `json:"store,omitempty"`
+	Tools        []interface{}    `json:"tools,omitempty"` // Tools like web_search
+	Reasoning    *reasoningConfig `json:"reasoning,omitempty"`
+	Stream       bool             `json:"stream,omitempty"`
+}
+
+// reasoningConfig controls reasoning behavior for models that support it
+type reasoningConfig struct {
+	Effort string `json:"effort"` // "none", "low", "medium", "high"
+}
+
+// webSearchTool represents the web_search tool configuration
+type webSearchTool struct {
+	Type string `json:"type"` // "web_search" or "web_search_preview" depending on API version
+}
+
+// responsesAPIResponse represents the response from Responses API
+type responsesAPIResponse struct {
+	ID         string                   `json:"id"`
+	OutputText string                   `json:"output_text"`
+	Output     interface{}              `json:"output,omitempty"` // Can be string or array of items
+	Items      []map[string]interface{} `json:"items,omitempty"`
+	Usage      *responsesUsage          `json:"usage,omitempty"`
+	Error      *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+	} `json:"error,omitempty"`
+}
+
+// responsesUsage is the token usage block of a Responses API response
+type responsesUsage struct {
+	InputTokens         int64 `json:"input_tokens"`
+	OutputTokens        int64 `json:"output_tokens"`
+	OutputTokensDetails struct {
+		ReasoningTokens int64 `json:"reasoning_tokens"`
+	} `json:"output_tokens_details"`
+}
+
+// Name returns the provider identifier
+func (p *OpenAI) Name() string {
+	return "openai"
+}
+
+// Models returns the OpenAI model catalog
+func (p *OpenAI) Models() []Model {
+	models := make([]Model, len(openAIModels))
+	for i, m := range openAIModels {
+		m.Provider = p.Name()
+		models[i] = m
+	}
+	return models
+}
+
+// Model reports whether the model is an OpenAI model
+func (p *OpenAI) Model(id string) (Model, bool) {
+	m, ok := openAIModels.find(id)
+	m.Provider = p.Name()
+	return m, ok
+}
+
+// Generate makes the HTTP request to OpenAI Responses API
+func (p *OpenAI) Generate(ctx context.Context, req Request) (Response, error) {
+	resp, err := p.send(ctx, p.buildRequest(req, false))
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+
+	// Read response body
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	// Check HTTP status
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("OpenAI API returned status %d: %s", resp.StatusCode, string(body))
+		return Response{}, statusError("API", resp.StatusCode)
+	}
+
+	// Parse response
+	var apiResp responsesAPIResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		log.Printf("Failed to parse response body: %s", string(body))
+		return Response{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	// Check for API-level errors
+	if apiResp.Error != nil {
+		return Response{}, &Error{Message: fmt.Sprintf("API error: %s (type: %s)", apiResp.Error.Message, apiResp.Error.Type), Type: apiResp.Error.Type}
+	}
+
+	if text := extractOutputText(apiResp); text != "" {
+		return Response{Text: text, Usage: openAIUsage(apiResp)}, nil
+	}
+
+	log.Printf("Empty response from API. Full response: %s", string(body))
+	return Response{}, fmt.Errorf("empty response from API")
+}
+
+// responsesStreamEvent is the subset of a Responses API streaming event we use
+type responsesStreamEvent struct {
+	Type     string                `json:"type"`
+	Delta    string                `json:"delta"`
+	Message  string                `json:"message"`            // Set on "error" events
+	Response *responsesAPIResponse `json:"response,omitempty"` // Set on lifecycle events; includes usage once completed
+}
+
+// Stream makes a streaming request to OpenAI Responses API, calling onDelta for each output text delta
+func (p *OpenAI) Stream(ctx context.Context, req Request, onDelta func(text string) error) (Response, error) {
+	resp, err := p.send(ctx, p.buildRequest(req, true))
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		log.Printf("OpenAI API returned status %d: %s", resp.StatusCode, string(body))
+		return Response{}, statusError("API", resp.StatusCode)
+	}
+
+	var text strings.Builder
+	var usage Usage
+	err = readSSE(resp.Body, func(event, data string) error {
+		var ev responsesStreamEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return nil // Ignore events we don't understand
+		}
+		switch ev.Type {
+		case "response.output_text.delta":
+			if ev.Delta != "" {
+				text.WriteString(ev.Delta)
+				return onDelta(ev.Delta)
+			}
+		case "response.failed", "response.incomplete":
+			if ev.Response != nil {
+				usage = openAIUsage(*ev.Response)
+			}
+			if ev.Response != nil && ev.Response.Error != nil {
+				return &Error{Message: fmt.Sprintf("API error: %s (code: %s)", ev.Response.Error.Message, ev.Response.Error.Code), Type: ev.Response.Error.Code}
+			}
+			if ev.Type == "response.failed" {
+				return fmt.Errorf("API error: response failed")
+			}
+			return errStreamDone // Incomplete (e.g. max tokens): keep what we have
+		case "error":
+			return fmt.Errorf("API error: %s", ev.Message)
+		case "response.completed":
+			if ev.Response != nil {
+				usage = openAIUsage(*ev.Response)
+			}
+			return errStreamDone
+		}
+		return nil
+	})
+	if err != nil {
+		return Response{Usage: usage}, err
+	}
+	if text.Len() == 0 {
+		return Response{Usage: usage}, fmt.Errorf("empty response from API")
+	}
+	return Response{Text: text.String(), Usage: usage}, nil
+}
+
+// buildRequest builds the Responses API body, including web search and reasoning settings
+func (p *OpenAI) buildRequest(req Request, stream bool) responsesAPIRequest {
+	model, _ := p.Model(req.Model)
+
+	store := true // Enable logging so usage appears in OpenAI logs
+	reqBody := responsesAPIRequest{
+		Model:        req.Model,
+		Input:        openAIInput(req),
+		Instructions: req.Instructions,
+		Store:        &store,
+		Stream:       stream,
+	}
+	if req.WebSearch {
+		reqBody.Tools = []interface{}{webSearchTool{Type: "web_search"}}
+	}
+
+	// Set reasoning effort only for models that support it (o1, o3, gpt-5 series)
+	// Models like gpt-4o, gpt-4-turbo don't support reasoning parameter
+	// IMPORTANT: gpt-5 requires reasoning >= "low" for web search to work
+	// According to OpenAI docs: "Web search is currently not supported in gpt-5 with minimal reasoning"
+	// Note: This only applies when using OpenAI's web_search tool, not when using Perplexity
+	if model.Reasoning {
+		reasoningEffort := req.ReasoningEffort
+		if req.WebSearch && model.WebSearchReasoning != "" {
+			if reasoningEffort == "" || reasoningEffort == "none" {
+				reasoningEffort = model.WebSearchReasoning // Minimum required for web search
+				log.Printf("%s with web search: using reasoning='%s' (minimum required)", req.Model, reasoningEffort)
+			}
+		}
+		if reasoningEffort != "" && reasoningEffort != "none" {
+			reqBody.Reasoning = &reasoningConfig{Effort: reasoningEffort}
+			log.Printf("Reasoning effort: %s", reasoningEffort)
+		}
+	}
+	return reqBody
+}
+
+// send POSTs a request body to the Responses API; the caller closes the response body
+func (p *OpenAI) send(ctx context.Context, reqBody responsesAPIRequest) (*http.Response, error) {
+	if p.APIKey == "" {
+		return nil, fmt.Errorf("OpenAI API key not configured")
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// Log request details (without sensitive data) for debugging
+	log.Printf("OpenAI Responses API request: model=%s, store=%v, has_tools=%v, stream=%v",
+		reqBody.Model, reqBody.Store != nil && *reqBody.Store, len(reqBody.Tools) > 0, reqBody.Stream)
+
+	// Create HTTP request to Responses API
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.BaseURL+"/responses", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.APIKey))
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	return resp, nil
+}
+
+// openAIInput builds the Responses API input: a plain string for single-shot requests,
+// or an array of role/content messages when there is conversation history
+func openAIInput(req Request) interface{} {
+	if len(req.History) == 0 {
+		return req.Input
+	}
+	input := make([]map[string]interface{}, 0, len(req.History)+1)
+	for _, m := range req.History {
+		input = append(input, map[string]interface{}{"role": m.Role, "content": m.Content})
+	}
+	return append(input, map[string]interface{}{"role": RoleUser, "content": req.Input})
+}
+
+// openAIUsage reads the token usage and the number of web searches made from a Responses API response
+func openAIUsage(apiResp responsesAPIResponse) Usage {
+	var usage Usage
+	if apiResp.Usage != nil {
+		usage.InputTokens = apiResp.Usage.InputTokens
+		usage.OutputTokens = apiResp.Usage.OutputTokens
+		usage.ReasoningTokens = apiResp.Usage.OutputTokensDetails.ReasoningTokens
+	}
+	if outputArr, ok := apiResp.Output.([]interface{}); ok {
+		for _, item := range outputArr {
+			if itemMap, ok := item.(map[string]interface{}); ok && itemMap["type"] == "web_search_call" {
+				usage.WebSearchCalls++
+			}
+		}
+	}
+	return usage
+}
+
+// extractOutputText finds the answer text in a Responses API response
+func extractOutputText(apiResp responsesAPIResponse) string {
+	// Responses API returns output as an array of items
+	// Structure: output[0].content[0].text (for message type items)
+	if outputArr, ok := apiResp.Output.([]interface{}); ok {
+		for _, item := range outputArr {
+			itemMap, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			// Look for message type items
+			if itemType, ok := itemMap["type"].(string); !ok || itemType != "message" {
+				continue
+			}
+			// Content is an array of content items
+			contentArr, ok := itemMap["content"].([]interface{})
+			if !ok {
+				continue
+			}
+			for _, contentItem := range contentArr {
+				if contentMap, ok := contentItem.(map[string]interface{}); ok {
+					// Look for output_text type content
+					if contentType, ok := contentMap["type"].(string); ok && contentType == "output_text" {
+						if text, ok := contentMap["text"].(string); ok && text != "" {
+							return text
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// Fallback: try output_text field (SDK-only convenience property, may not be in raw API response)
+	return apiResp.OutputText
+}
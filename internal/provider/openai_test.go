@@ -0,0 +1,173 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAI_Generate(t *testing.T) {
+	var captured responsesAPIRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/responses" {
+			t.Errorf("Expected path /responses, got %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("Expected bearer auth header, got %q", r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&captured)
+		w.Write([]byte(`{"id":"resp_1","output":[{"type":"web_search_call","status":"completed"},{"type":"message","content":[{"type":"output_text","text":"Olá!"}]}],` +
+			`"usage":{"input_tokens":120,"output_tokens":80,"output_tokens_details":{"reasoning_tokens":64}}}`))
+	}))
+	defer server.Close()
+
+	p := NewOpenAI("test-key")
+	p.BaseURL = server.URL
+
+	resp, err := p.Generate(context.Background(), Request{
+		Model:        "gpt-5",
+		Instructions: "Sistema",
+		Input:        "Oi",
+		WebSearch:    true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.Text != "Olá!" {
+		t.Errorf("Expected 'Olá!', got %q", resp.Text)
+	}
+	if want := (Usage{InputTokens: 120, OutputTokens: 80, ReasoningTokens: 64, WebSearchCalls: 1}); resp.Usage != want {
+		t.Errorf("Expected usage %+v, got %+v", want, resp.Usage)
+	}
+
+	if len(captured.Tools) != 1 {
+		t.Errorf("Expected web_search tool to be sent, got %v", captured.Tools)
+	}
+	// gpt-5 requires at least medium reasoning when using web_search
+	if captured.Reasoning == nil || captured.Reasoning.Effort != "medium" {
+		t.Errorf("Expected reasoning effort 'medium', got %+v", captured.Reasoning)
+	}
+}
+
+func TestOpenAI_NoReasoningForUnsupportedModel(t *testing.T) {
+	var captured responsesAPIRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&captured)
+		w.Write([]byte(`{"output_text":"ok"}`))
+	}))
+	defer server.Close()
+
+	p := NewOpenAI("test-key")
+	p.BaseURL = server.URL
+
+	if _, err := p.Generate(context.Background(), Request{Model: "gpt-4o-mini", Input: "Oi", ReasoningEffort: "high"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if captured.Reasoning != nil {
+		t.Errorf("gpt-4o-mini should not receive reasoning config, got %+v", captured.Reasoning)
+	}
+}
+
+func TestOpenAI_InputArrayWithHistory(t *testing.T) {
+	var captured map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&captured)
+		w.Write([]byte(`{"output_text":"ok"}`))
+	}))
+	defer server.Close()
+
+	p := NewOpenAI("test-key")
+	p.BaseURL = server.URL
+
+	_, err := p.Generate(context.Background(), Request{
+		Model:   "gpt-4o-mini",
+		History: []Message{{Role: RoleUser, Content: "Vai chover em SP?"}, {Role: RoleAssistant, Content: "Sim."}},
+		Input:   "E amanhã?",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	input, ok := captured["input"].([]interface{})
+	if !ok || len(input) != 3 {
+		t.Fatalf("Expected input array with 3 messages, got %v", captured["input"])
+	}
+	last := input[2].(map[string]interface{})
+	if last["role"] != "user" || last["content"] != "E amanhã?" {
+		t.Errorf("Expected current question last, got %v", last)
+	}
+}
+
+func TestOpenAI_Stream(t *testing.T) {
+	var captured responsesAPIRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&captured)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: response.created\ndata: {\"type\":\"response.created\"}\n\n" +
+			"event: response.output_text.delta\ndata: {\"type\":\"response.output_text.delta\",\"delta\":\"Olá, \"}\n\n" +
+			"event: response.output_text.delta\ndata: {\"type\":\"response.output_text.delta\",\"delta\":\"tudo bem?\"}\n\n" +
+			"event: response.completed\ndata: {\"type\":\"response.completed\",\"response\":{\"usage\":{\"input_tokens\":30,\"output_tokens\":5}}}\n\n"))
+	}))
+	defer server.Close()
+
+	p := NewOpenAI("test-key")
+	p.BaseURL = server.URL
+
+	var deltas []string
+	resp, err := p.Stream(context.Background(), Request{Model: "gpt-4o-mini", Input: "Oi"}, func(text string) error {
+		deltas = append(deltas, text)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !captured.Stream {
+		t.Error("Expected stream=true in request body")
+	}
+	if len(deltas) != 2 || resp.Text != "Olá, tudo bem?" {
+		t.Errorf("Unexpected stream result: deltas=%q text=%q", deltas, resp.Text)
+	}
+	if resp.Usage.InputTokens != 30 || resp.Usage.OutputTokens != 5 {
+		t.Errorf("Expected usage from response.completed, got %+v", resp.Usage)
+	}
+}
+
+func TestOpenAI_StreamFailed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: {\"type\":\"response.failed\",\"response\":{\"error\":{\"message\":\"boom\",\"code\":\"server_error\"},\"usage\":{\"input_tokens\":30,\"output_tokens\":2}}}\n\n"))
+	}))
+	defer server.Close()
+
+	p := NewOpenAI("test-key")
+	p.BaseURL = server.URL
+
+	resp, err := p.Stream(context.Background(), Request{Model: "gpt-4o-mini", Input: "Oi"}, func(string) error { return nil })
+	if err == nil {
+		t.Error("Expected error for failed response")
+	}
+	if resp.Usage.InputTokens != 30 || resp.Usage.OutputTokens != 2 {
+		t.Errorf("Expected the usage of the failed response, got %+v", resp.Usage)
+	}
+}
+
+func TestOpenAI_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	p := NewOpenAI("test-key")
+	p.BaseURL = server.URL
+
+	if _, err := p.Generate(context.Background(), Request{Model: "gpt-4o-mini", Input: "Oi"}); err == nil {
+		t.Error("Expected error for non-200 status")
+	}
+}
+
+func TestOpenAI_MissingAPIKey(t *testing.T) {
+	if _, err := NewOpenAI("").Generate(context.Background(), Request{Model: "gpt-4o-mini"}); err == nil {
+		t.Error("Expected error when API key is not configured")
+	}
+}
@@ -0,0 +1,222 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ClientTimeout bounds provider calls made without a deadline
+// Chat requests carry the deadline of the request budget, which decides how long a model may take
+const ClientTimeout = 30 * time.Second
+
+// Capabilities describes what a model supports
+type Capabilities struct {
+	WebSearch          bool   `json:"web_search"`                     // Native web search tool (e.g. OpenAI web_search)
+	ExternalSearch     bool   `json:"external_search"`                // Relies on Perplexity results injected into the instructions
+	Reasoning          bool   `json:"reasoning"`                      // Accepts a reasoning effort configuration
+	WebSearchReasoning string `json:"web_search_reasoning,omitempty"` // Minimum reasoning effort required when the native web search tool is used
+	Local              bool   `json:"local"`                          // Self-hosted model; never rerouted to a cloud model
+}
+
+// Model describes a model served by a provider
+type Model struct {
+	ID       string `json:"id"`
+	Provider string `json:"provider"`
+	Capabilities
+}
+
+// Message roles used in conversation history
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message is a previous turn of the conversation
+type Message struct {
+	Role    string // RoleUser or RoleAssistant
+	Content string
+}
+
+// Request is a provider-agnostic generation request
+type Request struct {
+	Model           string
+	Instructions    string    // System prompt
+	History         []Message // Previous turns, oldest first (empty for single-shot requests)
+	Input           string    // User message
+	WebSearch       bool      // Enable the provider's native web search tool
+	ReasoningEffort string    // "none", "low", "medium", "high" - empty means no reasoning config
+}
+
+// Usage is the consumption a backend reports for a request
+// OutputTokens includes ReasoningTokens, since reasoning is billed as output; token counts are
+// zero when the backend does not report usage
+type Usage struct {
+	InputTokens     int64
+	OutputTokens    int64
+	ReasoningTokens int64
+	WebSearchCalls  int // Native web search tool calls (OpenAI web_search, Gemini grounding)
+}
+
+// Reported reports whether the backend returned token counts
+func (u Usage) Reported() bool {
+	return u.InputTokens > 0 || u.OutputTokens > 0
+}
+
+// Response is the result of a generation request
+type Response struct {
+	Text  string
+	Usage Usage
+}
+
+// Provider is an LLM backend that can answer requests for the models it serves
+type Provider interface {
+	// Name returns a short identifier for the provider (e.g. "openai")
+	Name() string
+	// Models returns the models advertised by the provider
+	Models() []Model
+	// Model reports whether the provider serves the given model ID, and its capabilities
+	Model(id string) (Model, bool)
+	// Generate sends the request to the backend and returns the answer text
+	Generate(ctx context.Context, req Request) (Response, error)
+}
+
+// StreamingProvider is implemented by providers that can stream the answer as it is generated
+type StreamingProvider interface {
+	Provider
+	// Stream sends the request and calls onDelta with each piece of answer text as it arrives
+	// Returning an error from onDelta aborts the stream; the full text is returned at the end
+	// A stream that fails returns the usage reported so far with the error, since those tokens are billed
+	Stream(ctx context.Context, req Request, onDelta func(text string) error) (Response, error)
+}
+
+// Registry holds the known providers and selects one by model ID
+type Registry struct {
+	mu        sync.RWMutex
+	providers []Provider
+}
+
+// NewRegistry creates an empty provider registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a provider to the registry
+// A provider with the same name is replaced, so configured instances can override the built-in defaults
+func (r *Registry) Register(p Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, existing := range r.providers {
+		if existing.Name() == p.Name() {
+			r.providers[i] = p
+			return
+		}
+	}
+	r.providers = append(r.providers, p)
+}
+
+// ForModel returns the provider that serves the given model ID
+// Providers are checked in registration order
+func (r *Registry) ForModel(model string) (Provider, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, p := range r.providers {
+		if _, ok := p.Model(model); ok {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// Lookup returns the model description (including capabilities) for a model ID
+func (r *Registry) Lookup(model string) (Model, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, p := range r.providers {
+		if m, ok := p.Model(model); ok {
+			return m, true
+		}
+	}
+	return Model{}, false
+}
+
+// Models returns every model advertised by the registered providers
+func (r *Registry) Models() []Model {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var models []Model
+	for _, p := range r.providers {
+		models = append(models, p.Models()...)
+	}
+	return models
+}
+
+// defaultRegistry is the process-wide registry used by the router, admin validation and the server.
+// Built-in providers are registered without credentials so their model catalog is always available;
+// main replaces them with configured instances at startup.
+var defaultRegistry = NewRegistry()
+
+func init() {
+	defaultRegistry.Register(NewOpenAI(""))
+	defaultRegistry.Register(NewAnthropic(""))
+	defaultRegistry.Register(NewGemini(""))
+}
+
+// Register adds or replaces a provider in the default registry
+func Register(p Provider) {
+	defaultRegistry.Register(p)
+}
+
+// ForModel returns the provider from the default registry that serves the given model ID
+func ForModel(model string) (Provider, bool) {
+	return defaultRegistry.ForModel(model)
+}
+
+// Lookup returns the model description from the default registry
+func Lookup(model string) (Model, bool) {
+	return defaultRegistry.Lookup(model)
+}
+
+// Models returns every model advertised by the default registry
+func Models() []Model {
+	return defaultRegistry.Models()
+}
+
+// IsKnownModel reports whether any registered provider serves the model
+func IsKnownModel(model string) bool {
+	_, ok := defaultRegistry.Lookup(model)
+	return ok
+}
+
+// CapabilitiesFor returns the capabilities of a model (zero value if unknown)
+func CapabilitiesFor(model string) Capabilities {
+	m, _ := defaultRegistry.Lookup(model)
+	return m.Capabilities
+}
+
+// SupportsWebSearch reports whether a model has a native web search tool
+func SupportsWebSearch(model string) bool {
+	return CapabilitiesFor(model).WebSearch
+}
+
+// SupportsReasoning reports whether a model accepts a reasoning effort configuration
+func SupportsReasoning(model string) bool {
+	return CapabilitiesFor(model).Reasoning
+}
+
+// catalog is a static list of models shared by providers with a fixed model set
+type catalog []Model
+
+// find returns the model with the given ID
+func (c catalog) find(id string) (Model, bool) {
+	for _, m := range c {
+		if m.ID == id {
+			return m, true
+		}
+	}
+	return Model{}, false
+}
@@ -0,0 +1,93 @@
+package provider
+
+import (
+	"context"
+	"testing"
+)
+
+// fakeProvider is a minimal Provider used to exercise the registry
+type fakeProvider struct {
+	name   string
+	models catalog
+	answer string
+}
+
+func (f *fakeProvider) Name() string    { return f.name }
+func (f *fakeProvider) Models() []Model { return f.models }
+func (f *fakeProvider) Model(id string) (Model, bool) {
+	return f.models.find(id)
+}
+func (f *fakeProvider) Generate(ctx context.Context, req Request) (Response, error) {
+	return Response{Text: f.answer}, nil
+}
+
+func TestRegistry_ForModel(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&fakeProvider{name: "a", models: catalog{{ID: "model-a"}}})
+	r.Register(&fakeProvider{name: "b", models: catalog{{ID: "model-b", Capabilities: Capabilities{WebSearch: true}}}})
+
+	p, ok := r.ForModel("model-b")
+	if !ok || p.Name() != "b" {
+		t.Fatalf("Expected provider b for model-b, got %v (ok=%v)", p, ok)
+	}
+
+	if _, ok := r.ForModel("unknown-model"); ok {
+		t.Error("Expected no provider for unknown model")
+	}
+
+	m, ok := r.Lookup("model-b")
+	if !ok || !m.WebSearch {
+		t.Errorf("Expected model-b to declare web search, got %+v", m)
+	}
+}
+
+func TestRegistry_RegisterReplacesByName(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&fakeProvider{name: "a", models: catalog{{ID: "model-a"}}, answer: "old"})
+	r.Register(&fakeProvider{name: "a", models: catalog{{ID: "model-a"}}, answer: "new"})
+
+	if got := len(r.Models()); got != 1 {
+		t.Fatalf("Expected 1 model after replacement, got %d", got)
+	}
+
+	p, _ := r.ForModel("model-a")
+	resp, _ := p.Generate(context.Background(), Request{Model: "model-a"})
+	if resp.Text != "new" {
+		t.Errorf("Expected replaced provider to answer, got %q", resp.Text)
+	}
+}
+
+func TestDefaultRegistry_BuiltInCapabilities(t *testing.T) {
+	tests := []struct {
+		model     string
+		known     bool
+		webSearch bool
+		reasoning bool
+	}{
+		{"gpt-4o-mini", true, true, false},
+		{"gpt-4.1-nano", true, false, false},
+		{"gpt-5", true, true, true},
+		{"o1", true, false, true},
+		{"claude-haiku-4-5-20251001", true, false, false},
+		{"gemini-2.5-flash", true, true, false},
+		{"invalid-model", false, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			if got := IsKnownModel(tt.model); got != tt.known {
+				t.Errorf("IsKnownModel(%q) = %v, want %v", tt.model, got, tt.known)
+			}
+			if got := SupportsWebSearch(tt.model); got != tt.webSearch {
+				t.Errorf("SupportsWebSearch(%q) = %v, want %v", tt.model, got, tt.webSearch)
+			}
+			if got := SupportsReasoning(tt.model); got != tt.reasoning {
+				t.Errorf("SupportsReasoning(%q) = %v, want %v", tt.model, got, tt.reasoning)
+			}
+		})
+	}
+
+	if !CapabilitiesFor("claude-haiku-4-5-20251001").ExternalSearch {
+		t.Error("Claude models should rely on external (Perplexity) search")
+	}
+}
@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+// errStreamDone is returned by an SSE handler to stop reading after the final event
+var errStreamDone = errors.New("stream done")
+
+// maxSSELineSize bounds a single SSE line (events carry small JSON deltas)
+const maxSSELineSize = 256 * 1024
+
+// readSSE parses a Server-Sent Events body and calls handle for each event
+// Multi-line data fields are joined with "\n"; a "[DONE]" data line ends the stream
+func readSSE(body io.Reader, handle func(event, data string) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSSELineSize)
+
+	var event string
+	var data []string
+	dispatch := func() error {
+		defer func() { event, data = "", nil }()
+		if len(data) == 0 {
+			return nil
+		}
+		payload := strings.Join(data, "\n")
+		if payload == "[DONE]" {
+			return errStreamDone
+		}
+		return handle(event, payload)
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				if errors.Is(err, errStreamDone) {
+					return nil
+				}
+				return err
+			}
+		case strings.HasPrefix(line, ":"):
+			// Comment / keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	// Body ended without a trailing blank line
+	if err := dispatch(); err != nil && !errors.Is(err, errStreamDone) {
+		return err
+	}
+	return nil
+}
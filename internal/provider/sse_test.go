@@ -0,0 +1,51 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadSSE(t *testing.T) {
+	body := ": keep-alive\n" +
+		"event: first\n" +
+		"data: {\"a\":1}\n\n" +
+		"data: line one\n" +
+		"data: line two\n\n" +
+		"data: [DONE]\n\n" +
+		"data: after done\n\n"
+
+	type ev struct{ event, data string }
+	var got []ev
+	err := readSSE(strings.NewReader(body), func(event, data string) error {
+		got = append(got, ev{event, data})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []ev{{"first", `{"a":1}`}, {"", "line one\nline two"}}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d events, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Event %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestReadSSE_StopsOnHandlerDone(t *testing.T) {
+	body := "data: 1\n\ndata: 2\n\ndata: 3"
+	count := 0
+	err := readSSE(strings.NewReader(body), func(event, data string) error {
+		count++
+		if data == "2" {
+			return errStreamDone
+		}
+		return nil
+	})
+	if err != nil || count != 2 {
+		t.Errorf("Expected to stop after 2 events without error, got count=%d err=%v", count, err)
+	}
+}
@@ -0,0 +1,146 @@
+package quota
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Price is the list price of a model in USD per million tokens
+type Price struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// Prices are runtime overrides of the built-in price table
+type Prices struct {
+	Models     map[string]Price // Model ID -> price, replacing the built-in price of the model
+	SearchCall float64          // USD per web search (native tool call or Perplexity query); 0 keeps the default
+}
+
+// prices of the models the providers advertise (standard tier, without caching discounts)
+var prices = map[string]Price{
+	// OpenAI
+	"gpt-4o":            {Input: 2.50, Output: 10.00},
+	"gpt-4o-2024-08-06": {Input: 2.50, Output: 10.00},
+	"chatgpt-4o-latest": {Input: 5.00, Output: 15.00},
+	"gpt-4o-mini":       {Input: 0.15, Output: 0.60},
+	"gpt-4-turbo":       {Input: 10.00, Output: 30.00},
+	"gpt-3.5-turbo":     {Input: 0.50, Output: 1.50},
+	"gpt-4.1":           {Input: 2.00, Output: 8.00},
+	"gpt-4.1-mini":      {Input: 0.40, Output: 1.60},
+	"gpt-4.1-nano":      {Input: 0.10, Output: 0.40},
+	"gpt-5":             {Input: 1.25, Output: 10.00},
+	"gpt-5.1":           {Input: 1.25, Output: 10.00},
+	"gpt-5-mini":        {Input: 0.25, Output: 2.00},
+	"gpt-5-nano":        {Input: 0.05, Output: 0.40},
+	"gpt-5-pro":         {Input: 15.00, Output: 120.00},
+	"o1":                {Input: 15.00, Output: 60.00},
+	"o1-mini":           {Input: 1.10, Output: 4.40},
+	"o1-pro":            {Input: 150.00, Output: 600.00},
+	"o3":                {Input: 2.00, Output: 8.00},
+	"o3-mini":           {Input: 1.10, Output: 4.40},
+	"o4-mini":           {Input: 1.10, Output: 4.40},
+
+	// Anthropic
+	"claude-haiku-4-5-20251001":  {Input: 1.00, Output: 5.00},
+	"claude-3-5-haiku-20241022":  {Input: 0.80, Output: 4.00},
+	"claude-3-5-haiku-latest":    {Input: 0.80, Output: 4.00},
+	"claude-3-5-sonnet-20241022": {Input: 3.00, Output: 15.00},
+	"claude-3-5-sonnet-latest":   {Input: 3.00, Output: 15.00},
+	"claude-sonnet-4-20250514":   {Input: 3.00, Output: 15.00},
+	"claude-3-opus-20240229":     {Input: 15.00, Output: 75.00},
+
+	// Google
+	"gemini-2.5-flash":      {Input: 0.30, Output: 2.50},
+	"gemini-2.5-flash-lite": {Input: 0.10, Output: 0.40},
+	"gemini-2.5-pro":        {Input: 1.25, Output: 10.00},
+	"gemini-2.0-flash":      {Input: 0.10, Output: 0.40},
+}
+
+// defaultPrice is charged for cloud models missing from the table
+// It is deliberately high so a new model cannot bypass budgets by being unknown
+var defaultPrice = Price{Input: 5.00, Output: 20.00}
+
+// searchCallCost is the default cost of one web search (native tool call or Perplexity query)
+const searchCallCost = 0.01
+
+var (
+	priceMu     sync.RWMutex
+	priceSource func() Prices // Current overrides, typically read from the runtime config
+)
+
+// SetPriceSource makes PriceFor and Cost read price overrides from source on every call,
+// so price changes take effect without a restart
+func SetPriceSource(source func() Prices) {
+	priceMu.Lock()
+	defer priceMu.Unlock()
+	priceSource = source
+}
+
+// currentPrices returns the configured overrides
+func currentPrices() Prices {
+	priceMu.RLock()
+	source := priceSource
+	priceMu.RUnlock()
+	if source == nil {
+		return Prices{}
+	}
+	return source()
+}
+
+// PriceFor returns the price of model: the configured override, else the built-in list price
+// Self-hosted models ("local:...") are free unless a price is configured for them
+func PriceFor(model string) Price {
+	return currentPrices().priceFor(model)
+}
+
+func (p Prices) priceFor(model string) Price {
+	if price, ok := p.Models[model]; ok {
+		return price
+	}
+	if strings.HasPrefix(model, "local:") {
+		return Price{}
+	}
+	if price, ok := prices[model]; ok {
+		return price
+	}
+	return defaultPrice
+}
+
+func (p Prices) searchCall() float64 {
+	if p.SearchCall > 0 {
+		return p.SearchCall
+	}
+	return searchCallCost
+}
+
+// Validate checks that no configured price is negative
+func (p Prices) Validate() error {
+	for model, price := range p.Models {
+		if strings.TrimSpace(model) == "" {
+			return fmt.Errorf("model prices need a model ID")
+		}
+		if price.Input < 0 || price.Output < 0 {
+			return fmt.Errorf("price of %s cannot be negative", model)
+		}
+	}
+	if p.SearchCall < 0 {
+		return fmt.Errorf("search price cannot be negative")
+	}
+	return nil
+}
+
+// EstimateTokens approximates the token count of text (~4 characters per token)
+func EstimateTokens(text string) int64 {
+	return int64(len(text)+3) / 4
+}
+
+// Cost returns the cost in USD of a request to model with the given token counts and web searches
+// Output tokens include reasoning tokens, which are billed at the output price
+func Cost(model string, inputTokens, outputTokens int64, searchCalls int) float64 {
+	prices := currentPrices()
+	price := prices.priceFor(model)
+	cost := (float64(inputTokens)*price.Input + float64(outputTokens)*price.Output) / 1e6
+	return cost + float64(searchCalls)*prices.searchCall()
+}
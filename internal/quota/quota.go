@@ -0,0 +1,192 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Actions when a budget is exhausted
+const (
+	ActionReject    = "reject"    // Refuse the request (default)
+	ActionDowngrade = "downgrade" // Answer with the standard model instead of the routed one
+)
+
+// Budget limits what one API key may consume; zero fields are unlimited
+type Budget struct {
+	DailyCost     float64 `json:"daily_cost,omitempty"`   // Estimated USD per day
+	MonthlyCost   float64 `json:"monthly_cost,omitempty"` // Estimated USD per month
+	DailyTokens   int64   `json:"daily_tokens,omitempty"`
+	MonthlyTokens int64   `json:"monthly_tokens,omitempty"`
+}
+
+// IsZero reports whether the budget sets no limit
+func (b Budget) IsZero() bool {
+	return b == Budget{}
+}
+
+// Or fills the unset fields of b from defaults
+func (b Budget) Or(defaults Budget) Budget {
+	if b.DailyCost == 0 {
+		b.DailyCost = defaults.DailyCost
+	}
+	if b.MonthlyCost == 0 {
+		b.MonthlyCost = defaults.MonthlyCost
+	}
+	if b.DailyTokens == 0 {
+		b.DailyTokens = defaults.DailyTokens
+	}
+	if b.MonthlyTokens == 0 {
+		b.MonthlyTokens = defaults.MonthlyTokens
+	}
+	return b
+}
+
+// Validate rejects negative limits
+func (b Budget) Validate() error {
+	if b.DailyCost < 0 || b.MonthlyCost < 0 || b.DailyTokens < 0 || b.MonthlyTokens < 0 {
+		return fmt.Errorf("budget limits must not be negative")
+	}
+	return nil
+}
+
+// Usage is what requests consumed
+type Usage struct {
+	Tokens int64   `json:"tokens"`
+	Cost   float64 `json:"cost"` // Estimated USD
+}
+
+// Report is the consumption of one API key against its budgets
+type Report struct {
+	Budget     Budget                    `json:"budget"`
+	Day        Usage                     `json:"day"`
+	Month      Usage                     `json:"month"`
+	Categories map[string]CategoryReport `json:"categories,omitempty"`
+}
+
+// CategoryReport is the consumption of one API key in a category with its own budget
+type CategoryReport struct {
+	Budget Budget `json:"budget"`
+	Day    Usage  `json:"day"`
+	Month  Usage  `json:"month"`
+}
+
+const (
+	// Counters are kept a little longer than their period so late reports still see them
+	dayCounterTTL   = 48 * time.Hour
+	monthCounterTTL = 32 * 24 * time.Hour
+)
+
+// Tracker charges requests to per-key daily and monthly counters and checks them against budgets
+type Tracker struct {
+	store Store
+	loc   *time.Location // Where days and months start
+	now   func() time.Time
+}
+
+// NewTracker creates a tracker keeping counters in store (nil for in-process counters)
+// Days and months follow loc (nil for UTC)
+func NewTracker(store Store, loc *time.Location) *Tracker {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &Tracker{store: store, loc: loc, now: time.Now}
+}
+
+// counters returns the day and month counter names of keyID (and of category, if not empty)
+func (t *Tracker) counters(keyID, category string) (day, month string) {
+	now := t.now().In(t.loc)
+	day = keyID + ":d:" + now.Format("2006-01-02")
+	month = keyID + ":m:" + now.Format("2006-01")
+	if category != "" {
+		day += ":" + category
+		month += ":" + category
+	}
+	return day, month
+}
+
+// Charge adds usage to the counters of keyID, in total and for category
+func (t *Tracker) Charge(ctx context.Context, keyID, category string, usage Usage) error {
+	day, month := t.counters(keyID, "")
+	days, months := []string{day}, []string{month}
+	if category != "" {
+		catDay, catMonth := t.counters(keyID, category)
+		days, months = append(days, catDay), append(months, catMonth)
+	}
+
+	if err := t.store.Add(ctx, days, usage, dayCounterTTL); err != nil {
+		return err
+	}
+	return t.store.Add(ctx, months, usage, monthCounterTTL)
+}
+
+// Check returns a description of the first budget keyID has used up, in total (budget) or in
+// category (categoryBudget), or "" when the request may proceed
+func (t *Tracker) Check(ctx context.Context, keyID, category string, budget, categoryBudget Budget) (string, error) {
+	if budget.IsZero() && categoryBudget.IsZero() {
+		return "", nil
+	}
+
+	day, month := t.counters(keyID, "")
+	catDay, catMonth := t.counters(keyID, category)
+	values, err := t.store.Get(ctx, []string{day, month, catDay, catMonth})
+	if err != nil {
+		return "", err
+	}
+
+	if reason := exhausted(budget, values[0], values[1]); reason != "" {
+		return reason, nil
+	}
+	if reason := exhausted(categoryBudget, values[2], values[3]); reason != "" {
+		return reason + " for " + category, nil
+	}
+	return "", nil
+}
+
+// exhausted names the first limit of budget reached by the day or month usage
+func exhausted(budget Budget, day, month Usage) string {
+	switch {
+	case budget.DailyCost > 0 && day.Cost >= budget.DailyCost:
+		return "daily cost budget"
+	case budget.MonthlyCost > 0 && month.Cost >= budget.MonthlyCost:
+		return "monthly cost budget"
+	case budget.DailyTokens > 0 && day.Tokens >= budget.DailyTokens:
+		return "daily token budget"
+	case budget.MonthlyTokens > 0 && month.Tokens >= budget.MonthlyTokens:
+		return "monthly token budget"
+	}
+	return ""
+}
+
+// Report returns the current consumption of keyID, with the categories that have a budget
+func (t *Tracker) Report(ctx context.Context, keyID string, budget Budget, categoryBudgets map[string]Budget) (Report, error) {
+	day, month := t.counters(keyID, "")
+	names := []string{day, month}
+	categories := make([]string, 0, len(categoryBudgets))
+	for category := range categoryBudgets {
+		catDay, catMonth := t.counters(keyID, category)
+		names = append(names, catDay, catMonth)
+		categories = append(categories, category)
+	}
+
+	values, err := t.store.Get(ctx, names)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{Budget: budget, Day: values[0], Month: values[1]}
+	if len(categories) > 0 {
+		report.Categories = make(map[string]CategoryReport, len(categories))
+		for i, category := range categories {
+			report.Categories[category] = CategoryReport{
+				Budget: categoryBudgets[category],
+				Day:    values[2+2*i],
+				Month:  values[3+2*i],
+			}
+		}
+	}
+	return report, nil
+}
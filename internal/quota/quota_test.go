@@ -0,0 +1,198 @@
+package quota
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func newTestTracker(now *time.Time) *Tracker {
+	tracker := NewTracker(nil, time.UTC)
+	tracker.now = func() time.Time { return *now }
+	store := tracker.store.(*MemoryStore)
+	store.now = tracker.now
+	return tracker
+}
+
+func TestTracker_DailyCostBudget(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	tracker := newTestTracker(&now)
+	ctx := context.Background()
+	budget := Budget{DailyCost: 0.05}
+
+	reason, err := tracker.Check(ctx, "key_a", "complex", budget, Budget{})
+	if err != nil || reason != "" {
+		t.Fatalf("Fresh key should be within budget, got %q (%v)", reason, err)
+	}
+
+	tracker.Charge(ctx, "key_a", "complex", Usage{Tokens: 1000, Cost: 0.03})
+	if reason, _ := tracker.Check(ctx, "key_a", "complex", budget, Budget{}); reason != "" {
+		t.Errorf("Expected budget left after $0.03, got %q", reason)
+	}
+
+	tracker.Charge(ctx, "key_a", "simple", Usage{Tokens: 1000, Cost: 0.03})
+	if reason, _ := tracker.Check(ctx, "key_a", "simple", budget, Budget{}); reason != "daily cost budget" {
+		t.Errorf("Expected daily cost budget exhausted, got %q", reason)
+	}
+
+	// Other keys have their own counters
+	if reason, _ := tracker.Check(ctx, "key_b", "simple", budget, Budget{}); reason != "" {
+		t.Errorf("Other key should not be affected, got %q", reason)
+	}
+
+	// A new day starts with a fresh daily budget
+	now = now.Add(24 * time.Hour)
+	if reason, _ := tracker.Check(ctx, "key_a", "simple", budget, Budget{}); reason != "" {
+		t.Errorf("Expected fresh daily budget the next day, got %q", reason)
+	}
+}
+
+func TestTracker_MonthlyAndTokenBudgets(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	tracker := newTestTracker(&now)
+	ctx := context.Background()
+
+	tracker.Charge(ctx, "key_a", "simple", Usage{Tokens: 600, Cost: 0.5})
+	now = now.Add(24 * time.Hour)
+	tracker.Charge(ctx, "key_a", "simple", Usage{Tokens: 600, Cost: 0.5})
+
+	if reason, _ := tracker.Check(ctx, "key_a", "simple", Budget{MonthlyCost: 1}, Budget{}); reason != "monthly cost budget" {
+		t.Errorf("Expected monthly cost budget exhausted, got %q", reason)
+	}
+	if reason, _ := tracker.Check(ctx, "key_a", "simple", Budget{DailyTokens: 1000}, Budget{}); reason != "" {
+		t.Errorf("Only 600 tokens today, got %q", reason)
+	}
+	if reason, _ := tracker.Check(ctx, "key_a", "simple", Budget{MonthlyTokens: 1000}, Budget{}); reason != "monthly token budget" {
+		t.Errorf("Expected monthly token budget exhausted, got %q", reason)
+	}
+}
+
+func TestTracker_CategoryBudget(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	tracker := newTestTracker(&now)
+	ctx := context.Background()
+	categoryBudget := Budget{DailyCost: 0.10}
+
+	tracker.Charge(ctx, "key_a", "complex", Usage{Tokens: 5000, Cost: 0.10})
+
+	if reason, _ := tracker.Check(ctx, "key_a", "complex", Budget{DailyCost: 1}, categoryBudget); reason != "daily cost budget for complex" {
+		t.Errorf("Expected complex budget exhausted, got %q", reason)
+	}
+	// Other categories still have budget
+	if reason, _ := tracker.Check(ctx, "key_a", "simple", Budget{DailyCost: 1}, categoryBudget); reason != "" {
+		t.Errorf("Simple questions should still be allowed, got %q", reason)
+	}
+}
+
+func TestTracker_Report(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	tracker := newTestTracker(&now)
+	ctx := context.Background()
+
+	tracker.Charge(ctx, "key_a", "complex", Usage{Tokens: 100, Cost: 0.02})
+	now = now.Add(24 * time.Hour)
+	tracker.Charge(ctx, "key_a", "simple", Usage{Tokens: 50, Cost: 0.01})
+
+	budget := Budget{DailyCost: 1}
+	report, err := tracker.Report(ctx, "key_a", budget, map[string]Budget{"complex": {DailyCost: 0.5}})
+	if err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if report.Budget != budget {
+		t.Errorf("Expected budget in report, got %+v", report.Budget)
+	}
+	if report.Day.Tokens != 50 || report.Month.Tokens != 150 {
+		t.Errorf("Expected 50 tokens today and 150 this month, got %+v / %+v", report.Day, report.Month)
+	}
+	if math.Abs(report.Month.Cost-0.03) > 1e-9 {
+		t.Errorf("Expected $0.03 this month, got %v", report.Month.Cost)
+	}
+	complex := report.Categories["complex"]
+	if complex.Day.Tokens != 0 || complex.Month.Tokens != 100 || complex.Budget.DailyCost != 0.5 {
+		t.Errorf("Unexpected complex report: %+v", complex)
+	}
+}
+
+func TestTracker_DaysFollowLocation(t *testing.T) {
+	loc := time.FixedZone("BRT", -3*60*60)
+	now := time.Date(2026, 10, 17, 23, 0, 0, 0, time.UTC) // 20:00 in Brazil
+	tracker := NewTracker(nil, loc)
+	tracker.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	tracker.Charge(ctx, "key_a", "", Usage{Tokens: 10})
+	now = now.Add(2 * time.Hour) // 01:00 UTC the next day, still 22:00 in Brazil
+
+	report, _ := tracker.Report(ctx, "key_a", Budget{}, nil)
+	if report.Day.Tokens != 10 {
+		t.Errorf("Expected the same local day, got %+v", report.Day)
+	}
+}
+
+func TestBudget_Or(t *testing.T) {
+	own := Budget{DailyCost: 2}
+	merged := own.Or(Budget{DailyCost: 1, MonthlyCost: 20})
+	if merged.DailyCost != 2 || merged.MonthlyCost != 20 {
+		t.Errorf("Expected own daily and default monthly budget, got %+v", merged)
+	}
+}
+
+func TestCost(t *testing.T) {
+	if cost := Cost("gpt-4o-mini", 1_000_000, 1_000_000, 0); math.Abs(cost-0.75) > 1e-9 {
+		t.Errorf("Expected $0.75 for 1M+1M gpt-4o-mini tokens, got %v", cost)
+	}
+
+	if cost := Cost("local:llama3", 1000, 1000, 0); cost != 0 {
+		t.Errorf("Local models should be free, got %v", cost)
+	}
+	// Perplexity queries are paid even when a local model writes the answer
+	if cost := Cost("local:llama3", 1000, 1000, 2); cost != 2*searchCallCost {
+		t.Errorf("Expected two searches charged, got %v", cost)
+	}
+
+	if cost := Cost("some-new-model", 1_000_000, 0, 0); cost != defaultPrice.Input {
+		t.Errorf("Unknown models should use the default price, got %v", cost)
+	}
+}
+
+func TestCost_RuntimeOverrides(t *testing.T) {
+	SetPriceSource(func() Prices {
+		return Prices{
+			Models:     map[string]Price{"gpt-4o-mini": {Input: 1, Output: 2}, "local:llama3": {Input: 0.5}},
+			SearchCall: 0.005,
+		}
+	})
+	defer SetPriceSource(nil)
+
+	if cost := Cost("gpt-4o-mini", 1_000_000, 1_000_000, 1); math.Abs(cost-3.005) > 1e-9 {
+		t.Errorf("Expected configured price and search cost, got %v", cost)
+	}
+	if price := PriceFor("local:llama3"); price.Input != 0.5 {
+		t.Errorf("Configured prices apply to local models too, got %+v", price)
+	}
+	if price := PriceFor("gpt-4o"); price != prices["gpt-4o"] {
+		t.Errorf("Models without an override keep the list price, got %+v", price)
+	}
+}
+
+func TestPrices_Validate(t *testing.T) {
+	if err := (Prices{Models: map[string]Price{"gpt-4o": {Input: -1}}}).Validate(); err == nil {
+		t.Error("Expected error for a negative price")
+	}
+	if err := (Prices{SearchCall: -0.01}).Validate(); err == nil {
+		t.Error("Expected error for a negative search price")
+	}
+	if err := (Prices{Models: map[string]Price{"gpt-4o": {Input: 2, Output: 8}}}).Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	if got := EstimateTokens(""); got != 0 {
+		t.Errorf("Expected 0 tokens for empty text, got %d", got)
+	}
+	if got := EstimateTokens("abcdefgh"); got != 2 {
+		t.Errorf("Expected 2 tokens for 8 characters, got %d", got)
+	}
+}
@@ -0,0 +1,130 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Store keeps usage counters
+type Store interface {
+	// Add increments each counter by usage; a counter may be dropped ttl after its last change
+	Add(ctx context.Context, counters []string, usage Usage, ttl time.Duration) error
+	// Get returns the value of each counter (zero for unknown counters)
+	Get(ctx context.Context, counters []string) ([]Usage, error)
+}
+
+// MemoryStore keeps counters in process: each instance has its own, and they reset on restart
+type MemoryStore struct {
+	mu       sync.Mutex
+	counters map[string]*memoryCounter
+	now      func() time.Time
+}
+
+type memoryCounter struct {
+	usage   Usage
+	expires time.Time
+}
+
+// NewMemoryStore creates an empty in-process store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: make(map[string]*memoryCounter), now: time.Now}
+}
+
+// Add increments the counters, dropping expired ones
+func (s *MemoryStore) Add(ctx context.Context, counters []string, usage Usage, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for name, counter := range s.counters {
+		if !now.Before(counter.expires) {
+			delete(s.counters, name)
+		}
+	}
+	for _, name := range counters {
+		counter, ok := s.counters[name]
+		if !ok {
+			counter = &memoryCounter{}
+			s.counters[name] = counter
+		}
+		counter.usage.Tokens += usage.Tokens
+		counter.usage.Cost += usage.Cost
+		counter.expires = now.Add(ttl)
+	}
+	return nil
+}
+
+// Get returns the counters
+func (s *MemoryStore) Get(ctx context.Context, counters []string) ([]Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	values := make([]Usage, len(counters))
+	for i, name := range counters {
+		if counter, ok := s.counters[name]; ok && now.Before(counter.expires) {
+			values[i] = counter.usage
+		}
+	}
+	return values, nil
+}
+
+// RedisStore keeps counters in Redis so budgets hold across instances and restarts
+// Each counter is a hash under prefix+name with "tokens" and "cost" fields
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisStore creates a store using client; prefix separates the counters from other data
+func NewRedisStore(client redis.UniversalClient, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// Add increments the counters in one round trip
+func (s *RedisStore) Add(ctx context.Context, counters []string, usage Usage, ttl time.Duration) error {
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, name := range counters {
+			key := s.prefix + name
+			pipe.HIncrBy(ctx, key, "tokens", usage.Tokens)
+			pipe.HIncrByFloat(ctx, key, "cost", usage.Cost)
+			pipe.Expire(ctx, key, ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record usage: %w", err)
+	}
+	return nil
+}
+
+// Get reads the counters in one round trip
+func (s *RedisStore) Get(ctx context.Context, counters []string) ([]Usage, error) {
+	cmds := make([]*redis.SliceCmd, len(counters))
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, name := range counters {
+			cmds[i] = pipe.HMGet(ctx, s.prefix+name, "tokens", "cost")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage: %w", err)
+	}
+
+	values := make([]Usage, len(counters))
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		if tokens, ok := fields[0].(string); ok {
+			values[i].Tokens, _ = strconv.ParseInt(tokens, 10, 64)
+		}
+		if cost, ok := fields[1].(string); ok {
+			values[i].Cost, _ = strconv.ParseFloat(cost, 64)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,81 @@
+package quota
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisStore_AddAndGet(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	store := NewRedisStore(client, "test:")
+	ctx := context.Background()
+
+	if err := store.Add(ctx, []string{"a", "b"}, Usage{Tokens: 100, Cost: 0.25}, time.Hour); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := store.Add(ctx, []string{"a"}, Usage{Tokens: 50, Cost: 0.5}, 2*time.Hour); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	values, err := store.Get(ctx, []string{"a", "b", "missing"})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if values[0].Tokens != 150 || math.Abs(values[0].Cost-0.75) > 1e-9 {
+		t.Errorf("Unexpected counter a: %+v", values[0])
+	}
+	if values[1].Tokens != 100 || math.Abs(values[1].Cost-0.25) > 1e-9 {
+		t.Errorf("Unexpected counter b: %+v", values[1])
+	}
+	if values[2] != (Usage{}) {
+		t.Errorf("Missing counter should be zero, got %+v", values[2])
+	}
+
+	if ttl := server.TTL("test:a"); ttl != 2*time.Hour {
+		t.Errorf("Expected TTL refreshed to 2h, got %v", ttl)
+	}
+}
+
+func TestTracker_SharedThroughRedis(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	// Two instances charging the same key
+	first := NewTracker(NewRedisStore(client, "quota:"), time.UTC)
+	second := NewTracker(NewRedisStore(client, "quota:"), time.UTC)
+	ctx := context.Background()
+	budget := Budget{DailyTokens: 100}
+
+	first.Charge(ctx, "key_a", "simple", Usage{Tokens: 60})
+	second.Charge(ctx, "key_a", "simple", Usage{Tokens: 60})
+
+	for _, tracker := range []*Tracker{first, second} {
+		if reason, err := tracker.Check(ctx, "key_a", "simple", budget, Budget{}); err != nil || reason != "daily token budget" {
+			t.Errorf("Expected shared daily token budget exhausted, got %q (%v)", reason, err)
+		}
+	}
+}
+
+func TestMemoryStore_Expiry(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	store.Add(ctx, []string{"a"}, Usage{Tokens: 10}, time.Hour)
+	now = now.Add(2 * time.Hour)
+
+	values, _ := store.Get(ctx, []string{"a"})
+	if values[0] != (Usage{}) {
+		t.Errorf("Expired counter should read as zero, got %+v", values[0])
+	}
+}
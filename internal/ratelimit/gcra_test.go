@@ -0,0 +1,156 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/clotilde/carplay-assistant/internal/auth"
+)
+
+func TestRateLimiter_BurstAndRefill(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	rl := newRateLimiter()
+	rl.now = func() time.Time { return now }
+	limits := Limits{PerMinute: 6, PerHour: 100, Burst: 3}
+
+	// The burst is available at once
+	for i := 0; i < 3; i++ {
+		d := rl.allow(context.Background(), "key", limits)
+		if !d.allowed {
+			t.Fatalf("Request %d within burst should be allowed", i+1)
+		}
+		if d.remaining != 2-i {
+			t.Errorf("Request %d: expected %d remaining, got %d", i+1, 2-i, d.remaining)
+		}
+	}
+
+	d := rl.allow(context.Background(), "key", limits)
+	if d.allowed {
+		t.Fatal("Request beyond burst should be denied")
+	}
+	if d.retryAfter != 10*time.Second {
+		t.Errorf("Expected retry after one token interval (10s), got %v", d.retryAfter)
+	}
+
+	// One token is earned every 10 seconds
+	now = now.Add(10 * time.Second)
+	if d := rl.allow(context.Background(), "key", limits); !d.allowed || d.remaining != 0 {
+		t.Errorf("Expected one refilled token, got allowed=%v remaining=%d", d.allowed, d.remaining)
+	}
+	if d := rl.allow(context.Background(), "key", limits); d.allowed {
+		t.Error("Only one token should have been refilled")
+	}
+
+	// A denied request does not consume tokens
+	now = now.Add(30 * time.Second)
+	if d := rl.allow(context.Background(), "key", limits); !d.allowed || d.remaining != 2 {
+		t.Errorf("Expected a full bucket after 30s, got allowed=%v remaining=%d", d.allowed, d.remaining)
+	}
+}
+
+func TestRateLimiter_HourlyQuota(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	rl := newRateLimiter()
+	rl.now = func() time.Time { return now }
+	limits := Limits{PerMinute: 60, PerHour: 5, Burst: 60}
+
+	for i := 0; i < 5; i++ {
+		if d := rl.allow(context.Background(), "key", limits); !d.allowed {
+			t.Fatalf("Request %d within hourly quota should be allowed", i+1)
+		}
+	}
+	d := rl.allow(context.Background(), "key", limits)
+	if d.allowed {
+		t.Fatal("Request beyond hourly quota should be denied")
+	}
+	if d.limit != 5 || d.retryAfter != 12*time.Minute {
+		t.Errorf("Expected the hourly window to bind (limit 5, retry in 12m), got limit %d retry %v", d.limit, d.retryAfter)
+	}
+}
+
+func TestRateLimiter_Cleanup(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	rl := newRateLimiter()
+	rl.now = func() time.Time { return now }
+	rl.local.now = rl.now
+
+	rl.allow(context.Background(), "key", Limits{PerMinute: 10, PerHour: 100, Burst: 10})
+	rl.cleanup()
+	if len(rl.local.states) != 1 {
+		t.Fatal("Active client should be kept")
+	}
+
+	now = now.Add(time.Hour)
+	rl.cleanup()
+	if len(rl.local.states) != 0 {
+		t.Error("Client with full buckets should be dropped")
+	}
+}
+
+func TestMiddleware_RateLimitHeaders(t *testing.T) {
+	SetSettingsSource(func() Settings {
+		return Settings{Client: Limits{PerMinute: 2, PerHour: 50}}
+	})
+	defer SetSettingsSource(nil)
+
+	handler := Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest("POST", "/chat", nil)
+		return req.WithContext(auth.WithIdentity(req.Context(), auth.Identity{KeyID: "key_headers"}))
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, newRequest())
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("RateLimit-Limit"); got != "2" {
+		t.Errorf("Expected RateLimit-Limit 2, got %q", got)
+	}
+	if got := rr.Header().Get("RateLimit-Remaining"); got != "1" {
+		t.Errorf("Expected RateLimit-Remaining 1, got %q", got)
+	}
+	if got := rr.Header().Get("RateLimit-Reset"); got != "30" {
+		t.Errorf("Expected RateLimit-Reset 30, got %q", got)
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), newRequest())
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, newRequest())
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Retry-After"); got == "" || got == "0" {
+		t.Errorf("Expected a Retry-After header, got %q", got)
+	}
+	if got := rr.Header().Get("RateLimit-Remaining"); got != "0" {
+		t.Errorf("Expected RateLimit-Remaining 0, got %q", got)
+	}
+}
+
+func TestPreAuthMiddleware_RateLimitHeaders(t *testing.T) {
+	handler := PreAuthMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	var rr *httptest.ResponseRecorder
+	for i := 0; i < 6; i++ {
+		req := httptest.NewRequest("POST", "/chat", nil)
+		req.RemoteAddr = "198.51.100.77:5555"
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+	}
+
+	// The default pre-auth limit is 5 per minute
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 after 6 requests, got %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") == "" || rr.Header().Get("RateLimit-Limit") != "5" {
+		t.Errorf("Expected rate limit headers, got %v", rr.Header())
+	}
+}
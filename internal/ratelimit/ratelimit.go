@@ -1,37 +1,50 @@
 package ratelimit
 
 import (
+	"context"
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/clotilde/carplay-assistant/internal/auth"
+	"github.com/clotilde/carplay-assistant/internal/metrics"
 )
 
-type rateLimiter struct {
-	requests map[string][]time.Time
-	mu       sync.Mutex
+// Limits are the request rates allowed for one client
+// Zero values use the defaults
+type Limits struct {
+	PerMinute int // Sustained rate
+	PerHour   int // Hourly quota
+	Burst     int // Requests allowed back to back (0 means PerMinute)
+}
+
+// Settings are the limits of both middlewares
+type Settings struct {
+	Client  Limits // Per validated API key (Middleware)
+	PreAuth Limits // Per IP before authentication (PreAuthMiddleware)
 }
 
 var (
-	globalLimiter = &rateLimiter{
-		requests: make(map[string][]time.Time),
-	}
-	
+	// Default limits
+	defaultClientLimits = Limits{PerMinute: 10, PerHour: 100}
+
+	// Pre-auth limits (stricter to prevent brute force)
+	defaultPreAuthLimits = Limits{PerMinute: 5, PerHour: 20}
+
+	settingsMu     sync.RWMutex
+	settingsSource func() Settings // Current limits, typically read from the runtime config
+
+	globalLimiter = newRateLimiter()
+
 	// Pre-auth IP-based rate limiter for brute force protection
-	preAuthLimiter = &rateLimiter{
-		requests: make(map[string][]time.Time),
-	}
-	
-	// Rate limits
-	requestsPerMinute = 10
-	requestsPerHour   = 100
-	cleanupInterval   = 5 * time.Minute
-	
-	// Pre-auth rate limits (stricter to prevent brute force)
-	preAuthRequestsPerMinute = 5  // Lower limit before authentication
-	preAuthRequestsPerHour   = 20 // Lower hourly limit
+	preAuthLimiter = newRateLimiter()
+
+	cleanupInterval = 5 * time.Minute
 )
 
 func init() {
@@ -46,74 +59,231 @@ func init() {
 	}()
 }
 
-func (rl *rateLimiter) cleanup() {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
+// SetSettingsSource makes the middlewares read their limits from source on every request,
+// so limit changes take effect without a restart
+func SetSettingsSource(source func() Settings) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	settingsSource = source
+}
 
-	now := time.Now()
-	oneHourAgo := now.Add(-1 * time.Hour)
+// SetStores moves the state of the API key and pre-auth limiters to shared stores, so limits
+// hold across instances and restarts (nil keeps the in-process state)
+func SetStores(client, preAuth Store) {
+	globalLimiter.setStore(client)
+	preAuthLimiter.setStore(preAuth)
+}
 
-	for key, times := range rl.requests {
-		// Remove old entries
-		validTimes := []time.Time{}
-		for _, t := range times {
-			if t.After(oneHourAgo) {
-				validTimes = append(validTimes, t)
-			}
-		}
+// currentSettings returns the configured limits with defaults filled in
+func currentSettings() Settings {
+	settingsMu.RLock()
+	source := settingsSource
+	settingsMu.RUnlock()
 
-		if len(validTimes) == 0 {
-			delete(rl.requests, key)
-		} else {
-			rl.requests[key] = validTimes
-		}
+	var settings Settings
+	if source != nil {
+		settings = source()
+	}
+	settings.Client = settings.Client.withDefaults(defaultClientLimits)
+	settings.PreAuth = settings.PreAuth.withDefaults(defaultPreAuthLimits)
+	return settings
+}
+
+// withDefaults replaces zero values with those of defaults
+func (l Limits) withDefaults(defaults Limits) Limits {
+	if l.PerMinute <= 0 {
+		l.PerMinute = defaults.PerMinute
 	}
+	if l.PerHour <= 0 {
+		l.PerHour = defaults.PerHour
+	}
+	if l.Burst <= 0 {
+		l.Burst = l.PerMinute
+	}
+	return l
+}
+
+// swapSlack is added to the burst to bound the retries when concurrent requests of one client race
+// on a shared store: each failed swap means another request took a token, so after Burst failures
+// the bucket is empty and the check denies without swapping (the slack covers tokens refilled meanwhile)
+const swapSlack = 5
+
+// errContention is returned when a client's state kept changing under us
+var errContention = errors.New("rate limit state changed concurrently")
+
+// rateLimiter implements the generic cell rate algorithm: a token bucket that stores one
+// timestamp per window instead of every request
+// The state lives in a Store; if a shared store fails, the limiter falls back to its local
+// in-process state so requests are still limited per instance
+type rateLimiter struct {
+	mu    sync.RWMutex
+	store Store // nil uses local
+
+	local     *MemoryStore
+	now       func() time.Time
+	lastError atomic.Int64 // Unix seconds of the last logged store error
 }
 
-func (rl *rateLimiter) isAllowed(key string) bool {
-	return rl.isAllowedWithLimits(key, requestsPerMinute, requestsPerHour)
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{local: NewMemoryStore(), now: time.Now}
 }
 
-func (rl *rateLimiter) isAllowedWithLimits(key string, perMinute, perHour int) bool {
+// setStore switches to store (nil for the in-process state)
+func (rl *rateLimiter) setStore(store Store) {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
+	rl.store = store
+}
+
+// decision is the outcome of a rate limit check, reported in the RateLimit-* headers
+type decision struct {
+	allowed    bool
+	limit      int           // Capacity of the most constraining window
+	remaining  int           // Requests left in that window
+	reset      time.Duration // Until that window is full again
+	retryAfter time.Duration // Until a request would be allowed (when denied)
+}
+
+// cleanup drops local clients whose buckets have refilled completely
+func (rl *rateLimiter) cleanup() {
+	rl.local.cleanup()
+}
 
-	now := time.Now()
-	oneMinuteAgo := now.Add(-1 * time.Minute)
-	oneHourAgo := now.Add(-1 * time.Hour)
+// allow checks a request from key against limits and records it if allowed
+func (rl *rateLimiter) allow(ctx context.Context, key string, limits Limits) decision {
+	rl.mu.RLock()
+	store := rl.store
+	rl.mu.RUnlock()
 
-	times, exists := rl.requests[key]
-	if !exists {
-		rl.requests[key] = []time.Time{now}
-		return true
+	if store != nil {
+		d, err := rl.allowWith(ctx, store, key, limits)
+		if err == nil {
+			return d
+		}
+		rl.logStoreError(err)
 	}
 
-	// Count requests in last minute
-	minuteCount := 0
-	hourCount := 0
-	for _, t := range times {
-		if t.After(oneMinuteAgo) {
-			minuteCount++
+	// The in-process store does not fail
+	d, _ := rl.allowWith(ctx, rl.local, key, limits)
+	return d
+}
+
+// allowWith runs the check against store, retrying if another request changed the state meanwhile
+func (rl *rateLimiter) allowWith(ctx context.Context, store Store, key string, limits Limits) (decision, error) {
+	for attempt := 0; attempt < limits.Burst+swapSlack; attempt++ {
+		state, err := store.Get(ctx, key)
+		if err != nil {
+			return decision{}, err
+		}
+
+		now := rl.now()
+		d, next := evaluate(state, now, limits)
+		if !d.allowed {
+			return d, nil
+		}
+
+		ttl := maxDuration(next.MinuteTAT.Sub(now), next.HourTAT.Sub(now))
+		swapped, err := store.CompareAndSwap(ctx, key, state, next, ttl)
+		if err != nil {
+			return decision{}, err
 		}
-		if t.After(oneHourAgo) {
-			hourCount++
+		if swapped {
+			return d, nil
 		}
 	}
+	return decision{}, errContention
+}
+
+// logStoreError logs store failures at most once a minute
+func (rl *rateLimiter) logStoreError(err error) {
+	now := time.Now().Unix()
+	last := rl.lastError.Load()
+	if now-last >= 60 && rl.lastError.CompareAndSwap(last, now) {
+		log.Printf("Rate limit store unavailable, limiting per instance: %v", err)
+	}
+}
 
-	// Check limits
-	if minuteCount >= perMinute {
-		return false
+// evaluate checks both windows; the request is allowed (and next is the state to save) only if
+// both allow it
+func evaluate(state State, now time.Time, limits Limits) (decision, State) {
+	minute := gcra(state.MinuteTAT, now, time.Minute, limits.PerMinute, limits.Burst)
+	hour := gcra(state.HourTAT, now, time.Hour, limits.PerHour, limits.PerHour)
+
+	d := minute.decision
+	if hour.remaining < minute.remaining || !hour.allowed {
+		d = hour.decision
 	}
-	if hourCount >= perHour {
-		return false
+	if !minute.allowed || !hour.allowed {
+		d.allowed = false
+		d.retryAfter = maxDuration(minute.retryAfter, hour.retryAfter)
+		return d, state
 	}
+	return d, State{MinuteTAT: minute.tat, HourTAT: hour.tat}
+}
 
-	// Add current request
-	rl.requests[key] = append(times, now)
-	return true
+type gcraResult struct {
+	decision
+	tat time.Time // TAT to store if the request is accepted
 }
 
-// Middleware implements rate limiting per validated API key or IP address
+// gcra evaluates one window: rate requests per window, up to burst at once
+func gcra(tat, now time.Time, window time.Duration, rate, burst int) gcraResult {
+	interval := window / time.Duration(rate) // Time to earn one token
+	tolerance := interval * time.Duration(burst)
+
+	if tat.Before(now) {
+		tat = now
+	}
+	newTAT := tat.Add(interval)
+
+	if wait := newTAT.Sub(now); wait > tolerance {
+		return gcraResult{decision: decision{
+			limit:      burst,
+			remaining:  0,
+			reset:      tat.Sub(now),
+			retryAfter: wait - tolerance,
+		}}
+	}
+
+	return gcraResult{
+		decision: decision{
+			allowed:   true,
+			limit:     burst,
+			remaining: int((tolerance - newTAT.Sub(now)) / interval),
+			reset:     newTAT.Sub(now),
+		},
+		tat: newTAT,
+	}
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// writeHeaders reports the limit state using the IETF RateLimit header fields
+func writeHeaders(w http.ResponseWriter, d decision) {
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(d.limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(d.remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(d.reset)))
+	if !d.allowed {
+		h.Set("Retry-After", strconv.Itoa(ceilSeconds(d.retryAfter)))
+	}
+}
+
+// ceilSeconds rounds a duration up to whole seconds (headers use delta-seconds)
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// Middleware implements rate limiting per validated API key ID or IP address
+// Keys from the registry may carry their own limits; the configured limits apply otherwise
 // IMPORTANT: This middleware must run AFTER auth.Middleware to ensure API keys are validated
 // Only validated API keys (from context) are used for rate limiting to prevent bypass attacks
 func Middleware() func(http.Handler) http.Handler {
@@ -125,14 +295,22 @@ func Middleware() func(http.Handler) http.Handler {
 				return
 			}
 
-			// Get validated API key from context (set by auth.Middleware)
-			// Only use API key if it has been validated to prevent rate limit bypass
-			validatedAPIKey := auth.GetValidatedAPIKey(r.Context())
-			
+			// Get the validated key identity from context (set by auth.Middleware)
+			// Only use it if it has been validated to prevent rate limit bypass
+			identity, ok := auth.GetIdentity(r.Context())
+
 			var key string
-			if validatedAPIKey != "" {
-				// Use validated API key for rate limiting
-				key = validatedAPIKey
+			limits := currentSettings().Client
+			if ok && identity.KeyID != "" {
+				// Use the key ID for rate limiting, with the key's own limits if set
+				key = identity.KeyID
+				if identity.RequestsPerMinute > 0 {
+					limits.PerMinute = identity.RequestsPerMinute
+					limits.Burst = identity.RequestsPerMinute
+				}
+				if identity.RequestsPerHour > 0 {
+					limits.PerHour = identity.RequestsPerHour
+				}
 			} else {
 				// Fallback to IP address if no validated API key
 				// This should only happen if auth middleware was skipped
@@ -140,7 +318,10 @@ func Middleware() func(http.Handler) http.Handler {
 				key = ip
 			}
 
-			if !globalLimiter.isAllowed(key) {
+			d := globalLimiter.allow(r.Context(), key, limits)
+			writeHeaders(w, d)
+			if !d.allowed {
+				metrics.RateLimitRejections.WithLabelValues(metrics.LimiterClient).Inc()
 				http.Error(w, `{"error":"Rate limit exceeded"}`, http.StatusTooManyRequests)
 				return
 			}
@@ -234,7 +415,10 @@ func PreAuthMiddleware() func(http.Handler) http.Handler {
 			ip := getClientIP(r)
 
 			// Use stricter limits for pre-auth requests
-			if !preAuthLimiter.isAllowedWithLimits(ip, preAuthRequestsPerMinute, preAuthRequestsPerHour) {
+			d := preAuthLimiter.allow(r.Context(), ip, currentSettings().PreAuth)
+			writeHeaders(w, d)
+			if !d.allowed {
+				metrics.RateLimitRejections.WithLabelValues(metrics.LimiterPreAuth).Inc()
 				http.Error(w, `{"error":"Too many requests"}`, http.StatusTooManyRequests)
 				return
 			}
@@ -8,6 +8,8 @@ import (
 	"testing"
 
 	"github.com/clotilde/carplay-assistant/internal/auth"
+	"github.com/clotilde/carplay-assistant/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestMiddleware_AllowsFirstRequest(t *testing.T) {
@@ -36,6 +38,7 @@ func TestMiddleware_RateLimitExceeded(t *testing.T) {
 	req := httptest.NewRequest("POST", "/chat", nil)
 	req.Header.Set("X-API-Key", apiKey)
 	rr := httptest.NewRecorder()
+	rejectionsBefore := testutil.ToFloat64(metrics.RateLimitRejections.WithLabelValues(metrics.LimiterClient))
 
 	// Make 11 requests (limit is 10 per minute)
 	for i := 0; i < 11; i++ {
@@ -52,6 +55,9 @@ func TestMiddleware_RateLimitExceeded(t *testing.T) {
 	if actualBody != expectedBody {
 		t.Errorf("Expected body %q, got %q", expectedBody, actualBody)
 	}
+	if got := testutil.ToFloat64(metrics.RateLimitRejections.WithLabelValues(metrics.LimiterClient)); got != rejectionsBefore+1 {
+		t.Errorf("Expected the rejection counted, got %v (was %v)", got, rejectionsBefore)
+	}
 }
 
 func TestMiddleware_HealthCheckBypass(t *testing.T) {
@@ -327,7 +333,7 @@ func TestRateLimiter_DifferentKeys(t *testing.T) {
 		req := httptest.NewRequest("POST", "/chat", nil)
 		req.Header.Set("X-API-Key", key1)
 		// Add validated API key to context (simulating auth middleware behavior)
-		ctx := auth.WithValidatedAPIKey(req.Context(), key1)
+		ctx := auth.WithIdentity(req.Context(), auth.Identity{KeyID: key1})
 		req = req.WithContext(ctx)
 		rr := httptest.NewRecorder()
 		handler.ServeHTTP(rr, req)
@@ -342,7 +348,7 @@ func TestRateLimiter_DifferentKeys(t *testing.T) {
 		req := httptest.NewRequest("POST", "/chat", nil)
 		req.Header.Set("X-API-Key", key2)
 		// Add validated API key to context (simulating auth middleware behavior)
-		ctx := auth.WithValidatedAPIKey(req.Context(), key2)
+		ctx := auth.WithIdentity(req.Context(), auth.Identity{KeyID: key2})
 		req = req.WithContext(ctx)
 		rr := httptest.NewRecorder()
 		handler.ServeHTTP(rr, req)
@@ -364,3 +370,25 @@ func TestGetClientIP_XForwardedFor_EmptyString(t *testing.T) {
 	}
 }
 
+
+func TestRateLimiter_PerKeyLimits(t *testing.T) {
+	handler := Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	identity := auth.Identity{KeyID: "key_perkeylimit", RequestsPerMinute: 2}
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("POST", "/chat", nil)
+		req = req.WithContext(auth.WithIdentity(req.Context(), identity))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		want := http.StatusOK
+		if i == 2 {
+			want = http.StatusTooManyRequests
+		}
+		if rr.Code != want {
+			t.Errorf("Request %d: expected %d, got %d", i+1, want, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// casScript replaces the state hash only if it still holds the expected TATs
+// KEYS[1] = state key; ARGV = expected minute TAT, expected hour TAT, new minute TAT, new hour TAT, TTL (ms)
+// TATs are Unix microseconds, "0" for a client without state
+var casScript = redis.NewScript(`
+local minute = redis.call('HGET', KEYS[1], 'm') or '0'
+local hour = redis.call('HGET', KEYS[1], 'h') or '0'
+if minute ~= ARGV[1] or hour ~= ARGV[2] then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'm', ARGV[3], 'h', ARGV[4])
+redis.call('PEXPIRE', KEYS[1], ARGV[5])
+return 1
+`)
+
+// RedisStore keeps the state in Redis so every instance enforces the same limits, and limits
+// survive cold starts
+// Each client is a hash under prefix+key that expires once its buckets are full again
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisStore creates a store using client; prefix separates the state of different limiters
+func NewRedisStore(client redis.UniversalClient, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// Get returns the state of key
+func (s *RedisStore) Get(ctx context.Context, key string) (State, error) {
+	values, err := s.client.HMGet(ctx, s.prefix+key, "m", "h").Result()
+	if err != nil {
+		return State{}, fmt.Errorf("failed to read rate limit state: %w", err)
+	}
+
+	minute, err := decodeTAT(values[0])
+	if err != nil {
+		return State{}, err
+	}
+	hour, err := decodeTAT(values[1])
+	if err != nil {
+		return State{}, err
+	}
+	return State{MinuteTAT: minute, HourTAT: hour}, nil
+}
+
+// CompareAndSwap saves next if the state of key is still prev, atomically in a Lua script
+func (s *RedisStore) CompareAndSwap(ctx context.Context, key string, prev, next State, ttl time.Duration) (bool, error) {
+	ttlMillis := ttl.Milliseconds()
+	if ttlMillis < 1000 {
+		ttlMillis = 1000
+	}
+
+	swapped, err := casScript.Run(ctx, s.client, []string{s.prefix + key},
+		encodeTAT(prev.MinuteTAT), encodeTAT(prev.HourTAT),
+		encodeTAT(next.MinuteTAT), encodeTAT(next.HourTAT),
+		ttlMillis,
+	).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to save rate limit state: %w", err)
+	}
+	return swapped == 1, nil
+}
+
+// encodeTAT stores a TAT as Unix microseconds ("0" for none)
+func encodeTAT(t time.Time) string {
+	if t.IsZero() {
+		return "0"
+	}
+	return strconv.FormatInt(t.UnixMicro(), 10)
+}
+
+func decodeTAT(value interface{}) (time.Time, error) {
+	s, ok := value.(string)
+	if !ok || s == "0" {
+		// Missing field
+		return time.Time{}, nil
+	}
+	micros, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid rate limit state %q", s)
+	}
+	return time.UnixMicro(micros), nil
+}
@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	return server, client
+}
+
+func TestRedisStore_SharedAcrossInstances(t *testing.T) {
+	_, client := newTestRedis(t)
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	limits := Limits{PerMinute: 6, PerHour: 100, Burst: 3}
+
+	// Two instances of the service, each with its own limiter
+	instances := []*rateLimiter{newRateLimiter(), newRateLimiter()}
+	for _, rl := range instances {
+		rl.now = func() time.Time { return now }
+		rl.setStore(NewRedisStore(client, "test:"))
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if d := instances[i%2].allow(ctx, "key", limits); !d.allowed {
+			t.Fatalf("Request %d within burst should be allowed", i+1)
+		}
+	}
+	for _, rl := range instances {
+		if d := rl.allow(ctx, "key", limits); d.allowed {
+			t.Error("Burst is shared, so every instance should deny the next request")
+		}
+	}
+
+	now = now.Add(10 * time.Second)
+	if d := instances[1].allow(ctx, "key", limits); !d.allowed || d.remaining != 0 {
+		t.Errorf("Expected one refilled token, got allowed=%v remaining=%d", d.allowed, d.remaining)
+	}
+
+	// Nothing was written to the local state
+	for _, rl := range instances {
+		if len(rl.local.states) != 0 {
+			t.Error("Local state should be unused while Redis is available")
+		}
+	}
+}
+
+func TestRedisStore_StateRoundTrip(t *testing.T) {
+	server, client := newTestRedis(t)
+	store := NewRedisStore(client, "test:")
+	ctx := context.Background()
+
+	state, err := store.Get(ctx, "key")
+	if err != nil || state != (State{}) {
+		t.Fatalf("Expected empty state for unknown client, got %+v (%v)", state, err)
+	}
+
+	next := State{
+		MinuteTAT: time.UnixMicro(1790000000123456),
+		HourTAT:   time.UnixMicro(1790000360123456),
+	}
+	swapped, err := store.CompareAndSwap(ctx, "key", State{}, next, time.Hour)
+	if err != nil || !swapped {
+		t.Fatalf("Expected swap from empty state, got %v (%v)", swapped, err)
+	}
+	if ttl := server.TTL("test:key"); ttl != time.Hour {
+		t.Errorf("Expected state to expire in 1h, got %v", ttl)
+	}
+
+	got, err := store.Get(ctx, "key")
+	if err != nil || !got.equal(next) {
+		t.Fatalf("Expected %+v, got %+v (%v)", next, got, err)
+	}
+
+	// A stale expected state must not overwrite
+	swapped, err = store.CompareAndSwap(ctx, "key", State{}, State{MinuteTAT: time.UnixMicro(1)}, time.Hour)
+	if err != nil || swapped {
+		t.Errorf("Expected stale swap to fail, got %v (%v)", swapped, err)
+	}
+}
+
+func TestRedisStore_ConcurrentRequests(t *testing.T) {
+	_, client := newTestRedis(t)
+	limits := Limits{PerMinute: 10, PerHour: 100, Burst: 10}
+
+	rl := newRateLimiter()
+	rl.setStore(NewRedisStore(client, "test:"))
+
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if rl.allow(context.Background(), "key", limits).allowed {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := allowed.Load(); got != 10 {
+		t.Errorf("Expected exactly 10 of 20 concurrent requests allowed, got %d", got)
+	}
+}
+
+func TestRedisStore_FallsBackWhenUnavailable(t *testing.T) {
+	server, client := newTestRedis(t)
+	limits := Limits{PerMinute: 2, PerHour: 100, Burst: 2}
+
+	rl := newRateLimiter()
+	rl.setStore(NewRedisStore(client, "test:"))
+	server.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if d := rl.allow(ctx, "key", limits); !d.allowed {
+			t.Fatalf("Request %d should be allowed by the local fallback", i+1)
+		}
+	}
+	if d := rl.allow(ctx, "key", limits); d.allowed {
+		t.Error("Local fallback should still enforce the limit")
+	}
+}
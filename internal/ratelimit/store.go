@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// State is the GCRA state of one client: the theoretical arrival time (TAT) of its next request
+// in each window
+type State struct {
+	MinuteTAT time.Time
+	HourTAT   time.Time
+}
+
+// equal compares states by instant (stores may not keep monotonic clock readings or locations)
+func (s State) equal(other State) bool {
+	return s.MinuteTAT.Equal(other.MinuteTAT) && s.HourTAT.Equal(other.HourTAT)
+}
+
+// Store keeps the state of every client of a rate limiter
+// Instances that share a store share their limits
+type Store interface {
+	// Get returns the state of key (the zero State for an unknown client)
+	Get(ctx context.Context, key string) (State, error)
+	// CompareAndSwap saves next as the state of key if it is still prev, and reports whether it did
+	// The state may be dropped after ttl, when its buckets are full again
+	CompareAndSwap(ctx context.Context, key string, prev, next State, ttl time.Duration) (bool, error)
+}
+
+// MemoryStore keeps the state in process, so each instance enforces its own limits
+type MemoryStore struct {
+	mu     sync.Mutex
+	states map[string]State
+	now    func() time.Time
+}
+
+// NewMemoryStore creates an empty in-process store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{states: make(map[string]State), now: time.Now}
+}
+
+// Get returns the state of key
+func (s *MemoryStore) Get(ctx context.Context, key string) (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.states[key], nil
+}
+
+// CompareAndSwap saves next if the state of key is still prev
+// ttl is not needed: cleanup drops states whose buckets are full
+func (s *MemoryStore) CompareAndSwap(ctx context.Context, key string, prev, next State, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.states[key].equal(prev) {
+		return false, nil
+	}
+	s.states[key] = next
+	return true, nil
+}
+
+// cleanup drops clients whose buckets have refilled completely
+func (s *MemoryStore) cleanup() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for key, state := range s.states {
+		if !state.MinuteTAT.After(now) && !state.HourTAT.After(now) {
+			delete(s.states, key)
+		}
+	}
+}
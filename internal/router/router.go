@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/clotilde/carplay-assistant/internal/admin"
+	"github.com/clotilde/carplay-assistant/internal/provider"
 )
 
 // Category represents the routing category
@@ -28,27 +29,6 @@ type RouteDecision struct {
 	ReasoningEffort string // "none", "low", "medium", "high" - empty means no reasoning config
 }
 
-// Models that support web search in Responses API
-var modelsWithWebSearch = map[string]bool{
-	"gpt-4o":            true,
-	"gpt-4o-mini":       true,
-	"gpt-4o-2024-08-06": true,
-	"chatgpt-4o-latest": true,
-	"gpt-4-turbo":       true,
-	"gpt-4.1":           true,
-	"gpt-4.1-mini":      true,
-	// gpt-4.1-nano does NOT support web search
-	// gpt-5 series needs reasoning >= "low" for web search
-	"gpt-5":     true, // with reasoning
-	"gpt-5.1":   true, // with reasoning
-	"gpt-5-pro": true, // with reasoning
-	// gpt-5-mini and gpt-5-nano may not support web search
-	"gpt-5-mini": true,
-	"o3":         true,
-	"o3-mini":    true,
-	"o4-mini":    true,
-}
-
 // Fallback model for web search when configured model doesn't support it
 const webSearchFallbackModel = "gpt-4o-mini"
 
@@ -166,6 +146,14 @@ func matchCategory(questionNormalized string, cat Category) float64 {
 
 // Route determines which category, model, and tools to use based on question
 func Route(question string) RouteDecision {
+	return RouteFollowUp(question, "")
+}
+
+// RouteFollowUp routes a question that continues a conversation
+// When the question matches no category on its own (e.g. "e em Curitiba?"), it inherits
+// the category of the previous question so follow-ups keep web search, reasoning, etc.
+// An empty previous category behaves exactly like Route
+func RouteFollowUp(question string, previous Category) RouteDecision {
 	config := admin.GetConfig()
 	standardModel := config.StandardModel
 	premiumModel := config.PremiumModel
@@ -203,6 +191,11 @@ func Route(question string) RouteDecision {
 		}
 	}
 
+	if _, known := scores[previous]; known && bestCategory == CategorySimple {
+		log.Printf("Route: follow-up with no category match, inheriting previous category %s", previous)
+		bestCategory = previous
+	}
+
 	// Determine model, web search, and reasoning based on category
 	// Check for category-specific model override first
 	var model string
@@ -251,25 +244,9 @@ func Route(question string) RouteDecision {
 		webSearch = false
 	}
 
-	// If web search is needed, ensure the model supports it
+	// If web search is needed, ensure the model supports it (capabilities are declared by providers)
 	if webSearch {
-		isClaude := strings.HasPrefix(model, "claude-")
-		supportsWebSearch := modelsWithWebSearch[model]
-		isGPT5 := strings.HasPrefix(model, "gpt-5")
-
-		// Claude models support web search via Perplexity integration (handled in main.go)
-		// Only allow if Perplexity is enabled in config
-		if isClaude && config.PerplexityEnabled {
-			// Claude + Perplexity is supported, no fallback needed
-			// Reasoning effort not applicable to Claude
-		} else if !supportsWebSearch {
-			log.Printf("Model %s does not support web search, using fallback: %s", model, webSearchFallbackModel)
-			model = webSearchFallbackModel
-			reasoningEffort = ""
-		} else if isGPT5 {
-			reasoningEffort = "medium"
-			log.Printf("gpt-5 with web search: using reasoning='medium' (minimum required)")
-		}
+		model, reasoningEffort = webSearchModel(model, config)
 	}
 
 	return RouteDecision{
@@ -279,3 +256,94 @@ func Route(question string) RouteDecision {
 		ReasoningEffort: reasoningEffort,
 	}
 }
+
+// webSearchModel returns the model and reasoning effort to answer a web search question with model
+func webSearchModel(model string, config admin.RuntimeConfig) (string, string) {
+	caps := provider.CapabilitiesFor(model)
+
+	// Models without native web search can use Perplexity results (handled in main.go)
+	// Only allow if Perplexity is enabled in config
+	if caps.ExternalSearch && config.PerplexityEnabled {
+		// External search is supported, no fallback needed
+		// Reasoning effort not applicable
+		return model, ""
+	}
+	if caps.Local {
+		// Self-hosted models are used for privacy-sensitive drivers: never send them to a cloud model
+		log.Printf("Local model %s has no web search available, answering from model knowledge", model)
+		return model, ""
+	}
+	if !caps.WebSearch {
+		log.Printf("Model %s does not support web search, using fallback: %s", model, webSearchFallbackModel)
+		return webSearchFallbackModel, ""
+	}
+	if caps.WebSearchReasoning != "" {
+		log.Printf("%s with web search: using reasoning='%s' (minimum required)", model, caps.WebSearchReasoning)
+		return model, caps.WebSearchReasoning
+	}
+	return model, ""
+}
+
+// isLocal reports whether model is self-hosted (also when the local provider is not registered)
+func isLocal(model string) bool {
+	return provider.CapabilitiesFor(model).Local || strings.HasPrefix(model, provider.LocalModelPrefix)
+}
+
+// Fallbacks returns the decisions to try, in order, when the model of decision fails with a
+// retryable error: the fallback chain configured for its category, with the web search capability
+// checks applied to each model; models already tried are skipped
+// A local model only falls back to other local models, so its questions never reach a cloud model
+func Fallbacks(decision RouteDecision) []RouteDecision {
+	config := admin.GetConfig()
+	local := isLocal(decision.Model)
+	seen := map[string]bool{decision.Model: true}
+	var fallbacks []RouteDecision
+	for _, model := range config.FallbackChain(string(decision.Category)) {
+		if local && !isLocal(model) {
+			continue
+		}
+		fallback := decision
+		fallback.Model, fallback.ReasoningEffort = model, ""
+		if fallback.WebSearch {
+			fallback.Model, fallback.ReasoningEffort = webSearchModel(model, config)
+		}
+		if seen[fallback.Model] {
+			continue
+		}
+		seen[fallback.Model] = true
+		fallbacks = append(fallbacks, fallback)
+	}
+	return fallbacks
+}
+
+// Hedge returns the decision for the hedge model, asked too when the routed model is slow to answer
+// ok is false when hedging is off or the hedge model is the routed model, and for a local model
+// unless the hedge model is local too, so its questions never reach a cloud model
+func Hedge(decision RouteDecision) (hedge RouteDecision, ok bool) {
+	config := admin.GetConfig()
+	if config.HedgeModel == "" || (isLocal(decision.Model) && !isLocal(config.HedgeModel)) {
+		return RouteDecision{}, false
+	}
+	hedge = decision
+	hedge.Model, hedge.ReasoningEffort = config.HedgeModel, ""
+	if hedge.WebSearch {
+		hedge.Model, hedge.ReasoningEffort = webSearchModel(config.HedgeModel, config)
+	}
+	return hedge, hedge.Model != decision.Model
+}
+
+// Downgrade moves a decision to the standard model (e.g. when the caller's budget is exhausted)
+// The category and web search are kept, with the capability checks applied to the new model
+// Local decisions are kept as they are: they cost nothing and must not be moved to a cloud model
+func Downgrade(decision RouteDecision) RouteDecision {
+	if isLocal(decision.Model) {
+		return decision
+	}
+	config := admin.GetConfig()
+	decision.Model = config.StandardModel
+	decision.ReasoningEffort = ""
+	if decision.WebSearch {
+		decision.Model, decision.ReasoningEffort = webSearchModel(decision.Model, config)
+	}
+	return decision
+}
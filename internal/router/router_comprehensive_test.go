@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/clotilde/carplay-assistant/internal/admin"
+	"github.com/clotilde/carplay-assistant/internal/provider"
 )
 
 func init() {
@@ -161,7 +162,7 @@ func TestModelSelection(t *testing.T) {
 				// Model should be standard, premium (if supports web search), or fallback
 				validModels := map[string]bool{
 					tt.standardModel:       true,
-					tt.premiumModel:        modelsWithWebSearch[tt.premiumModel],
+					tt.premiumModel:        provider.SupportsWebSearch(tt.premiumModel),
 					webSearchFallbackModel: true,
 				}
 				if !validModels[decision.Model] {
@@ -220,6 +221,38 @@ func TestWebSearchFallback(t *testing.T) {
 	})
 }
 
+// TestWebSearchLocalModelNoCloudFallback tests that local models are never rerouted to a cloud model
+func TestWebSearchLocalModelNoCloudFallback(t *testing.T) {
+	provider.Register(provider.NewLocal("http://localhost:11434/v1", "", nil))
+
+	config := admin.GetConfig()
+	originalStandard := config.StandardModel
+
+	// PerplexityEnabled=false: no external search available
+	if err := admin.SetConfig(admin.RuntimeConfig{
+		BaseSystemPrompt: config.BaseSystemPrompt,
+		StandardModel:    "local:llama3",
+		PremiumModel:     "gpt-4.1",
+	}); err != nil {
+		t.Fatalf("Failed to set config: %v", err)
+	}
+
+	decision := Route("Quais as notícias?")
+	if !decision.WebSearch {
+		t.Error("Expected webSearch=true")
+	}
+	if decision.Model != "local:llama3" {
+		t.Errorf("Expected local model to be kept, got %s", decision.Model)
+	}
+
+	// Restore
+	admin.SetConfig(admin.RuntimeConfig{
+		BaseSystemPrompt: config.BaseSystemPrompt,
+		StandardModel:    originalStandard,
+		PremiumModel:     config.PremiumModel,
+	})
+}
+
 // TestGPT5ReasoningEffort tests GPT-5 series requires reasoning for web search
 func TestGPT5ReasoningEffort(t *testing.T) {
 	config := admin.GetConfig()
@@ -114,3 +114,155 @@ func TestRoute(t *testing.T) {
 		})
 	}
 }
+
+func TestRouteFollowUp(t *testing.T) {
+	admin.SetDefaultConfig("System prompt %s")
+
+	tests := []struct {
+		name             string
+		question         string
+		previous         Category
+		expectedCategory Category
+	}{
+		{"Unmatched follow-up inherits web search", "E em Curitiba?", CategoryWebSearch, CategoryWebSearch},
+		{"Matched question keeps its own category", "Explique a teoria da relatividade", CategoryWebSearch, CategoryComplex},
+		{"No previous category", "E em Curitiba?", "", CategorySimple},
+		{"Unknown previous category is ignored", "E em Curitiba?", Category("bogus"), CategorySimple},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := RouteFollowUp(tt.question, tt.previous)
+			if decision.Category != tt.expectedCategory {
+				t.Errorf("RouteFollowUp(%q, %q) category = %v, want %v", tt.question, tt.previous, decision.Category, tt.expectedCategory)
+			}
+		})
+	}
+
+	if decision := RouteFollowUp("E em Curitiba?", CategoryWebSearch); !decision.WebSearch {
+		t.Error("Inherited web search category should enable web search")
+	}
+}
+
+func TestDowngrade(t *testing.T) {
+	admin.SetDefaultConfig("System prompt %s")
+	original := admin.GetConfig()
+	defer admin.SetConfig(original)
+
+	config := original
+	config.StandardModel = "gpt-4.1-nano" // No web search support
+	config.PremiumModel = "gpt-5"
+	if err := admin.SetConfig(config); err != nil {
+		t.Fatalf("SetConfig failed: %v", err)
+	}
+
+	decision := Downgrade(RouteDecision{Category: CategoryComplex, Model: "gpt-5", ReasoningEffort: "high"})
+	if decision.Model != "gpt-4.1-nano" || decision.ReasoningEffort != "" || decision.Category != CategoryComplex {
+		t.Errorf("Expected complex question on the standard model, got %+v", decision)
+	}
+
+	// Web search is kept, falling back to a model that supports it
+	decision = Downgrade(RouteDecision{Category: CategoryWebSearch, Model: "gpt-5", WebSearch: true, ReasoningEffort: "medium"})
+	if !decision.WebSearch || decision.Model != webSearchFallbackModel || decision.ReasoningEffort != "" {
+		t.Errorf("Expected web search on the fallback model, got %+v", decision)
+	}
+
+	// A local model stays local
+	local := RouteDecision{Category: CategoryComplex, Model: "local:llama3"}
+	if decision := Downgrade(local); decision != local {
+		t.Errorf("Expected the local decision unchanged, got %+v", decision)
+	}
+}
+
+func TestFallbacks(t *testing.T) {
+	admin.SetDefaultConfig("System prompt %s")
+	original := admin.GetConfig()
+	defer admin.SetConfig(original)
+
+	config := original
+	config.PerplexityEnabled = false
+	config.FallbackModels = map[string][]string{
+		"default":    {"gpt-4o-mini", "gpt-5", "gpt-4.1-nano"},
+		"web_search": {"gpt-4.1-nano", webSearchFallbackModel}, // No web search support, then the same fallback
+		"creative":   {},
+	}
+	if err := admin.SetConfig(config); err != nil {
+		t.Fatalf("SetConfig failed: %v", err)
+	}
+
+	fallbacks := Fallbacks(RouteDecision{Category: CategoryComplex, Model: "gpt-5", ReasoningEffort: "high"})
+	if len(fallbacks) != 2 || fallbacks[0].Model != "gpt-4o-mini" || fallbacks[1].Model != "gpt-4.1-nano" {
+		t.Fatalf("Expected the default chain without the routed model, got %+v", fallbacks)
+	}
+	if fallbacks[0].Category != CategoryComplex || fallbacks[0].ReasoningEffort != "" {
+		t.Errorf("Expected the category kept and no reasoning effort, got %+v", fallbacks[0])
+	}
+
+	// Web search is kept, with the capability checks applied to each model
+	fallbacks = Fallbacks(RouteDecision{Category: CategoryWebSearch, Model: "gpt-5", WebSearch: true, ReasoningEffort: "medium"})
+	if len(fallbacks) != 1 || fallbacks[0].Model != webSearchFallbackModel || !fallbacks[0].WebSearch {
+		t.Errorf("Expected one web search capable fallback, got %+v", fallbacks)
+	}
+
+	// A local model never falls back to a cloud model
+	config.FallbackModels["default"] = []string{"gpt-4o-mini", "local:qwen3", "gpt-5"}
+	if err := admin.SetConfig(config); err != nil {
+		t.Fatalf("SetConfig failed: %v", err)
+	}
+	fallbacks = Fallbacks(RouteDecision{Category: CategorySimple, Model: "local:llama3"})
+	if len(fallbacks) != 1 || fallbacks[0].Model != "local:qwen3" {
+		t.Errorf("Expected only the local fallback, got %+v", fallbacks)
+	}
+	fallbacks = Fallbacks(RouteDecision{Category: CategoryWebSearch, Model: "local:llama3", WebSearch: true})
+	if len(fallbacks) != 0 {
+		t.Errorf("Expected no cloud fallback for a local web search, got %+v", fallbacks)
+	}
+
+	// An empty chain turns fallbacks off for the category
+	if fallbacks := Fallbacks(RouteDecision{Category: CategoryCreative, Model: "gpt-5"}); len(fallbacks) != 0 {
+		t.Errorf("Expected no fallbacks for creative, got %+v", fallbacks)
+	}
+}
+
+func TestHedge(t *testing.T) {
+	admin.SetDefaultConfig("System prompt %s")
+	original := admin.GetConfig()
+	defer admin.SetConfig(original)
+
+	if _, ok := Hedge(RouteDecision{Category: CategorySimple, Model: "gpt-4o"}); ok {
+		t.Error("Expected no hedge without a hedge model")
+	}
+
+	config := original
+	config.PerplexityEnabled = false
+	config.HedgeModel = "gpt-4.1-nano"
+	if err := admin.SetConfig(config); err != nil {
+		t.Fatalf("SetConfig failed: %v", err)
+	}
+
+	hedge, ok := Hedge(RouteDecision{Category: CategoryComplex, Model: "gpt-5", ReasoningEffort: "high"})
+	if !ok || hedge.Model != "gpt-4.1-nano" || hedge.Category != CategoryComplex || hedge.ReasoningEffort != "" {
+		t.Errorf("Expected the hedge model without reasoning effort, got %+v %v", hedge, ok)
+	}
+	if _, ok := Hedge(RouteDecision{Category: CategorySimple, Model: "gpt-4.1-nano"}); ok {
+		t.Error("Expected no hedge when the routed model is the hedge model")
+	}
+
+	// Without Perplexity a web search hedge needs a model with native web search
+	hedge, ok = Hedge(RouteDecision{Category: CategoryWebSearch, Model: "gpt-5", WebSearch: true})
+	if !ok || hedge.Model != webSearchFallbackModel || !hedge.WebSearch {
+		t.Errorf("Expected a web search capable hedge, got %+v %v", hedge, ok)
+	}
+
+	// A local model is never hedged with a cloud model, only with another local one
+	if hedge, ok := Hedge(RouteDecision{Category: CategorySimple, Model: "local:llama3"}); ok {
+		t.Errorf("Expected no cloud hedge for a local model, got %+v", hedge)
+	}
+	config.HedgeModel = "local:qwen3"
+	if err := admin.SetConfig(config); err != nil {
+		t.Fatalf("SetConfig failed: %v", err)
+	}
+	if hedge, ok := Hedge(RouteDecision{Category: CategorySimple, Model: "local:llama3"}); !ok || hedge.Model != "local:qwen3" {
+		t.Errorf("Expected the local hedge model, got %+v %v", hedge, ok)
+	}
+}
@@ -0,0 +1,143 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryConfig bounds the in-memory session store
+type MemoryConfig struct {
+	TTL         time.Duration // Idle time after which a session expires
+	MaxTurns    int           // Maximum turns kept per session (oldest dropped first)
+	MaxSessions int           // Maximum sessions kept per instance (least recently used evicted first)
+}
+
+// DefaultMemoryConfig returns limits suited to a small Cloud Run instance (256MB RAM):
+// a driving conversation rarely needs more than a few exchanges, and instances are recycled anyway
+func DefaultMemoryConfig() MemoryConfig {
+	return MemoryConfig{
+		TTL:         30 * time.Minute,
+		MaxTurns:    10, // 5 question/answer exchanges
+		MaxSessions: 500,
+	}
+}
+
+// MemoryStore is an in-process Store with TTL expiry and LRU eviction
+// Sessions are per-instance: on Cloud Run a follow-up routed to another instance starts a new conversation
+type MemoryStore struct {
+	config   MemoryConfig
+	sessions map[string]Session
+	mu       sync.Mutex
+	now      func() time.Time
+}
+
+// NewMemoryStore creates an in-memory store and starts its cleanup goroutine
+func NewMemoryStore(config MemoryConfig) *MemoryStore {
+	defaults := DefaultMemoryConfig()
+	if config.TTL <= 0 {
+		config.TTL = defaults.TTL
+	}
+	if config.MaxTurns <= 0 {
+		config.MaxTurns = defaults.MaxTurns
+	}
+	if config.MaxSessions <= 0 {
+		config.MaxSessions = defaults.MaxSessions
+	}
+
+	s := &MemoryStore{
+		config:   config,
+		sessions: make(map[string]Session),
+		now:      time.Now,
+	}
+	go s.cleanupLoop()
+	return s
+}
+
+// Load returns a copy of the session if it exists and has not expired
+func (s *MemoryStore) Load(ctx context.Context, id string) (Session, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[id]
+	if !ok {
+		return Session{}, false, nil
+	}
+	if s.now().Sub(sess.UpdatedAt) > s.config.TTL {
+		delete(s.sessions, id)
+		return Session{}, false, nil
+	}
+
+	turns := make([]Turn, len(sess.Turns))
+	copy(turns, sess.Turns)
+	sess.Turns = turns
+	return sess, true, nil
+}
+
+// Save stores the session, keeping only the most recent MaxTurns turns
+func (s *MemoryStore) Save(ctx context.Context, sess Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(sess.Turns) > s.config.MaxTurns {
+		sess.Turns = sess.Turns[len(sess.Turns)-s.config.MaxTurns:]
+	}
+	turns := make([]Turn, len(sess.Turns))
+	copy(turns, sess.Turns)
+	sess.Turns = turns
+	sess.UpdatedAt = s.now()
+
+	// Enforce session limit (LRU eviction) before adding a new session
+	if _, exists := s.sessions[sess.ID]; !exists && len(s.sessions) >= s.config.MaxSessions {
+		var oldestID string
+		var oldestTime time.Time
+		for id, existing := range s.sessions {
+			if oldestID == "" || existing.UpdatedAt.Before(oldestTime) {
+				oldestID = id
+				oldestTime = existing.UpdatedAt
+			}
+		}
+		delete(s.sessions, oldestID)
+	}
+
+	s.sessions[sess.ID] = sess
+	return nil
+}
+
+// Delete removes a session
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+// Len returns the number of sessions currently held (including expired ones not yet cleaned up)
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
+
+// cleanupLoop periodically removes expired sessions
+func (s *MemoryStore) cleanupLoop() {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.cleanup()
+	}
+}
+
+// cleanup removes expired sessions
+func (s *MemoryStore) cleanup() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for id, sess := range s.sessions {
+		if now.Sub(sess.UpdatedAt) > s.config.TTL {
+			delete(s.sessions, id)
+		}
+	}
+}
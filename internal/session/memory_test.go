@@ -0,0 +1,131 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func newTestStore(config MemoryConfig) (*MemoryStore, *time.Time) {
+	store := NewMemoryStore(config)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	return store, &now
+}
+
+func TestMemoryStore_SaveAndLoad(t *testing.T) {
+	store, _ := newTestStore(DefaultMemoryConfig())
+	ctx := context.Background()
+
+	if _, found, _ := store.Load(ctx, "missing"); found {
+		t.Error("Expected missing session to not be found")
+	}
+
+	store.Save(ctx, Session{
+		ID:           "abc",
+		Turns:        []Turn{{Role: RoleUser, Content: "Vai chover em SP?"}, {Role: RoleAssistant, Content: "Sim."}},
+		LastCategory: "web_search",
+	})
+
+	sess, found, err := store.Load(ctx, "abc")
+	if err != nil || !found {
+		t.Fatalf("Expected session to be found, err=%v", err)
+	}
+	if len(sess.Turns) != 2 || sess.LastCategory != "web_search" {
+		t.Errorf("Unexpected session: %+v", sess)
+	}
+
+	// Mutating the loaded copy must not affect the stored session
+	sess.Turns[0].Content = "changed"
+	again, _, _ := store.Load(ctx, "abc")
+	if again.Turns[0].Content != "Vai chover em SP?" {
+		t.Error("Load should return a copy of the stored turns")
+	}
+}
+
+func TestMemoryStore_TrimsHistory(t *testing.T) {
+	store, _ := newTestStore(MemoryConfig{MaxTurns: 4})
+	ctx := context.Background()
+
+	var turns []Turn
+	for i := 0; i < 6; i++ {
+		turns = append(turns, Turn{Role: RoleUser, Content: fmt.Sprintf("msg %d", i)})
+	}
+	store.Save(ctx, Session{ID: "abc", Turns: turns})
+
+	sess, _, _ := store.Load(ctx, "abc")
+	if len(sess.Turns) != 4 {
+		t.Fatalf("Expected 4 turns, got %d", len(sess.Turns))
+	}
+	if sess.Turns[0].Content != "msg 2" {
+		t.Errorf("Expected oldest turns to be dropped, first turn is %q", sess.Turns[0].Content)
+	}
+}
+
+func TestMemoryStore_TTLExpiry(t *testing.T) {
+	store, now := newTestStore(MemoryConfig{TTL: 10 * time.Minute})
+	ctx := context.Background()
+
+	store.Save(ctx, Session{ID: "abc"})
+
+	*now = now.Add(9 * time.Minute)
+	if _, found, _ := store.Load(ctx, "abc"); !found {
+		t.Error("Session should still be valid before TTL")
+	}
+
+	*now = now.Add(11 * time.Minute)
+	if _, found, _ := store.Load(ctx, "abc"); found {
+		t.Error("Session should expire after TTL")
+	}
+}
+
+func TestMemoryStore_LRUEviction(t *testing.T) {
+	store, now := newTestStore(MemoryConfig{MaxSessions: 2})
+	ctx := context.Background()
+
+	store.Save(ctx, Session{ID: "first"})
+	*now = now.Add(time.Second)
+	store.Save(ctx, Session{ID: "second"})
+	*now = now.Add(time.Second)
+	store.Save(ctx, Session{ID: "first"}) // Touch first so second becomes least recently used
+	*now = now.Add(time.Second)
+	store.Save(ctx, Session{ID: "third"})
+
+	if store.Len() != 2 {
+		t.Errorf("Expected 2 sessions, got %d", store.Len())
+	}
+	if _, found, _ := store.Load(ctx, "second"); found {
+		t.Error("Least recently used session should have been evicted")
+	}
+	if _, found, _ := store.Load(ctx, "first"); !found {
+		t.Error("Recently used session should be kept")
+	}
+}
+
+func TestValidID(t *testing.T) {
+	tests := []struct {
+		id    string
+		valid bool
+	}{
+		{"trip-2025_01", true},
+		{"", false},
+		{"has space", false},
+		{"../etc", false},
+		{string(make([]byte, 65)), false},
+	}
+	for _, tt := range tests {
+		if got := ValidID(tt.id); got != tt.valid {
+			t.Errorf("ValidID(%q) = %v, want %v", tt.id, got, tt.valid)
+		}
+	}
+}
+
+func TestScopedID(t *testing.T) {
+	if ScopedID("key-a", "trip") == ScopedID("key-b", "trip") {
+		t.Error("Same session ID under different owners must not collide")
+	}
+	if ScopedID("key-a", "trip") != ScopedID("key-a", "trip") {
+		t.Error("ScopedID must be deterministic")
+	}
+}
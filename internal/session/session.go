@@ -0,0 +1,66 @@
+package session
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Conversation roles
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+const maxSessionIDLength = 64
+
+// Turn is a single message in a conversation
+type Turn struct {
+	Role      string    `json:"role"` // "user" or "assistant"
+	Content   string    `json:"content"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Session holds the recent history of a conversation
+type Session struct {
+	ID           string    `json:"id"`
+	Turns        []Turn    `json:"turns"`
+	LastCategory string    `json:"last_category,omitempty"` // Router category of the last answered question
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Store persists conversation sessions
+// Implementations must bound history length and expire idle sessions
+type Store interface {
+	// Load returns the session with the given ID (found=false if missing or expired)
+	Load(ctx context.Context, id string) (sess Session, found bool, err error)
+	// Save creates or replaces a session, trimming its history to the store's bound
+	Save(ctx context.Context, sess Session) error
+	// Delete removes a session
+	Delete(ctx context.Context, id string) error
+}
+
+// ValidID checks that a client-supplied session ID is short and only uses safe characters
+func ValidID(id string) bool {
+	if id == "" || len(id) > maxSessionIDLength {
+		return false
+	}
+	for _, r := range id {
+		if (r >= 'a' && r <= 'z') ||
+			(r >= 'A' && r <= 'Z') ||
+			(r >= '0' && r <= '9') ||
+			r == '-' || r == '_' {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// ScopedID namespaces a client session ID by the caller's identity (e.g. validated API key)
+// so two clients picking the same session ID never see each other's history
+func ScopedID(owner, id string) string {
+	hash := sha256.Sum256([]byte(owner + "|" + id))
+	return hex.EncodeToString(hash[:16])
+}
@@ -0,0 +1,253 @@
+package speech
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// number matches Brazilian-formatted numbers ("1.234,56", "3,5") and the "3.5" style LLMs sometimes use
+const number = `\d{1,3}(?:\.\d{3})+(?:,\d+)?|\d+(?:[.,]\d+)?`
+
+var (
+	// Compile regular expressions once at package level
+	markdownEmphasisRegexp = regexp.MustCompile(`\*\*|__|\*|` + "`")
+	markdownPrefixRegexp   = regexp.MustCompile(`(?m)^\s*(?:#+|[-•]|\d+\.)\s+`)
+	dateRegexp             = regexp.MustCompile(`\b(\d{1,2})/(\d{1,2})/(\d{4}|\d{2})\b`)
+	timeRegexp             = regexp.MustCompile(`\b([01]?\d|2[0-3])(?::([0-5]\d)|h([0-5]\d)?)\b`)
+	currencyRegexp         = regexp.MustCompile(`(R\$|US\$|€)\s?(` + number + `)(?:\s(mil|milhão|milhões|bilhão|bilhões|trilhão|trilhões)\b)?`)
+	percentRegexp          = regexp.MustCompile(`\b(` + number + `)\s?%`)
+	degreesRegexp          = regexp.MustCompile(`\b(` + number + `)\s?(?:°C|ºC|°)`)
+	unitRegexp             = regexp.MustCompile(`\b(` + number + `)\s?(km/h|km|kg|min|h|m)\b`)
+	ordinalRegexp          = regexp.MustCompile(`\b(\d{1,2})(º|ª)`)
+	negativeRegexp         = regexp.MustCompile(`(^|\s)-(\d)`)
+	numberRegexp           = regexp.MustCompile(`\b(?:` + number + `)\b`)
+	thousandsRegexp        = regexp.MustCompile(`^\d{1,3}(?:\.\d{3})+(?:,\d+)?$`)
+)
+
+// currencies maps a currency symbol to its singular and plural names
+var currencies = map[string][2]string{
+	"R$":  {"real", "reais"},
+	"US$": {"dólar", "dólares"},
+	"€":   {"euro", "euros"},
+}
+
+// units maps a unit abbreviation to its singular and plural names, and whether it is feminine
+var units = map[string]struct {
+	singular, plural string
+	feminine         bool
+}{
+	"km/h": {"quilômetro por hora", "quilômetros por hora", false},
+	"km":   {"quilômetro", "quilômetros", false},
+	"kg":   {"quilo", "quilos", false},
+	"min":  {"minuto", "minutos", false},
+	"h":    {"hora", "horas", true},
+	"m":    {"metro", "metros", false},
+}
+
+// abbreviations are expanded anywhere in the text (longest first where prefixes overlap)
+var abbreviations = []struct {
+	pattern *regexp.Regexp
+	words   string
+}{
+	{regexp.MustCompile(`\bkm/h\b`), "quilômetros por hora"},
+	{regexp.MustCompile(`\bAv\.\s?`), "Avenida "},
+	{regexp.MustCompile(`\bDra\.\s?`), "Doutora "},
+	{regexp.MustCompile(`\bDr\.\s?`), "Doutor "},
+	{regexp.MustCompile(`\bSra\.\s?`), "Senhora "},
+	{regexp.MustCompile(`\bSr\.\s?`), "Senhor "},
+	{regexp.MustCompile(`\bProfa\.\s?`), "Professora "},
+	{regexp.MustCompile(`\bProf\.\s?`), "Professor "},
+	{regexp.MustCompile(`\b[Nn][º°]\s?`), "número "},
+	{regexp.MustCompile(`\baprox\.`), "aproximadamente"},
+	{regexp.MustCompile(`\bvs\.`), "versus"},
+	{regexp.MustCompile(`\betc\.`), "etcétera"},
+}
+
+// endsWithAbbreviation reports whether the last word of text is one of the abbreviations
+// (e.g. "na Av."), so its period does not end a sentence
+func endsWithAbbreviation(text string) bool {
+	word := text[strings.LastIndexByte(text, ' ')+1:]
+	for _, abbr := range abbreviations {
+		if loc := abbr.pattern.FindStringIndex(word); loc != nil && loc[1] == len(word) {
+			return true
+		}
+	}
+	return false
+}
+
+// ToSpeech rewrites text so a text-to-speech engine reads it naturally in Brazilian Portuguese:
+// markdown is removed, and dates, times, currency, percentages, units, ordinals, abbreviations
+// and numbers are spelled out (e.g. "R$ 10,50" -> "dez reais e cinquenta centavos")
+func ToSpeech(text string) string {
+	text = stripMarkdown(text)
+
+	// Abbreviations first: "Nº 5" must become "número 5" before the ordinal/degree rules see "º"
+	for _, abbr := range abbreviations {
+		text = abbr.pattern.ReplaceAllString(text, abbr.words)
+	}
+
+	text = negativeRegexp.ReplaceAllString(text, "${1}menos ${2}")
+
+	text = dateRegexp.ReplaceAllStringFunc(text, func(match string) string {
+		m := dateRegexp.FindStringSubmatch(match)
+		day, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		year, _ := strconv.Atoi(m[3])
+		if day < 1 || day > 31 || month < 1 || month > 12 {
+			return match
+		}
+		if len(m[3]) == 2 {
+			year += 2000
+		}
+		dayWords := Cardinal(int64(day), false)
+		if day == 1 {
+			dayWords = "primeiro"
+		}
+		return dayWords + " de " + monthNames[month] + " de " + Cardinal(int64(year), false)
+	})
+
+	text = timeRegexp.ReplaceAllStringFunc(text, func(match string) string {
+		m := timeRegexp.FindStringSubmatch(match)
+		hours, _ := strconv.Atoi(m[1])
+		minutesText := m[2] + m[3]
+		words := Cardinal(int64(hours), true) + " " + plural(hours == 1, "hora", "horas")
+		if minutes, _ := strconv.Atoi(minutesText); minutes > 0 {
+			words += " e " + Cardinal(int64(minutes), false) + " " + plural(minutes == 1, "minuto", "minutos")
+		}
+		return words
+	})
+
+	text = currencyRegexp.ReplaceAllStringFunc(text, func(match string) string {
+		m := currencyRegexp.FindStringSubmatch(match)
+		return spellCurrency(currencies[m[1]], m[2], m[3])
+	})
+
+	text = percentRegexp.ReplaceAllStringFunc(text, func(match string) string {
+		return spellNumber(percentRegexp.FindStringSubmatch(match)[1], false) + " por cento"
+	})
+
+	text = degreesRegexp.ReplaceAllStringFunc(text, func(match string) string {
+		n := degreesRegexp.FindStringSubmatch(match)[1]
+		return spellNumber(n, false) + " " + plural(isOne(n), "grau", "graus")
+	})
+
+	text = unitRegexp.ReplaceAllStringFunc(text, func(match string) string {
+		m := unitRegexp.FindStringSubmatch(match)
+		unit := units[m[2]]
+		return spellNumber(m[1], unit.feminine) + " " + plural(isOne(m[1]), unit.singular, unit.plural)
+	})
+
+	text = ordinalRegexp.ReplaceAllStringFunc(text, func(match string) string {
+		m := ordinalRegexp.FindStringSubmatch(match)
+		forms, ok := ordinalWords[strings.TrimLeft(m[1], "0")]
+		if !ok {
+			return spellNumber(m[1], m[2] == "ª")
+		}
+		if m[2] == "ª" {
+			return forms[1]
+		}
+		return forms[0]
+	})
+
+	text = numberRegexp.ReplaceAllStringFunc(text, func(match string) string {
+		return spellNumber(match, false)
+	})
+
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// stripMarkdown removes emphasis markers, headings and list bullets that would be read aloud
+func stripMarkdown(text string) string {
+	text = markdownPrefixRegexp.ReplaceAllString(text, "")
+	return markdownEmphasisRegexp.ReplaceAllString(text, "")
+}
+
+// splitNumber separates a matched number into its integer digits and decimal digits
+func splitNumber(n string) (integer, decimals string) {
+	if thousandsRegexp.MatchString(n) {
+		n = strings.ReplaceAll(n, ".", "")
+	}
+	if i := strings.IndexAny(n, ".,"); i >= 0 {
+		return n[:i], n[i+1:]
+	}
+	return n, ""
+}
+
+// spellNumber spells out a matched number, reading decimals after "vírgula"
+func spellNumber(n string, feminine bool) string {
+	integer, decimals := splitNumber(n)
+	words := spellInteger(integer, feminine)
+	if decimals == "" {
+		return words
+	}
+	if strings.HasPrefix(decimals, "0") {
+		return words + " vírgula " + digitsWords(decimals)
+	}
+	return words + " vírgula " + spellInteger(decimals, false)
+}
+
+// spellInteger spells out a string of digits; very long digit strings (IDs, phone numbers) are read digit by digit
+func spellInteger(digits string, feminine bool) string {
+	if len(digits) > 15 {
+		return digitsWords(digits)
+	}
+	value, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return digits
+	}
+	return Cardinal(value, feminine)
+}
+
+// spellCurrency spells out an amount such as "10,50" or "2,5 bilhões" in the given currency
+func spellCurrency(names [2]string, amount, scale string) string {
+	if scale != "" {
+		// "R$ 2,5 bilhões" -> "dois vírgula cinco bilhões de reais"; "R$ 3 mil" -> "três mil reais"
+		words := spellNumber(amount, false) + " " + scale
+		if scale == "mil" {
+			return words + " " + names[1]
+		}
+		return words + " de " + names[1]
+	}
+
+	integer, decimals := splitNumber(amount)
+	value, err := strconv.ParseInt(integer, 10, 64)
+	if err != nil {
+		return spellNumber(amount, false) + " " + names[1]
+	}
+
+	cents := 0
+	if decimals != "" {
+		if len(decimals) == 1 {
+			decimals += "0"
+		}
+		cents, _ = strconv.Atoi(decimals[:2])
+	}
+
+	var parts []string
+	if value > 0 || cents == 0 {
+		words := Cardinal(value, false) + " "
+		// Round millions take "de": "um milhão de reais"
+		if value >= 1000000 && value%1000000 == 0 {
+			words += "de "
+		}
+		parts = append(parts, words+plural(value == 1, names[0], names[1]))
+	}
+	if cents > 0 {
+		parts = append(parts, Cardinal(int64(cents), false)+" "+plural(cents == 1, "centavo", "centavos"))
+	}
+	return strings.Join(parts, " e ")
+}
+
+// isOne reports whether a matched number is exactly one (for singular/plural agreement)
+func isOne(n string) bool {
+	integer, decimals := splitNumber(n)
+	return integer == "1" && strings.Trim(decimals, "0") == ""
+}
+
+func plural(singular bool, one, many string) string {
+	if singular {
+		return one
+	}
+	return many
+}
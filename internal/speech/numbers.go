@@ -0,0 +1,139 @@
+package speech
+
+import "strings"
+
+var (
+	unitWords = []string{
+		"zero", "um", "dois", "três", "quatro", "cinco", "seis", "sete", "oito", "nove",
+		"dez", "onze", "doze", "treze", "quatorze", "quinze", "dezesseis", "dezessete", "dezoito", "dezenove",
+	}
+	tensWords = []string{
+		"", "", "vinte", "trinta", "quarenta", "cinquenta", "sessenta", "setenta", "oitenta", "noventa",
+	}
+	hundredsWords = []string{
+		"", "cento", "duzentos", "trezentos", "quatrocentos", "quinhentos", "seiscentos", "setecentos", "oitocentos", "novecentos",
+	}
+	monthNames = []string{
+		"", "janeiro", "fevereiro", "março", "abril", "maio", "junho",
+		"julho", "agosto", "setembro", "outubro", "novembro", "dezembro",
+	}
+	ordinalWords = map[string][2]string{ // masculine, feminine
+		"1": {"primeiro", "primeira"}, "2": {"segundo", "segunda"}, "3": {"terceiro", "terceira"},
+		"4": {"quarto", "quarta"}, "5": {"quinto", "quinta"}, "6": {"sexto", "sexta"},
+		"7": {"sétimo", "sétima"}, "8": {"oitavo", "oitava"}, "9": {"nono", "nona"}, "10": {"décimo", "décima"},
+	}
+)
+
+// largeScales names the groups of three digits above the thousands (singular, plural)
+var largeScales = [][2]string{
+	{"milhão", "milhões"},
+	{"bilhão", "bilhões"},
+	{"trilhão", "trilhões"},
+}
+
+// Cardinal spells out a non-negative integer in Brazilian Portuguese (e.g. 2025 -> "dois mil e vinte e cinco")
+// feminine selects the feminine forms of one, two and the hundreds (e.g. "duas horas", "duzentas pessoas")
+func Cardinal(n int64, feminine bool) string {
+	if n < 0 {
+		return "menos " + Cardinal(-n, feminine)
+	}
+	if n == 0 {
+		return "zero"
+	}
+
+	// Split into groups of three digits, lowest first
+	var groups []int
+	for n > 0 {
+		groups = append(groups, int(n%1000))
+		n /= 1000
+	}
+
+	// Find the lowest non-zero group: Portuguese joins it with "e" when it is below 100 or a round hundred
+	lowest := 0
+	for lowest < len(groups) && groups[lowest] == 0 {
+		lowest++
+	}
+
+	var parts []string
+	for i := len(groups) - 1; i >= 0; i-- {
+		g := groups[i]
+		if g == 0 {
+			continue
+		}
+
+		var word string
+		switch {
+		case i == 0:
+			word = underThousand(g, feminine)
+		case i == 1:
+			if g == 1 {
+				word = "mil" // "mil", never "um mil"
+			} else {
+				word = underThousand(g, feminine) + " mil"
+			}
+		default:
+			scale := largeScales[i-2]
+			if g == 1 {
+				word = "um " + scale[0]
+			} else {
+				word = underThousand(g, false) + " " + scale[1]
+			}
+		}
+
+		if len(parts) > 0 && i == lowest && (g < 100 || g%100 == 0) {
+			word = "e " + word
+		}
+		parts = append(parts, word)
+	}
+	return strings.Join(parts, " ")
+}
+
+// underThousand spells out 1..999
+func underThousand(n int, feminine bool) string {
+	if n == 100 {
+		return "cem"
+	}
+
+	var parts []string
+	if h := n / 100; h > 0 {
+		word := hundredsWords[h]
+		if feminine && h > 1 {
+			word = strings.TrimSuffix(word, "os") + "as"
+		}
+		parts = append(parts, word)
+	}
+
+	rest := n % 100
+	switch {
+	case rest == 0:
+	case rest < 20:
+		parts = append(parts, unitWord(rest, feminine))
+	default:
+		parts = append(parts, tensWords[rest/10])
+		if u := rest % 10; u > 0 {
+			parts = append(parts, unitWord(u, feminine))
+		}
+	}
+	return strings.Join(parts, " e ")
+}
+
+func unitWord(n int, feminine bool) string {
+	if feminine {
+		switch n {
+		case 1:
+			return "uma"
+		case 2:
+			return "duas"
+		}
+	}
+	return unitWords[n]
+}
+
+// digitsWords reads a string of digits one by one (e.g. "05" -> "zero cinco")
+func digitsWords(digits string) string {
+	words := make([]string, 0, len(digits))
+	for _, d := range digits {
+		words = append(words, unitWords[d-'0'])
+	}
+	return strings.Join(words, " ")
+}
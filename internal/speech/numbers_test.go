@@ -0,0 +1,39 @@
+package speech
+
+import "testing"
+
+func TestCardinal(t *testing.T) {
+	tests := []struct {
+		n        int64
+		feminine bool
+		want     string
+	}{
+		{0, false, "zero"},
+		{1, false, "um"},
+		{1, true, "uma"},
+		{2, true, "duas"},
+		{14, false, "quatorze"},
+		{21, false, "vinte e um"},
+		{100, false, "cem"},
+		{101, false, "cento e um"},
+		{200, true, "duzentas"},
+		{999, false, "novecentos e noventa e nove"},
+		{1000, false, "mil"},
+		{1001, false, "mil e um"},
+		{1100, false, "mil e cem"},
+		{1234, false, "mil duzentos e trinta e quatro"},
+		{2025, false, "dois mil e vinte e cinco"},
+		{21000, false, "vinte e um mil"},
+		{1000000, false, "um milhão"},
+		{2500000, false, "dois milhões e quinhentos mil"},
+		{1200300, false, "um milhão duzentos mil e trezentos"},
+		{3000000000, false, "três bilhões"},
+		{-5, false, "menos cinco"},
+	}
+
+	for _, tt := range tests {
+		if got := Cardinal(tt.n, tt.feminine); got != tt.want {
+			t.Errorf("Cardinal(%d, %v) = %q, want %q", tt.n, tt.feminine, got, tt.want)
+		}
+	}
+}
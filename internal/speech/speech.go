@@ -0,0 +1,115 @@
+package speech
+
+import (
+	"strings"
+)
+
+// Format selects how an answer is rendered for the client
+type Format string
+
+const (
+	FormatText   Format = "text"   // Plain text (default, unchanged behavior)
+	FormatSSML   Format = "ssml"   // SSML document with pauses between paragraphs and sentences
+	FormatSpeech Format = "speech" // Plain text with numbers, currency, dates and abbreviations spelled out
+)
+
+// Pauses inserted in SSML output
+const (
+	paragraphBreak = `<break time="600ms"/>`
+	sentenceBreak  = `<break time="250ms"/>`
+)
+
+// ParseFormat validates a client-supplied format (empty means FormatText)
+func ParseFormat(s string) (Format, bool) {
+	switch Format(strings.ToLower(strings.TrimSpace(s))) {
+	case "", FormatText:
+		return FormatText, true
+	case FormatSSML:
+		return FormatSSML, true
+	case FormatSpeech:
+		return FormatSpeech, true
+	}
+	return "", false
+}
+
+// Render converts an answer to the requested format
+// Paragraphs are separated by newlines; text must already be free of URLs
+func Render(text string, format Format) string {
+	switch format {
+	case FormatSSML:
+		return ToSSML(text)
+	case FormatSpeech:
+		return ToSpeech(text)
+	default:
+		return text
+	}
+}
+
+// ToSSML wraps text in a <speak> document, with a long pause between paragraphs
+// and a short pause between sentences. Text is XML-escaped; numbers and dates are
+// left for the speech engine, which handles them with its own pt-BR rules
+func ToSSML(text string) string {
+	var b strings.Builder
+	b.WriteString("<speak>")
+
+	first := true
+	for _, paragraph := range splitParagraphs(stripMarkdown(text)) {
+		if !first {
+			b.WriteString(paragraphBreak)
+		}
+		first = false
+
+		for i, sentence := range splitSentences(paragraph) {
+			if i > 0 {
+				b.WriteString(sentenceBreak)
+			}
+			b.WriteString(escapeXML(sentence))
+		}
+	}
+
+	b.WriteString("</speak>")
+	return b.String()
+}
+
+// splitParagraphs splits text on newlines, dropping empty lines
+func splitParagraphs(text string) []string {
+	var paragraphs []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			paragraphs = append(paragraphs, line)
+		}
+	}
+	return paragraphs
+}
+
+// splitSentences splits a paragraph after ".", "!" or "?" followed by a space
+// The period of an abbreviation ("Dr. Silva", "Av. Paulista") does not end a sentence
+func splitSentences(paragraph string) []string {
+	var sentences []string
+	start := 0
+	for i := 0; i < len(paragraph)-1; i++ {
+		switch paragraph[i] {
+		case '.', '!', '?':
+			if paragraph[i+1] == ' ' && (paragraph[i] != '.' || !endsWithAbbreviation(paragraph[start:i+1])) {
+				sentences = append(sentences, strings.TrimSpace(paragraph[start:i+1]))
+				start = i + 1
+			}
+		}
+	}
+	if rest := strings.TrimSpace(paragraph[start:]); rest != "" {
+		sentences = append(sentences, rest)
+	}
+	return sentences
+}
+
+var xmlEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+	"'", "&apos;",
+)
+
+func escapeXML(s string) string {
+	return xmlEscaper.Replace(s)
+}
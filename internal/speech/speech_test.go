@@ -0,0 +1,105 @@
+package speech
+
+import "testing"
+
+func TestToSpeech(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain text unchanged", "Bom dia, tudo bem?", "Bom dia, tudo bem?"},
+		{"integer", "São 42 carros", "São quarenta e dois carros"},
+		{"thousands separator", "Foram 1.234 pessoas", "Foram mil duzentos e trinta e quatro pessoas"},
+		{"decimal comma", "Subiu 3,5 pontos", "Subiu três vírgula cinco pontos"},
+		{"decimal with leading zero", "Caiu 0,05 ponto", "Caiu zero vírgula zero cinco ponto"},
+		{"year", "Em 2025 o Brasil", "Em dois mil e vinte e cinco o Brasil"},
+		{"currency with cents", "Custa R$ 10,50", "Custa dez reais e cinquenta centavos"},
+		{"currency singular", "Custa R$1,00", "Custa um real"},
+		{"currency cents only", "Custa R$ 0,99", "Custa noventa e nove centavos"},
+		{"currency thousands", "O carro custa R$ 85.000", "O carro custa oitenta e cinco mil reais"},
+		{"currency with scale", "Lucro de R$ 2,5 bilhões", "Lucro de dois vírgula cinco bilhões de reais"},
+		{"currency round million", "Prêmio de R$ 1.000.000,00", "Prêmio de um milhão de reais"},
+		{"currency mil", "Custa R$ 3 mil", "Custa três mil reais"},
+		{"dollar", "O dólar está em US$ 1", "O dólar está em um dólar"},
+		{"percentage", "Alta de 12%", "Alta de doze por cento"},
+		{"percentage decimal", "Inflação de 4,5 %", "Inflação de quatro vírgula cinco por cento"},
+		{"speed", "Limite de 80 km/h", "Limite de oitenta quilômetros por hora"},
+		{"distance singular", "Faltam 1 km", "Faltam um quilômetro"},
+		{"distance decimal", "Faltam 2,5 km", "Faltam dois vírgula cinco quilômetros"},
+		{"temperature", "Máxima de 31°C", "Máxima de trinta e um graus"},
+		{"duration in hours", "Aberto 24h por dia", "Aberto vinte e quatro horas por dia"},
+		{"time with colon", "Começa às 14:30", "Começa às quatorze horas e trinta minutos"},
+		{"time with h", "Abre às 9h", "Abre às nove horas"},
+		{"time one o'clock", "Fecha à 1h05", "Fecha à uma hora e cinco minutos"},
+		{"date", "Em 10/01/2025", "Em dez de janeiro de dois mil e vinte e cinco"},
+		{"first of month", "Dia 1/5/25", "Dia primeiro de maio de dois mil e vinte e cinco"},
+		{"invalid date read as numbers", "Placar 31/13/2025", "Placar trinta e um/treze/dois mil e vinte e cinco"},
+		{"ordinal masculine", "Ficou em 1º lugar", "Ficou em primeiro lugar"},
+		{"ordinal feminine", "Na 2ª rodada", "Na segunda rodada"},
+		{"avenue", "Fica na Av. Paulista", "Fica na Avenida Paulista"},
+		{"doctor", "Consulta com Dr. Silva e Dra. Souza", "Consulta com Doutor Silva e Doutora Souza"},
+		{"number abbreviation", "Casa nº 15", "Casa número quinze"},
+		{"speed without number", "Medida em km/h", "Medida em quilômetros por hora"},
+		{"negative", "Mínima de -2°C", "Mínima de menos dois graus"},
+		{"alphanumeric untouched", "Rede 4G e canal G1", "Rede 4G e canal G1"},
+		{"markdown removed", "**Atenção**: trânsito lento", "Atenção: trânsito lento"},
+		{"list bullets removed", "- Item um\n- Item dois", "Item um Item dois"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToSpeech(tt.input); got != tt.want {
+				t.Errorf("ToSpeech(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToSSML(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"single sentence", "Bom dia.", "<speak>Bom dia.</speak>"},
+		{"sentences", "Vai chover. Leve guarda-chuva.", `<speak>Vai chover.<break time="250ms"/>Leve guarda-chuva.</speak>`},
+		{"paragraphs", "Primeiro parágrafo.\n\nSegundo parágrafo.", `<speak>Primeiro parágrafo.<break time="600ms"/>Segundo parágrafo.</speak>`},
+		{"escaping", "Preço < R$ 10 & \"barato\"", "<speak>Preço &lt; R$ 10 &amp; &quot;barato&quot;</speak>"},
+		{"decimal not split", "Subiu 3.5 pontos.", "<speak>Subiu 3.5 pontos.</speak>"},
+		{"abbreviations not split", "O consultório do Dr. Silva fica na Av. Paulista", "<speak>O consultório do Dr. Silva fica na Av. Paulista</speak>"},
+		{"feminine abbreviation not split", "Fale com a Sra. Souza ou a Profa. Lima.", "<speak>Fale com a Sra. Souza ou a Profa. Lima.</speak>"},
+		{"sentence after abbreviation", "Fica na Av. Paulista. Abre às 9h.", `<speak>Fica na Av. Paulista.<break time="250ms"/>Abre às 9h.</speak>`},
+		{"unit before period split", "Limite de 80 km/h. Cuidado.", `<speak>Limite de 80 km/h.<break time="250ms"/>Cuidado.</speak>`},
+		{"markdown removed", "**Atenção**", "<speak>Atenção</speak>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToSSML(tt.input); got != tt.want {
+				t.Errorf("ToSSML(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Format
+		ok    bool
+	}{
+		{"", FormatText, true},
+		{"text", FormatText, true},
+		{"SSML", FormatSSML, true},
+		{" speech ", FormatSpeech, true},
+		{"mp3", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseFormat(tt.input)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseFormat(%q) = (%q, %v), want (%q, %v)", tt.input, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package stt
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/clotilde/carplay-assistant/internal/tracing"
+)
+
+const (
+	openAIDefaultBaseURL = "https://api.openai.com/v1"
+	openAIDefaultModel   = "whisper-1"
+)
+
+// OpenAI transcribes audio with the OpenAI audio transcriptions API (Whisper)
+type OpenAI struct {
+	APIKey   string
+	BaseURL  string // Defaults to https://api.openai.com/v1 (overridable for tests)
+	Model    string // Defaults to whisper-1 (gpt-4o-mini-transcribe also works)
+	Language string // ISO-639-1 hint, e.g. "pt" (empty lets the API detect it)
+	client   *http.Client
+}
+
+// NewOpenAI creates a Whisper API transcriber
+func NewOpenAI(apiKey, model, language string) *OpenAI {
+	if model == "" {
+		model = openAIDefaultModel
+	}
+	return &OpenAI{
+		APIKey:   apiKey,
+		BaseURL:  openAIDefaultBaseURL,
+		Model:    model,
+		Language: language,
+		// Short clips transcribe in 1-3s; 10s leaves most of the 25s budget for the answer
+		client: &http.Client{Timeout: 10 * time.Second, Transport: tracing.Transport(nil)},
+	}
+}
+
+// Name returns the backend identifier
+func (t *OpenAI) Name() string {
+	return "openai"
+}
+
+// Transcribe sends the audio to the transcriptions endpoint
+func (t *OpenAI) Transcribe(ctx context.Context, audio []byte, format string) (string, error) {
+	if t.APIKey == "" {
+		return "", fmt.Errorf("OpenAI API key not configured")
+	}
+
+	log.Printf("Whisper API request: model=%s, format=%s, size=%d", t.Model, format, len(audio))
+	return postAudio(ctx, t.client, t.BaseURL+"/audio/transcriptions",
+		map[string]string{"Authorization": "Bearer " + t.APIKey},
+		map[string]string{"model": t.Model, "language": t.Language, "response_format": "json"},
+		audio, format)
+}
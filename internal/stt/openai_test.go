@@ -0,0 +1,68 @@
+package stt
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAI_Transcribe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/audio/transcriptions" {
+			t.Errorf("Expected path /audio/transcriptions, got %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("Expected bearer token, got %q", r.Header.Get("Authorization"))
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatalf("Expected multipart body: %v", err)
+		}
+		if r.FormValue("model") != "whisper-1" || r.FormValue("language") != "pt" {
+			t.Errorf("Unexpected form fields: model=%q language=%q", r.FormValue("model"), r.FormValue("language"))
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Fatalf("Expected file field: %v", err)
+		}
+		if header.Filename != "audio.m4a" {
+			t.Errorf("Expected filename audio.m4a, got %q", header.Filename)
+		}
+		data, _ := io.ReadAll(file)
+		if string(data) != "fake-audio" {
+			t.Errorf("Unexpected audio bytes: %q", data)
+		}
+		w.Write([]byte(`{"text":" Qual a previsão do tempo? "}`))
+	}))
+	defer server.Close()
+
+	tr := NewOpenAI("test-key", "", "pt")
+	tr.BaseURL = server.URL
+
+	text, err := tr.Transcribe(context.Background(), []byte("fake-audio"), FormatM4A)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if text != "Qual a previsão do tempo?" {
+		t.Errorf("Expected trimmed transcript, got %q", text)
+	}
+}
+
+func TestOpenAI_TranscribeErrors(t *testing.T) {
+	if _, err := NewOpenAI("", "", "pt").Transcribe(context.Background(), []byte("x"), FormatWAV); err == nil {
+		t.Error("Expected error without API key")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"message":"Invalid file format."}}`))
+	}))
+	defer server.Close()
+
+	tr := NewOpenAI("test-key", "", "pt")
+	tr.BaseURL = server.URL
+	if _, err := tr.Transcribe(context.Background(), []byte("x"), FormatWAV); err == nil {
+		t.Error("Expected error for non-200 status")
+	}
+}
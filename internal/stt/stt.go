@@ -0,0 +1,112 @@
+package stt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// Audio formats accepted for transcription
+const (
+	FormatM4A = "m4a"
+	FormatWAV = "wav"
+	FormatOGG = "ogg"
+)
+
+// Transcriber converts a short audio clip to text
+type Transcriber interface {
+	// Name returns a short identifier for the backend (e.g. "openai")
+	Name() string
+	// Transcribe returns the text spoken in the audio; format is one of the Format* constants
+	Transcribe(ctx context.Context, audio []byte, format string) (string, error)
+}
+
+// DetectFormat identifies the audio container from its magic bytes
+// Clients (Apple Shortcuts in particular) often send a generic Content-Type, so the bytes are trusted instead
+func DetectFormat(audio []byte) (string, bool) {
+	switch {
+	case len(audio) >= 12 && bytes.Equal(audio[0:4], []byte("RIFF")) && bytes.Equal(audio[8:12], []byte("WAVE")):
+		return FormatWAV, true
+	case len(audio) >= 4 && bytes.Equal(audio[0:4], []byte("OggS")):
+		return FormatOGG, true
+	case len(audio) >= 8 && bytes.Equal(audio[4:8], []byte("ftyp")):
+		// MP4 container (m4a/AAC recorded by iOS)
+		return FormatM4A, true
+	}
+	return "", false
+}
+
+// transcriptionResponse is the JSON body returned by both Whisper API and whisper.cpp server
+type transcriptionResponse struct {
+	Text  string `json:"text"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// postAudio uploads audio as multipart/form-data (field "file") with extra form fields,
+// and returns the transcribed text from a {"text": "..."} JSON response
+func postAudio(ctx context.Context, client *http.Client, url string, headers map[string]string, fields map[string]string, audio []byte, format string) (string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, value := range fields {
+		if value == "" {
+			continue
+		}
+		if err := writer.WriteField(name, value); err != nil {
+			return "", fmt.Errorf("failed to write form field: %w", err)
+		}
+	}
+	// The file extension tells the backend which decoder to use
+	part, err := writer.CreateFormFile("file", "audio."+format)
+	if err != nil {
+		return "", fmt.Errorf("failed to create form file: %w", err)
+	}
+	if _, err := part.Write(audio); err != nil {
+		return "", fmt.Errorf("failed to write audio: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("failed to close multipart body: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, &body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", writer.FormDataContentType())
+	for name, value := range headers {
+		httpReq.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to make transcription request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read transcription response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Transcription API returned status %d: %s", resp.StatusCode, string(respBody))
+		return "", fmt.Errorf("transcription API returned status %d", resp.StatusCode)
+	}
+
+	var result transcriptionResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return "", fmt.Errorf("failed to parse transcription response: %w", err)
+	}
+	if result.Error != nil {
+		return "", fmt.Errorf("transcription error: %s", result.Error.Message)
+	}
+
+	return strings.TrimSpace(result.Text), nil
+}
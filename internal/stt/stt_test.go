@@ -0,0 +1,28 @@
+package stt
+
+import "testing"
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		audio  []byte
+		format string
+		ok     bool
+	}{
+		{"wav", []byte("RIFF\x24\x00\x00\x00WAVEfmt "), FormatWAV, true},
+		{"ogg", []byte("OggS\x00\x02\x00\x00"), FormatOGG, true},
+		{"m4a", []byte("\x00\x00\x00\x20ftypM4A "), FormatM4A, true},
+		{"mp3 not accepted", []byte("ID3\x04\x00\x00\x00\x00"), "", false},
+		{"json body", []byte(`{"message":"oi"}`), "", false},
+		{"too short", []byte("RIF"), "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, ok := DetectFormat(tt.audio)
+			if format != tt.format || ok != tt.ok {
+				t.Errorf("DetectFormat() = (%q, %v), want (%q, %v)", format, ok, tt.format, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package stt
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/clotilde/carplay-assistant/internal/tracing"
+)
+
+// WhisperCPP transcribes audio with a self-hosted whisper.cpp server (examples/server)
+// Audio never leaves the user's infrastructure
+type WhisperCPP struct {
+	BaseURL  string // e.g. http://localhost:8081
+	Language string // e.g. "pt" (empty uses the server default)
+	client   *http.Client
+}
+
+// NewWhisperCPP creates a transcriber for a whisper.cpp server
+func NewWhisperCPP(baseURL, language string) *WhisperCPP {
+	return &WhisperCPP{
+		BaseURL:  strings.TrimSuffix(baseURL, "/"),
+		Language: language,
+		// Local CPU inference is slower than the hosted API
+		client: &http.Client{Timeout: 15 * time.Second, Transport: tracing.Transport(nil)},
+	}
+}
+
+// Name returns the backend identifier
+func (t *WhisperCPP) Name() string {
+	return "whispercpp"
+}
+
+// Transcribe sends the audio to the server's /inference endpoint
+func (t *WhisperCPP) Transcribe(ctx context.Context, audio []byte, format string) (string, error) {
+	if t.BaseURL == "" {
+		return "", fmt.Errorf("whisper.cpp server not configured")
+	}
+
+	log.Printf("whisper.cpp request: format=%s, size=%d", format, len(audio))
+	return postAudio(ctx, t.client, t.BaseURL+"/inference", nil,
+		map[string]string{"language": t.Language, "response_format": "json", "temperature": "0"},
+		audio, format)
+}
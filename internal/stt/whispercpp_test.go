@@ -0,0 +1,42 @@
+package stt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWhisperCPP_Transcribe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/inference" {
+			t.Errorf("Expected path /inference, got %s", r.URL.Path)
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatalf("Expected multipart body: %v", err)
+		}
+		if r.FormValue("response_format") != "json" {
+			t.Errorf("Expected json response format, got %q", r.FormValue("response_format"))
+		}
+		if _, header, err := r.FormFile("file"); err != nil || header.Filename != "audio.wav" {
+			t.Errorf("Expected audio.wav file field, got %v (err=%v)", header, err)
+		}
+		w.Write([]byte(`{"text":"Bom dia"}`))
+	}))
+	defer server.Close()
+
+	tr := NewWhisperCPP(server.URL+"/", "pt")
+	text, err := tr.Transcribe(context.Background(), []byte("RIFF....WAVE"), FormatWAV)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if text != "Bom dia" {
+		t.Errorf("Expected 'Bom dia', got %q", text)
+	}
+}
+
+func TestWhisperCPP_NotConfigured(t *testing.T) {
+	if _, err := NewWhisperCPP("", "").Transcribe(context.Background(), []byte("x"), FormatWAV); err == nil {
+		t.Error("Expected error when server URL is not set")
+	}
+}
@@ -0,0 +1,122 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans created by Clotilde
+const instrumentationName = "github.com/clotilde/carplay-assistant"
+
+// defaultServiceName is the service.name of the spans unless OTEL_SERVICE_NAME is set
+const defaultServiceName = "clotilde"
+
+// Exporters selected with OTEL_TRACES_EXPORTER
+const (
+	ExporterNone   = "none"   // Default: no spans are recorded, trace context is still propagated
+	ExporterOTLP   = "otlp"   // OTLP over HTTP to OTEL_EXPORTER_OTLP_ENDPOINT (default localhost:4318)
+	ExporterStdout = "stdout" // Spans printed as JSON, for local debugging
+)
+
+// Setup installs the tracer provider selected by OTEL_TRACES_EXPORTER and the W3C trace context
+// propagator; the returned function flushes the spans on shutdown
+// Sampling follows OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG (default: every trace)
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch name := strings.ToLower(os.Getenv("OTEL_TRACES_EXPORTER")); name {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("OTEL_TRACES_EXPORTER=%s not recognized (use otlp, stdout or none)", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the default service name
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", defaultServiceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span as a child of the span in ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err (if any) on the span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Wrap gives a middleware its own span, covering the middleware and everything it calls
+func Wrap(name string, middleware func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		handler := middleware(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, span := Start(r.Context(), name)
+			defer span.End()
+			handler.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Middleware starts the server span of each request, continuing the trace of the caller when the
+// request carries W3C trace context (traceparent); it must wrap the whole chain
+// The request ID set by logging.RequestIDMiddleware is added to the span so traces and logs can be matched
+func Middleware(next http.Handler) http.Handler {
+	tagged := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		if requestID := w.Header().Get("X-Request-ID"); requestID != "" {
+			trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("clotilde.request_id", requestID))
+		}
+	})
+	return otelhttp.NewHandler(tagged, "clotilde",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return "HTTP " + r.Method
+		}),
+	)
+}
+
+// Transport returns an HTTP transport that creates a client span for each outgoing request and
+// sends the trace context along in its headers
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(base)
+}
@@ -0,0 +1,144 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider that keeps the spans in memory for the test
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return exporter
+}
+
+func findSpan(spans tracetest.SpanStubs, name string) *tracetest.SpanStub {
+	for i := range spans {
+		if spans[i].Name == name {
+			return &spans[i]
+		}
+	}
+	return nil
+}
+
+const incomingTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestMiddleware_ContinuesIncomingTrace(t *testing.T) {
+	exporter := recordSpans(t)
+
+	inner := Wrap("inner", func(next http.Handler) http.Handler { return next })
+	outer := Wrap("outer", func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Request-ID", "req-123")
+			next.ServeHTTP(w, r)
+		})
+	})
+	handler := Middleware(outer(inner(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))))
+
+	req := httptest.NewRequest(http.MethodPost, "/chat", nil)
+	req.Header.Set("traceparent", incomingTraceparent)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	server, outerSpan, innerSpan := findSpan(spans, "HTTP POST"), findSpan(spans, "outer"), findSpan(spans, "inner")
+	if server == nil || outerSpan == nil || innerSpan == nil {
+		t.Fatalf("Expected server, outer and inner spans, got %d spans", len(spans))
+	}
+	if got := server.SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected the server span to continue the incoming trace, got %s", got)
+	}
+	if outerSpan.Parent.SpanID() != server.SpanContext.SpanID() || innerSpan.Parent.SpanID() != outerSpan.SpanContext.SpanID() {
+		t.Error("Expected the middleware spans nested in the order they run")
+	}
+
+	found := false
+	for _, attr := range server.Attributes {
+		if attr.Key == "clotilde.request_id" && attr.Value.AsString() == "req-123" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected the request ID on the server span, got %v", server.Attributes)
+	}
+}
+
+func TestTransport_SendsTraceContext(t *testing.T) {
+	exporter := recordSpans(t)
+
+	var traceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer upstream.Close()
+
+	ctx, span := Start(context.Background(), "parent")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL, nil)
+	resp, err := (&http.Client{Transport: Transport(nil)}).Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	End(span, nil)
+
+	traceID := span.SpanContext().TraceID().String()
+	if len(traceparent) != 55 || traceparent[3:35] != traceID {
+		t.Errorf("Expected a traceparent for trace %s, got %q", traceID, traceparent)
+	}
+	if len(exporter.GetSpans()) != 2 {
+		t.Errorf("Expected a client span under the parent, got %d spans", len(exporter.GetSpans()))
+	}
+}
+
+func TestEnd_RecordsError(t *testing.T) {
+	exporter := recordSpans(t)
+
+	_, span := Start(context.Background(), "failing")
+	End(span, context.DeadlineExceeded)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Status.Description != context.DeadlineExceeded.Error() || len(spans[0].Events) != 1 {
+		t.Errorf("Expected the error recorded on the span, got %+v", spans)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	t.Setenv("OTEL_TRACES_EXPORTER", "")
+	shutdown, err := Setup(context.Background())
+	if err != nil || shutdown(context.Background()) != nil {
+		t.Errorf("Expected tracing off by default, got %v", err)
+	}
+
+	t.Setenv("OTEL_TRACES_EXPORTER", "stdout")
+	shutdown, err = Setup(context.Background())
+	if err != nil {
+		t.Fatalf("Expected the stdout exporter, got %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown failed: %v", err)
+	}
+
+	t.Setenv("OTEL_TRACES_EXPORTER", "zipkin")
+	if _, err := Setup(context.Background()); err == nil {
+		t.Error("Expected an unknown exporter to be rejected")
+	}
+}
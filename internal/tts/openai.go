@@ -0,0 +1,76 @@
+package tts
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/clotilde/carplay-assistant/internal/tracing"
+)
+
+const (
+	openAIDefaultBaseURL = "https://api.openai.com/v1"
+	openAIDefaultModel   = "tts-1"
+	openAIDefaultVoice   = "nova"
+)
+
+// OpenAI synthesizes speech with the OpenAI audio speech API
+type OpenAI struct {
+	APIKey  string
+	BaseURL string // Defaults to https://api.openai.com/v1 (overridable for tests)
+	Model   string // Defaults to tts-1 (lowest latency; tts-1-hd and gpt-4o-mini-tts also work)
+	client  *http.Client
+}
+
+// openAISpeechRequest is the body of POST /audio/speech
+type openAISpeechRequest struct {
+	Model          string  `json:"model"`
+	Input          string  `json:"input"`
+	Voice          string  `json:"voice"`
+	Speed          float64 `json:"speed"`
+	ResponseFormat string  `json:"response_format"`
+}
+
+// NewOpenAI creates an OpenAI speech synthesizer
+func NewOpenAI(apiKey, model string) *OpenAI {
+	if model == "" {
+		model = openAIDefaultModel
+	}
+	return &OpenAI{
+		APIKey:  apiKey,
+		BaseURL: openAIDefaultBaseURL,
+		Model:   model,
+		// A short answer synthesizes in 1-2s; the caller's deadline is usually tighter
+		client: &http.Client{Timeout: 10 * time.Second, Transport: tracing.Transport(nil)},
+	}
+}
+
+// Name returns the backend identifier
+func (s *OpenAI) Name() string {
+	return "openai"
+}
+
+// Synthesize returns MP3 audio for text
+func (s *OpenAI) Synthesize(ctx context.Context, text string, opts Options) (Audio, error) {
+	if s.APIKey == "" {
+		return Audio{}, fmt.Errorf("OpenAI API key not configured")
+	}
+
+	voice := opts.Voice
+	if voice == "" {
+		voice = openAIDefaultVoice
+	}
+
+	log.Printf("OpenAI speech request: model=%s, voice=%s, speed=%.2f, length=%d", s.Model, voice, opts.speed(), len(text))
+	return postJSON(ctx, s.client, s.BaseURL+"/audio/speech",
+		map[string]string{"Authorization": "Bearer " + s.APIKey},
+		openAISpeechRequest{
+			Model:          s.Model,
+			Input:          text,
+			Voice:          voice,
+			Speed:          opts.speed(),
+			ResponseFormat: "mp3",
+		}, "audio/mpeg")
+}
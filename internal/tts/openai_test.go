@@ -0,0 +1,86 @@
+package tts
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAI_Synthesize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/audio/speech" {
+			t.Errorf("Expected path /audio/speech, got %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("Expected bearer token, got %q", r.Header.Get("Authorization"))
+		}
+		var body openAISpeechRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("Expected JSON body: %v", err)
+		}
+		if body.Model != "tts-1" || body.Voice != "shimmer" || body.Speed != 1.25 || body.ResponseFormat != "mp3" {
+			t.Errorf("Unexpected request: %+v", body)
+		}
+		if body.Input != "Vai chover hoje." {
+			t.Errorf("Unexpected input: %q", body.Input)
+		}
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Write([]byte("fake-mp3"))
+	}))
+	defer server.Close()
+
+	s := NewOpenAI("test-key", "")
+	s.BaseURL = server.URL
+
+	audio, err := s.Synthesize(context.Background(), "Vai chover hoje.", Options{Voice: "shimmer", Speed: 1.25})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(audio.Data) != "fake-mp3" || audio.ContentType != "audio/mpeg" {
+		t.Errorf("Unexpected audio: %q (%s)", audio.Data, audio.ContentType)
+	}
+}
+
+func TestOpenAI_SynthesizeDefaults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body openAISpeechRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Voice != openAIDefaultVoice || body.Speed != DefaultSpeed {
+			t.Errorf("Expected default voice and speed, got %+v", body)
+		}
+		// No Content-Type: the synthesizer falls back to audio/mpeg
+		w.Write([]byte("fake-mp3"))
+	}))
+	defer server.Close()
+
+	s := NewOpenAI("test-key", "")
+	s.BaseURL = server.URL
+
+	audio, err := s.Synthesize(context.Background(), "Olá", Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if audio.ContentType != "audio/mpeg" {
+		t.Errorf("Expected audio/mpeg, got %s", audio.ContentType)
+	}
+}
+
+func TestOpenAI_SynthesizeErrors(t *testing.T) {
+	if _, err := NewOpenAI("", "").Synthesize(context.Background(), "Olá", Options{}); err == nil {
+		t.Error("Expected error without API key")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"message":"Invalid voice."}}`))
+	}))
+	defer server.Close()
+
+	s := NewOpenAI("test-key", "")
+	s.BaseURL = server.URL
+	if _, err := s.Synthesize(context.Background(), "Olá", Options{Voice: "nope"}); err == nil {
+		t.Error("Expected error on non-200 response")
+	}
+}
@@ -0,0 +1,55 @@
+package tts
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/clotilde/carplay-assistant/internal/tracing"
+)
+
+// Piper synthesizes speech with a self-hosted Piper HTTP server (piper.http_server)
+// Answers are spoken without leaving the user's infrastructure
+type Piper struct {
+	BaseURL string // e.g. http://localhost:5000
+	client  *http.Client
+}
+
+// piperRequest is the JSON body accepted by the Piper HTTP server
+type piperRequest struct {
+	Text        string  `json:"text"`
+	Voice       string  `json:"voice,omitempty"`
+	LengthScale float64 `json:"length_scale"`
+}
+
+// NewPiper creates a synthesizer for a Piper HTTP server
+func NewPiper(baseURL string) *Piper {
+	return &Piper{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: 10 * time.Second, Transport: tracing.Transport(nil)},
+	}
+}
+
+// Name returns the backend identifier
+func (s *Piper) Name() string {
+	return "piper"
+}
+
+// Synthesize returns WAV audio for text
+func (s *Piper) Synthesize(ctx context.Context, text string, opts Options) (Audio, error) {
+	if s.BaseURL == "" {
+		return Audio{}, fmt.Errorf("Piper server not configured")
+	}
+
+	// Piper controls the rate through phoneme length, so faster speech means a smaller scale
+	lengthScale := 1 / opts.speed()
+
+	log.Printf("Piper request: voice=%s, length_scale=%.2f, length=%d", opts.Voice, lengthScale, len(text))
+	return postJSON(ctx, s.client, s.BaseURL+"/",
+		nil,
+		piperRequest{Text: text, Voice: opts.Voice, LengthScale: lengthScale},
+		"audio/wav")
+}
@@ -0,0 +1,51 @@
+package tts
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPiper_Synthesize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body piperRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("Expected JSON body: %v", err)
+		}
+		if body.Text != "Bom dia." || body.Voice != "pt_BR-faber-medium" {
+			t.Errorf("Unexpected request: %+v", body)
+		}
+		// Speed 2.0 halves the phoneme length
+		if body.LengthScale != 0.5 {
+			t.Errorf("Expected length_scale 0.5, got %v", body.LengthScale)
+		}
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write([]byte("RIFF....WAVE"))
+	}))
+	defer server.Close()
+
+	audio, err := NewPiper(server.URL+"/").Synthesize(context.Background(), "Bom dia.", Options{Voice: "pt_BR-faber-medium", Speed: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if audio.ContentType != "audio/wav" || string(audio.Data) != "RIFF....WAVE" {
+		t.Errorf("Unexpected audio: %q (%s)", audio.Data, audio.ContentType)
+	}
+}
+
+func TestPiper_SynthesizeErrors(t *testing.T) {
+	if _, err := NewPiper("").Synthesize(context.Background(), "Olá", Options{}); err == nil {
+		t.Error("Expected error without base URL")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 200 with no audio is treated as a failure
+	}))
+	defer server.Close()
+
+	if _, err := NewPiper(server.URL).Synthesize(context.Background(), "Olá", Options{}); err == nil {
+		t.Error("Expected error on empty audio")
+	}
+}
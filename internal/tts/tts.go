@@ -0,0 +1,93 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+)
+
+// Speed limits accepted by the backends (1.0 is normal speed)
+const (
+	DefaultSpeed = 1.0
+	MinSpeed     = 0.5
+	MaxSpeed     = 2.0
+)
+
+// Options selects how the text is spoken
+type Options struct {
+	Voice string  // Backend-specific voice name (empty uses the backend default)
+	Speed float64 // Speaking rate, 0 means DefaultSpeed
+}
+
+// Audio is a synthesized clip
+type Audio struct {
+	Data        []byte
+	ContentType string // e.g. audio/mpeg
+}
+
+// Synthesizer converts text to spoken audio
+type Synthesizer interface {
+	// Name returns a short identifier for the backend (e.g. "openai")
+	Name() string
+	// Synthesize returns the spoken audio for text
+	Synthesize(ctx context.Context, text string, opts Options) (Audio, error)
+}
+
+// speed returns the requested speed clamped to the supported range
+func (o Options) speed() float64 {
+	switch {
+	case o.Speed == 0:
+		return DefaultSpeed
+	case o.Speed < MinSpeed:
+		return MinSpeed
+	case o.Speed > MaxSpeed:
+		return MaxSpeed
+	}
+	return o.Speed
+}
+
+// postJSON sends a JSON body and returns the raw audio response
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, payload interface{}, contentType string) (Audio, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Audio{}, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return Audio{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		httpReq.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return Audio{}, fmt.Errorf("failed to make speech request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Audio{}, fmt.Errorf("failed to read speech response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Speech API returned status %d: %s", resp.StatusCode, string(data))
+		return Audio{}, fmt.Errorf("speech API returned status %d", resp.StatusCode)
+	}
+	if len(data) == 0 {
+		return Audio{}, fmt.Errorf("speech API returned empty audio")
+	}
+
+	// Prefer the server's content type when it declares an audio one
+	if declared := resp.Header.Get("Content-Type"); len(declared) > 6 && declared[:6] == "audio/" {
+		contentType = declared
+	}
+	return Audio{Data: data, ContentType: contentType}, nil
+}
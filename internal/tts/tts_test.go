@@ -0,0 +1,21 @@
+package tts
+
+import "testing"
+
+func TestOptionsSpeed(t *testing.T) {
+	tests := []struct {
+		speed float64
+		want  float64
+	}{
+		{0, DefaultSpeed},
+		{0.2, MinSpeed},
+		{1.5, 1.5},
+		{3, MaxSpeed},
+	}
+
+	for _, tt := range tests {
+		if got := (Options{Speed: tt.speed}).speed(); got != tt.want {
+			t.Errorf("speed(%v) = %v, want %v", tt.speed, got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package validator
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -299,3 +301,85 @@ func TestMiddleware_UnicodeMessage(t *testing.T) {
 	}
 }
 
+
+func TestMiddleware_AudioUpload(t *testing.T) {
+	var received []byte
+	handler := Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Binary audio larger than the JSON limit is accepted on the audio endpoint
+	audio := bytes.Repeat([]byte{0x00, 0xFF}, 50*1024)
+	req := httptest.NewRequest("POST", AudioPath, bytes.NewReader(audio))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+	if !bytes.Equal(received, audio) {
+		t.Error("Audio body should be passed through unchanged")
+	}
+}
+
+func TestMiddleware_AudioTooLarge(t *testing.T) {
+	handler := Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called")
+	}))
+
+	audio := bytes.Repeat([]byte("a"), maxAudioBodySize+1)
+	req := httptest.NewRequest("POST", AudioPath, bytes.NewReader(audio))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d", rr.Code)
+	}
+}
+
+func TestMiddleware_AudioExactLimit(t *testing.T) {
+	var received int
+	handler := Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("Expected a body of exactly the limit to be readable, got %v", err)
+		}
+		received = len(body)
+	}))
+
+	req := httptest.NewRequest("POST", AudioPath, bytes.NewReader(bytes.Repeat([]byte("a"), maxAudioBodySize)))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if received != maxAudioBodySize {
+		t.Errorf("Expected %d bytes, got %d", maxAudioBodySize, received)
+	}
+}
+
+func TestMiddleware_AudioUndeclaredSize(t *testing.T) {
+	var readErr error
+	handler := Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+	}))
+
+	// A chunked upload declares no size: the body is capped while the handler reads it
+	req := httptest.NewRequest("POST", AudioPath, bytes.NewReader(bytes.Repeat([]byte("a"), maxAudioBodySize+1)))
+	req.ContentLength = -1
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var maxBytesErr *http.MaxBytesError
+	if !errors.As(readErr, &maxBytesErr) {
+		t.Errorf("Expected the body capped at the limit, got %v", readErr)
+	}
+}
+
+func TestMiddleware_AudioMethod(t *testing.T) {
+	handler := Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called")
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("PUT", AudioPath, bytes.NewReader([]byte("audio"))))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", rr.Code)
+	}
+}